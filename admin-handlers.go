@@ -0,0 +1,173 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+)
+
+// Maximum size of a bucket quota document.
+const maxBucketQuotaSize = 1024
+
+// isAdminReqAuthenticated - admin requests are only allowed with
+// signed requests using server credentials.
+func isAdminReqAuthenticated(r *http.Request) APIErrorCode {
+	switch getRequestAuthType(r) {
+	case authTypePresigned, authTypeSigned:
+		return isReqAuthenticated(r)
+	}
+	return ErrAccessDenied
+}
+
+// getBucketUsage - computes current usage of a bucket by listing all
+// objects, used only once while setting up the bucket quota.
+func getBucketUsage(objAPI ObjectLayer, bucket string) (usage bucketUsage, err error) {
+	marker := ""
+	for {
+		var result ListObjectsInfo
+		result, err = objAPI.ListObjects(bucket, "", marker, "", maxObjectList)
+		if err != nil {
+			return bucketUsage{}, err
+		}
+		for _, object := range result.Objects {
+			usage.Size += object.Size
+			usage.Objects++
+			marker = object.Name
+		}
+		if !result.IsTruncated {
+			break
+		}
+	}
+	return usage, nil
+}
+
+// PutBucketQuotaHandler - PUT Bucket quota
+// ----------
+// Sets a hard quota on a bucket, writes are rejected once the bucket
+// usage reaches the quota.
+func (admin adminAPIHandlers) PutBucketQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if s3Error := isAdminReqAuthenticated(r); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
+	// Usage is tracked by each node on its own, quota cannot be
+	// enforced when disks are spread over several nodes.
+	if globalIsDistXL {
+		writeErrorResponse(w, r, ErrBucketQuotaNotSupported, r.URL.Path)
+		return
+	}
+
+	// Verify if bucket exists.
+	if _, err := admin.ObjectAPI.GetBucketInfo(bucket); err != nil {
+		errorIf(err, "Unable to fetch bucket info.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+
+	quotaBuf, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBucketQuotaSize))
+	if err != nil {
+		errorIf(err, "Unable to read bucket quota.")
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	var quota bucketQuota
+	if err = json.Unmarshal(quotaBuf, &quota); err != nil {
+		writeErrorResponse(w, r, ErrInvalidBucketQuota, r.URL.Path)
+		return
+	}
+	if err = quota.Validate(); err != nil {
+		writeErrorResponse(w, r, ErrInvalidBucketQuota, r.URL.Path)
+		return
+	}
+
+	// Initialize bucket usage, only if the bucket doesn't have a quota
+	// already. Usage is tracked incrementally after this.
+	var usage bucketUsage
+	if _, err = globalBucketQuotas.get(bucket); err != nil {
+		usage, err = getBucketUsage(admin.ObjectAPI, bucket)
+		if err != nil {
+			errorIf(err, "Unable to compute bucket usage.")
+			writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+			return
+		}
+	}
+
+	// Save bucket quota.
+	if err = globalBucketQuotas.set(bucket, quota, usage); err != nil {
+		errorIf(err, "Unable to write bucket quota.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+	writeSuccessNoContent(w)
+}
+
+// GetBucketQuotaHandler - GET Bucket quota
+// ----------
+// Returns bucket quota along with the current bucket usage.
+func (admin adminAPIHandlers) GetBucketQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if s3Error := isAdminReqAuthenticated(r); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
+	info, err := globalBucketQuotas.get(bucket)
+	if err != nil {
+		errorIf(err, "Unable to read bucket quota.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+	infoBuf, err := json.Marshal(info)
+	if err != nil {
+		errorIf(err, "Unable to marshal bucket quota.")
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	writeSuccessResponse(w, infoBuf)
+}
+
+// DeleteBucketQuotaHandler - DELETE Bucket quota
+// ----------
+// Removes bucket quota, bucket usage is not tracked anymore.
+func (admin adminAPIHandlers) DeleteBucketQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	if s3Error := isAdminReqAuthenticated(r); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
+	if err := globalBucketQuotas.remove(bucket); err != nil {
+		errorIf(err, "Unable to remove bucket quota.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+	writeSuccessNoContent(w)
+}
@@ -0,0 +1,45 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import router "github.com/gorilla/mux"
+
+const (
+	// Admin API path prefix.
+	adminAPIPath = reservedBucket + "/admin"
+)
+
+// adminAPIHandlers implements and provides http handlers for admin API.
+type adminAPIHandlers struct {
+	ObjectAPI ObjectLayer
+}
+
+// registerAdminRouter - registers admin APIs, requests are
+// authenticated with server credentials.
+func registerAdminRouter(mux *router.Router, admin adminAPIHandlers) {
+	// Admin router
+	adminRouter := mux.NewRoute().PathPrefix(adminAPIPath).Subrouter()
+
+	/// Bucket quota operations
+
+	// GetBucketQuota
+	adminRouter.Methods("GET").Path("/quota/{bucket}").HandlerFunc(admin.GetBucketQuotaHandler)
+	// PutBucketQuota
+	adminRouter.Methods("PUT").Path("/quota/{bucket}").HandlerFunc(admin.PutBucketQuotaHandler)
+	// DeleteBucketQuota
+	adminRouter.Methods("DELETE").Path("/quota/{bucket}").HandlerFunc(admin.DeleteBucketQuotaHandler)
+}
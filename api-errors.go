@@ -61,9 +61,15 @@ const (
 	ErrInvalidMaxUploads
 	ErrInvalidMaxParts
 	ErrInvalidPartNumberMarker
+	ErrIncorrectContinuationToken
+	ErrInvalidPartNumber
+	ErrInvalidPartNumberRange
+	ErrPartNumberNotSatisfiable
 	ErrInvalidRequestBody
 	ErrInvalidCopySource
 	ErrInvalidCopyDest
+	ErrInvalidCopyPartRange
+	ErrInvalidMetadataDirective
 	ErrInvalidPolicyDocument
 	ErrMalformedXML
 	ErrMissingContentLength
@@ -103,6 +109,29 @@ const (
 	ErrInvalidQuerySignatureAlgo
 	ErrInvalidQueryParams
 	ErrBucketAlreadyOwnedByYou
+	ErrObjectLocked
+	ErrObjectLockConfigurationNotFound
+	ErrInvalidBucketState
+	ErrInvalidObjectLockMode
+	ErrNoSuchObjectLockConfiguration
+	ErrInvalidRetainUntilDate
+	ErrInvalidLegalHoldStatus
+	ErrObjectLockHeadersMissing
+	ErrReplicationConfigurationNotFound
+	ErrInvalidExpressionType
+	ErrInvalidCompressionFormat
+	ErrInvalidDataSource
+	ErrInvalidFileHeaderInfo
+	ErrInvalidJSONType
+	ErrInvalidQuoteFields
+	ErrInvalidRequestParameter
+	ErrUnsupportedSyntax
+	ErrInvalidChecksum
+	ErrInvalidChecksumAlgorithm
+	ErrMultipleChecksums
+	ErrChecksumMismatch
+	ErrMalformedTrailer
+	ErrInvalidRequest
 	// Add new error codes here.
 
 	// Minio extended errors.
@@ -111,6 +140,10 @@ const (
 	ErrStorageFull
 	ErrObjectExistsAsDirectory
 	ErrPolicyNesting
+	ErrBucketQuotaExceeded
+	ErrNoSuchBucketQuota
+	ErrInvalidBucketQuota
+	ErrBucketQuotaNotSupported
 )
 
 // error code to APIError structure, these fields carry respective
@@ -126,6 +159,16 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 		Description:    "Copy Source must mention the source bucket and key: sourcebucket/sourcekey.",
 		HTTPStatusCode: http.StatusBadRequest,
 	},
+	ErrInvalidCopyPartRange: {
+		Code:           "InvalidArgument",
+		Description:    "The x-amz-copy-source-range value must be of the form bytes=first-last where first and last are the zero-based offsets of the first and last bytes to copy.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidMetadataDirective: {
+		Code:           "InvalidArgument",
+		Description:    "Unknown metadata directive.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 	ErrInvalidRequestBody: {
 		Code:           "InvalidArgument",
 		Description:    "Body shouldn't be set for this request.",
@@ -151,6 +194,26 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 		Description:    "Argument partNumberMarker must be an integer.",
 		HTTPStatusCode: http.StatusBadRequest,
 	},
+	ErrIncorrectContinuationToken: {
+		Code:           "InvalidArgument",
+		Description:    "The continuation token provided is incorrect.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidPartNumber: {
+		Code:           "InvalidArgument",
+		Description:    "Part number must be an integer between 1 and 10000, inclusive.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidPartNumberRange: {
+		Code:           "InvalidRequest",
+		Description:    "Cannot specify both Range header and partNumber query parameter.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrPartNumberNotSatisfiable: {
+		Code:           "InvalidPartNumber",
+		Description:    "The requested partnumber is not satisfiable.",
+		HTTPStatusCode: http.StatusRequestedRangeNotSatisfiable,
+	},
 	ErrInvalidPolicyDocument: {
 		Code:           "InvalidPolicyDocument",
 		Description:    "The content of the form does not meet the conditions specified in the policy document.",
@@ -401,6 +464,121 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 		Description:    "Your previous request to create the named bucket succeeded and you already own it.",
 		HTTPStatusCode: http.StatusConflict,
 	},
+	ErrObjectLocked: {
+		Code:           "AccessDenied",
+		Description:    "Object is WORM protected and cannot be overwritten or deleted.",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrObjectLockConfigurationNotFound: {
+		Code:           "ObjectLockConfigurationNotFoundError",
+		Description:    "Object Lock configuration does not exist for this bucket.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidBucketState: {
+		Code:           "InvalidBucketState",
+		Description:    "Object Lock configuration cannot be enabled on existing buckets.",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrInvalidObjectLockMode: {
+		Code:           "InvalidArgument",
+		Description:    "Unknown wormMode directive.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchObjectLockConfiguration: {
+		Code:           "NoSuchObjectLockConfiguration",
+		Description:    "The specified object does not have a ObjectLock configuration.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidRetainUntilDate: {
+		Code:           "InvalidArgument",
+		Description:    "The retain until date must be in the future and in ISO 8601 format.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidLegalHoldStatus: {
+		Code:           "InvalidArgument",
+		Description:    "Legal Hold must be either of 'ON' or 'OFF'.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrObjectLockHeadersMissing: {
+		Code:           "InvalidRequest",
+		Description:    "Bucket is missing ObjectLockConfiguration, or both x-amz-object-lock-mode and x-amz-object-lock-retain-until-date must be specified.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrReplicationConfigurationNotFound: {
+		Code:           "ReplicationConfigurationNotFoundError",
+		Description:    "The replication configuration was not found.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidExpressionType: {
+		Code:           "InvalidExpressionType",
+		Description:    "The ExpressionType is invalid. Only SQL expressions are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidCompressionFormat: {
+		Code:           "InvalidCompressionFormat",
+		Description:    "The file is not in a supported compression format. Only GZIP is supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidDataSource: {
+		Code:           "InvalidDataSource",
+		Description:    "Invalid data source type. Only CSV and JSON are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidFileHeaderInfo: {
+		Code:           "InvalidFileHeaderInfo",
+		Description:    "The FileHeaderInfo is invalid. Only NONE, USE, and IGNORE are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidJSONType: {
+		Code:           "InvalidJsonType",
+		Description:    "The JsonType is invalid. Only DOCUMENT and LINES are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidQuoteFields: {
+		Code:           "InvalidQuoteFields",
+		Description:    "The QuoteFields is invalid. Only ALWAYS and ASNEEDED are supported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidRequestParameter: {
+		Code:           "InvalidRequestParameter",
+		Description:    "The value of a parameter in SelectRequest element is invalid or unsupported.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrUnsupportedSyntax: {
+		Code:           "UnsupportedSyntax",
+		Description:    "The SQL expression is invalid or uses unsupported syntax.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidChecksum: {
+		Code:           "InvalidRequest",
+		Description:    "Value for x-amz-checksum header is invalid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidChecksumAlgorithm: {
+		Code:           "InvalidRequest",
+		Description:    "Checksum algorithm provided is unsupported. Please try again with any of the valid types: [CRC32, CRC32C, SHA1, SHA256].",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrMultipleChecksums: {
+		Code:           "InvalidRequest",
+		Description:    "Expecting a single x-amz-checksum- header. Multiple checksum Types are not allowed.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrChecksumMismatch: {
+		Code:           "BadDigest",
+		Description:    "The checksum you specified did not match the calculated checksum.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrMalformedTrailer: {
+		Code:           "MalformedTrailerError",
+		Description:    "The request contained trailing data that was not well-formed or did not conform to our published schema.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidRequest: {
+		Code:           "InvalidRequest",
+		Description:    "Invalid Request",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
 	/// Minio extensions.
 	ErrStorageFull: {
 		Code:           "XMinioStorageFull",
@@ -427,6 +605,26 @@ var errorCodeResponse = map[APIErrorCode]APIError{
 		Description:    "Policy nesting conflict has occurred.",
 		HTTPStatusCode: http.StatusConflict,
 	},
+	ErrBucketQuotaExceeded: {
+		Code:           "XMinioBucketQuotaExceeded",
+		Description:    "Bucket quota exceeded, please delete few objects or increase the quota to proceed.",
+		HTTPStatusCode: http.StatusForbidden,
+	},
+	ErrNoSuchBucketQuota: {
+		Code:           "XMinioNoSuchBucketQuota",
+		Description:    "The specified bucket does not have a bucket quota.",
+		HTTPStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidBucketQuota: {
+		Code:           "XMinioInvalidBucketQuota",
+		Description:    "The bucket quota you provided is not valid.",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrBucketQuotaNotSupported: {
+		Code:           "XMinioBucketQuotaNotSupported",
+		Description:    "Bucket quota is not supported on distributed setups.",
+		HTTPStatusCode: http.StatusNotImplemented,
+	},
 	// Add your error structure here.
 }
 
@@ -441,11 +639,16 @@ func toAPIErrorCode(err error) (apiErr APIErrorCode) {
 	if err == errSignatureMismatch {
 		return ErrSignatureDoesNotMatch
 	}
+	if err == errMalformedTrailer {
+		return ErrMalformedTrailer
+	}
 	switch err.(type) {
 	case StorageFull:
 		apiErr = ErrStorageFull
 	case BadDigest:
 		apiErr = ErrBadDigest
+	case BadChecksum:
+		apiErr = ErrChecksumMismatch
 	case IncompleteBody:
 		apiErr = ErrIncompleteBody
 	case ObjectExistsAsDirectory:
@@ -472,6 +675,20 @@ func toAPIErrorCode(err error) (apiErr APIErrorCode) {
 		apiErr = ErrReadQuorum
 	case PartTooSmall:
 		apiErr = ErrEntityTooSmall
+	case ObjectLocked:
+		apiErr = ErrObjectLocked
+	case PrefixAccessDenied:
+		apiErr = ErrAccessDenied
+	case NotImplemented:
+		apiErr = ErrNotImplemented
+	case BucketObjectLockConfigNotFound:
+		apiErr = ErrObjectLockConfigurationNotFound
+	case BucketQuotaExceeded:
+		apiErr = ErrBucketQuotaExceeded
+	case BucketQuotaNotFound:
+		apiErr = ErrNoSuchBucketQuota
+	case BucketReplicationConfigNotFound:
+		apiErr = ErrReplicationConfigurationNotFound
 	default:
 		apiErr = ErrInternalError
 	}
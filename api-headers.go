@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"runtime"
 	"strconv"
+	"strings"
 )
 
 //// helpers
@@ -57,7 +58,8 @@ func encodeResponse(response interface{}) []byte {
 	return bytesBuffer.Bytes()
 }
 
-// Write object header
+// Write object header, the status is written by the caller once the
+// request preconditions are verified.
 func setObjectHeaders(w http.ResponseWriter, objInfo ObjectInfo, contentRange *httpRange) {
 	// set common headers
 	setCommonHeaders(w)
@@ -67,19 +69,47 @@ func setObjectHeaders(w http.ResponseWriter, objInfo ObjectInfo, contentRange *h
 	w.Header().Set("Last-Modified", lastModified)
 
 	w.Header().Set("Content-Type", objInfo.ContentType)
+	if objInfo.ContentEncoding != "" {
+		w.Header().Set("Content-Encoding", objInfo.ContentEncoding)
+	}
 	if objInfo.MD5Sum != "" {
 		w.Header().Set("ETag", "\""+objInfo.MD5Sum+"\"")
 	}
 
+	// Set saved content headers and user metadata if any.
+	for _, key := range objectContentHeaders {
+		if value, ok := objInfo.UserDefined[strings.ToLower(key)]; ok {
+			w.Header().Set(key, value)
+		}
+	}
+	for key, value := range objInfo.UserDefined {
+		if isUserMetadataKey(key) {
+			w.Header().Set(key, value)
+		}
+	}
+
+	// Set object lock headers if any.
+	for key, value := range getObjectLockMetadata(objInfo.UserDefined) {
+		w.Header().Set(key, value)
+	}
+
+	// Set replication status if any.
+	if status := getReplicationStatus(objInfo); status != "" {
+		w.Header().Set(replicationStatusKey, status)
+	}
+
 	w.Header().Set("Content-Length", strconv.FormatInt(objInfo.Size, 10))
 
 	// for providing ranged content
-	if contentRange != nil {
-		if contentRange.start > 0 || contentRange.length > 0 {
-			// override content-length
-			w.Header().Set("Content-Length", strconv.FormatInt(contentRange.length, 10))
-			w.Header().Set("Content-Range", contentRange.String())
-			w.WriteHeader(http.StatusPartialContent)
-		}
+	if isPartialContent(contentRange) {
+		// override content-length
+		w.Header().Set("Content-Length", strconv.FormatInt(contentRange.length, 10))
+		w.Header().Set("Content-Range", contentRange.String())
 	}
 }
+
+// isPartialContent - returns true if the range is only a part of the
+// object, replied with 206 (partial content).
+func isPartialContent(contentRange *httpRange) bool {
+	return contentRange != nil && (contentRange.start > 0 || contentRange.length > 0)
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"encoding/base64"
 	"net/url"
 	"strconv"
 )
@@ -36,10 +37,11 @@ func getListObjectsV1Args(values url.Values) (prefix, marker, delimiter string,
 }
 
 // Parse bucket url queries for ListObjects V2.
-func getListObjectsV2Args(values url.Values) (prefix, token, startAfter, delimiter string, maxkeys int, encodingType string) {
+func getListObjectsV2Args(values url.Values) (prefix, token, startAfter, delimiter string, fetchOwner bool, maxkeys int, encodingType string) {
 	prefix = values.Get("prefix")
 	startAfter = values.Get("start-after")
 	delimiter = values.Get("delimiter")
+	fetchOwner = values.Get("fetch-owner") == "true"
 	if values.Get("max-keys") != "" {
 		maxkeys, _ = strconv.Atoi(values.Get("max-keys"))
 	} else {
@@ -50,6 +52,23 @@ func getListObjectsV2Args(values url.Values) (prefix, token, startAfter, delimit
 	return
 }
 
+// encodeContinuationToken - returns an opaque ListObjects V2 continuation
+// token for the marker the listing resumes from, the same marker tree
+// walks are saved with in the list pool.
+func encodeContinuationToken(marker string) string {
+	return base64.StdEncoding.EncodeToString([]byte(marker))
+}
+
+// decodeContinuationToken - returns the marker encoded in a ListObjects
+// V2 continuation token.
+func decodeContinuationToken(token string) (string, error) {
+	marker, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return "", err
+	}
+	return string(marker), nil
+}
+
 // Parse bucket url queries
 func getBucketResources(values url.Values) (listType int, prefix, marker, delimiter string, maxkeys int, encodingType string) {
 	if values.Get("list-type") != "" {
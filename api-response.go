@@ -85,13 +85,13 @@ type ListObjectsV2Response struct {
 	MaxKeys     int
 	Name        string
 
+	// Number of keys and common prefixes included in the response.
+	KeyCount int
+
 	// When response is truncated (the IsTruncated element value in the response
-	// is true), you can use the key name in this field as marker in the subsequent
-	// request to get next set of objects. Server lists objects in alphabetical
-	// order Note: This element is returned only if you have delimiter request parameter
-	// specified. If response does not include the NextMaker and it is truncated,
-	// you can use the value of the last Key in the response as the marker in the
-	// subsequent request to get the next set of object keys.
+	// is true), NextContinuationToken is sent as continuation-token in the
+	// subsequent request to get next set of objects. Tokens are opaque to the
+	// client.
 	ContinuationToken     string
 	NextContinuationToken string
 	Prefix                string
@@ -103,6 +103,12 @@ type Part struct {
 	ETag         string
 	LastModified string
 	Size         int64
+
+	// Checksum of the part, of the checksum algorithm of the upload.
+	ChecksumCRC32  string `xml:",omitempty"`
+	ChecksumCRC32C string `xml:",omitempty"`
+	ChecksumSHA1   string `xml:",omitempty"`
+	ChecksumSHA256 string `xml:",omitempty"`
 }
 
 // ListPartsResponse - format for list parts response.
@@ -124,6 +130,9 @@ type ListPartsResponse struct {
 	MaxParts             int
 	IsTruncated          bool
 
+	// Checksum algorithm of the parts, if any.
+	ChecksumAlgorithm string `xml:",omitempty"`
+
 	// List of parts.
 	Parts []Part `xml:"Part"`
 }
@@ -184,7 +193,8 @@ type Object struct {
 	LastModified string // time string of format "2006-01-02T15:04:05.000Z"
 	Size         int64
 
-	Owner Owner
+	// Owner is not sent by ListObjects V2 unless requested.
+	Owner *Owner
 
 	// The class of storage used to store the object.
 	StorageClass string
@@ -198,6 +208,14 @@ type CopyObjectResponse struct {
 	LastModified string // time string of format "2006-01-02T15:04:05.000Z"
 }
 
+// CopyObjectPartResponse container returns ETag and LastModified of the
+// successfully copied object part
+type CopyObjectPartResponse struct {
+	XMLName      xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CopyPartResult" json:"-"`
+	ETag         string
+	LastModified string // time string of format "2006-01-02T15:04:05.000Z"
+}
+
 // Initiator inherit from Owner struct, fields are same
 type Initiator Owner
 
@@ -313,7 +331,7 @@ func generateListObjectsResponse(bucket, prefix, marker, delimiter string, maxKe
 		}
 		content.Size = object.Size
 		content.StorageClass = "STANDARD"
-		content.Owner = owner
+		content.Owner = &owner
 		contents = append(contents, content)
 	}
 	// TODO - support EncodingType in xml decoding
@@ -336,8 +354,8 @@ func generateListObjectsResponse(bucket, prefix, marker, delimiter string, maxKe
 	return data
 }
 
-// generates an ListObjects response for the said bucket with other enumerated options.
-func generateListObjectsV2Response(bucket, prefix, token, startAfter, delimiter string, maxKeys int, resp ListObjectsInfo) ListObjectsV2Response {
+// generates an ListObjects V2 response for the said bucket with other enumerated options.
+func generateListObjectsV2Response(bucket, prefix, token, startAfter, delimiter string, fetchOwner bool, maxKeys int, resp ListObjectsInfo) ListObjectsV2Response {
 	var contents []Object
 	var prefixes []CommonPrefix
 	var owner = Owner{}
@@ -358,7 +376,9 @@ func generateListObjectsV2Response(bucket, prefix, token, startAfter, delimiter
 		}
 		content.Size = object.Size
 		content.StorageClass = "STANDARD"
-		content.Owner = owner
+		if fetchOwner {
+			content.Owner = &owner
+		}
 		contents = append(contents, content)
 	}
 	// TODO - support EncodingType in xml decoding
@@ -370,14 +390,23 @@ func generateListObjectsV2Response(bucket, prefix, token, startAfter, delimiter
 	data.Prefix = prefix
 	data.MaxKeys = maxKeys
 	data.ContinuationToken = token
-	data.NextContinuationToken = resp.NextMarker
 	data.IsTruncated = resp.IsTruncated
+	if resp.IsTruncated {
+		// Listing resumes after the last entry, which is not always
+		// returned as NextMarker without a delimiter.
+		nextMarker := resp.NextMarker
+		if nextMarker == "" && len(resp.Objects) > 0 {
+			nextMarker = resp.Objects[len(resp.Objects)-1].Name
+		}
+		data.NextContinuationToken = encodeContinuationToken(nextMarker)
+	}
 	for _, prefix := range resp.Prefixes {
 		var prefixItem = CommonPrefix{}
 		prefixItem.Prefix = prefix
 		prefixes = append(prefixes, prefixItem)
 	}
 	data.CommonPrefixes = prefixes
+	data.KeyCount = len(contents) + len(prefixes)
 	return data
 }
 
@@ -389,6 +418,14 @@ func generateCopyObjectResponse(etag string, lastModified time.Time) CopyObjectR
 	}
 }
 
+// generateCopyObjectPartResponse
+func generateCopyObjectPartResponse(etag string, lastModified time.Time) CopyObjectPartResponse {
+	return CopyObjectPartResponse{
+		ETag:         "\"" + etag + "\"",
+		LastModified: lastModified.UTC().Format(timeFormatAMZ),
+	}
+}
+
 // generateInitiateMultipartUploadResponse
 func generateInitiateMultipartUploadResponse(bucket, key, uploadID string) InitiateMultipartUploadResponse {
 	return InitiateMultipartUploadResponse{
@@ -425,6 +462,7 @@ func generateListPartsResponse(partsInfo ListPartsInfo) ListPartsResponse {
 	listPartsResponse.PartNumberMarker = partsInfo.PartNumberMarker
 	listPartsResponse.IsTruncated = partsInfo.IsTruncated
 	listPartsResponse.NextPartNumberMarker = partsInfo.NextPartNumberMarker
+	listPartsResponse.ChecksumAlgorithm = partsInfo.ChecksumAlgorithm
 
 	listPartsResponse.Parts = make([]Part, len(partsInfo.Parts))
 	for index, part := range partsInfo.Parts {
@@ -433,6 +471,16 @@ func generateListPartsResponse(partsInfo ListPartsInfo) ListPartsResponse {
 		newPart.ETag = "\"" + part.ETag + "\""
 		newPart.Size = part.Size
 		newPart.LastModified = part.LastModified.UTC().Format(timeFormatAMZ)
+		switch partsInfo.ChecksumAlgorithm {
+		case "CRC32":
+			newPart.ChecksumCRC32 = part.Checksum
+		case "CRC32C":
+			newPart.ChecksumCRC32C = part.Checksum
+		case "SHA1":
+			newPart.ChecksumSHA1 = part.Checksum
+		case "SHA256":
+			newPart.ChecksumSHA256 = part.Checksum
+		}
 		listPartsResponse.Parts[index] = newPart
 	}
 	return listPartsResponse
@@ -31,9 +31,23 @@ import (
 // client did not calculate sha256 of the payload.
 const unsignedPayload = "UNSIGNED-PAYLOAD"
 
+// Payload hash of aws-chunked bodies of unsigned payload with trailers.
+const unsignedPayloadTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+
 // Verify if the request http Header "x-amz-content-sha256" == "UNSIGNED-PAYLOAD"
+// or "STREAMING-UNSIGNED-PAYLOAD-TRAILER".
 func isRequestUnsignedPayload(r *http.Request) bool {
-	return r.Header.Get("x-amz-content-sha256") == unsignedPayload
+	shaHeader := r.Header.Get("x-amz-content-sha256")
+	return shaHeader == unsignedPayload || shaHeader == unsignedPayloadTrailer
+}
+
+// getUnsignedPayload - returns the payload hash signed by requests of
+// unsigned payload.
+func getUnsignedPayload(r *http.Request) string {
+	if r.Header.Get("x-amz-content-sha256") == unsignedPayloadTrailer {
+		return unsignedPayloadTrailer
+	}
+	return unsignedPayload
 }
 
 // Verify if request has JWT.
@@ -137,7 +151,7 @@ func isReqAuthenticated(r *http.Request) (s3Error APIErrorCode) {
 	validateRegion := true // Validate region.
 	var sha256sum string
 	if skipSHA256Calculation(r) {
-		sha256sum = unsignedPayload
+		sha256sum = getUnsignedPayload(r)
 	} else {
 		sha256sum = hex.EncodeToString(sum256(payload))
 	}
@@ -169,6 +183,11 @@ func (a authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		a.handler.ServeHTTP(w, r)
 		return
 	case authTypeJWT:
+		// Storage rpc tokens are validated by the storage rpc server.
+		if strings.HasPrefix(r.URL.Path, storageRPCPath+"/") {
+			a.handler.ServeHTTP(w, r)
+			return
+		}
 		// Validate Authorization header if its valid for JWT request.
 		if !isJWTReqAuthenticated(r) {
 			w.WriteHeader(http.StatusUnauthorized)
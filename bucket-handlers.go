@@ -24,15 +24,17 @@ import (
 	"io"
 	"io/ioutil"
 	"mime/multipart"
+	"net"
 	"net/http"
-	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	mux "github.com/gorilla/mux"
 )
 
 // http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
-func enforceBucketPolicy(action string, bucket string, reqURL *url.URL) (s3Error APIErrorCode) {
+func enforceBucketPolicy(action string, bucket string, r *http.Request) (s3Error APIErrorCode) {
 	// Read saved bucket policy.
 	policy, err := readBucketPolicy(bucket)
 	if err != nil {
@@ -54,14 +56,16 @@ func enforceBucketPolicy(action string, bucket string, reqURL *url.URL) (s3Error
 		return ErrAccessDenied
 	}
 
-	// Construct resource in 'arn:aws:s3:::examplebucket/object' format.
-	resource := AWSResourcePrefix + strings.TrimPrefix(reqURL.Path, "/")
+	// Construct resource in 'arn:aws:s3:::examplebucket/object' format,
+	// requests on the bucket itself may end with a slash.
+	resourcePath := strings.TrimPrefix(r.URL.Path, "/")
+	if resourcePath == bucket+"/" {
+		resourcePath = bucket
+	}
+	resource := AWSResourcePrefix + resourcePath
 
 	// Get conditions for policy verification.
-	conditions := make(map[string]string)
-	for queryParam := range reqURL.Query() {
-		conditions[queryParam] = reqURL.Query().Get(queryParam)
-	}
+	conditions := getPolicyConditionValues(r)
 
 	// Validate action, resource and conditions with current policy statements.
 	if !bucketPolicyEvalStatements(action, resource, conditions, bucketPolicy.Statements) {
@@ -70,6 +74,26 @@ func enforceBucketPolicy(action string, bucket string, reqURL *url.URL) (s3Error
 	return ErrNone
 }
 
+// getPolicyConditionValues - collects values of all the supported
+// policy condition keys from the incoming request.
+func getPolicyConditionValues(r *http.Request) map[string]string {
+	// Source IP is always the address of the remote peer,
+	// forwarded headers are not trusted here.
+	sourceIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		sourceIP = r.RemoteAddr
+	}
+	queryValues := r.URL.Query()
+	return map[string]string{
+		"s3:prefix":           queryValues.Get("prefix"),
+		"s3:max-keys":         queryValues.Get("max-keys"),
+		"aws:SourceIp":        sourceIP,
+		"aws:CurrentTime":     time.Now().UTC().Format(time.RFC3339),
+		"aws:SecureTransport": strconv.FormatBool(r.TLS != nil),
+		"aws:Referer":         r.Header.Get("Referer"),
+	}
+}
+
 // GetBucketLocationHandler - GET Bucket location.
 // -------------------------
 // This operation returns bucket location.
@@ -84,7 +108,7 @@ func (api objectAPIHandlers) GetBucketLocationHandler(w http.ResponseWriter, r *
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
-		if s3Error := enforceBucketPolicy("s3:GetBucketLocation", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:GetBucketLocation", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -154,7 +178,7 @@ func (api objectAPIHandlers) ListMultipartUploadsHandler(w http.ResponseWriter,
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/mpuAndPermissions.html
-		if s3Error := enforceBucketPolicy("s3:ListBucketMultipartUploads", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:ListBucketMultipartUploads", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -210,7 +234,7 @@ func (api objectAPIHandlers) ListObjectsHandler(w http.ResponseWriter, r *http.R
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
-		if s3Error := enforceBucketPolicy("s3:ListBucket", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:ListBucket", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -220,31 +244,12 @@ func (api objectAPIHandlers) ListObjectsHandler(w http.ResponseWriter, r *http.R
 			return
 		}
 	}
-	var prefix, marker, token, delimiter, startAfter string
-	var maxkeys int
-	var listV2 bool
 	// TODO handle encoding type.
-	if r.URL.Query().Get("list-type") == "2" {
-		listV2 = true
-		prefix, token, startAfter, delimiter, maxkeys, _ = getListObjectsV2Args(r.URL.Query())
-		// For ListV2 "start-after" is considered only if "continuation-token" is empty.
-		if token == "" {
-			marker = startAfter
-		} else {
-			marker = token
-		}
-	} else {
-		prefix, marker, delimiter, maxkeys, _ = getListObjectsV1Args(r.URL.Query())
-	}
+	prefix, marker, delimiter, maxkeys, _ := getListObjectsV1Args(r.URL.Query())
 	if maxkeys < 0 {
 		writeErrorResponse(w, r, ErrInvalidMaxKeys, r.URL.Path)
 		return
 	}
-	// Verify if delimiter is anything other than '/', which we do not support.
-	if delimiter != "" && delimiter != "/" {
-		writeErrorResponse(w, r, ErrNotImplemented, r.URL.Path)
-		return
-	}
 	// If marker is set unescape.
 	if marker != "" {
 		// Marker not common with prefix is not implemented.
@@ -257,15 +262,9 @@ func (api objectAPIHandlers) ListObjectsHandler(w http.ResponseWriter, r *http.R
 	listObjectsInfo, err := api.ObjectAPI.ListObjects(bucket, prefix, marker, delimiter, maxkeys)
 
 	if err == nil {
-		var encodedSuccessResponse []byte
 		// generate response
-		if listV2 {
-			response := generateListObjectsV2Response(bucket, prefix, token, startAfter, delimiter, maxkeys, listObjectsInfo)
-			encodedSuccessResponse = encodeResponse(response)
-		} else {
-			response := generateListObjectsResponse(bucket, prefix, marker, delimiter, maxkeys, listObjectsInfo)
-			encodedSuccessResponse = encodeResponse(response)
-		}
+		response := generateListObjectsResponse(bucket, prefix, marker, delimiter, maxkeys, listObjectsInfo)
+		encodedSuccessResponse := encodeResponse(response)
 		// Write headers
 		setCommonHeaders(w)
 		// Write success response.
@@ -276,6 +275,77 @@ func (api objectAPIHandlers) ListObjectsHandler(w http.ResponseWriter, r *http.R
 	writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
 }
 
+// ListObjectsV2Handler - GET Bucket (List Objects) Version 2
+// -- -----------------------
+// This implementation of the GET operation returns some or all (up to 1000)
+// of the objects in a bucket, listing is resumed with the continuation
+// token of the previous response.
+//
+func (api objectAPIHandlers) ListObjectsV2Handler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypeAnonymous:
+		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
+		if s3Error := enforceBucketPolicy("s3:ListBucket", bucket, r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	case authTypeSigned, authTypePresigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+	// TODO handle encoding type.
+	prefix, token, startAfter, delimiter, fetchOwner, maxkeys, _ := getListObjectsV2Args(r.URL.Query())
+	if maxkeys < 0 {
+		writeErrorResponse(w, r, ErrInvalidMaxKeys, r.URL.Path)
+		return
+	}
+
+	// "start-after" is considered only if "continuation-token" is empty.
+	marker := startAfter
+	if token != "" {
+		var err error
+		marker, err = decodeContinuationToken(token)
+		if err != nil || !strings.HasPrefix(marker, prefix) {
+			writeErrorResponse(w, r, ErrIncorrectContinuationToken, r.URL.Path)
+			return
+		}
+	}
+
+	var listObjectsInfo ListObjectsInfo
+	var err error
+	switch {
+	case strings.HasPrefix(marker, prefix):
+		listObjectsInfo, err = api.ObjectAPI.ListObjects(bucket, prefix, marker, delimiter, maxkeys)
+	case marker < prefix:
+		// All objects at prefix sort after "start-after".
+		listObjectsInfo, err = api.ObjectAPI.ListObjects(bucket, prefix, "", delimiter, maxkeys)
+	default:
+		// All objects at prefix sort before "start-after", nothing to list.
+	}
+	if err != nil {
+		errorIf(err, "Unable to list objects.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+
+	// generate response
+	response := generateListObjectsV2Response(bucket, prefix, token, startAfter, delimiter, fetchOwner, maxkeys, listObjectsInfo)
+	encodedSuccessResponse := encodeResponse(response)
+	// Write headers
+	setCommonHeaders(w)
+	// Write success response.
+	writeSuccessResponse(w, encodedSuccessResponse)
+}
+
 // ListBucketsHandler - GET Service
 // -----------
 // This implementation of the GET operation returns a list of all buckets
@@ -342,7 +412,7 @@ func (api objectAPIHandlers) DeleteMultipleObjectsHandler(w http.ResponseWriter,
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
-		if s3Error := enforceBucketPolicy("s3:DeleteObject", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:DeleteObject", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -387,13 +457,23 @@ func (api objectAPIHandlers) DeleteMultipleObjectsHandler(w http.ResponseWriter,
 
 	var deleteErrors []DeleteError
 	var deletedObjects []ObjectIdentifier
+	// Owner can delete objects under governance retention.
+	bypassGovernance := isBypassGovernanceRequest(r)
 	// Loop through all the objects and delete them sequentially.
 	for _, object := range deleteObjects.Objects {
-		err := api.ObjectAPI.DeleteObject(bucket, object.ObjectName)
+		var err error
+		if bypassGovernance {
+			err = api.removeGovernanceRetention(bucket, object.ObjectName)
+		}
+		if err == nil {
+			err = api.ObjectAPI.DeleteObject(bucket, object.ObjectName)
+		}
 		if err == nil {
 			deletedObjects = append(deletedObjects, ObjectIdentifier{
 				ObjectName: object.ObjectName,
 			})
+			// Queue the delete for replication.
+			queueReplication(bucket, object.ObjectName, r)
 		} else {
 			errorIf(err, "Unable to delete object.")
 			deleteErrors = append(deleteErrors, DeleteError{
@@ -447,12 +527,22 @@ func (api objectAPIHandlers) PutBucketHandler(w http.ResponseWriter, r *http.Req
 		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
 		return
 	}
+	// Enable object lock if requested, object lock can only be
+	// enabled while creating a bucket.
+	if strings.EqualFold(r.Header.Get(bucketObjectLockEnabledHeader), "true") {
+		config := objectLockConfiguration{ObjectLockEnabled: objectLockEnabled}
+		if err = writeBucketObjectLockConfig(bucket, config); err != nil {
+			errorIf(err, "Unable to enable object lock on bucket.")
+			writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+			return
+		}
+	}
 	// Make sure to add Location information here only for bucket
 	w.Header().Set("Location", getLocation(r))
 	writeSuccessResponse(w, nil)
 }
 
-func extractHTTPFormValues(reader *multipart.Reader) (io.Reader, map[string]string, error) {
+func extractHTTPFormValues(reader *multipart.Reader) (*bytes.Buffer, map[string]string, error) {
 	/// HTML Form values
 	formValues := make(map[string]string)
 	filePart := new(bytes.Buffer)
@@ -508,14 +598,13 @@ func (api objectAPIHandlers) PostPolicyBucketHandler(w http.ResponseWriter, r *h
 		writeErrorResponse(w, r, apiErr, r.URL.Path)
 		return
 	}
-	if apiErr = checkPostPolicy(formValues); apiErr != ErrNone {
+	if apiErr = checkPostPolicy(formValues, int64(fileBody.Len())); apiErr != ErrNone {
 		writeErrorResponse(w, r, apiErr, r.URL.Path)
 		return
 	}
 
-	// Save metadata.
-	metadata := make(map[string]string)
-	// Nothing to store right now.
+	// Save metadata, mark the object for replication if any.
+	metadata := getReplicationMetadata(bucket, object, nil)
 
 	md5Sum, err := api.ObjectAPI.PutObject(bucket, object, -1, fileBody, metadata)
 	if err != nil {
@@ -523,6 +612,8 @@ func (api objectAPIHandlers) PostPolicyBucketHandler(w http.ResponseWriter, r *h
 		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
 		return
 	}
+	// Queue the new object for replication.
+	queueReplication(bucket, object, nil)
 	if md5Sum != "" {
 		w.Header().Set("ETag", "\""+md5Sum+"\"")
 	}
@@ -553,7 +644,7 @@ func (api objectAPIHandlers) HeadBucketHandler(w http.ResponseWriter, r *http.Re
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
-		if s3Error := enforceBucketPolicy("s3:ListBucket", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:ListBucket", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -582,6 +673,12 @@ func (api objectAPIHandlers) DeleteBucketHandler(w http.ResponseWriter, r *http.
 		// For all unknown auth types return error.
 		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
 		return
+	case authTypeAnonymous:
+		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
+		if s3Error := enforceBucketPolicy("s3:DeleteBucket", bucket, r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
 	case authTypePresigned, authTypeSigned:
 		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
@@ -598,6 +695,15 @@ func (api objectAPIHandlers) DeleteBucketHandler(w http.ResponseWriter, r *http.
 	// Delete bucket access policy, if present - ignore any errors.
 	removeBucketPolicy(bucket)
 
+	// Delete bucket object lock configuration, if present - ignore any errors.
+	removeBucketObjectLockConfig(bucket)
+
+	// Delete bucket quota, if present - ignore any errors.
+	globalBucketQuotas.remove(bucket)
+
+	// Delete bucket replication configuration, if present - ignore any errors.
+	removeBucketReplicationConfig(bucket)
+
 	// Write success response.
 	writeSuccessNoContent(w)
 }
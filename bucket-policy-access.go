@@ -0,0 +1,311 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// bucketAccessPolicy - canned access of anonymous users to the objects
+// of a prefix of a bucket.
+type bucketAccessPolicy string
+
+// Canned access policies.
+const (
+	accessPolicyNone      bucketAccessPolicy = "none"
+	accessPolicyReadOnly  bucketAccessPolicy = "readonly"
+	accessPolicyWriteOnly bucketAccessPolicy = "writeonly"
+	accessPolicyReadWrite bucketAccessPolicy = "readwrite"
+)
+
+// Read write actions.
+var (
+	readWriteBucketActions = []string{
+		"s3:GetBucketLocation",
+		"s3:ListBucket",
+		"s3:ListBucketMultipartUploads",
+		// Add more bucket level read-write actions here.
+	}
+	readWriteObjectActions = []string{
+		"s3:AbortMultipartUpload",
+		"s3:DeleteObject",
+		"s3:GetObject",
+		"s3:ListMultipartUploadParts",
+		"s3:PutObject",
+		// Add more object level read-write actions here.
+	}
+)
+
+// Write only actions.
+var (
+	writeOnlyBucketActions = []string{
+		"s3:GetBucketLocation",
+		"s3:ListBucketMultipartUploads",
+		// Add more bucket level write actions here.
+	}
+	writeOnlyObjectActions = []string{
+		"s3:AbortMultipartUpload",
+		"s3:DeleteObject",
+		"s3:ListMultipartUploadParts",
+		"s3:PutObject",
+		// Add more object level write actions here.
+	}
+)
+
+// Read only actions.
+var (
+	readOnlyBucketActions = []string{
+		"s3:GetBucketLocation",
+		"s3:ListBucket",
+		// Add more bucket level read actions here.
+	}
+	readOnlyObjectActions = []string{
+		"s3:GetObject",
+		// Add more object level read actions here.
+	}
+)
+
+// isValidBucketAccessPolicy - verifies a canned access policy.
+func isValidBucketAccessPolicy(access bucketAccessPolicy) bool {
+	switch access {
+	case accessPolicyNone, accessPolicyReadOnly, accessPolicyWriteOnly, accessPolicyReadWrite:
+		return true
+	}
+	return false
+}
+
+// isSameActions - verifies if two lists have the same actions.
+func isSameActions(actions, expectedActions []string) bool {
+	if len(actions) != len(expectedActions) {
+		return false
+	}
+	for _, action := range expectedActions {
+		if !contains(actions, action) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSameConditions - verifies if two statements have the same conditions.
+func isSameConditions(conditions, expectedConditions map[string]map[string]string) bool {
+	if len(conditions) == 0 && len(expectedConditions) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(conditions, expectedConditions)
+}
+
+// isCannedStatement - verifies if a statement is one of the statements
+// of canned access policies of a bucket, as getCannedStatements returns
+// them. Bucket statements are canned only if they are among the canned
+// statements of the policies found in the bucket policy, which are
+// passed in cannedStatements. Other statements are kept as they are.
+func isCannedStatement(bucket string, statement policyStatement, cannedStatements []policyStatement) bool {
+	if statement.Sid != "" || statement.Effect != "Allow" || len(statement.Principal.AWS) != 1 || statement.Principal.AWS[0] != "*" {
+		return false
+	}
+	if len(statement.Resources) != 1 {
+		return false
+	}
+	bucketResource := AWSResourcePrefix + bucket
+	resource := statement.Resources[0]
+
+	// Bucket actions and listing of readable prefixes, hand-written
+	// statements on the bucket are kept.
+	if resource == bucketResource {
+		for _, cannedStatement := range cannedStatements {
+			if cannedStatement.Resources[0] == bucketResource &&
+				isSameActions(statement.Actions, cannedStatement.Actions) &&
+				isSameConditions(statement.Conditions, cannedStatement.Conditions) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(statement.Conditions) != 0 {
+		return false
+	}
+
+	// Object actions of a prefix.
+	if !strings.HasPrefix(resource, bucketResource+"/") || !strings.HasSuffix(resource, "*") {
+		return false
+	}
+	return isSameActions(statement.Actions, readOnlyObjectActions) ||
+		isSameActions(statement.Actions, writeOnlyObjectActions) ||
+		isSameActions(statement.Actions, readWriteObjectActions)
+}
+
+// getBucketAccessPolicies - returns canned access policies of prefixes
+// of a bucket from its policy statements. Objects are readable with
+// s3:GetObject and writable with s3:PutObject.
+func getBucketAccessPolicies(bucket string, policy BucketPolicy) map[string]bucketAccessPolicy {
+	var readable, writable = make(map[string]bool), make(map[string]bool)
+	for _, statement := range policy.Statements {
+		// Only object statements tell the policy of a prefix.
+		if !isCannedStatement(bucket, statement, nil) {
+			continue
+		}
+		for _, resource := range statement.Resources {
+			objectResource := strings.TrimPrefix(resource, AWSResourcePrefix+bucket+"/")
+			if objectResource == resource || !strings.HasSuffix(objectResource, "*") {
+				continue
+			}
+			prefix := strings.TrimSuffix(objectResource, "*")
+			if contains(statement.Actions, "s3:GetObject") {
+				readable[prefix] = true
+			}
+			if contains(statement.Actions, "s3:PutObject") {
+				writable[prefix] = true
+			}
+		}
+	}
+	policies := make(map[string]bucketAccessPolicy)
+	for prefix := range readable {
+		policies[prefix] = accessPolicyReadOnly
+	}
+	for prefix := range writable {
+		if readable[prefix] {
+			policies[prefix] = accessPolicyReadWrite
+		} else {
+			policies[prefix] = accessPolicyWriteOnly
+		}
+	}
+	return policies
+}
+
+// getCannedStatements - returns policy statements of canned access
+// policies of prefixes of a bucket. Listing of readable prefixes is
+// allowed with a condition on the listed prefix.
+func getCannedStatements(bucket string, policies map[string]bucketAccessPolicy) []policyStatement {
+	var prefixes []string
+	for prefix := range policies {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	newStatement := func(actions []string, resource string) policyStatement {
+		statement := policyStatement{}
+		statement.Effect = "Allow"
+		statement.Principal.AWS = []string{"*"}
+		statement.Actions = actions
+		statement.Resources = []string{AWSResourcePrefix + resource}
+		return statement
+	}
+
+	var statements, listStatements []policyStatement
+	var bucketActions = []string{"s3:GetBucketLocation"}
+	for _, prefix := range prefixes {
+		access := policies[prefix]
+		var objectActions []string
+		switch access {
+		case accessPolicyReadOnly:
+			objectActions = readOnlyObjectActions
+		case accessPolicyWriteOnly:
+			objectActions = writeOnlyObjectActions
+		case accessPolicyReadWrite:
+			objectActions = readWriteObjectActions
+		default:
+			continue
+		}
+		statements = append(statements, newStatement(objectActions, bucket+"/"+prefix+"*"))
+		if access != accessPolicyReadOnly && !contains(bucketActions, "s3:ListBucketMultipartUploads") {
+			bucketActions = append(bucketActions, "s3:ListBucketMultipartUploads")
+		}
+		if access == accessPolicyWriteOnly {
+			continue
+		}
+		listStatement := newStatement([]string{"s3:ListBucket"}, bucket)
+		if prefix != "" {
+			listStatement.Conditions = map[string]map[string]string{
+				"StringLike": {"s3:prefix": prefix + "*"},
+			}
+		}
+		listStatements = append(listStatements, listStatement)
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+	statements = append([]policyStatement{newStatement(bucketActions, bucket)}, statements...)
+	return append(statements, listStatements...)
+}
+
+// setBucketAccessPolicy - returns the policy of a bucket with the canned
+// access policy of a prefix replaced. Other canned statements are
+// regenerated, statements which are not canned are kept as is.
+func setBucketAccessPolicy(bucket string, policy BucketPolicy, prefix string, access bucketAccessPolicy) BucketPolicy {
+	policies := getBucketAccessPolicies(bucket, policy)
+	cannedStatements := getCannedStatements(bucket, policies)
+	if access == accessPolicyNone {
+		delete(policies, prefix)
+	} else {
+		policies[prefix] = access
+	}
+	newPolicy := BucketPolicy{Version: policy.Version}
+	if newPolicy.Version == "" {
+		newPolicy.Version = "2012-10-17"
+	}
+	for _, statement := range policy.Statements {
+		if !isCannedStatement(bucket, statement, cannedStatements) {
+			newPolicy.Statements = append(newPolicy.Statements, statement)
+		}
+	}
+	newPolicy.Statements = append(newPolicy.Statements, getCannedStatements(bucket, policies)...)
+	return newPolicy
+}
+
+// readBucketAccessPolicies - reads the policy of a bucket, an empty
+// policy if it has none.
+func readBucketAccessPolicies(bucket string) (BucketPolicy, error) {
+	policyBytes, err := readBucketPolicy(bucket)
+	if err != nil {
+		if _, ok := err.(BucketPolicyNotFound); ok {
+			return BucketPolicy{}, nil
+		}
+		return BucketPolicy{}, err
+	}
+	return parseBucketPolicy(policyBytes)
+}
+
+// writeBucketAccessPolicy - validates and saves the policy of a bucket,
+// the policy is removed if it has no statements.
+func writeBucketAccessPolicy(bucket string, policy BucketPolicy) error {
+	if len(policy.Statements) == 0 {
+		if err := removeBucketPolicy(bucket); err != nil {
+			if _, ok := err.(BucketPolicyNotFound); !ok {
+				return err
+			}
+		}
+		return nil
+	}
+	policyBytes, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	// Validate the policy as PutBucketPolicy would.
+	parsedPolicy, err := parseBucketPolicy(policyBytes)
+	if err != nil {
+		return err
+	}
+	if s3Error := checkBucketPolicyResources(bucket, parsedPolicy); s3Error != ErrNone {
+		return fmt.Errorf("%s", getAPIError(s3Error).Description)
+	}
+	return writeBucketPolicy(bucket, policyBytes)
+}
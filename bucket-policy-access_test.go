@@ -0,0 +1,171 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Tests validate canned access policies are translated to and from
+// bucket policy statements.
+func TestBucketAccessPolicies(t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to initialize config: <ERROR> %s", err)
+	}
+	defer removeAll(rootPath)
+
+	bucket := "bucket"
+	// Statement which is not canned is kept.
+	customStatement := getReadOnlyObjectStatement(bucket, "private/")
+	customStatement.Conditions = map[string]map[string]string{"IpAddress": {"aws:SourceIp": "192.168.1.0/24"}}
+	policy := BucketPolicy{Version: "2012-10-17", Statements: []policyStatement{customStatement}}
+
+	policy = setBucketAccessPolicy(bucket, policy, "photos/", accessPolicyReadOnly)
+	policy = setBucketAccessPolicy(bucket, policy, "uploads/", accessPolicyWriteOnly)
+	policy = setBucketAccessPolicy(bucket, policy, "shared/", accessPolicyReadWrite)
+	if err = writeBucketAccessPolicy(bucket, policy); err != nil {
+		t.Fatalf("Unable to write bucket policy: <ERROR> %s", err)
+	}
+	policy, err = readBucketAccessPolicies(bucket)
+	if err != nil {
+		t.Fatalf("Unable to read bucket policy: <ERROR> %s", err)
+	}
+	expectedPolicies := map[string]bucketAccessPolicy{
+		"photos/":  accessPolicyReadOnly,
+		"uploads/": accessPolicyWriteOnly,
+		"shared/":  accessPolicyReadWrite,
+	}
+	if policies := getBucketAccessPolicies(bucket, policy); !reflect.DeepEqual(policies, expectedPolicies) {
+		t.Errorf("Expected policies %v, found %v", expectedPolicies, policies)
+	}
+
+	testCases := []struct {
+		action     string
+		resource   string
+		prefix     string
+		shouldPass bool
+	}{
+		// Test case - 1.
+		// Read of a readonly prefix.
+		{"s3:GetObject", "bucket/photos/a.jpg", "", true},
+		// Test case - 2.
+		// Write of a readonly prefix.
+		{"s3:PutObject", "bucket/photos/a.jpg", "", false},
+		// Test case - 3.
+		// Write of a writeonly prefix.
+		{"s3:PutObject", "bucket/uploads/a.jpg", "", true},
+		// Test case - 4.
+		// Read of a writeonly prefix.
+		{"s3:GetObject", "bucket/uploads/a.jpg", "", false},
+		// Test case - 5.
+		// Read and write of a readwrite prefix.
+		{"s3:DeleteObject", "bucket/shared/a.jpg", "", true},
+		// Test case - 6.
+		// Listing of a readonly prefix.
+		{"s3:ListBucket", "bucket", "photos/2016/", true},
+		// Test case - 7.
+		// Listing of a writeonly prefix.
+		{"s3:ListBucket", "bucket", "uploads/", false},
+		// Test case - 8.
+		// Listing of the bucket.
+		{"s3:ListBucket", "bucket", "", false},
+		// Test case - 9.
+		// Objects without policy.
+		{"s3:GetObject", "bucket/other/a.jpg", "", false},
+	}
+	for i, testCase := range testCases {
+		conditions := map[string]string{"s3:prefix": testCase.prefix}
+		allowed := bucketPolicyEvalStatements(testCase.action, AWSResourcePrefix+testCase.resource, conditions, policy.Statements)
+		if allowed != testCase.shouldPass {
+			t.Errorf("Test %d: Expected %s on %s to be allowed %v, found %v", i+1, testCase.action, testCase.resource, testCase.shouldPass, allowed)
+		}
+	}
+
+	// Nested prefixes are refused.
+	if err = writeBucketAccessPolicy(bucket, setBucketAccessPolicy(bucket, policy, "photos/2016/", accessPolicyReadWrite)); err == nil {
+		t.Error("Expected policy of nested prefix to fail")
+	}
+
+	// Changing a policy keeps the others.
+	policy = setBucketAccessPolicy(bucket, policy, "photos/", accessPolicyReadWrite)
+	policy = setBucketAccessPolicy(bucket, policy, "uploads/", accessPolicyNone)
+	expectedPolicies = map[string]bucketAccessPolicy{
+		"photos/": accessPolicyReadWrite,
+		"shared/": accessPolicyReadWrite,
+	}
+	if policies := getBucketAccessPolicies(bucket, policy); !reflect.DeepEqual(policies, expectedPolicies) {
+		t.Errorf("Expected policies %v, found %v", expectedPolicies, policies)
+	}
+	if !reflect.DeepEqual(policy.Statements[0], customStatement) {
+		t.Errorf("Expected statement %#v to be kept, found %#v", customStatement, policy.Statements[0])
+	}
+
+	// Statements differing from the canned ones are kept.
+	namedStatement := getReadOnlyObjectStatement(bucket, "named/")
+	namedStatement.Sid = "named"
+	partialStatement := getReadOnlyObjectStatement(bucket, "partial/")
+	partialStatement.Actions = []string{"s3:GetObject", "s3:PutObject"}
+	multiStatement := getReadOnlyObjectStatement(bucket, "multi/")
+	multiStatement.Resources = append(multiStatement.Resources, AWSResourcePrefix+bucket+"/other/*")
+	listStatement := getReadOnlyObjectStatement(bucket, "")
+	listStatement.Actions = []string{"s3:ListBucket"}
+	listStatement.Resources = []string{AWSResourcePrefix + bucket}
+	listStatement.Conditions = map[string]map[string]string{"StringLike": {"s3:prefix": "list"}}
+	for i, statement := range []policyStatement{namedStatement, partialStatement, multiStatement, listStatement} {
+		if isCannedStatement(bucket, statement, getCannedStatements(bucket, expectedPolicies)) {
+			t.Errorf("Test %d: Expected statement %#v not to be canned", i+1, statement)
+		}
+	}
+	for i, statement := range getCannedStatements(bucket, expectedPolicies) {
+		if !isCannedStatement(bucket, statement, getCannedStatements(bucket, expectedPolicies)) {
+			t.Errorf("Test %d: Expected statement %#v to be canned", i+1, statement)
+		}
+	}
+	policy = BucketPolicy{Version: "2012-10-17", Statements: []policyStatement{namedStatement, partialStatement, multiStatement, listStatement}}
+	policy = setBucketAccessPolicy(bucket, policy, "photos/", accessPolicyReadOnly)
+	if !reflect.DeepEqual(policy.Statements[:4], []policyStatement{namedStatement, partialStatement, multiStatement, listStatement}) {
+		t.Errorf("Expected statements to be kept, found %#v", policy.Statements)
+	}
+
+	// Hand-written bucket statements which are not canned statements of
+	// the policies found are kept.
+	bucketListStatement := getReadOnlyObjectStatement(bucket, "")
+	bucketListStatement.Actions = []string{"s3:ListBucket"}
+	bucketListStatement.Resources = []string{AWSResourcePrefix + bucket}
+	locationStatement := getReadOnlyObjectStatement(bucket, "")
+	locationStatement.Actions = []string{"s3:GetBucketLocation"}
+	locationStatement.Resources = []string{AWSResourcePrefix + bucket}
+	policy = BucketPolicy{Version: "2012-10-17", Statements: []policyStatement{bucketListStatement, locationStatement}}
+	policy = setBucketAccessPolicy(bucket, policy, "photos/", accessPolicyReadWrite)
+	policy = setBucketAccessPolicy(bucket, policy, "photos/", accessPolicyNone)
+	if !reflect.DeepEqual(policy.Statements, []policyStatement{bucketListStatement, locationStatement}) {
+		t.Errorf("Expected bucket statements to be kept, found %#v", policy.Statements)
+	}
+
+	// Policy is removed once it has no statements.
+	policy = BucketPolicy{Version: "2012-10-17"}
+	policy = setBucketAccessPolicy(bucket, policy, "", accessPolicyReadOnly)
+	policy = setBucketAccessPolicy(bucket, policy, "", accessPolicyNone)
+	if err = writeBucketAccessPolicy(bucket, policy); err != nil {
+		t.Fatalf("Unable to write bucket policy: <ERROR> %s", err)
+	}
+	if _, err = readBucketPolicy(bucket); err == nil {
+		t.Error("Expected bucket policy to be removed")
+	}
+}
@@ -20,8 +20,11 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	mux "github.com/gorilla/mux"
 )
@@ -61,9 +64,18 @@ func bucketPolicyMatchStatement(action string, resource string, conditions map[s
 	return false
 }
 
-// Verify if given action matches with policy statement.
+// Verify if given action matches with policy statement. Owner actions
+// are only allowed by statements naming them, so that policies allowing
+// all actions keep them restricted to the owner.
 func bucketPolicyActionMatch(action string, statement policyStatement) bool {
+	_, isOwnerAction := ownerActions[action]
 	for _, policyAction := range statement.Actions {
+		if isOwnerAction && statement.Effect == "Allow" {
+			if policyAction == action {
+				return true
+			}
+			continue
+		}
 		if matched := actionMatch(policyAction, action); matched {
 			return true
 		}
@@ -125,35 +137,79 @@ func bucketPolicyResourceMatch(resource string, statement policyStatement) bool
 // Verify if given condition matches with policy statement.
 func bucketPolicyConditionMatch(conditions map[string]string, statement policyStatement) bool {
 	// Supports following conditions.
-	// - StringEquals
-	// - StringNotEquals
+	// - StringEquals, StringNotEquals
+	// - StringLike, StringNotLike
+	// - IpAddress, NotIpAddress
+	// - DateLessThan, DateGreaterThan
+	// - Bool
 	//
 	// Supported applicable condition keys for each conditions.
-	// - s3:prefix
-	// - s3:max-keys
-	var conditionMatches = true
+	// - s3:prefix, s3:max-keys, aws:Referer
+	// - aws:SourceIp
+	// - aws:CurrentTime
+	// - aws:SecureTransport
 	for condition, conditionKeys := range statement.Conditions {
-		if condition == "StringEquals" {
-			if conditionKeys["s3:prefix"] != conditions["prefix"] {
-				conditionMatches = false
-				break
-			}
-			if conditionKeys["s3:max-keys"] != conditions["max-keys"] {
-				conditionMatches = false
-				break
-			}
-		} else if condition == "StringNotEquals" {
-			if conditionKeys["s3:prefix"] == conditions["prefix"] {
-				conditionMatches = false
-				break
-			}
-			if conditionKeys["s3:max-keys"] == conditions["max-keys"] {
-				conditionMatches = false
-				break
+		for key, value := range conditionKeys {
+			if !conditionMatch(condition, value, conditions[key]) {
+				return false
 			}
 		}
 	}
-	return conditionMatches
+	return true
+}
+
+// conditionMatch - matches a single policy condition value against
+// the value of the same key in the incoming request.
+func conditionMatch(condition, policyValue, requestValue string) bool {
+	switch condition {
+	case "StringEquals":
+		return policyValue == requestValue
+	case "StringNotEquals":
+		return policyValue != requestValue
+	case "StringLike":
+		return wildCardMatch(policyValue, requestValue)
+	case "StringNotLike":
+		return !wildCardMatch(policyValue, requestValue)
+	case "IpAddress", "NotIpAddress":
+		ipNet, err := parsePolicyIPNet(policyValue)
+		if err != nil {
+			return false
+		}
+		ip := net.ParseIP(requestValue)
+		if ip == nil {
+			// Source address unknown, never matches.
+			return false
+		}
+		if condition == "IpAddress" {
+			return ipNet.Contains(ip)
+		}
+		return !ipNet.Contains(ip)
+	case "DateLessThan", "DateGreaterThan":
+		policyTime, err := parsePolicyDate(policyValue)
+		if err != nil {
+			return false
+		}
+		requestTime, err := time.Parse(time.RFC3339, requestValue)
+		if err != nil {
+			return false
+		}
+		if condition == "DateLessThan" {
+			return requestTime.Before(policyTime)
+		}
+		return requestTime.After(policyTime)
+	case "Bool":
+		policyBool, err := strconv.ParseBool(policyValue)
+		if err != nil {
+			return false
+		}
+		requestBool, err := strconv.ParseBool(requestValue)
+		if err != nil {
+			return false
+		}
+		return policyBool == requestBool
+	}
+	// Unknown conditions never match.
+	return false
 }
 
 // PutBucketPolicyHandler - PUT Bucket policy
@@ -168,6 +224,12 @@ func (api objectAPIHandlers) PutBucketPolicyHandler(w http.ResponseWriter, r *ht
 		// For all unknown auth types return error.
 		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
 		return
+	case authTypeAnonymous:
+		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
+		if s3Error := enforceBucketPolicy("s3:PutBucketPolicy", bucket, r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
 	case authTypePresigned, authTypeSigned:
 		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
@@ -215,6 +277,8 @@ func (api objectAPIHandlers) PutBucketPolicyHandler(w http.ResponseWriter, r *ht
 	}
 
 	// Save bucket policy.
+	lockBucketPolicy(bucket)
+	defer unlockBucketPolicy(bucket)
 	if err := writeBucketPolicy(bucket, bucketPolicyBuf); err != nil {
 		errorIf(err, "Unable to write bucket policy.")
 		switch err.(type) {
@@ -241,6 +305,12 @@ func (api objectAPIHandlers) DeleteBucketPolicyHandler(w http.ResponseWriter, r
 		// For all unknown auth types return error.
 		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
 		return
+	case authTypeAnonymous:
+		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
+		if s3Error := enforceBucketPolicy("s3:DeleteBucketPolicy", bucket, r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
 	case authTypePresigned, authTypeSigned:
 		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
@@ -249,6 +319,8 @@ func (api objectAPIHandlers) DeleteBucketPolicyHandler(w http.ResponseWriter, r
 	}
 
 	// Delete bucket access policy.
+	lockBucketPolicy(bucket)
+	defer unlockBucketPolicy(bucket)
 	if err := removeBucketPolicy(bucket); err != nil {
 		errorIf(err, "Unable to remove bucket policy.")
 		switch err.(type) {
@@ -277,6 +349,12 @@ func (api objectAPIHandlers) GetBucketPolicyHandler(w http.ResponseWriter, r *ht
 		// For all unknown auth types return error.
 		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
 		return
+	case authTypeAnonymous:
+		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
+		if s3Error := enforceBucketPolicy("s3:GetBucketPolicy", bucket, r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
 	case authTypePresigned, authTypeSigned:
 		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
@@ -80,6 +80,62 @@ func TestBucketPolicyResourceMatch(t *testing.T) {
 	}
 }
 
+// Tests validate Bucket policy condition matcher.
+func TestBucketPolicyConditionMatch(t *testing.T) {
+	// generates statement with given condition.
+	generateStatement := func(condition, key, value string) policyStatement {
+		statement := policyStatement{}
+		statement.Conditions = map[string]map[string]string{
+			condition: {key: value},
+		}
+		return statement
+	}
+
+	// Request condition values used for all the test cases.
+	conditions := map[string]string{
+		"s3:prefix":           "Asia/",
+		"s3:max-keys":         "",
+		"aws:SourceIp":        "192.168.1.10",
+		"aws:CurrentTime":     "2016-06-01T10:00:00Z",
+		"aws:SecureTransport": "false",
+		"aws:Referer":         "http://www.example.com/index.html",
+	}
+
+	testCases := []struct {
+		statement      policyStatement
+		expectedResult bool
+	}{
+		// Test case - 1.
+		// Statement without conditions always matches.
+		{policyStatement{}, true},
+		// Test case 2-3.
+		{generateStatement("StringEquals", "s3:prefix", "Asia/"), true},
+		{generateStatement("StringNotEquals", "s3:prefix", "Asia/"), false},
+		// Test case 4-5.
+		{generateStatement("StringLike", "aws:Referer", "http://www.example.com/*"), true},
+		{generateStatement("StringNotLike", "aws:Referer", "http://www.example.com/*"), false},
+		// Test case 6-9.
+		{generateStatement("IpAddress", "aws:SourceIp", "192.168.1.0/24"), true},
+		{generateStatement("IpAddress", "aws:SourceIp", "10.0.0.0/8"), false},
+		{generateStatement("NotIpAddress", "aws:SourceIp", "10.0.0.0/8"), true},
+		{generateStatement("IpAddress", "aws:SourceIp", "192.168.1.10"), true},
+		// Test case 10-13.
+		{generateStatement("DateLessThan", "aws:CurrentTime", "2016-12-31T00:00:00Z"), true},
+		{generateStatement("DateLessThan", "aws:CurrentTime", "2016-01-01"), false},
+		{generateStatement("DateGreaterThan", "aws:CurrentTime", "2016-01-01"), true},
+		{generateStatement("DateGreaterThan", "aws:CurrentTime", "2016-12-31T00:00:00Z"), false},
+		// Test case 14-15.
+		{generateStatement("Bool", "aws:SecureTransport", "false"), true},
+		{generateStatement("Bool", "aws:SecureTransport", "true"), false},
+	}
+	for i, testCase := range testCases {
+		actualResult := bucketPolicyConditionMatch(conditions, testCase.statement)
+		if testCase.expectedResult != actualResult {
+			t.Errorf("Test %d: Expected condition match to be `%v`, but instead found it to be `%v`", i+1, testCase.expectedResult, actualResult)
+		}
+	}
+}
+
 // TestBucketPolicyActionMatch - Test validates whether given action on the
 // bucket/object matches the allowed actions in policyStatement.
 // This test preserves the allowed actions for all 3 sets of policies, that is read-write,read-only, write-only.
@@ -325,7 +381,7 @@ func testPutBucketPolicyHandler(obj ObjectLayer, instanceType string, t *testing
 		t.Fatalf("%s : %s", instanceType, err)
 	}
 	// Register the API end points with XL/FS object layer.
-	apiRouter := initTestAPIEndPoints(obj, []string{"PutBucketPolicy"})
+	apiRouter := initTestAPIEndPoints(obj, []string{"PutBucketPolicy", "GetBucketPolicy", "DeleteBucketPolicy", "DeleteBucket"})
 	// initialize the server and obtain the credentials and root.
 	// credentials are necessary to sign the HTTP request.
 	credentials, rootPath, err := initTestConfig("us-east-1")
@@ -401,4 +457,58 @@ func testPutBucketPolicyHandler(obj ObjectLayer, instanceType string, t *testing
 			t.Errorf("Test %d: Expected the response status to be `%d`, but instead found `%d`", i+1, testCase.expectedRespStatus, rec.Code)
 		}
 	}
+
+	// Anonymous requests cannot replace the policy when the policy
+	// allows all the actions, only when it names the action.
+	bucketPolicyStr := fmt.Sprintf(`{"Version": "2012-10-17", "Statement": [{"Action": ["s3:*"], "Effect": "Allow", `+
+		`"Principal": {"AWS": ["*"]}, "Resource": ["arn:aws:s3:::%s", "arn:aws:s3:::%s/*"]}]}`, bucketName, bucketName)
+	if err = writeBucketPolicy(bucketName, []byte(bucketPolicyStr)); err != nil {
+		t.Fatalf("%s: Unable to write bucket policy: <ERROR> %s", instanceType, err)
+	}
+	req, err := http.NewRequest("PUT", getPutPolicyURL("", bucketName), bytes.NewReader([]byte(bucketPolicyStr)))
+	if err != nil {
+		t.Fatalf("%s: Failed to create HTTP request for PutBucketPolicyHandler: <ERROR> %v", instanceType, err)
+	}
+	req.ContentLength = int64(len(bucketPolicyStr))
+	rec := httptest.NewRecorder()
+	apiRouter.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("%s: Expected anonymous request to be denied with `%d`, but instead found `%d`", instanceType, http.StatusForbidden, rec.Code)
+	}
+
+	bucketPolicyStr = fmt.Sprintf(`{"Version": "2012-10-17", "Statement": [{"Action": ["s3:GetBucketPolicy", "s3:PutBucketPolicy"], "Effect": "Allow", `+
+		`"Principal": {"AWS": ["*"]}, "Resource": ["arn:aws:s3:::%s"]}]}`, bucketName)
+	if err = writeBucketPolicy(bucketName, []byte(bucketPolicyStr)); err != nil {
+		t.Fatalf("%s: Unable to write bucket policy: <ERROR> %s", instanceType, err)
+	}
+	testCases2 := []struct {
+		method             string
+		url                string
+		expectedRespStatus int
+	}{
+		// Test case - 1.
+		// Policy read is allowed by name.
+		{"GET", getGetPolicyURL("", bucketName), http.StatusOK},
+		// Test case - 2.
+		// Policy write is allowed by name.
+		{"PUT", getPutPolicyURL("", bucketName), http.StatusNoContent},
+		// Test case - 3.
+		// Policy removal is not allowed.
+		{"DELETE", getDeletePolicyURL("", bucketName), http.StatusForbidden},
+		// Test case - 4.
+		// Bucket removal is not allowed.
+		{"DELETE", getDeleteBucketURL("", bucketName), http.StatusForbidden},
+	}
+	for i, testCase := range testCases2 {
+		req, err = http.NewRequest(testCase.method, testCase.url, bytes.NewReader([]byte(bucketPolicyStr)))
+		if err != nil {
+			t.Fatalf("%s: Test %d: Failed to create HTTP request: <ERROR> %v", instanceType, i+1, err)
+		}
+		req.ContentLength = int64(len(bucketPolicyStr))
+		rec = httptest.NewRecorder()
+		apiRouter.ServeHTTP(rec, req)
+		if rec.Code != testCase.expectedRespStatus {
+			t.Errorf("%s: Test %d: Expected anonymous request status `%d`, but instead found `%d`", instanceType, i+1, testCase.expectedRespStatus, rec.Code)
+		}
+	}
 }
@@ -22,9 +22,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -44,19 +47,125 @@ var supportedActionMap = map[string]struct{}{
 	"s3:AbortMultipartUpload":       {},
 	"s3:ListBucketMultipartUploads": {},
 	"s3:ListMultipartUploadParts":   {},
+	"s3:DeleteBucket":               {},
+	"s3:GetBucketPolicy":            {},
+	"s3:PutBucketPolicy":            {},
+	"s3:DeleteBucketPolicy":         {},
+}
+
+// ownerActions - bucket level actions which are restricted to the owner
+// unless a statement allows them by name, wildcards never allow them.
+var ownerActions = map[string]struct{}{
+	"s3:DeleteBucket":       {},
+	"s3:GetBucketPolicy":    {},
+	"s3:PutBucketPolicy":    {},
+	"s3:DeleteBucketPolicy": {},
+}
+
+// unenforcedActionMap - actions which cannot be enforced by a bucket
+// policy, with the reason they are rejected.
+var unenforcedActionMap = map[string]string{
+	"s3:CreateBucket":        "buckets are created before they can have a policy",
+	"s3:ListAllMyBuckets":    "it applies to all the buckets of the server",
+	"s3:PutObjectTagging":    "object tagging is not supported yet",
+	"s3:GetObjectTagging":    "object tagging is not supported yet",
+	"s3:DeleteObjectTagging": "object tagging is not supported yet",
 }
 
 // supported Conditions type.
 var supportedConditionsType = map[string]struct{}{
 	"StringEquals":    {},
 	"StringNotEquals": {},
+	"StringLike":      {},
+	"StringNotLike":   {},
+	"IpAddress":       {},
+	"NotIpAddress":    {},
+	"DateLessThan":    {},
+	"DateGreaterThan": {},
+	"Bool":            {},
 }
 
 // Validate s3:prefix, s3:max-keys are present if not
 // supported keys for the conditions.
 var supportedConditionsKey = map[string]struct{}{
-	"s3:prefix":   {},
-	"s3:max-keys": {},
+	"s3:prefix":           {},
+	"s3:max-keys":         {},
+	"aws:SourceIp":        {},
+	"aws:CurrentTime":     {},
+	"aws:SecureTransport": {},
+	"aws:Referer":         {},
+}
+
+// Date formats accepted for 'aws:CurrentTime' condition values.
+var policyDateFormats = []string{
+	time.RFC3339,
+	"2006-01-02",
+}
+
+// parsePolicyDate - parses a condition date value in any of the
+// supported policy date formats.
+func parsePolicyDate(value string) (t time.Time, err error) {
+	for _, format := range policyDateFormats {
+		if t, err = time.Parse(format, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// parsePolicyIPNet - parses a condition IP value, a single IP address
+// is treated as a network of its own.
+func parsePolicyIPNet(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("Invalid IP address '%s'", value)
+		}
+		if ip.To4() != nil {
+			value += "/32"
+		} else {
+			value += "/128"
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(value)
+	return ipNet, err
+}
+
+// isValidConditionValue - validates if condition key is applicable
+// for the condition type and its value is well formed.
+func isValidConditionValue(conditionType, key, value string) (err error) {
+	switch conditionType {
+	case "IpAddress", "NotIpAddress":
+		if key != "aws:SourceIp" {
+			break
+		}
+		if _, err = parsePolicyIPNet(value); err != nil {
+			return fmt.Errorf("Invalid IP address value '%s' for condition key '%s', please validate your policy document.", value, key)
+		}
+		return nil
+	case "DateLessThan", "DateGreaterThan":
+		if key != "aws:CurrentTime" {
+			break
+		}
+		if _, err = parsePolicyDate(value); err != nil {
+			return fmt.Errorf("Invalid date value '%s' for condition key '%s', please validate your policy document.", value, key)
+		}
+		return nil
+	case "Bool":
+		if key != "aws:SecureTransport" {
+			break
+		}
+		if _, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("Invalid boolean value '%s' for condition key '%s', please validate your policy document.", value, key)
+		}
+		return nil
+	default:
+		// String conditions apply only to string keys.
+		if key == "s3:prefix" || key == "s3:max-keys" || key == "aws:Referer" {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unsupported condition key '%s' for condition type '%s', please validate your policy document.", key, conditionType)
 }
 
 // User - canonical users list.
@@ -94,6 +203,10 @@ func isValidActions(actions []string) (err error) {
 		return err
 	}
 	for _, action := range actions {
+		if reason, ok := unenforcedActionMap[action]; ok {
+			err = errors.New("Action ‘" + action + "’ cannot be granted by a bucket policy, " + reason + ".")
+			return err
+		}
 		if _, ok := supportedActionMap[action]; !ok {
 			err = errors.New("Unsupported action found: ‘" + action + "’, please validate your policy document.")
 			return err
@@ -183,6 +296,9 @@ func isValidConditions(conditions map[string]map[string]string) (err error) {
 				err = fmt.Errorf("Unsupported condition key '%s', please validate your policy document.", conditionType)
 				return err
 			}
+			if err = isValidConditionValue(conditionType, key, conditions[conditionType][key]); err != nil {
+				return err
+			}
 			conditionArray, ok := conditionKeyVal[key]
 			if ok && findString(conditions[conditionType][key], conditionArray) {
 				err = fmt.Errorf("Ambigious condition values for key '%s', please validate your policy document.", key)
@@ -199,6 +315,10 @@ var invalidPrefixActions = map[string]struct{}{
 	"s3:GetBucketLocation":          {},
 	"s3:ListBucket":                 {},
 	"s3:ListBucketMultipartUploads": {},
+	"s3:DeleteBucket":               {},
+	"s3:GetBucketPolicy":            {},
+	"s3:PutBucketPolicy":            {},
+	"s3:DeleteBucketPolicy":         {},
 	// Add actions which do not honor prefixes.
 }
 
@@ -23,52 +23,6 @@ import (
 	"testing"
 )
 
-var (
-	readWriteBucketActions = []string{
-		"s3:GetBucketLocation",
-		"s3:ListBucket",
-		"s3:ListBucketMultipartUploads",
-		// Add more bucket level read-write actions here.
-	}
-	readWriteObjectActions = []string{
-		"s3:AbortMultipartUpload",
-		"s3:DeleteObject",
-		"s3:GetObject",
-		"s3:ListMultipartUploadParts",
-		"s3:PutObject",
-		// Add more object level read-write actions here.
-	}
-)
-
-// Write only actions.
-var (
-	writeOnlyBucketActions = []string{
-		"s3:GetBucketLocation",
-		"s3:ListBucketMultipartUploads",
-		// Add more bucket level write actions here.
-	}
-	writeOnlyObjectActions = []string{
-		"s3:AbortMultipartUpload",
-		"s3:DeleteObject",
-		"s3:ListMultipartUploadParts",
-		"s3:PutObject",
-		// Add more object level write actions here.
-	}
-)
-
-// Read only actions.
-var (
-	readOnlyBucketActions = []string{
-		"s3:GetBucketLocation",
-		"s3:ListBucket",
-		// Add more bucket level read actions here.
-	}
-	readOnlyObjectActions = []string{
-		"s3:GetObject",
-		// Add more object level read actions here.
-	}
-)
-
 // Obtain bucket statement for read-write BucketPolicy.
 func getReadWriteObjectStatement(bucketName, objectPrefix string) policyStatement {
 	objectResourceStatement := policyStatement{}
@@ -176,11 +130,22 @@ func TestIsValidActions(t *testing.T) {
 		// "s3:DeleteEverything"" is an invalid Action.
 		{[]string{"s3:GetObject", "s3:ListBucket", "s3:PutObject", "s3:DeleteEverything"},
 			errors.New("Unsupported action found: ‘s3:DeleteEverything’, please validate your policy document."), false},
+		// Test case - 4.
+		// Object tagging is not supported yet.
+		{[]string{"s3:GetObject", "s3:PutObjectTagging"},
+			errors.New("Action ‘s3:PutObjectTagging’ cannot be granted by a bucket policy, object tagging is not supported yet."), false},
+		// Test case - 5.
+		// Bucket creation does not apply to an existing bucket.
+		{[]string{"s3:CreateBucket"},
+			errors.New("Action ‘s3:CreateBucket’ cannot be granted by a bucket policy, buckets are created before they can have a policy."), false},
 
 		// Inputs with valid Action.
-		// Test Case - 4.
+		// Test Case - 6.
 		{[]string{"s3:*", "*", "s3:GetObject", "s3:ListBucket",
 			"s3:PutObject", "s3:GetBucketLocation", "s3:DeleteObject", "s3:AbortMultipartUpload", "s3:ListBucketMultipartUploads", "s3:ListMultipartUploadParts"}, nil, true},
+		// Test Case - 7.
+		// Bucket level actions.
+		{[]string{"s3:DeleteBucket", "s3:GetBucketPolicy", "s3:PutBucketPolicy", "s3:DeleteBucketPolicy"}, nil, true},
 	}
 	for i, testCase := range testCases {
 		err := isValidActions(testCase.actions)
@@ -407,6 +372,16 @@ func TestIsValidConditions(t *testing.T) {
 		generateConditions("StringEquals", "s3:max-keys", "100"),
 		generateConditions("StringNotEquals", "s3:prefix", "Asia/"),
 		generateConditions("StringNotEquals", "s3:max-keys", "100"),
+		generateConditions("IpAddress", "aws:SourceIp", "192.168.1.0/24"),
+		generateConditions("NotIpAddress", "aws:SourceIp", "10.0.0.1"),
+		generateConditions("StringLike", "aws:Referer", "http://example.com/*"),
+		generateConditions("DateLessThan", "aws:CurrentTime", "2016-12-31T12:00:00Z"),
+		generateConditions("Bool", "aws:SecureTransport", "true"),
+		generateConditions("IpAddress", "aws:SourceIp", "192.168.1"),
+		generateConditions("IpAddress", "s3:prefix", "Asia/"),
+		generateConditions("DateGreaterThan", "aws:CurrentTime", "yesterday"),
+		generateConditions("Bool", "aws:SecureTransport", "yes"),
+		generateConditions("StringEquals", "aws:SourceIp", "10.0.0.1"),
 	}
 
 	testCases := []struct {
@@ -452,6 +427,36 @@ func TestIsValidConditions(t *testing.T) {
 		{testConditions[10], nil, true},
 		// Test case 10.
 		{testConditions[11], nil, true},
+		// Test case - 13.
+		{testConditions[12], nil, true},
+		// Test case - 14.
+		{testConditions[13], nil, true},
+		// Test case - 15.
+		{testConditions[14], nil, true},
+		// Test case - 16.
+		{testConditions[15], nil, true},
+		// Test case - 17.
+		{testConditions[16], nil, true},
+		// Test case - 18.
+		// Malformed IP address.
+		{testConditions[17], fmt.Errorf("Invalid IP address value '192.168.1' for condition key 'aws:SourceIp', " +
+			"please validate your policy document."), false},
+		// Test case - 19.
+		// Key not applicable for the condition type.
+		{testConditions[18], fmt.Errorf("Unsupported condition key 's3:prefix' for condition type 'IpAddress', " +
+			"please validate your policy document."), false},
+		// Test case - 20.
+		// Malformed date.
+		{testConditions[19], fmt.Errorf("Invalid date value 'yesterday' for condition key 'aws:CurrentTime', " +
+			"please validate your policy document."), false},
+		// Test case - 21.
+		// Malformed boolean.
+		{testConditions[20], fmt.Errorf("Invalid boolean value 'yes' for condition key 'aws:SecureTransport', " +
+			"please validate your policy document."), false},
+		// Test case - 22.
+		// String condition on a non string key.
+		{testConditions[21], fmt.Errorf("Unsupported condition key 'aws:SourceIp' for condition type 'StringEquals', " +
+			"please validate your policy document."), false},
 	}
 	for i, testCase := range testCases {
 		actualErr := isValidConditions(testCase.inputCondition)
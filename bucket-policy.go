@@ -19,6 +19,7 @@ package main
 import (
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 )
 
@@ -49,6 +50,17 @@ func createBucketConfigPath(bucket string) error {
 	return os.MkdirAll(bucketConfigPath, 0700)
 }
 
+// lockBucketPolicy - locks the policy of a bucket, it is held while
+// the policy is changed so that concurrent changes are not lost.
+func lockBucketPolicy(bucket string) {
+	nsMutex.Lock(minioMetaBucket, path.Join("buckets", bucket, "access-policy.json"))
+}
+
+// unlockBucketPolicy - unlocks the policy of a bucket.
+func unlockBucketPolicy(bucket string) {
+	nsMutex.Unlock(minioMetaBucket, path.Join("buckets", bucket, "access-policy.json"))
+}
+
 // readBucketPolicy - read bucket policy.
 func readBucketPolicy(bucket string) ([]byte, error) {
 	// Verify bucket is valid.
@@ -0,0 +1,343 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/minio/minio/pkg/safe"
+)
+
+const (
+	// Bucket quota file name, saved in bucket config path.
+	bucketQuotaConfigFile = "quota.json"
+
+	// Bucket usage file name, saved in bucket config path.
+	bucketUsageConfigFile = "usage.json"
+)
+
+// bucketQuota - bucket quota configuration, zero value means no limit.
+type bucketQuota struct {
+	// Maximum total size of all objects in bytes.
+	Size int64 `json:"size"`
+
+	// Maximum number of objects, optional.
+	Objects int64 `json:"objects,omitempty"`
+}
+
+// Validate - validates bucket quota.
+func (quota bucketQuota) Validate() error {
+	if quota.Size < 0 || quota.Objects < 0 {
+		return errors.New("Bucket quota cannot be negative")
+	}
+	if quota.Size == 0 && quota.Objects == 0 {
+		return errors.New("Bucket quota requires either size or objects")
+	}
+	return nil
+}
+
+// bucketUsage - current usage of a bucket.
+type bucketUsage struct {
+	Size    int64 `json:"size"`
+	Objects int64 `json:"objects"`
+}
+
+// bucketQuotaInfo - quota and usage of a bucket.
+type bucketQuotaInfo struct {
+	Quota bucketQuota `json:"quota"`
+	Usage bucketUsage `json:"usage"`
+}
+
+// bucketQuotas - tracks quota and usage of buckets. Usage is updated
+// incrementally by the object layer on every change and saved in
+// bucket config path, only buckets with a quota are tracked.
+type bucketQuotas struct {
+	mutex *sync.Mutex
+	// A nil entry represents a bucket without quota.
+	buckets map[string]*bucketQuotaInfo
+}
+
+// Global bucket quotas, shared by all object layers.
+var globalBucketQuotas = newBucketQuotas()
+
+// newBucketQuotas - initialize new bucket quotas.
+func newBucketQuotas() *bucketQuotas {
+	return &bucketQuotas{
+		mutex:   &sync.Mutex{},
+		buckets: make(map[string]*bucketQuotaInfo),
+	}
+}
+
+// load - returns cached quota info of a bucket, loads it from bucket
+// config path if not cached. Should be called with mutex held.
+func (q *bucketQuotas) load(bucket string) *bucketQuotaInfo {
+	if info, ok := q.buckets[bucket]; ok {
+		return info
+	}
+	var info *bucketQuotaInfo
+	quota, err := readBucketQuota(bucket)
+	if err == nil {
+		info = &bucketQuotaInfo{Quota: quota}
+		info.Usage, err = readBucketUsage(bucket)
+		errorIf(err, "Unable to read bucket usage.")
+	} else if _, ok := err.(BucketQuotaNotFound); !ok {
+		// Do not cache on failure, attempt again on next request.
+		errorIf(err, "Unable to read bucket quota.")
+		return nil
+	}
+	q.buckets[bucket] = info
+	return info
+}
+
+// reserve - verifies if the usage change is allowed by the bucket
+// quota and accounts for it. Reserved usage should be released with
+// update if the change is not committed.
+func (q *bucketQuotas) reserve(bucket string, size, objects int64) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	info := q.load(bucket)
+	if info == nil {
+		return nil
+	}
+	// Usage is allowed to shrink even if the bucket is over quota.
+	if size > 0 && info.Quota.Size > 0 && info.Usage.Size+size > info.Quota.Size {
+		return BucketQuotaExceeded{Bucket: bucket}
+	}
+	if objects > 0 && info.Quota.Objects > 0 && info.Usage.Objects+objects > info.Quota.Objects {
+		return BucketQuotaExceeded{Bucket: bucket}
+	}
+	q.apply(bucket, info, size, objects)
+	return nil
+}
+
+// check - verifies if additional size fits in the bucket quota,
+// without accounting for it.
+func (q *bucketQuotas) check(bucket string, size int64) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	info := q.load(bucket)
+	if info == nil {
+		return nil
+	}
+	if size > 0 && info.Quota.Size > 0 && info.Usage.Size+size > info.Quota.Size {
+		return BucketQuotaExceeded{Bucket: bucket}
+	}
+	return nil
+}
+
+// checkUploadPart - verifies if a multipart upload fits in the bucket
+// quota with its part partID of given size, parts already uploaded
+// count towards the size of the object.
+func (q *bucketQuotas) checkUploadPart(bucket string, parts []objectPartInfo, partID int, size int64) error {
+	if size < 0 {
+		size = 0
+	}
+	for _, part := range parts {
+		if part.Number != partID {
+			size += part.Size
+		}
+	}
+	return q.check(bucket, size)
+}
+
+// update - accounts for usage change without verifying the quota.
+func (q *bucketQuotas) update(bucket string, size, objects int64) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	info := q.load(bucket)
+	if info == nil {
+		return
+	}
+	q.apply(bucket, info, size, objects)
+}
+
+// apply - applies usage change and saves it. Should be called with mutex held.
+func (q *bucketQuotas) apply(bucket string, info *bucketQuotaInfo, size, objects int64) {
+	info.Usage.Size += size
+	info.Usage.Objects += objects
+	// Usage cannot go negative, happens only for changes made before quota was set.
+	if info.Usage.Size < 0 {
+		info.Usage.Size = 0
+	}
+	if info.Usage.Objects < 0 {
+		info.Usage.Objects = 0
+	}
+	errorIf(writeBucketUsage(bucket, info.Usage), "Unable to save bucket usage.")
+}
+
+// get - returns quota and usage of a bucket.
+func (q *bucketQuotas) get(bucket string) (bucketQuotaInfo, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	info := q.load(bucket)
+	if info == nil {
+		return bucketQuotaInfo{}, BucketQuotaNotFound{Bucket: bucket}
+	}
+	return *info, nil
+}
+
+// set - sets bucket quota, usage is initialized only for buckets
+// which didn't have a quota before.
+func (q *bucketQuotas) set(bucket string, quota bucketQuota, usage bucketUsage) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if info := q.load(bucket); info != nil {
+		usage = info.Usage
+	}
+	if err := writeBucketQuota(bucket, quota); err != nil {
+		return err
+	}
+	if err := writeBucketUsage(bucket, usage); err != nil {
+		return err
+	}
+	q.buckets[bucket] = &bucketQuotaInfo{Quota: quota, Usage: usage}
+	return nil
+}
+
+// remove - removes bucket quota and usage.
+func (q *bucketQuotas) remove(bucket string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	delete(q.buckets, bucket)
+	return removeBucketQuota(bucket)
+}
+
+// readBucketQuota - read bucket quota.
+func readBucketQuota(bucket string) (quota bucketQuota, err error) {
+	buf, err := readBucketConfigFile(bucket, bucketQuotaConfigFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return bucketQuota{}, BucketQuotaNotFound{Bucket: bucket}
+		}
+		return bucketQuota{}, err
+	}
+	if err = json.Unmarshal(buf, &quota); err != nil {
+		return bucketQuota{}, err
+	}
+	return quota, nil
+}
+
+// writeBucketQuota - save bucket quota.
+func writeBucketQuota(bucket string, quota bucketQuota) error {
+	buf, err := json.Marshal(quota)
+	if err != nil {
+		return err
+	}
+	return writeBucketConfigFile(bucket, bucketQuotaConfigFile, buf)
+}
+
+// readBucketUsage - read bucket usage.
+func readBucketUsage(bucket string) (usage bucketUsage, err error) {
+	buf, err := readBucketConfigFile(bucket, bucketUsageConfigFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return bucketUsage{}, nil
+		}
+		return bucketUsage{}, err
+	}
+	if err = json.Unmarshal(buf, &usage); err != nil {
+		return bucketUsage{}, err
+	}
+	return usage, nil
+}
+
+// writeBucketUsage - save bucket usage.
+func writeBucketUsage(bucket string, usage bucketUsage) error {
+	buf, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+	return writeBucketConfigFile(bucket, bucketUsageConfigFile, buf)
+}
+
+// removeBucketQuota - remove bucket quota and usage.
+func removeBucketQuota(bucket string) error {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return err
+	}
+
+	// Remove usage, ignore if not present.
+	if err = os.Remove(filepath.Join(bucketConfigPath, bucketUsageConfigFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err = os.Remove(filepath.Join(bucketConfigPath, bucketQuotaConfigFile)); err != nil {
+		if os.IsNotExist(err) {
+			return BucketQuotaNotFound{Bucket: bucket}
+		}
+		return err
+	}
+	return nil
+}
+
+// readBucketConfigFile - read a file from bucket config path.
+func readBucketConfigFile(bucket, configFile string) ([]byte, error) {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return nil, BucketNameInvalid{Bucket: bucket}
+	}
+
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(filepath.Join(bucketConfigPath, configFile))
+}
+
+// writeBucketConfigFile - save a file in bucket config path, the file
+// is replaced atomically so that readers never see a partial write.
+func writeBucketConfigFile(bucket, configFile string, buf []byte) error {
+	// Verify if bucket path legal
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+
+	// Create bucket config path.
+	if err := createBucketConfigPath(bucket); err != nil {
+		return err
+	}
+
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return err
+	}
+	file, err := safe.CreateFile(filepath.Join(bucketConfigPath, configFile))
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(buf); err != nil {
+		file.Abort()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,207 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Tests validate bucket quota reservation and usage accounting.
+func TestBucketQuotas(t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Init Test config failed")
+	}
+	defer removeAll(rootPath)
+
+	quotas := newBucketQuotas()
+	bucket := getRandomBucketName()
+
+	// Buckets without quota are never limited.
+	if err = quotas.reserve(bucket, 1<<40, 1); err != nil {
+		t.Fatalf("Expected no quota on bucket, but failed with: <ERROR> %s", err)
+	}
+	if err = quotas.set(bucket, bucketQuota{Size: 100, Objects: 2}, bucketUsage{Size: 10, Objects: 1}); err != nil {
+		t.Fatalf("Unable to set bucket quota: <ERROR> %s", err)
+	}
+
+	testCases := []struct {
+		size       int64
+		objects    int64
+		shouldPass bool
+		usage      bucketUsage
+	}{
+		// Test case - 1.
+		// Within quota.
+		{80, 0, true, bucketUsage{90, 1}},
+		// Test case - 2.
+		// Size exceeds quota.
+		{20, 0, false, bucketUsage{90, 1}},
+		// Test case - 3.
+		// Exactly at quota.
+		{10, 1, true, bucketUsage{100, 2}},
+		// Test case - 4.
+		// Object count exceeds quota.
+		{0, 1, false, bucketUsage{100, 2}},
+		// Test case - 5.
+		// Shrinking is always allowed.
+		{-50, -1, true, bucketUsage{50, 1}},
+	}
+	for i, testCase := range testCases {
+		err = quotas.reserve(bucket, testCase.size, testCase.objects)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: Expected to pass, but failed with: <ERROR> %s", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: Expected to fail, but passed instead", i+1)
+		}
+		if err != nil && !testCase.shouldPass {
+			if _, ok := err.(BucketQuotaExceeded); !ok {
+				t.Errorf("Test %d: Expected to fail with BucketQuotaExceeded, but failed with: <ERROR> %s", i+1, err)
+			}
+		}
+		info, gErr := quotas.get(bucket)
+		if gErr != nil {
+			t.Fatalf("Test %d: Unable to get bucket quota: <ERROR> %s", i+1, gErr)
+		}
+		if info.Usage != testCase.usage {
+			t.Errorf("Test %d: Expected usage %v, but found %v", i+1, testCase.usage, info.Usage)
+		}
+	}
+
+	// Usage is saved and reloaded from bucket config path.
+	info, err := newBucketQuotas().get(bucket)
+	if err != nil {
+		t.Fatalf("Unable to reload bucket quota: <ERROR> %s", err)
+	}
+	if info.Usage != (bucketUsage{50, 1}) {
+		t.Errorf("Expected reloaded usage %v, but found %v", bucketUsage{50, 1}, info.Usage)
+	}
+
+	if err = quotas.remove(bucket); err != nil {
+		t.Fatalf("Unable to remove bucket quota: <ERROR> %s", err)
+	}
+	if _, err = quotas.get(bucket); err == nil {
+		t.Errorf("Expected bucket quota to be removed")
+	}
+}
+
+// Wrapper for calling bucket quota enforcement tests for both XL multiple disks and single node setup.
+func TestBucketQuotaEnforcement(t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Init Test config failed")
+	}
+	defer removeAll(rootPath)
+
+	ExecObjectLayerTest(t, testBucketQuotaEnforcement)
+}
+
+// Tests validate object layer enforces bucket quota and tracks usage.
+func testBucketQuotaEnforcement(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := getRandomBucketName()
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	defer globalBucketQuotas.remove(bucket)
+
+	data := bytes.Repeat([]byte("a"), 10)
+	if _, err := obj.PutObject(bucket, "existing", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	usage, err := getBucketUsage(obj, bucket)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if err = globalBucketQuotas.set(bucket, bucketQuota{Size: 25}, usage); err != nil {
+		t.Fatalf("%s: Unable to set bucket quota: <ERROR> %s", instanceType, err)
+	}
+
+	testCases := []struct {
+		object     string
+		size       int
+		shouldPass bool
+		usage      bucketUsage
+	}{
+		// Test case - 1.
+		// New object within quota.
+		{"object1", 10, true, bucketUsage{20, 2}},
+		// Test case - 2.
+		// New object exceeding quota.
+		{"object2", 10, false, bucketUsage{20, 2}},
+		// Test case - 3.
+		// Overwrite only accounts the difference.
+		{"object1", 15, true, bucketUsage{25, 2}},
+	}
+	for i, testCase := range testCases {
+		data = bytes.Repeat([]byte("a"), testCase.size)
+		_, err = obj.PutObject(bucket, testCase.object, int64(len(data)), bytes.NewReader(data), nil)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: %s: Expected to pass, but failed with: <ERROR> %s", i+1, instanceType, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: %s: Expected to fail, but passed instead", i+1, instanceType)
+		}
+		info, gErr := globalBucketQuotas.get(bucket)
+		if gErr != nil {
+			t.Fatalf("Test %d: %s: Unable to get bucket quota: <ERROR> %s", i+1, instanceType, gErr)
+		}
+		if info.Usage != testCase.usage {
+			t.Errorf("Test %d: %s: Expected usage %v, but found %v", i+1, instanceType, testCase.usage, info.Usage)
+		}
+	}
+
+	// Deleting an object releases its usage.
+	if err = obj.DeleteObject(bucket, "existing"); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	info, err := globalBucketQuotas.get(bucket)
+	if err != nil {
+		t.Fatalf("%s: Unable to get bucket quota: <ERROR> %s", instanceType, err)
+	}
+	if info.Usage != (bucketUsage{15, 1}) {
+		t.Errorf("%s: Expected usage %v after delete, but found %v", instanceType, bucketUsage{15, 1}, info.Usage)
+	}
+
+	// Declared size exceeding quota is rejected before reading data.
+	reader := bytes.NewReader(bytes.Repeat([]byte("a"), 20))
+	if _, err = obj.PutObject(bucket, "object3", int64(reader.Len()), reader, nil); err == nil {
+		t.Errorf("%s: Expected object exceeding quota to fail", instanceType)
+	}
+	if reader.Len() != 20 {
+		t.Errorf("%s: Expected data not to be read, but %d bytes were read", instanceType, 20-reader.Len())
+	}
+
+	// Parts already uploaded count towards the quota.
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	defer obj.AbortMultipartUpload(bucket, "multipart", uploadID)
+	data = bytes.Repeat([]byte("a"), 6)
+	if _, err = obj.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), ""); err != nil {
+		t.Fatalf("%s: Expected part within quota to pass, but failed with: <ERROR> %s", instanceType, err)
+	}
+	if _, err = obj.PutObjectPart(bucket, "multipart", uploadID, 2, int64(len(data)), bytes.NewReader(data), ""); err == nil {
+		t.Errorf("%s: Expected part exceeding quota with uploaded parts to fail", instanceType)
+	}
+	// Replacing a part only counts the new part.
+	if _, err = obj.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), ""); err != nil {
+		t.Errorf("%s: Expected part replacement to pass, but failed with: <ERROR> %s", instanceType, err)
+	}
+}
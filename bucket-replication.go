@@ -0,0 +1,318 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/minio/minio/pkg/safe"
+)
+
+const (
+	// Replication config file name, saved in bucket config path.
+	bucketReplicationConfigFile = "replication.xml"
+
+	// Maximum size of a replication configuration document.
+	maxReplicationConfigSize = 128 * 1024
+
+	// Maximum number of rules in a replication configuration.
+	maxReplicationRules = 1000
+
+	// Destination bucket ARN prefix.
+	replicationARNPrefix = "arn:aws:s3:::"
+
+	// Key encrypting destination secret keys, saved in the
+	// replication directory under the config path.
+	replicationSecretKeyFile = "secret.key"
+
+	// Length of the key encrypting destination secret keys in bytes.
+	replicationSecretKeyLength = 32
+)
+
+// Replication rule status.
+const (
+	replicationRuleEnabled  = "Enabled"
+	replicationRuleDisabled = "Disabled"
+)
+
+// replicationDestination - destination of a replication rule, along
+// with the standard destination bucket ARN this carries the remote
+// S3 compatible endpoint and its credentials.
+type replicationDestination struct {
+	Bucket    string `xml:"Bucket"`
+	Endpoint  string `xml:"Endpoint"`
+	AccessKey string `xml:"AccessKey"`
+	SecretKey string `xml:"SecretKey,omitempty"`
+	Region    string `xml:"Region,omitempty"`
+
+	// Secret key encrypted with the replication secret key, only
+	// set in the saved configuration.
+	EncryptedSecretKey string `xml:"EncryptedSecretKey,omitempty"`
+}
+
+// BucketName - returns destination bucket name from bucket ARN.
+func (dest replicationDestination) BucketName() string {
+	return strings.TrimPrefix(dest.Bucket, replicationARNPrefix)
+}
+
+// replicationRule - replicates all objects matching the prefix.
+type replicationRule struct {
+	ID          string                 `xml:"ID,omitempty"`
+	Status      string                 `xml:"Status"`
+	Prefix      string                 `xml:"Prefix"`
+	Destination replicationDestination `xml:"Destination"`
+}
+
+// replicationConfiguration - bucket replication configuration.
+type replicationConfiguration struct {
+	XMLName xml.Name          `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ReplicationConfiguration" json:"-"`
+	Role    string            `xml:"Role,omitempty"`
+	Rules   []replicationRule `xml:"Rule"`
+}
+
+// Validate - validates replication configuration.
+func (config replicationConfiguration) Validate() error {
+	if len(config.Rules) == 0 {
+		return errors.New("Replication configuration requires at least one rule")
+	}
+	if len(config.Rules) > maxReplicationRules {
+		return fmt.Errorf("Replication configuration cannot have more than %d rules", maxReplicationRules)
+	}
+	for _, rule := range config.Rules {
+		if rule.Status != replicationRuleEnabled && rule.Status != replicationRuleDisabled {
+			return fmt.Errorf("Invalid replication rule status '%s'", rule.Status)
+		}
+		if !IsValidObjectPrefix(rule.Prefix) {
+			return fmt.Errorf("Invalid replication rule prefix '%s'", rule.Prefix)
+		}
+		dest := rule.Destination
+		if !IsValidBucketName(dest.BucketName()) {
+			return fmt.Errorf("Invalid replication destination bucket '%s'", dest.Bucket)
+		}
+		endpoint, err := url.Parse(dest.Endpoint)
+		if err != nil {
+			return err
+		}
+		if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return fmt.Errorf("Invalid replication destination endpoint '%s'", dest.Endpoint)
+		}
+		if dest.AccessKey == "" || dest.SecretKey == "" {
+			return errors.New("Replication destination requires access key and secret key")
+		}
+	}
+	return nil
+}
+
+// matchRule - returns the first enabled rule matching the object.
+func (config replicationConfiguration) matchRule(object string) *replicationRule {
+	for i, rule := range config.Rules {
+		if rule.Status != replicationRuleEnabled {
+			continue
+		}
+		if strings.HasPrefix(object, rule.Prefix) {
+			return &config.Rules[i]
+		}
+	}
+	return nil
+}
+
+// parseReplicationConfig - parses and validates replication configuration.
+func parseReplicationConfig(configBytes []byte) (config replicationConfiguration, err error) {
+	if err = xml.Unmarshal(configBytes, &config); err != nil {
+		return replicationConfiguration{}, err
+	}
+	if err = config.Validate(); err != nil {
+		return replicationConfiguration{}, err
+	}
+	return config, nil
+}
+
+// readBucketReplicationConfig - read bucket replication configuration,
+// destination secret keys are decrypted.
+func readBucketReplicationConfig(bucket string) (replicationConfiguration, error) {
+	configBytes, err := readBucketConfigFile(bucket, bucketReplicationConfigFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return replicationConfiguration{}, BucketReplicationConfigNotFound{Bucket: bucket}
+		}
+		return replicationConfiguration{}, err
+	}
+	var config replicationConfiguration
+	if err = xml.Unmarshal(configBytes, &config); err != nil {
+		return replicationConfiguration{}, err
+	}
+	for i, rule := range config.Rules {
+		// Configurations saved by older versions carry plain secret keys.
+		if rule.Destination.EncryptedSecretKey == "" {
+			continue
+		}
+		secretKey, dErr := decryptReplicationSecret(rule.Destination.EncryptedSecretKey)
+		if dErr != nil {
+			return replicationConfiguration{}, dErr
+		}
+		config.Rules[i].Destination.SecretKey = secretKey
+		config.Rules[i].Destination.EncryptedSecretKey = ""
+	}
+	if err = config.Validate(); err != nil {
+		return replicationConfiguration{}, err
+	}
+	return config, nil
+}
+
+// writeBucketReplicationConfig - save bucket replication configuration,
+// destination secret keys are saved encrypted.
+func writeBucketReplicationConfig(bucket string, config replicationConfiguration) error {
+	rules := make([]replicationRule, len(config.Rules))
+	for i, rule := range config.Rules {
+		encryptedSecretKey, err := encryptReplicationSecret(rule.Destination.SecretKey)
+		if err != nil {
+			return err
+		}
+		rule.Destination.SecretKey = ""
+		rule.Destination.EncryptedSecretKey = encryptedSecretKey
+		rules[i] = rule
+	}
+	config.Rules = rules
+	configBytes, err := xml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return writeBucketConfigFile(bucket, bucketReplicationConfigFile, configBytes)
+}
+
+// Serializes generation of the replication secret key.
+var replicationSecretKeyMutex = &sync.Mutex{}
+
+// getReplicationSecretKey - returns the key encrypting destination
+// secret keys, the key is generated on first use.
+func getReplicationSecretKey() ([]byte, error) {
+	replicationSecretKeyMutex.Lock()
+	defer replicationSecretKeyMutex.Unlock()
+
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	keyPath := filepath.Join(configPath, replicationQueueDir, replicationSecretKeyFile)
+	keyBytes, err := ioutil.ReadFile(keyPath)
+	if err == nil {
+		return hex.DecodeString(strings.TrimSpace(string(keyBytes)))
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	key := make([]byte, replicationSecretKeyLength)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	file, err := safe.CreateFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = file.Write([]byte(hex.EncodeToString(key))); err != nil {
+		file.Abort()
+		return nil, err
+	}
+	if err = file.Close(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// newReplicationSecretCipher - returns AES-GCM cipher of the
+// replication secret key.
+func newReplicationSecretCipher() (cipher.AEAD, error) {
+	key, err := getReplicationSecretKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptReplicationSecret - encrypts a destination secret key,
+// returns base64 encoded nonce followed by the ciphertext.
+func encryptReplicationSecret(secret string) (string, error) {
+	aead, err := newReplicationSecretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// decryptReplicationSecret - decrypts a destination secret key
+// encrypted with encryptReplicationSecret.
+func decryptReplicationSecret(encrypted string) (string, error) {
+	aead, err := newReplicationSecretCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("Malformed encrypted secret key")
+	}
+	secret, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// removeBucketReplicationConfig - remove bucket replication configuration.
+func removeBucketReplicationConfig(bucket string) error {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(filepath.Join(bucketConfigPath, bucketReplicationConfigFile)); err != nil {
+		if os.IsNotExist(err) {
+			return BucketReplicationConfigNotFound{Bucket: bucket}
+		}
+		return err
+	}
+	return nil
+}
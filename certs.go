@@ -17,6 +17,11 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 )
@@ -87,3 +92,38 @@ func isSSL() bool {
 	}
 	return false
 }
+
+// getRootCAs - returns the system root CAs along with the public
+// certificate of this server, so that nodes sharing a self signed
+// certificate trust each other.
+func getRootCAs() (*x509.CertPool, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+	certPEM, err := ioutil.ReadFile(mustGetCertFile())
+	if err != nil {
+		return nil, err
+	}
+	if !rootCAs.AppendCertsFromPEM(certPEM) {
+		return nil, errors.New("Unable to parse public certificate " + mustGetCertFile())
+	}
+	return rootCAs, nil
+}
+
+// getClientTLSConfig - returns TLS config to connect to another node
+// at netAddr.
+func getClientTLSConfig(netAddr string) (*tls.Config, error) {
+	rootCAs, err := getRootCAs()
+	if err != nil {
+		return nil, err
+	}
+	host, _, err := net.SplitHostPort(netAddr)
+	if err != nil {
+		host = netAddr
+	}
+	return &tls.Config{
+		ServerName: host,
+		RootCAs:    rootCAs,
+	}, nil
+}
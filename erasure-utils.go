@@ -234,3 +234,28 @@ func copyBuffer(writer io.Writer, disk StorageAPI, volume string, path string, b
 	// Success.
 	return nil
 }
+
+// storageWriter - appends writes to a file on disk.
+type storageWriter struct {
+	disk   StorageAPI
+	volume string
+	path   string
+}
+
+func (w storageWriter) Write(p []byte) (int, error) {
+	if err := w.disk.AppendFile(w.volume, w.path, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// copyFile - copies a file to another location on the same disk,
+// without going through the object layer.
+func copyFile(disk StorageAPI, srcVolume, srcPath, dstVolume, dstPath string) error {
+	// Create the destination file, source file could be empty.
+	if err := disk.AppendFile(dstVolume, dstPath, []byte("")); err != nil {
+		return err
+	}
+	buf := make([]byte, readSizeV1)
+	return copyBuffer(storageWriter{disk, dstVolume, dstPath}, disk, srcVolume, srcPath, buf)
+}
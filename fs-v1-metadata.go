@@ -3,7 +3,11 @@ package main
 import (
 	"encoding/json"
 	"path"
+	"path/filepath"
 	"sort"
+	"strings"
+
+	"github.com/minio/minio/pkg/mimedb"
 )
 
 const (
@@ -18,7 +22,8 @@ type fsMetaV1 struct {
 	Minio   struct {
 		Release string `json:"release"`
 	} `json:"minio"`
-	Parts []objectPartInfo `json:"parts,omitempty"`
+	Parts []objectPartInfo  `json:"parts,omitempty"`
+	Meta  map[string]string `json:"meta,omitempty"`
 }
 
 // ObjectPartIndex - returns the index of matching object part number.
@@ -33,12 +38,13 @@ func (m fsMetaV1) ObjectPartIndex(partNumber int) (partIndex int) {
 }
 
 // AddObjectPart - add a new object part in order.
-func (m *fsMetaV1) AddObjectPart(partNumber int, partName string, partETag string, partSize int64) {
+func (m *fsMetaV1) AddObjectPart(partNumber int, partName string, partETag string, partSize int64, partChecksum string) {
 	partInfo := objectPartInfo{
-		Number: partNumber,
-		Name:   partName,
-		ETag:   partETag,
-		Size:   partSize,
+		Number:   partNumber,
+		Name:     partName,
+		ETag:     partETag,
+		Size:     partSize,
+		Checksum: partChecksum,
 	}
 
 	// Update part info if it already exists.
@@ -117,3 +123,121 @@ func (fs fsObjects) writeFSMetadata(bucket, prefix string, fsMeta fsMetaV1) erro
 	}
 	return nil
 }
+
+// getFSObjectMetadata - returns a copy of the object metadata to be
+// saved in `fs.json`, with the md5Sum of the object and a content-type
+// guessed from the extension if none was set.
+func getFSObjectMetadata(object string, metadata map[string]string, md5Hex string) map[string]string {
+	meta := make(map[string]string, len(metadata)+2)
+	for key, value := range metadata {
+		meta[key] = value
+	}
+	if md5Hex != "" {
+		meta["md5Sum"] = md5Hex
+	}
+	if meta["content-type"] == "" {
+		if contentType := guessContentType(object); contentType != "" {
+			meta["content-type"] = contentType
+		}
+	}
+	return meta
+}
+
+// guessContentType - guesses content-type from the object extension,
+// returns "" if unknown.
+func guessContentType(object string) string {
+	if objectExt := filepath.Ext(object); objectExt != "" {
+		if content, ok := mimedb.DB[strings.ToLower(strings.TrimPrefix(objectExt, "."))]; ok {
+			return content.ContentType
+		}
+	}
+	return ""
+}
+
+// toObjectInfo - converts file info and saved `fs.json` metadata to
+// object info. Objects saved before metadata was kept have no md5Sum,
+// their content-type is guessed from the extension.
+func (m fsMetaV1) toObjectInfo(bucket, object string, fi FileInfo) ObjectInfo {
+	objInfo := ObjectInfo{
+		Bucket:          bucket,
+		Name:            object,
+		ModTime:         fi.ModTime,
+		Size:            fi.Size,
+		IsDir:           fi.Mode.IsDir(),
+		MD5Sum:          m.Meta["md5Sum"],
+		ContentType:     m.Meta["content-type"],
+		ContentEncoding: m.Meta["content-encoding"],
+		UserDefined:     m.Meta,
+		Parts:           m.Parts,
+	}
+	if objInfo.ContentType == "" {
+		objInfo.ContentType = guessContentType(object)
+	}
+	return objInfo
+}
+
+// checkObjectLock - returns ObjectLocked if an existing object is
+// protected by object lock retention or legal hold. Objects are only
+// considered unlocked if they have no metadata, any other error while
+// reading the metadata is returned.
+func (fs fsObjects) checkObjectLock(bucket, object string) error {
+	fsMeta, err := readFSMetadata(fs.storage, minioMetaBucket, path.Join(bucketMetaPrefix, bucket, object))
+	if err != nil {
+		// Objects with too long names cannot exist.
+		if err == errFileNotFound || err == errFileNameTooLong {
+			return nil
+		}
+		return toObjectErr(err, bucket, object)
+	}
+	if isObjectLocked(fsMeta.Meta) {
+		return ObjectLocked{Bucket: bucket, Object: object}
+	}
+	return nil
+}
+
+// writeObjectMetadata - writes object `fs.json` at
+// '.minio/buckets/bucket/object/fs.json' along with the parts of
+// multipart objects, any previously saved metadata is removed if
+// there is nothing to save.
+func (fs fsObjects) writeObjectMetadata(bucket, object string, meta map[string]string, parts []objectPartInfo) error {
+	if len(meta) == 0 && len(parts) == 0 {
+		return fs.deleteObjectMetadata(bucket, object)
+	}
+	fsMeta := newFSMetaV1()
+	fsMeta.Meta = meta
+	fsMeta.Parts = parts
+
+	// Write `fs.json` to the temporary location and rename it to
+	// the actual location.
+	tempMetaPath := path.Join(tmpMetaPrefix, getUUID())
+	if err := fs.writeFSMetadata(minioMetaBucket, tempMetaPath, fsMeta); err != nil {
+		return err
+	}
+	objectMetaPath := path.Join(bucketMetaPrefix, bucket, object, fsMetaJSONFile)
+	if err := fs.storage.RenameFile(minioMetaBucket, path.Join(tempMetaPath, fsMetaJSONFile), minioMetaBucket, objectMetaPath); err != nil {
+		fs.storage.DeleteFile(minioMetaBucket, path.Join(tempMetaPath, fsMetaJSONFile))
+		return err
+	}
+	return nil
+}
+
+// deleteObjectMetadata - deletes object `fs.json` if present.
+func (fs fsObjects) deleteObjectMetadata(bucket, object string) error {
+	err := fs.storage.DeleteFile(minioMetaBucket, path.Join(bucketMetaPrefix, bucket, object, fsMetaJSONFile))
+	if err != nil && err != errFileNotFound {
+		return err
+	}
+	return nil
+}
+
+// objectUsage - returns size and count of an existing object, used
+// for bucket quota accounting.
+func (fs fsObjects) objectUsage(bucket, object string) (size int64, objects int64) {
+	fi, err := fs.storage.StatFile(bucket, object)
+	if err != nil {
+		return 0, 0
+	}
+	// Metadata is only needed for the size of compressed objects.
+	fsMeta, _ := readFSMetadata(fs.storage, minioMetaBucket, path.Join(bucketMetaPrefix, bucket, object))
+	return getQuotaSize(fsMeta.Meta, fi.Size), 1
+}
@@ -214,6 +214,7 @@ func (fs fsObjects) newMultipartUpload(bucket string, object string, meta map[st
 
 	// Initialize `fs.json` values.
 	fsMeta := newFSMetaV1()
+	fsMeta.Meta = meta
 
 	// This lock needs to be held for any changes to the directory contents of ".minio/multipart/object/"
 	nsMutex.Lock(minioMetaBucket, pathJoin(mpartMetaPrefix, bucket, object))
@@ -247,7 +248,11 @@ func (fs fsObjects) newMultipartUpload(bucket string, object string, meta map[st
 //
 // Implements S3 compatible initiate multipart API.
 func (fs fsObjects) NewMultipartUpload(bucket, object string, meta map[string]string) (string, error) {
-	meta = make(map[string]string) // Reset the meta value, we are not going to save headers for fs.
+	meta = getFSObjectMetadata(object, meta, "")
+	// If not set default to "application/octet-stream"
+	if meta["content-type"] == "" {
+		meta["content-type"] = "application/octet-stream"
+	}
 	// Verify if bucket name is valid.
 	if !IsValidBucketName(bucket) {
 		return "", BucketNameInvalid{Bucket: bucket}
@@ -260,6 +265,11 @@ func (fs fsObjects) NewMultipartUpload(bucket, object string, meta map[string]st
 	if !IsValidObjectName(object) {
 		return "", ObjectNameInvalid{Bucket: bucket, Object: object}
 	}
+	// Uploaded object is retained as per the bucket default retention.
+	meta, err := applyDefaultRetention(bucket, meta)
+	if err != nil {
+		return "", err
+	}
 	return fs.newMultipartUpload(bucket, object, meta)
 }
 
@@ -279,7 +289,6 @@ func (fs fsObjects) PutObjectPart(bucket, object, uploadID string, partID int, s
 	if !IsValidObjectName(object) {
 		return "", ObjectNameInvalid{Bucket: bucket, Object: object}
 	}
-
 	uploadIDPath := path.Join(mpartMetaPrefix, bucket, object, uploadID)
 
 	nsMutex.RLock(minioMetaBucket, uploadIDPath)
@@ -300,6 +309,19 @@ func (fs fsObjects) PutObjectPart(bucket, object, uploadID string, partID int, s
 	// Initialize md5 writer.
 	md5Writer := md5.New()
 
+	// Initialize checksum of the part, if the upload has a checksum
+	// algorithm.
+	uploadMeta, err := readFSMetadata(fs.storage, minioMetaBucket, uploadIDPath)
+	if err != nil {
+		return "", toObjectErr(err, minioMetaBucket, uploadIDPath)
+	}
+	// Verify if the upload fits in bucket quota before writing the part.
+	if err = globalBucketQuotas.checkUploadPart(bucket, uploadMeta.Parts, partID, size); err != nil {
+		return "", err
+	}
+	checksumHash := newChecksumHash(uploadMeta.Meta)
+	checksumWriter := newChecksumWriter(md5Writer, checksumHash)
+
 	// Allocate 32KiB buffer for staging buffer.
 	var buf = make([]byte, 128*1024)
 	for {
@@ -310,8 +332,8 @@ func (fs fsObjects) PutObjectPart(bucket, object, uploadID string, partID int, s
 		if err != nil && err != io.ErrUnexpectedEOF {
 			return "", toObjectErr(err, bucket, object)
 		}
-		// Update md5 writer.
-		md5Writer.Write(buf[:n])
+		// Update md5 and checksum writers.
+		checksumWriter.Write(buf[:n])
 		if err = fs.storage.AppendFile(minioMetaBucket, tmpPartPath, buf[:n]); err != nil {
 			return "", toObjectErr(err, bucket, object)
 		}
@@ -337,7 +359,7 @@ func (fs fsObjects) PutObjectPart(bucket, object, uploadID string, partID int, s
 	if err != nil {
 		return "", toObjectErr(err, minioMetaBucket, uploadIDPath)
 	}
-	fsMeta.AddObjectPart(partID, partSuffix, newMD5Hex, size)
+	fsMeta.AddObjectPart(partID, partSuffix, newMD5Hex, size, encodeChecksum(checksumHash))
 
 	partPath := path.Join(mpartMetaPrefix, bucket, object, uploadID, partSuffix)
 	err = fs.storage.RenameFile(minioMetaBucket, tmpPartPath, minioMetaBucket, partPath)
@@ -392,6 +414,7 @@ func (fs fsObjects) listObjectParts(bucket, object, uploadID string, partNumberM
 			ETag:         part.ETag,
 			LastModified: fi.ModTime,
 			Size:         fi.Size,
+			Checksum:     part.Checksum,
 		})
 		count--
 		if count == 0 {
@@ -410,6 +433,7 @@ func (fs fsObjects) listObjectParts(bucket, object, uploadID string, partNumberM
 	result.Object = object
 	result.UploadID = uploadID
 	result.MaxParts = maxParts
+	result.ChecksumAlgorithm = fsMeta.Meta[checksumAlgorithmKey]
 	return result, nil
 }
 
@@ -493,6 +517,12 @@ func (fs fsObjects) CompleteMultipartUpload(bucket string, object string, upload
 	// Allocate 128KiB of staging buffer.
 	var buf = make([]byte, readSizeV1)
 
+	// Total size of the object, used for bucket quota accounting.
+	var objectSize int64
+
+	// Parts of the completed object, saved to serve part numbers.
+	var objectParts []objectPartInfo
+
 	// Loop through all parts, validate them and then commit to disk.
 	for i, part := range parts {
 		partIdx := fsMeta.ObjectPartIndex(part.PartNumber)
@@ -510,6 +540,8 @@ func (fs fsObjects) CompleteMultipartUpload(bucket string, object string, upload
 				PartETag:   part.ETag,
 			}
 		}
+		objectSize += fsMeta.Parts[partIdx].Size
+		objectParts = append(objectParts, fsMeta.Parts[partIdx])
 		// Construct part suffix.
 		partSuffix := fmt.Sprintf("object%d", part.PartNumber)
 		multipartPartFile := path.Join(mpartMetaPrefix, bucket, object, uploadID, partSuffix)
@@ -541,15 +573,45 @@ func (fs fsObjects) CompleteMultipartUpload(bucket string, object string, upload
 		}
 	}
 
+	// Save the checksum of the object, if the upload has a checksum
+	// algorithm.
+	if err = completeMultipartChecksum(fsMeta.Meta, parts, objectParts); err != nil {
+		fs.storage.DeleteFile(minioMetaBucket, tempObj)
+		return "", err
+	}
+
+	// Hold write lock on the destination before rename.
+	nsMutex.Lock(bucket, object)
+	defer nsMutex.Unlock(bucket, object)
+
+	// Objects protected by object lock cannot be overwritten.
+	if err := fs.checkObjectLock(bucket, object); err != nil {
+		fs.storage.DeleteFile(minioMetaBucket, tempObj)
+		return "", err
+	}
+
+	// Verify and account for the new object in bucket quota.
+	oldSize, oldObjects := fs.objectUsage(bucket, object)
+	if err = globalBucketQuotas.reserve(bucket, objectSize-oldSize, 1-oldObjects); err != nil {
+		fs.storage.DeleteFile(minioMetaBucket, tempObj)
+		return "", err
+	}
+
 	// Rename the file back to original location, if not delete the temporary object.
 	err = fs.storage.RenameFile(minioMetaBucket, tempObj, bucket, object)
 	if err != nil {
+		globalBucketQuotas.update(bucket, oldSize-objectSize, oldObjects-1)
 		if dErr := fs.storage.DeleteFile(minioMetaBucket, tempObj); dErr != nil {
 			return "", toObjectErr(dErr, minioMetaBucket, tempObj)
 		}
 		return "", toObjectErr(err, bucket, object)
 	}
 
+	// Save object metadata along with the s3 compatible md5sum.
+	if err = fs.writeObjectMetadata(bucket, object, getFSObjectMetadata(object, fsMeta.Meta, s3MD5), objectParts); err != nil {
+		return "", toObjectErr(err, bucket, object)
+	}
+
 	// Cleanup all the parts if everything else has been safely committed.
 	if err = cleanupUploadedParts(bucket, object, uploadID, fs.storage); err != nil {
 		return "", err
@@ -23,12 +23,10 @@ import (
 	"io"
 	"os"
 	"path"
-	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/minio/minio/pkg/disk"
-	"github.com/minio/minio/pkg/mimedb"
 )
 
 // fsObjects - Implements fs object layer.
@@ -69,6 +67,11 @@ func shutdownFS(storage StorageAPI) {
 		// Multipart directory is not empty hence do not remove .minio volume.
 		os.Exit(0)
 	}
+	_, err = storage.ListDir(minioMetaBucket, bucketMetaPrefix)
+	if err != errFileNotFound {
+		// Object metadata is present hence do not remove .minio volume.
+		os.Exit(0)
+	}
 	prefix := ""
 	if err := cleanupDir(storage, minioMetaBucket, prefix); err != nil {
 		os.Exit(0)
@@ -271,23 +274,10 @@ func (fs fsObjects) GetObjectInfo(bucket, object string) (ObjectInfo, error) {
 		return ObjectInfo{}, toObjectErr(err, bucket, object)
 	}
 
-	// Guess content-type from the extension if possible.
-	contentType := ""
-	if objectExt := filepath.Ext(object); objectExt != "" {
-		if content, ok := mimedb.DB[strings.ToLower(strings.TrimPrefix(objectExt, "."))]; ok {
-			contentType = content.ContentType
-		}
-	}
-
-	return ObjectInfo{
-		Bucket:      bucket,
-		Name:        object,
-		ModTime:     fi.ModTime,
-		Size:        fi.Size,
-		IsDir:       fi.Mode.IsDir(),
-		ContentType: contentType,
-		MD5Sum:      "", // Read from metadata.
-	}, nil
+	// Read saved object metadata and parts if any, objects without
+	// saved metadata have none.
+	fsMeta, _ := readFSMetadata(fs.storage, minioMetaBucket, path.Join(bucketMetaPrefix, bucket, object))
+	return fsMeta.toObjectInfo(bucket, object, fi), nil
 }
 
 // PutObject - create an object.
@@ -303,6 +293,38 @@ func (fs fsObjects) PutObject(bucket string, object string, size int64, data io.
 		}
 	}
 
+	// New objects are retained as per the bucket default retention.
+	metadata, err := applyDefaultRetention(bucket, metadata)
+	if err != nil {
+		return "", err
+	}
+
+	nsMutex.Lock(bucket, object)
+	defer nsMutex.Unlock(bucket, object)
+
+	// Objects protected by object lock cannot be overwritten.
+	if err := fs.checkObjectLock(bucket, object); err != nil {
+		return "", err
+	}
+
+	// Verify and account for the new object in bucket quota before
+	// writing it, size of chunked uploads is accounted once written.
+	oldSize, oldObjects := fs.objectUsage(bucket, object)
+	reservedSize := getQuotaSize(metadata, size)
+	if reservedSize < 0 {
+		reservedSize = 0
+	}
+	if err := globalBucketQuotas.reserve(bucket, reservedSize-oldSize, 1-oldObjects); err != nil {
+		return "", err
+	}
+	committed := false
+	defer func() {
+		// Release the reserved usage if the object was not saved.
+		if !committed {
+			globalBucketQuotas.update(bucket, oldSize-reservedSize, oldObjects-1)
+		}
+	}()
+
 	uniqueID := getUUID()
 
 	// Uploaded object will first be written to the temporary location which will eventually
@@ -313,6 +335,12 @@ func (fs fsObjects) PutObject(bucket string, object string, size int64, data io.
 	// Initialize md5 writer.
 	md5Writer := md5.New()
 
+	// Initialize checksum of the object, if any.
+	checksumHash := newChecksumHash(metadata)
+	checksumWriter := newChecksumWriter(md5Writer, checksumHash)
+
+	// Total size written, size could be -1 for chunked uploads.
+	var sizeWritten int64
 	if size == 0 {
 		// For size 0 we write a 0byte file.
 		err := fs.storage.AppendFile(minioMetaBucket, tempObj, []byte(""))
@@ -330,12 +358,13 @@ func (fs fsObjects) PutObject(bucket string, object string, size int64, data io.
 				return "", toObjectErr(rErr, bucket, object)
 			}
 			if n > 0 {
-				// Update md5 writer.
-				md5Writer.Write(buf[:n])
+				// Update md5 and checksum writers.
+				checksumWriter.Write(buf[:n])
 				wErr := fs.storage.AppendFile(minioMetaBucket, tempObj, buf[:n])
 				if wErr != nil {
 					return "", toObjectErr(wErr, bucket, object)
 				}
+				sizeWritten += int64(n)
 			}
 			if rErr == io.EOF {
 				break
@@ -355,17 +384,164 @@ func (fs fsObjects) PutObject(bucket string, object string, size int64, data io.
 		}
 	}
 
+	// Save the checksum or verify it if it was set.
+	if err := updateChecksum(metadata, checksumHash); err != nil {
+		fs.storage.DeleteFile(minioMetaBucket, tempObj)
+		return "", err
+	}
+
+	// Account for the difference to the reserved size in bucket quota.
+	if quotaSize := getQuotaSize(metadata, sizeWritten); quotaSize != reservedSize {
+		if err := globalBucketQuotas.reserve(bucket, quotaSize-reservedSize, 0); err != nil {
+			fs.storage.DeleteFile(minioMetaBucket, tempObj)
+			return "", err
+		}
+		reservedSize = quotaSize
+	}
+
 	// Entire object was written to the temp location, now it's safe to rename it
 	// to the actual location.
-	err := fs.storage.RenameFile(minioMetaBucket, tempObj, bucket, object)
+	err = fs.storage.RenameFile(minioMetaBucket, tempObj, bucket, object)
 	if err != nil {
 		return "", toObjectErr(err, bucket, object)
 	}
+	committed = true
+
+	// Save object metadata along with its md5sum.
+	if err = fs.writeObjectMetadata(bucket, object, getFSObjectMetadata(object, metadata, newMD5Hex), nil); err != nil {
+		return "", toObjectErr(err, bucket, object)
+	}
 
 	// Return md5sum, successfully wrote object.
 	return newMD5Hex, nil
 }
 
+// CopyObject - copies an object by copying its file on the same disk,
+// only the metadata is rewritten if source and destination are the
+// same object, retaining the md5sum of the object.
+func (fs fsObjects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(srcBucket) {
+		return "", BucketNameInvalid{Bucket: srcBucket}
+	}
+	if !IsValidBucketName(dstBucket) {
+		return "", BucketNameInvalid{Bucket: dstBucket}
+	}
+	if !IsValidObjectName(srcObject) {
+		return "", ObjectNameInvalid{Bucket: srcBucket, Object: srcObject}
+	}
+	if !IsValidObjectName(dstObject) {
+		return "", ObjectNameInvalid{Bucket: dstBucket, Object: dstObject}
+	}
+	// New objects are retained as per the bucket default retention.
+	metadata, err := applyDefaultRetention(dstBucket, metadata)
+	if err != nil {
+		return "", err
+	}
+
+	// Metadata only update.
+	if srcBucket == dstBucket && srcObject == dstObject {
+		nsMutex.Lock(dstBucket, dstObject)
+		defer nsMutex.Unlock(dstBucket, dstObject)
+
+		if _, err := fs.storage.StatFile(srcBucket, srcObject); err != nil {
+			return "", toObjectErr(err, srcBucket, srcObject)
+		}
+		// Objects protected by object lock cannot be overwritten.
+		if err := fs.checkObjectLock(dstBucket, dstObject); err != nil {
+			return "", err
+		}
+		// Parts and md5sum of the object are retained.
+		fsMeta, _ := readFSMetadata(fs.storage, minioMetaBucket, path.Join(bucketMetaPrefix, dstBucket, dstObject))
+		md5Hex := fsMeta.Meta["md5Sum"]
+		if err := fs.writeObjectMetadata(dstBucket, dstObject, getFSObjectMetadata(dstObject, metadata, md5Hex), fsMeta.Parts); err != nil {
+			return "", toObjectErr(err, dstBucket, dstObject)
+		}
+		return md5Hex, nil
+	}
+
+	// Copy the source object to the temporary location, md5sum of the
+	// copy is calculated on the way.
+	tempObj := path.Join(tmpMetaPrefix, getUUID())
+	md5Writer := md5.New()
+	nsMutex.RLock(srcBucket, srcObject)
+	err = fs.storage.AppendFile(minioMetaBucket, tempObj, []byte(""))
+	if err == nil {
+		tempWriter := storageWriter{fs.storage, minioMetaBucket, tempObj}
+		err = copyBuffer(io.MultiWriter(tempWriter, md5Writer), fs.storage, srcBucket, srcObject, make([]byte, readSizeV1))
+	}
+	nsMutex.RUnlock(srcBucket, srcObject)
+	if err != nil {
+		fs.storage.DeleteFile(minioMetaBucket, tempObj)
+		return "", toObjectErr(err, srcBucket, srcObject)
+	}
+	fi, err := fs.storage.StatFile(minioMetaBucket, tempObj)
+	if err != nil {
+		fs.storage.DeleteFile(minioMetaBucket, tempObj)
+		return "", toObjectErr(err, srcBucket, srcObject)
+	}
+
+	nsMutex.Lock(dstBucket, dstObject)
+	defer nsMutex.Unlock(dstBucket, dstObject)
+
+	// Objects protected by object lock cannot be overwritten.
+	if err := fs.checkObjectLock(dstBucket, dstObject); err != nil {
+		fs.storage.DeleteFile(minioMetaBucket, tempObj)
+		return "", err
+	}
+
+	// Verify and account for the new object in bucket quota.
+	size := getQuotaSize(metadata, fi.Size)
+	oldSize, oldObjects := fs.objectUsage(dstBucket, dstObject)
+	if err = globalBucketQuotas.reserve(dstBucket, size-oldSize, 1-oldObjects); err != nil {
+		fs.storage.DeleteFile(minioMetaBucket, tempObj)
+		return "", err
+	}
+
+	if err = fs.storage.RenameFile(minioMetaBucket, tempObj, dstBucket, dstObject); err != nil {
+		globalBucketQuotas.update(dstBucket, oldSize-size, oldObjects-1)
+		fs.storage.DeleteFile(minioMetaBucket, tempObj)
+		return "", toObjectErr(err, dstBucket, dstObject)
+	}
+
+	// Save object metadata along with its md5sum.
+	md5Hex := hex.EncodeToString(md5Writer.Sum(nil))
+	if err = fs.writeObjectMetadata(dstBucket, dstObject, getFSObjectMetadata(dstObject, metadata, md5Hex), nil); err != nil {
+		return "", toObjectErr(err, dstBucket, dstObject)
+	}
+	return md5Hex, nil
+}
+
+// SetObjectLock - replaces the object lock metadata of an object,
+// retention of a locked object can only be extended.
+func (fs fsObjects) SetObjectLock(bucket, object string, lockMeta map[string]string) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	if !IsValidObjectName(object) {
+		return ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	nsMutex.Lock(bucket, object)
+	defer nsMutex.Unlock(bucket, object)
+
+	if _, err := fs.storage.StatFile(bucket, object); err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	fsMeta, err := readFSMetadata(fs.storage, minioMetaBucket, path.Join(bucketMetaPrefix, bucket, object))
+	if err != nil && err != errFileNotFound {
+		return toObjectErr(err, bucket, object)
+	}
+	metadata, ok := updateObjectLockMetadata(fsMeta.Meta, lockMeta)
+	if !ok {
+		return ObjectLocked{Bucket: bucket, Object: object}
+	}
+	if err = fs.writeObjectMetadata(bucket, object, metadata, fsMeta.Parts); err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+	return nil
+}
+
 func (fs fsObjects) DeleteObject(bucket, object string) error {
 	// Verify if bucket is valid.
 	if !IsValidBucketName(bucket) {
@@ -374,9 +550,24 @@ func (fs fsObjects) DeleteObject(bucket, object string) error {
 	if !IsValidObjectName(object) {
 		return ObjectNameInvalid{Bucket: bucket, Object: object}
 	}
+	nsMutex.Lock(bucket, object)
+	defer nsMutex.Unlock(bucket, object)
+
+	// Objects protected by object lock cannot be deleted.
+	if err := fs.checkObjectLock(bucket, object); err != nil {
+		return err
+	}
+	// Save object size for bucket quota accounting.
+	size, objects := fs.objectUsage(bucket, object)
 	if err := fs.storage.DeleteFile(bucket, object); err != nil {
 		return toObjectErr(err, bucket, object)
 	}
+	// Release the deleted object from bucket quota.
+	globalBucketQuotas.update(bucket, -size, -objects)
+	// Delete object metadata if any.
+	if err := fs.deleteObjectMetadata(bucket, object); err != nil {
+		return toObjectErr(err, bucket, object)
+	}
 	return nil
 }
 
@@ -422,12 +613,6 @@ func (fs fsObjects) listObjects(bucket, prefix, marker, delimiter string, maxKey
 	if !IsValidObjectPrefix(prefix) {
 		return ListObjectsInfo{}, ObjectNameInvalid{Bucket: bucket, Object: prefix}
 	}
-	// Verify if delimiter is anything other than '/', which we do not support.
-	if delimiter != "" && delimiter != slashSeparator {
-		return ListObjectsInfo{}, UnsupportedDelimiter{
-			Delimiter: delimiter,
-		}
-	}
 	// Verify if marker has prefix.
 	if marker != "" {
 		if !strings.HasPrefix(marker, prefix) {
@@ -457,6 +642,11 @@ func (fs fsObjects) listObjects(bucket, prefix, marker, delimiter string, maxKey
 		maxKeys = maxObjectList
 	}
 
+	// Delimiters other than '/' group the keys of a recursive listing.
+	if delimiter != "" && delimiter != slashSeparator {
+		return listObjectsDelimited(fs.listObjects, bucket, prefix, marker, delimiter, maxKeys)
+	}
+
 	// Default is recursive, if delimiter is set then list non recursive.
 	recursive := true
 	if delimiter == slashSeparator {
@@ -515,12 +705,9 @@ func (fs fsObjects) listObjects(bucket, prefix, marker, delimiter string, maxKey
 				continue
 			}
 		}
-		result.Objects = append(result.Objects, ObjectInfo{
-			Name:    fileInfo.Name,
-			ModTime: fileInfo.ModTime,
-			Size:    fileInfo.Size,
-			IsDir:   false,
-		})
+		// Read saved object metadata if any.
+		fsMeta, _ := readFSMetadata(fs.storage, minioMetaBucket, path.Join(bucketMetaPrefix, bucket, fileInfo.Name))
+		result.Objects = append(result.Objects, fsMeta.toObjectInfo(bucket, fileInfo.Name, fileInfo))
 	}
 	return result, nil
 }
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"testing"
@@ -37,3 +38,98 @@ func TestNewFS(t *testing.T) {
 		t.Fatalf("Unable to initialize erasure, %s", err)
 	}
 }
+
+// TestFSObjectMetadata - tests user metadata, content-type and md5sum
+// are saved and returned by FS like XL.
+func TestFSObjectMetadata(t *testing.T) {
+	obj, fsDir, err := getSingleNodeObjectLayer()
+	if err != nil {
+		t.Fatalf("Unable to initialize FS, %s", err)
+	}
+	defer removeAll(fsDir)
+
+	bucket := "bucket"
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// Single PUT.
+	metadata := map[string]string{
+		"content-type":        "application/custom",
+		"content-encoding":    "gzip",
+		"content-disposition": "attachment",
+		"cache-control":       "no-cache",
+		"X-Amz-Meta-Color":    "blue",
+	}
+	md5Hex, err := obj.PutObject(bucket, "object", 5, bytes.NewReader([]byte("hello")), metadata)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	objInfo, err := obj.GetObjectInfo(bucket, "object")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if objInfo.MD5Sum != md5Hex || objInfo.ContentType != "application/custom" || objInfo.ContentEncoding != "gzip" {
+		t.Errorf("Expected md5sum %s and saved content headers, but found %#v", md5Hex, objInfo)
+	}
+	for key, value := range metadata {
+		if objInfo.UserDefined[key] != value {
+			t.Errorf("Expected %s to be %s, but found %s", key, value, objInfo.UserDefined[key])
+		}
+	}
+
+	// Listing returns md5sum.
+	result, err := obj.ListObjects(bucket, "", "", "", 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(result.Objects) != 1 || result.Objects[0].MD5Sum != md5Hex {
+		t.Errorf("Expected listed object with md5sum %s, but found %#v", md5Hex, result.Objects)
+	}
+
+	// Metadata only copy retains md5sum.
+	copyMD5Hex, err := obj.CopyObject(bucket, "object", bucket, "object", map[string]string{"X-Amz-Meta-Color": "red"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if objInfo, err = obj.GetObjectInfo(bucket, "object"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if copyMD5Hex != md5Hex || objInfo.MD5Sum != md5Hex || objInfo.UserDefined["X-Amz-Meta-Color"] != "red" {
+		t.Errorf("Expected md5sum %s and replaced metadata, but found %s and %#v", md5Hex, copyMD5Hex, objInfo)
+	}
+
+	// Content-type is guessed if not set.
+	if _, err = obj.PutObject(bucket, "object.png", 5, bytes.NewReader([]byte("hello")), nil); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if objInfo, err = obj.GetObjectInfo(bucket, "object.png"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if objInfo.ContentType != "image/png" || objInfo.MD5Sum == "" {
+		t.Errorf("Expected guessed content-type and md5sum, but found %#v", objInfo)
+	}
+
+	// Multipart upload.
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", map[string]string{"X-Amz-Meta-Color": "green"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	partMD5Hex, err := obj.PutObjectPart(bucket, "multipart", uploadID, 1, 5, bytes.NewReader([]byte("hello")), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	s3MD5, err := obj.CompleteMultipartUpload(bucket, "multipart", uploadID, []completePart{{PartNumber: 1, ETag: partMD5Hex}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if objInfo, err = obj.GetObjectInfo(bucket, "multipart"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if objInfo.MD5Sum != s3MD5 || !isMultipartMD5(objInfo.MD5Sum) || len(objInfo.Parts) != 1 {
+		t.Errorf("Expected multipart md5sum %s with parts, but found %#v", s3MD5, objInfo)
+	}
+	if objInfo.ContentType != "application/octet-stream" || objInfo.UserDefined["X-Amz-Meta-Color"] != "green" {
+		t.Errorf("Expected default content-type and saved metadata, but found %#v", objInfo)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/minio/cli"
+)
+
+var gatewayCmd = cli.Command{
+	Name:  "gateway",
+	Usage: "Start object storage gateway to a remote S3 compatible server.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "address",
+			Value: ":9000",
+		},
+		cli.StringFlag{
+			Name:  "region",
+			Value: s3DefaultRegion,
+			Usage: "Region of the remote S3 compatible server.",
+		},
+	},
+	Action: gatewayMain,
+	CustomHelpTemplate: `NAME:
+  minio {{.Name}} - {{.Usage}}
+
+USAGE:
+  minio {{.Name}} [OPTIONS] s3 ENDPOINT
+
+OPTIONS:
+  {{range .Flags}}{{.}}
+  {{end}}
+ENVIRONMENT VARIABLES:
+  MINIO_ACCESS_KEY: Access key string of 5 to 20 characters in length.
+  MINIO_SECRET_KEY: Secret key string of 8 to 40 characters in length.
+  MINIO_GATEWAY_ACCESS_KEY: Access key of the remote S3 compatible server.
+  MINIO_GATEWAY_SECRET_KEY: Secret key of the remote S3 compatible server.
+  MINIO_CACHE_DRIVES: Comma separated list of drives to cache recently read objects.
+
+EXAMPLES:
+  1. Start minio gateway to AWS S3.
+      $ minio {{.Name}} s3 https://s3.amazonaws.com
+
+  2. Start minio gateway to another minio server in a different region.
+      $ minio {{.Name}} --region eu-west-1 s3 http://192.168.1.102:9000
+`,
+}
+
+// gatewayConfig - remote S3 compatible server of the gateway.
+type gatewayConfig struct {
+	endpoint  string
+	accessKey string
+	secretKey string
+	region    string
+}
+
+// Check gateway arguments.
+func checkGatewaySyntax(c *cli.Context) {
+	if len(c.Args()) != 2 || c.Args().First() != "s3" {
+		cli.ShowCommandHelpAndExit(c, "gateway", 1)
+	}
+}
+
+func gatewayMain(c *cli.Context) {
+	// check 'gateway' cli arguments.
+	checkGatewaySyntax(c)
+
+	// Initialize server config.
+	initServerConfig(c)
+
+	// Fetch remote server credentials from environment variables.
+	accessKey := os.Getenv("MINIO_GATEWAY_ACCESS_KEY")
+	secretKey := os.Getenv("MINIO_GATEWAY_SECRET_KEY")
+	if accessKey == "" || secretKey == "" {
+		fatalIf(errors.New("MINIO_GATEWAY_ACCESS_KEY and MINIO_GATEWAY_SECRET_KEY are not set"), "Remote server credentials are required.")
+	}
+
+	startServer(serverCmdConfig{
+		serverAddr: c.String("address"),
+		gateway: &gatewayConfig{
+			endpoint:  c.Args().Get(1),
+			accessKey: accessKey,
+			secretKey: secretKey,
+			region:    c.String("region"),
+		},
+	})
+}
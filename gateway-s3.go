@@ -0,0 +1,692 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s3Objects - implements ObjectLayer by forwarding all operations to
+// a remote S3 compatible endpoint.
+type s3Objects struct {
+	client *s3Client
+}
+
+// newS3Objects - initialize new s3 gateway object layer.
+func newS3Objects(endpoint, accessKey, secretKey, region string) (ObjectLayer, error) {
+	client, err := newS3Client(endpoint, accessKey, secretKey, region)
+	if err != nil {
+		return nil, err
+	}
+	return s3Objects{client: client}, nil
+}
+
+// s3CompleteMultipartUpload - complete multipart upload request body.
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+	Parts   []completePart `xml:"Part"`
+}
+
+// s3ToObjectErr - converts remote endpoint errors to object layer errors.
+func s3ToObjectErr(err error, params ...string) error {
+	errResp, ok := err.(s3ResponseError)
+	if !ok {
+		return err
+	}
+	var bucket, object, uploadID string
+	if len(params) >= 1 {
+		bucket = params[0]
+	}
+	if len(params) >= 2 {
+		object = params[1]
+	}
+	if len(params) >= 3 {
+		uploadID = params[2]
+	}
+	switch errResp.Code {
+	case "NoSuchBucket":
+		return BucketNotFound{Bucket: bucket}
+	case "BucketAlreadyOwnedByYou", "BucketAlreadyExists":
+		return BucketExists{Bucket: bucket}
+	case "BucketNotEmpty":
+		return BucketNotEmpty{Bucket: bucket}
+	case "InvalidBucketName":
+		return BucketNameInvalid{Bucket: bucket}
+	case "NoSuchKey", "NotFound":
+		if object == "" {
+			return BucketNotFound{Bucket: bucket}
+		}
+		return ObjectNotFound{Bucket: bucket, Object: object}
+	case "XMinioInvalidObjectName":
+		return ObjectNameInvalid{Bucket: bucket, Object: object}
+	case "NoSuchUpload":
+		return InvalidUploadID{UploadID: uploadID}
+	case "InvalidPart":
+		return InvalidPart{}
+	case "InvalidPartOrder":
+		return InvalidPartOrder{UploadID: uploadID}
+	case "EntityTooSmall":
+		return PartTooSmall{}
+	case "BadDigest":
+		return BadDigest{}
+	case "IncompleteBody":
+		return IncompleteBody{}
+	case "XMinioStorageFull":
+		return StorageFull{}
+	case "XMinioBucketQuotaExceeded":
+		return BucketQuotaExceeded{Bucket: bucket}
+	case "AccessDenied":
+		if object != "" && isObjectLockedMessage(errResp.Message) {
+			return ObjectLocked{Bucket: bucket, Object: object}
+		}
+		return PrefixAccessDenied{Bucket: bucket, Object: object}
+	}
+	return err
+}
+
+// isObjectLockedMessage - returns true if an access denied message
+// of the remote endpoint refers to object lock, as returned by minio
+// and by AWS S3.
+func isObjectLockedMessage(message string) bool {
+	if message == getAPIError(ErrObjectLocked).Description {
+		return true
+	}
+	return strings.Contains(strings.ToLower(message), "object lock")
+}
+
+// s3CopyToObjectErr - converts remote endpoint errors of a copy, a
+// missing object refers to the source and a missing bucket to the
+// bucket named by the remote endpoint, other errors refer to the
+// destination.
+func s3CopyToObjectErr(err error, srcBucket, srcObject, dstBucket, dstObject string) error {
+	errResp, ok := err.(s3ResponseError)
+	if !ok {
+		return err
+	}
+	switch errResp.Code {
+	case "NoSuchKey", "NotFound":
+		return s3ToObjectErr(err, srcBucket, srcObject)
+	case "NoSuchBucket":
+		if errResp.BucketName == srcBucket && srcBucket != dstBucket {
+			return s3ToObjectErr(err, srcBucket, srcObject)
+		}
+	}
+	return s3ToObjectErr(err, dstBucket, dstObject)
+}
+
+// s3MetadataHeaders - converts object metadata to request headers,
+// only content headers, user metadata and object lock metadata are
+// forwarded.
+func s3MetadataHeaders(metadata map[string]string) http.Header {
+	header := make(http.Header)
+	for key, value := range metadata {
+		if value == "" {
+			continue
+		}
+		lkey := strings.ToLower(key)
+		switch {
+		case lkey == "md5sum":
+			md5Bytes, err := hex.DecodeString(value)
+			if err == nil {
+				header.Set("Content-Md5", base64.StdEncoding.EncodeToString(md5Bytes))
+			}
+		case lkey == "content-type", lkey == "content-encoding":
+			header.Set(key, value)
+		case strings.HasPrefix(lkey, "x-amz-meta-"), strings.HasPrefix(lkey, "x-amz-object-lock-"):
+			header.Set(key, value)
+		}
+	}
+	return header
+}
+
+// parseS3Time - parses time returned by the remote endpoint, invalid
+// time is returned as zero time.
+func parseS3Time(value string) time.Time {
+	t, err := time.Parse(timeFormatAMZ, value)
+	if err != nil {
+		t, _ = time.Parse(time.RFC3339, value)
+	}
+	return t
+}
+
+// StorageInfo - storage capacity of the remote endpoint is unknown.
+func (s3 s3Objects) StorageInfo() StorageInfo {
+	return StorageInfo{}
+}
+
+/// Bucket operations
+
+// MakeBucket - make a bucket.
+func (s3 s3Objects) MakeBucket(bucket string) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	var body io.Reader
+	var size int64
+	// Buckets are created in the region of the remote endpoint.
+	if s3.client.region != s3DefaultRegion {
+		configBytes, err := xml.Marshal(createBucketLocationConfiguration{Location: s3.client.region})
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(configBytes)
+		size = int64(len(configBytes))
+	}
+	req, err := s3.client.newRequest("PUT", bucket, "", nil, body, size, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = s3.client.executeNoBody(req); err != nil {
+		return s3ToObjectErr(err, bucket)
+	}
+	return nil
+}
+
+// GetBucketInfo - get bucket info.
+func (s3 s3Objects) GetBucketInfo(bucket string) (BucketInfo, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	// Bucket creation date is only available from bucket listing.
+	buckets, err := s3.ListBuckets()
+	if err != nil {
+		return BucketInfo{}, err
+	}
+	for _, bucketInfo := range buckets {
+		if bucketInfo.Name == bucket {
+			return bucketInfo, nil
+		}
+	}
+	return BucketInfo{}, BucketNotFound{Bucket: bucket}
+}
+
+// ListBuckets - list buckets.
+func (s3 s3Objects) ListBuckets() ([]BucketInfo, error) {
+	req, err := s3.client.newRequest("GET", "", "", nil, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	var listBuckets ListBucketsResponse
+	if err = s3.client.executeXML(req, &listBuckets); err != nil {
+		return nil, s3ToObjectErr(err)
+	}
+	var bucketInfos []BucketInfo
+	for _, bucket := range listBuckets.Buckets.Buckets {
+		bucketInfos = append(bucketInfos, BucketInfo{
+			Name:    bucket.Name,
+			Created: parseS3Time(bucket.CreationDate),
+		})
+	}
+	sort.Sort(byBucketName(bucketInfos))
+	return bucketInfos, nil
+}
+
+// DeleteBucket - delete a bucket.
+func (s3 s3Objects) DeleteBucket(bucket string) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	req, err := s3.client.newRequest("DELETE", bucket, "", nil, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = s3.client.executeNoBody(req); err != nil {
+		return s3ToObjectErr(err, bucket)
+	}
+	return nil
+}
+
+// ListObjects - list all objects.
+func (s3 s3Objects) ListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ListObjectsInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	query := make(url.Values)
+	query.Set("prefix", prefix)
+	query.Set("marker", marker)
+	query.Set("delimiter", delimiter)
+	query.Set("max-keys", strconv.Itoa(maxKeys))
+	req, err := s3.client.newRequest("GET", bucket, "", query, nil, 0, nil)
+	if err != nil {
+		return ListObjectsInfo{}, err
+	}
+	var listObjects ListObjectsResponse
+	if err = s3.client.executeXML(req, &listObjects); err != nil {
+		return ListObjectsInfo{}, s3ToObjectErr(err, bucket)
+	}
+	result := ListObjectsInfo{
+		IsTruncated: listObjects.IsTruncated,
+		NextMarker:  listObjects.NextMarker,
+	}
+	for _, object := range listObjects.Contents {
+		result.Objects = append(result.Objects, ObjectInfo{
+			Bucket:  bucket,
+			Name:    object.Key,
+			ModTime: parseS3Time(object.LastModified),
+			Size:    object.Size,
+			MD5Sum:  strings.Trim(object.ETag, "\""),
+		})
+	}
+	for _, prefix := range listObjects.CommonPrefixes {
+		result.Prefixes = append(result.Prefixes, prefix.Prefix)
+	}
+	// Remote endpoint returns next marker only with delimiter.
+	if result.IsTruncated && result.NextMarker == "" && len(result.Objects) > 0 {
+		result.NextMarker = result.Objects[len(result.Objects)-1].Name
+	}
+	return result, nil
+}
+
+/// Object Operations
+
+// GetObject - get an object.
+func (s3 s3Objects) GetObject(bucket, object string, offset int64, length int64, writer io.Writer) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	if length <= 0 {
+		return nil
+	}
+	header := make(http.Header)
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	req, err := s3.client.newRequest("GET", bucket, object, nil, nil, 0, header)
+	if err != nil {
+		return err
+	}
+	resp, err := s3.client.execute(req)
+	if err != nil {
+		return s3ToObjectErr(err, bucket, object)
+	}
+	defer resp.Body.Close()
+	n, err := io.Copy(writer, io.LimitReader(resp.Body, length))
+	if err != nil {
+		return err
+	}
+	if n < length {
+		return IncompleteBody{}
+	}
+	return nil
+}
+
+// GetObjectInfo - get object info.
+func (s3 s3Objects) GetObjectInfo(bucket, object string) (ObjectInfo, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ObjectInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return ObjectInfo{}, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	req, err := s3.client.newRequest("HEAD", bucket, object, nil, nil, 0, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	header, err := s3.client.executeNoBody(req)
+	if err != nil {
+		return ObjectInfo{}, s3ToObjectErr(err, bucket, object)
+	}
+	size, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	modTime, _ := time.Parse(http.TimeFormat, header.Get("Last-Modified"))
+
+	// Save remote metadata as user defined metadata.
+	userDefined := make(map[string]string)
+	for key := range header {
+		lkey := strings.ToLower(key)
+		switch {
+		case lkey == "content-type", lkey == "content-encoding", lkey == replicationStatusKey:
+			userDefined[lkey] = header.Get(key)
+		case strings.HasPrefix(lkey, "x-amz-meta-"), strings.HasPrefix(lkey, "x-amz-object-lock-"):
+			userDefined[lkey] = header.Get(key)
+		}
+	}
+
+	return ObjectInfo{
+		Bucket:          bucket,
+		Name:            object,
+		ModTime:         modTime,
+		Size:            size,
+		MD5Sum:          strings.Trim(header.Get("ETag"), "\""),
+		ContentType:     header.Get("Content-Type"),
+		ContentEncoding: header.Get("Content-Encoding"),
+		UserDefined:     userDefined,
+	}, nil
+}
+
+// PutObject - create an object.
+func (s3 s3Objects) PutObject(bucket string, object string, size int64, data io.Reader, metadata map[string]string) (string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return "", BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return "", ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+
+	// Remote endpoint requires content length, spool data of
+	// unknown size to a temporary file first.
+	if size == -1 {
+		tmpFile, err := ioutil.TempFile("", "minio-gateway-")
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(tmpFile.Name())
+		defer tmpFile.Close()
+		if size, err = io.Copy(tmpFile, data); err != nil {
+			return "", toObjectErr(err, bucket, object)
+		}
+		if _, err = tmpFile.Seek(0, 0); err != nil {
+			return "", err
+		}
+		data = tmpFile
+	}
+
+	md5Hex, err := s3.client.putObject(bucket, object, size, data, s3MetadataHeaders(metadata))
+	if err != nil {
+		return "", s3ToObjectErr(err, bucket, object)
+	}
+	return md5Hex, nil
+}
+
+// CopyObject - copies an object with server side copy on the remote
+// endpoint, data is never read through the gateway.
+func (s3 s3Objects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(srcBucket) {
+		return "", BucketNameInvalid{Bucket: srcBucket}
+	}
+	if !IsValidBucketName(dstBucket) {
+		return "", BucketNameInvalid{Bucket: dstBucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(srcObject) {
+		return "", ObjectNameInvalid{Bucket: srcBucket, Object: srcObject}
+	}
+	if !IsValidObjectName(dstObject) {
+		return "", ObjectNameInvalid{Bucket: dstBucket, Object: dstObject}
+	}
+
+	header := s3MetadataHeaders(metadata)
+	header.Del("Content-Md5")
+	header.Set("X-Amz-Copy-Source", getURLEncodedName("/"+srcBucket+"/"+srcObject))
+	header.Set("X-Amz-Metadata-Directive", "REPLACE")
+	req, err := s3.client.newRequest("PUT", dstBucket, dstObject, nil, nil, 0, header)
+	if err != nil {
+		return "", err
+	}
+	var result CopyObjectResponse
+	if err = s3.client.executeXML(req, &result); err != nil {
+		return "", s3CopyToObjectErr(err, srcBucket, srcObject, dstBucket, dstObject)
+	}
+	return strings.Trim(result.ETag, "\""), nil
+}
+
+// DeleteObject - delete an object.
+func (s3 s3Objects) DeleteObject(bucket, object string) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	req, err := s3.client.newRequest("DELETE", bucket, object, nil, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = s3.client.executeNoBody(req); err != nil {
+		return s3ToObjectErr(err, bucket, object)
+	}
+	return nil
+}
+
+// SetObjectLock - object lock of remote objects is managed on the
+// remote endpoint, not supported.
+func (s3 s3Objects) SetObjectLock(bucket, object string, lockMeta map[string]string) error {
+	return NotImplemented{}
+}
+
+/// Multipart operations
+
+// ListMultipartUploads - list incomplete multipart uploads.
+func (s3 s3Objects) ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (ListMultipartsInfo, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ListMultipartsInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	query := make(url.Values)
+	query.Set("uploads", "")
+	query.Set("prefix", prefix)
+	query.Set("key-marker", keyMarker)
+	query.Set("upload-id-marker", uploadIDMarker)
+	query.Set("delimiter", delimiter)
+	query.Set("max-uploads", strconv.Itoa(maxUploads))
+	req, err := s3.client.newRequest("GET", bucket, "", query, nil, 0, nil)
+	if err != nil {
+		return ListMultipartsInfo{}, err
+	}
+	var listUploads ListMultipartUploadsResponse
+	if err = s3.client.executeXML(req, &listUploads); err != nil {
+		return ListMultipartsInfo{}, s3ToObjectErr(err, bucket)
+	}
+	result := ListMultipartsInfo{
+		KeyMarker:          listUploads.KeyMarker,
+		UploadIDMarker:     listUploads.UploadIDMarker,
+		NextKeyMarker:      listUploads.NextKeyMarker,
+		NextUploadIDMarker: listUploads.NextUploadIDMarker,
+		MaxUploads:         listUploads.MaxUploads,
+		IsTruncated:        listUploads.IsTruncated,
+		Prefix:             listUploads.Prefix,
+		Delimiter:          listUploads.Delimiter,
+	}
+	for _, upload := range listUploads.Uploads {
+		result.Uploads = append(result.Uploads, uploadMetadata{
+			Object:    upload.Key,
+			UploadID:  upload.UploadID,
+			Initiated: parseS3Time(upload.Initiated),
+		})
+	}
+	for _, prefix := range listUploads.CommonPrefixes {
+		result.CommonPrefixes = append(result.CommonPrefixes, prefix.Prefix)
+	}
+	return result, nil
+}
+
+// NewMultipartUpload - initialize a new multipart upload.
+func (s3 s3Objects) NewMultipartUpload(bucket, object string, metadata map[string]string) (string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return "", BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return "", ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	query := make(url.Values)
+	query.Set("uploads", "")
+	req, err := s3.client.newRequest("POST", bucket, object, query, nil, 0, s3MetadataHeaders(metadata))
+	if err != nil {
+		return "", err
+	}
+	var initiateUpload InitiateMultipartUploadResponse
+	if err = s3.client.executeXML(req, &initiateUpload); err != nil {
+		return "", s3ToObjectErr(err, bucket, object)
+	}
+	return initiateUpload.UploadID, nil
+}
+
+// PutObjectPart - upload a part of a multipart upload.
+func (s3 s3Objects) PutObjectPart(bucket, object, uploadID string, partID int, size int64, data io.Reader, md5Hex string) (string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return "", BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return "", ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	query := make(url.Values)
+	query.Set("partNumber", strconv.Itoa(partID))
+	query.Set("uploadId", uploadID)
+	var body io.Reader
+	if size != 0 {
+		body = data
+	}
+	req, err := s3.client.newRequest("PUT", bucket, object, query, body, size, s3MetadataHeaders(map[string]string{"md5Sum": md5Hex}))
+	if err != nil {
+		return "", err
+	}
+	header, err := s3.client.executeNoBody(req)
+	if err != nil {
+		return "", s3ToObjectErr(err, bucket, object, uploadID)
+	}
+	return strings.Trim(header.Get("ETag"), "\""), nil
+}
+
+// ListObjectParts - list uploaded parts of a multipart upload.
+func (s3 s3Objects) ListObjectParts(bucket, object, uploadID string, partNumberMarker, maxParts int) (ListPartsInfo, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return ListPartsInfo{}, BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return ListPartsInfo{}, ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	query := make(url.Values)
+	query.Set("uploadId", uploadID)
+	query.Set("part-number-marker", strconv.Itoa(partNumberMarker))
+	query.Set("max-parts", strconv.Itoa(maxParts))
+	req, err := s3.client.newRequest("GET", bucket, object, query, nil, 0, nil)
+	if err != nil {
+		return ListPartsInfo{}, err
+	}
+	var listParts ListPartsResponse
+	if err = s3.client.executeXML(req, &listParts); err != nil {
+		return ListPartsInfo{}, s3ToObjectErr(err, bucket, object, uploadID)
+	}
+	result := ListPartsInfo{
+		Bucket:               bucket,
+		Object:               object,
+		UploadID:             uploadID,
+		StorageClass:         listParts.StorageClass,
+		PartNumberMarker:     listParts.PartNumberMarker,
+		NextPartNumberMarker: listParts.NextPartNumberMarker,
+		MaxParts:             listParts.MaxParts,
+		IsTruncated:          listParts.IsTruncated,
+	}
+	for _, part := range listParts.Parts {
+		result.Parts = append(result.Parts, partInfo{
+			PartNumber:   part.PartNumber,
+			LastModified: parseS3Time(part.LastModified),
+			ETag:         strings.Trim(part.ETag, "\""),
+			Size:         part.Size,
+		})
+	}
+	return result, nil
+}
+
+// AbortMultipartUpload - abort a multipart upload.
+func (s3 s3Objects) AbortMultipartUpload(bucket, object, uploadID string) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	query := make(url.Values)
+	query.Set("uploadId", uploadID)
+	req, err := s3.client.newRequest("DELETE", bucket, object, query, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = s3.client.executeNoBody(req); err != nil {
+		return s3ToObjectErr(err, bucket, object, uploadID)
+	}
+	return nil
+}
+
+// CompleteMultipartUpload - complete a multipart upload.
+func (s3 s3Objects) CompleteMultipartUpload(bucket, object, uploadID string, parts []completePart) (string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return "", BucketNameInvalid{Bucket: bucket}
+	}
+	// Verify if object is valid.
+	if !IsValidObjectName(object) {
+		return "", ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	completeBytes, err := xml.Marshal(s3CompleteMultipartUpload{Parts: parts})
+	if err != nil {
+		return "", err
+	}
+	query := make(url.Values)
+	query.Set("uploadId", uploadID)
+	req, err := s3.client.newRequest("POST", bucket, object, query, bytes.NewReader(completeBytes), int64(len(completeBytes)), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s3.client.execute(req)
+	if err != nil {
+		return "", s3ToObjectErr(err, bucket, object, uploadID)
+	}
+	defer resp.Body.Close()
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	// Remote endpoint may fail with an error document after
+	// returning success status.
+	var errResp APIErrorResponse
+	if xml.Unmarshal(respBytes, &errResp) == nil && errResp.Code != "" {
+		return "", s3ToObjectErr(s3ResponseError{StatusCode: resp.StatusCode, APIErrorResponse: errResp}, bucket, object, uploadID)
+	}
+	var completeUpload CompleteMultipartUploadResponse
+	if err = xml.Unmarshal(respBytes, &completeUpload); err != nil {
+		return "", err
+	}
+	return strings.Trim(completeUpload.ETag, "\""), nil
+}
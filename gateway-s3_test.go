@@ -0,0 +1,292 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Tests validate conversion of remote endpoint errors.
+func TestS3ToObjectErr(t *testing.T) {
+	testCases := []struct {
+		code        string
+		message     string
+		params      []string
+		expectedErr error
+	}{
+		// Test case - 1.
+		{"NoSuchBucket", "", []string{"bucket"}, BucketNotFound{Bucket: "bucket"}},
+		// Test case - 2.
+		{"NoSuchKey", "", []string{"bucket", "object"}, ObjectNotFound{Bucket: "bucket", Object: "object"}},
+		// Test case - 3.
+		// HEAD responses carry no error code.
+		{"NotFound", "", []string{"bucket", "object"}, ObjectNotFound{Bucket: "bucket", Object: "object"}},
+		// Test case - 4.
+		{"NoSuchUpload", "", []string{"bucket", "object", "upload"}, InvalidUploadID{UploadID: "upload"}},
+		// Test case - 5.
+		// Object locked on a minio server.
+		{"AccessDenied", getAPIError(ErrObjectLocked).Description, []string{"bucket", "object"}, ObjectLocked{Bucket: "bucket", Object: "object"}},
+		// Test case - 6.
+		// Object locked on AWS S3.
+		{"AccessDenied", "Access Denied because object protected by object lock.", []string{"bucket", "object"}, ObjectLocked{Bucket: "bucket", Object: "object"}},
+		// Test case - 7.
+		// Access denied for other reasons.
+		{"AccessDenied", "Access Denied", []string{"bucket", "object"}, PrefixAccessDenied{Bucket: "bucket", Object: "object"}},
+		// Test case - 8.
+		{"BucketAlreadyOwnedByYou", "", []string{"bucket"}, BucketExists{Bucket: "bucket"}},
+	}
+	for i, testCase := range testCases {
+		err := s3ToObjectErr(s3ResponseError{APIErrorResponse: APIErrorResponse{Code: testCase.code, Message: testCase.message}}, testCase.params...)
+		if err != testCase.expectedErr {
+			t.Errorf("Test %d: Expected error '%v', but found '%v'", i+1, testCase.expectedErr, err)
+		}
+	}
+
+	// Unknown errors are returned as is.
+	errResp := s3ResponseError{StatusCode: 403, APIErrorResponse: APIErrorResponse{Code: "SignatureDoesNotMatch"}}
+	if err := s3ToObjectErr(errResp, "bucket"); err != errResp {
+		t.Errorf("Expected error '%v', but found '%v'", errResp, err)
+	}
+
+	// Copy errors refer to the source only for a missing source.
+	copyErr := func(code, bucketName string) error {
+		return s3CopyToObjectErr(s3ResponseError{APIErrorResponse: APIErrorResponse{Code: code, BucketName: bucketName}}, "src", "a", "dst", "b")
+	}
+	if err := copyErr("NoSuchKey", ""); err != (ObjectNotFound{Bucket: "src", Object: "a"}) {
+		t.Errorf("Expected missing source object, but found '%v'", err)
+	}
+	if err := copyErr("NoSuchBucket", "src"); err != (BucketNotFound{Bucket: "src"}) {
+		t.Errorf("Expected missing source bucket, but found '%v'", err)
+	}
+	if err := copyErr("NoSuchBucket", "dst"); err != (BucketNotFound{Bucket: "dst"}) {
+		t.Errorf("Expected missing destination bucket, but found '%v'", err)
+	}
+	if err := copyErr("XMinioBucketQuotaExceeded", ""); err != (BucketQuotaExceeded{Bucket: "dst"}) {
+		t.Errorf("Expected destination quota exceeded, but found '%v'", err)
+	}
+}
+
+// Tests validate s3 gateway object layer against a remote server.
+func TestS3Gateway(t *testing.T) {
+	remote := StartTestServer(t, "XL")
+	defer remote.Stop()
+
+	obj, err := newS3Objects(remote.Server.URL, remote.AccessKey, remote.SecretKey, "us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bucket := getRandomBucketName()
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+	if err = obj.MakeBucket(bucket); err == nil {
+		t.Fatal("Expected to fail creating an existing bucket, but passed instead")
+	}
+	bucketInfo, err := obj.GetBucketInfo(bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bucketInfo.Name != bucket || bucketInfo.Created.IsZero() {
+		t.Errorf("Expected bucket info of '%s', but found '%v'", bucket, bucketInfo)
+	}
+	if _, err = obj.GetBucketInfo("missing-" + bucket[:20]); err == nil {
+		t.Error("Expected missing bucket to fail, but passed instead")
+	} else if _, ok := err.(BucketNotFound); !ok {
+		t.Errorf("Expected BucketNotFound, but found '%v'", err)
+	}
+
+	// Objects of known and unknown size.
+	data := []byte("hello, world")
+	md5Bytes := md5.Sum(data)
+	md5Hex := hex.EncodeToString(md5Bytes[:])
+	metadata := map[string]string{"md5Sum": md5Hex, "content-type": "text/plain"}
+	if md5Sum, pErr := obj.PutObject(bucket, "dir/a.txt", int64(len(data)), bytes.NewReader(data), metadata); pErr != nil {
+		t.Fatal(pErr)
+	} else if md5Sum != md5Hex {
+		t.Errorf("Expected md5 '%s', but found '%s'", md5Hex, md5Sum)
+	}
+	if _, err = obj.PutObject(bucket, "b.txt", -1, bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.PutObject(bucket, "bad.txt", int64(len(data)), bytes.NewReader(data), map[string]string{"md5Sum": hex.EncodeToString(make([]byte, 16))}); err == nil {
+		t.Error("Expected bad digest to fail, but passed instead")
+	}
+
+	objInfo, err := obj.GetObjectInfo(bucket, "dir/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if objInfo.Size != int64(len(data)) || objInfo.MD5Sum != md5Hex || objInfo.ContentType != "text/plain" {
+		t.Errorf("Unexpected object info '%v'", objInfo)
+	}
+	if _, err = obj.GetObjectInfo(bucket, "missing"); err == nil {
+		t.Error("Expected missing object to fail, but passed instead")
+	} else if _, ok := err.(ObjectNotFound); !ok {
+		t.Errorf("Expected ObjectNotFound, but found '%v'", err)
+	}
+
+	var buffer bytes.Buffer
+	if err = obj.GetObject(bucket, "dir/a.txt", 7, 5, &buffer); err != nil {
+		t.Fatal(err)
+	}
+	if buffer.String() != "world" {
+		t.Errorf("Expected ranged data 'world', but found '%s'", buffer.String())
+	}
+
+	// Server side copy on the remote endpoint.
+	if md5Sum, cErr := obj.CopyObject(bucket, "dir/a.txt", bucket, "dir/copy.txt", map[string]string{"content-type": "text/plain"}); cErr != nil {
+		t.Fatal(cErr)
+	} else if md5Sum != md5Hex {
+		t.Errorf("Expected copied md5 '%s', but found '%s'", md5Hex, md5Sum)
+	}
+	buffer.Reset()
+	if err = obj.GetObject(bucket, "dir/copy.txt", 0, int64(len(data)), &buffer); err != nil {
+		t.Fatal(err)
+	}
+	if buffer.String() != string(data) {
+		t.Errorf("Expected copied data '%s', but found '%s'", data, buffer.String())
+	}
+
+	result, err := obj.ListObjects(bucket, "", "", "/", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 1 || result.Objects[0].Name != "b.txt" {
+		t.Errorf("Expected object 'b.txt', but found '%v'", result.Objects)
+	}
+	if len(result.Prefixes) != 1 || result.Prefixes[0] != "dir/" {
+		t.Errorf("Expected prefix 'dir/', but found '%v'", result.Prefixes)
+	}
+
+	// Multipart upload.
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploads, err := obj.ListMultipartUploads(bucket, "", "", "", "", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uploads.Uploads) != 1 || uploads.Uploads[0].UploadID != uploadID {
+		t.Errorf("Expected upload '%s', but found '%v'", uploadID, uploads.Uploads)
+	}
+	partMD5, err := obj.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), md5Hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := obj.ListObjectParts(bucket, "multipart", uploadID, 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts.Parts) != 1 || parts.Parts[0].ETag != partMD5 {
+		t.Errorf("Expected part '%s', but found '%v'", partMD5, parts.Parts)
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, []completePart{{PartNumber: 1, ETag: partMD5}}); err != nil {
+		t.Fatal(err)
+	}
+	if objInfo, err = obj.GetObjectInfo(bucket, "multipart"); err != nil {
+		t.Fatal(err)
+	} else if objInfo.Size != int64(len(data)) {
+		t.Errorf("Expected multipart object size %d, but found %d", len(data), objInfo.Size)
+	}
+	if err = obj.AbortMultipartUpload(bucket, "multipart", uploadID); err == nil {
+		t.Error("Expected aborting completed upload to fail, but passed instead")
+	} else if _, ok := err.(InvalidUploadID); !ok {
+		t.Errorf("Expected InvalidUploadID, but found '%v'", err)
+	}
+
+	// Bucket cannot be removed until all objects are deleted.
+	if err = obj.DeleteBucket(bucket); err == nil {
+		t.Error("Expected deleting non empty bucket to fail, but passed instead")
+	} else if _, ok := err.(BucketNotEmpty); !ok {
+		t.Errorf("Expected BucketNotEmpty, but found '%v'", err)
+	}
+	for _, object := range []string{"dir/a.txt", "dir/copy.txt", "b.txt", "multipart"} {
+		if err = obj.DeleteObject(bucket, object); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = obj.DeleteBucket(bucket); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Tests validate s3 gateway server serves requests from a remote server.
+func TestS3GatewayServer(t *testing.T) {
+	remote := StartTestServer(t, "XL")
+	defer remote.Stop()
+
+	gateway := httptest.NewServer(configureServerHandler(serverCmdConfig{
+		gateway: &gatewayConfig{
+			endpoint:  remote.Server.URL,
+			accessKey: remote.AccessKey,
+			secretKey: remote.SecretKey,
+		},
+	}))
+	defer gateway.Close()
+
+	bucket := getRandomBucketName()
+	data := []byte("hello, world")
+	testCases := []struct {
+		method     string
+		url        string
+		body       []byte
+		statusCode int
+	}{
+		// Test case - 1.
+		{"PUT", getMakeBucketURL(gateway.URL, bucket), nil, http.StatusOK},
+		// Test case - 2.
+		{"PUT", getPutObjectURL(gateway.URL, bucket, "object"), data, http.StatusOK},
+		// Test case - 3.
+		// Object is stored on the remote server.
+		{"GET", getGetObjectURL(remote.Server.URL, bucket, "object"), nil, http.StatusOK},
+		// Test case - 4.
+		{"GET", getGetObjectURL(gateway.URL, bucket, "object"), nil, http.StatusOK},
+		// Test case - 5.
+		{"DELETE", getDeleteObjectURL(gateway.URL, bucket, "object"), nil, http.StatusNoContent},
+		// Test case - 6.
+		{"GET", getGetObjectURL(gateway.URL, bucket, "object"), nil, http.StatusNotFound},
+	}
+	for i, testCase := range testCases {
+		req, err := newTestRequest(testCase.method, testCase.url, int64(len(testCase.body)), bytes.NewReader(testCase.body), remote.AccessKey, remote.SecretKey)
+		if err != nil {
+			t.Fatalf("Test %d: <ERROR> %s", i+1, err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: <ERROR> %s", i+1, err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Test %d: <ERROR> %s", i+1, err)
+		}
+		if resp.StatusCode != testCase.statusCode {
+			t.Fatalf("Test %d: Expected status %d, but found %d: %s", i+1, testCase.statusCode, resp.StatusCode, body)
+		}
+		if testCase.method == "GET" && resp.StatusCode == http.StatusOK && !bytes.Equal(body, data) {
+			t.Errorf("Test %d: Expected data '%s', but found '%s'", i+1, data, body)
+		}
+	}
+}
@@ -276,7 +276,6 @@ var notimplementedBucketResourceNames = map[string]bool{
 	"lifecycle":      true,
 	"logging":        true,
 	"notification":   true,
-	"replication":    true,
 	"tagging":        true,
 	"versions":       true,
 	"requestPayment": true,
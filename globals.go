@@ -43,6 +43,13 @@ var (
 	// Maximum connections handled per
 	// server, defaults to 0 (unlimited).
 	globalMaxConn = 0
+
+	// Set if the server runs XL with disks on remote nodes.
+	globalIsDistXL = false
+
+	// Deadline of disk calls, defaults to diskCallTimeout, calls
+	// have no deadline if it is 0.
+	globalDiskCallTimeout = diskCallTimeout
 	// Add new variable global values here.
 )
 
@@ -19,12 +19,16 @@ package main
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 const (
 	b = "bytes="
+
+	// Maximum number of ranges accepted in a single Range header.
+	maxHTTPRanges = 100
 )
 
 // InvalidRange - invalid range
@@ -34,6 +38,9 @@ func (e InvalidRange) Error() string {
 	return "The requested range is not satisfiable"
 }
 
+// errUnsatisfiableRange - range starts beyond the end of the object.
+var errUnsatisfiableRange = errors.New("range starts beyond the end of the object")
+
 // HttpRange specifies the byte range to be sent to the client.
 type httpRange struct {
 	start, length, size int64
@@ -44,23 +51,76 @@ func (r *httpRange) String() string {
 	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, r.size)
 }
 
-// Grab new range from request header
-func getRequestedRange(hrange string, size int64) (*httpRange, error) {
-	r := &httpRange{
-		start:  0,
-		length: 0,
-		size:   0,
+// getRequestedRanges - parses all the ranges of a Range header as per
+// RFC 7233, returns no ranges if the header is empty. Multiple ranges
+// are sorted by offset, overlapping and adjacent ranges are collapsed
+// into one, empty and unsatisfiable ranges are dropped. InvalidRange is
+// returned if none of the ranges is satisfiable.
+func getRequestedRanges(hrange string, size int64) ([]*httpRange, error) {
+	if hrange == "" {
+		return nil, nil
 	}
-	r.size = size
-	if hrange != "" {
-		err := r.parseRange(hrange)
-		if err != nil {
+	if !strings.HasPrefix(hrange, b) {
+		return nil, InvalidRange{}
+	}
+	ras := strings.Split(hrange[len(b):], ",")
+	// Guard against requests for a large number of small ranges.
+	if len(ras) > maxHTTPRanges {
+		return nil, InvalidRange{}
+	}
+	var ranges []*httpRange
+	for _, ra := range ras {
+		ra = strings.TrimSpace(ra)
+		if ra == "" {
+			return nil, InvalidRange{}
+		}
+		r := &httpRange{size: size}
+		if err := r.parse(ra); err != nil {
+			if err == errUnsatisfiableRange {
+				// Satisfiable ranges of the list are still served.
+				continue
+			}
 			return nil, err
 		}
+		ranges = append(ranges, r)
 	}
-	return r, nil
+	if len(ras) == 1 {
+		if len(ranges) == 0 {
+			return nil, InvalidRange{}
+		}
+		return ranges, nil
+	}
+
+	sort.Sort(byRangeStart(ranges))
+	var collapsed []*httpRange
+	for _, r := range ranges {
+		if r.length == 0 {
+			continue
+		}
+		if len(collapsed) > 0 {
+			last := collapsed[len(collapsed)-1]
+			if r.start <= last.start+last.length {
+				if end := r.start + r.length; end > last.start+last.length {
+					last.length = end - last.start
+				}
+				continue
+			}
+		}
+		collapsed = append(collapsed, r)
+	}
+	if len(collapsed) == 0 {
+		return nil, InvalidRange{}
+	}
+	return collapsed, nil
 }
 
+// byRangeStart is a collection satisfying sort.Interface.
+type byRangeStart []*httpRange
+
+func (d byRangeStart) Len() int           { return len(d) }
+func (d byRangeStart) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d byRangeStart) Less(i, j int) bool { return d[i].start < d[j].start }
+
 func (r *httpRange) parse(ra string) error {
 	i := strings.Index(ra, "-")
 	if i < 0 {
@@ -81,9 +141,12 @@ func (r *httpRange) parse(ra string) error {
 		r.length = r.size - r.start
 	} else {
 		i, err := strconv.ParseInt(start, 10, 64)
-		if err != nil || i > r.size || i < 0 {
+		if err != nil || i < 0 {
 			return InvalidRange{}
 		}
+		if i > r.size {
+			return errUnsatisfiableRange
+		}
 		r.start = i
 		if end == "" {
 			// If no end is specified, range extends to end of the file.
@@ -102,27 +165,27 @@ func (r *httpRange) parse(ra string) error {
 	return nil
 }
 
-// parseRange parses a Range header string as per RFC 2616.
-func (r *httpRange) parseRange(s string) error {
-	if s == "" {
-		return errors.New("header not present")
-	}
+// parseCopyPartRange parses x-amz-copy-source-range of the form
+// bytes=first-last, both offsets are required unlike the Range header.
+func parseCopyPartRange(s string, size int64) (*httpRange, error) {
 	if !strings.HasPrefix(s, b) {
-		return InvalidRange{}
+		return nil, errors.New("invalid copy source range")
 	}
-
-	ras := strings.Split(s[len(b):], ",")
-	if len(ras) == 0 {
-		return errors.New("invalid request")
+	offsets := strings.SplitN(s[len(b):], "-", 2)
+	if len(offsets) != 2 {
+		return nil, errors.New("invalid copy source range")
 	}
-	// Just pick the first one and ignore the rest, we only support one range per object
-	if len(ras) > 1 {
-		return errors.New("multiple ranges specified")
+	start, err := strconv.ParseInt(offsets[0], 10, 64)
+	if err != nil || start < 0 {
+		return nil, errors.New("invalid copy source range")
 	}
-
-	ra := strings.TrimSpace(ras[0])
-	if ra == "" {
-		return InvalidRange{}
+	end, err := strconv.ParseInt(offsets[1], 10, 64)
+	if err != nil || end < start {
+		return nil, errors.New("invalid copy source range")
+	}
+	// The range must be within the source object.
+	if end >= size {
+		return nil, InvalidRange{}
 	}
-	return r.parse(ra)
+	return &httpRange{start: start, length: end - start + 1, size: size}, nil
 }
@@ -0,0 +1,100 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Tests validate parsing of single and multiple ranges.
+func TestGetRequestedRanges(t *testing.T) {
+	testCases := []struct {
+		hrange string
+		// expected ranges as start, length pairs.
+		ranges [][2]int64
+		// flag indicating whether the test should pass.
+		shouldPass bool
+	}{
+		// Test case - 1.
+		// No range.
+		{"", nil, true},
+		// Test case - 2.
+		// Single range.
+		{"bytes=10-19", [][2]int64{{10, 10}}, true},
+		// Test case - 3.
+		// Multiple ranges are sorted.
+		{"bytes=50-59, 0-9", [][2]int64{{0, 10}, {50, 10}}, true},
+		// Test case - 4.
+		// Overlapping and adjacent ranges are collapsed.
+		{"bytes=0-9,5-14,15-19,40-", [][2]int64{{0, 20}, {40, 60}}, true},
+		// Test case - 5.
+		// Suffix range contained in another range.
+		{"bytes=0-,-10", [][2]int64{{0, 100}}, true},
+		// Test case - 6.
+		// Empty ranges are dropped.
+		{"bytes=100-,0-0", [][2]int64{{0, 1}}, true},
+		// Test case - 7.
+		// Invalid unit.
+		{"items=0-9", nil, false},
+		// Test case - 8.
+		// Invalid range in a list.
+		{"bytes=0-9,20-10", nil, false},
+		// Test case - 9.
+		// Empty range in a list.
+		{"bytes=0-9,,20-29", nil, false},
+		// Test case - 10.
+		// Too many ranges.
+		{"bytes=" + strings.Repeat("0-0,", maxHTTPRanges) + "0-0", nil, false},
+		// Test case - 11.
+		// Unsatisfiable range in a list is dropped.
+		{"bytes=0-9,5000-6000", [][2]int64{{0, 10}}, true},
+		// Test case - 12.
+		// Unsatisfiable range in a list is dropped, regardless of its position.
+		{"bytes=5000-6000,50-59,0-9", [][2]int64{{0, 10}, {50, 10}}, true},
+		// Test case - 13.
+		// No satisfiable range in a list.
+		{"bytes=5000-6000,100-", nil, false},
+		// Test case - 14.
+		// Single unsatisfiable range.
+		{"bytes=5000-6000", nil, false},
+		// Test case - 15.
+		// Invalid range in a list with an unsatisfiable range.
+		{"bytes=5000-6000,20-10", nil, false},
+	}
+	for i, testCase := range testCases {
+		ranges, err := getRequestedRanges(testCase.hrange, 100)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: Expected to pass, but failed with: <ERROR> %s", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: Expected to fail, but passed", i+1)
+		}
+		if err != nil || !testCase.shouldPass {
+			continue
+		}
+		if len(ranges) != len(testCase.ranges) {
+			t.Errorf("Test %d: Expected %d ranges, but found %d", i+1, len(testCase.ranges), len(ranges))
+			continue
+		}
+		for j, r := range ranges {
+			if r.start != testCase.ranges[j][0] || r.length != testCase.ranges[j][1] {
+				t.Errorf("Test %d: Expected range %d to be %v, but found %d, %d", i+1, j+1, testCase.ranges[j], r.start, r.length)
+			}
+		}
+	}
+}
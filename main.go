@@ -105,6 +105,8 @@ func findClosestCommands(command string) []string {
 func registerApp() *cli.App {
 	// Register all commands.
 	registerCommand(serverCmd)
+	registerCommand(gatewayCmd)
+	registerCommand(migrateCmd)
 	registerCommand(versionCmd)
 	registerCommand(updateCmd)
 
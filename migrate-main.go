@@ -0,0 +1,561 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/console"
+)
+
+var migrateCmd = cli.Command{
+	Name:  "migrate",
+	Usage: "Migrate an FS export into a new erasure coded (XL) backend.",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "target-config-dir",
+			Usage: "Path to configuration folder of the XL server, bucket policies are copied into it.",
+		},
+	},
+	Action: migrateMain,
+	CustomHelpTemplate: `NAME:
+  minio {{.Name}} - {{.Usage}}
+
+USAGE:
+  minio {{.Name}} [OPTIONS] FS-PATH XL-PATH [XL-PATH...]
+
+OPTIONS:
+  {{range .Flags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  Buckets, objects and incomplete multipart uploads are copied from FS-PATH into
+  the XL disks and verified against their checksums. Stop the server of FS-PATH
+  before migrating. An interrupted migration resumes when run again with the
+  same arguments.
+
+EXAMPLES:
+  1. Migrate an FS export into 8 disks.
+      $ minio {{.Name}} /home/shared /mnt/export1/backend /mnt/export2/backend /mnt/export3/backend \
+          /mnt/export4/backend /mnt/export5/backend /mnt/export6/backend /mnt/export7/backend /mnt/export8/backend
+
+  2. Migrate and copy bucket policies into the configuration folder of the new server.
+      $ minio {{.Name}} --target-config-dir /etc/minio-xl /home/shared /mnt/export1/backend /mnt/export2/backend \
+          /mnt/export3/backend /mnt/export4/backend /mnt/export5/backend /mnt/export6/backend /mnt/export7/backend \
+          /mnt/export8/backend
+`,
+}
+
+// Journal of a migration, saved in the meta volume of the FS export.
+const migrateJournalFile = "migrate.json"
+
+var errMigrateDstNotEmpty = errors.New("XL disks already have buckets, migration needs fresh disks")
+
+// migrateJournal - progress of a migration, an interrupted migration
+// resumes from it.
+type migrateJournal struct {
+	Version string   `json:"version"`
+	Disks   []string `json:"disks"`
+	// Multipart objects being copied, object to upload id on XL.
+	Objects map[string]string `json:"objects"`
+	// Incomplete uploads, bucket/object/uploadID on FS to upload id
+	// on XL.
+	Uploads map[string]string `json:"uploads"`
+}
+
+// migrateStats - summary of a migration.
+type migrateStats struct {
+	Buckets  int
+	Objects  int
+	Skipped  int
+	Bytes    int64
+	Uploads  int
+	Parts    int
+	Policies int
+}
+
+// fsMigration - migration of an FS export into XL.
+type fsMigration struct {
+	src             fsObjects
+	dst             ObjectLayer
+	targetConfigDir string
+	journal         migrateJournal
+	stats           migrateStats
+}
+
+// Check migrate arguments.
+func checkMigrateSyntax(c *cli.Context) {
+	if len(c.Args()) < 2 || c.Args().First() == "help" {
+		cli.ShowCommandHelpAndExit(c, "migrate", 1)
+	}
+}
+
+func migrateMain(c *cli.Context) {
+	// check 'migrate' cli arguments.
+	checkMigrateSyntax(c)
+
+	// Initialize server config.
+	initServerConfig(c)
+
+	srcPath := c.Args().First()
+	stats, err := migrateFSToXL(srcPath, c.Args().Tail(), c.String("target-config-dir"))
+	fatalIf(err, "Unable to migrate %s.", srcPath)
+
+	console.Printf("Migrated %d buckets, %d objects (%s), %d incomplete uploads and %d bucket policies, %d objects were already migrated.\n",
+		stats.Buckets, stats.Objects, humanize.IBytes(uint64(stats.Bytes)), stats.Uploads, stats.Policies, stats.Skipped)
+}
+
+// printMigrateProgress - prints progress of a migration unless quiet.
+func printMigrateProgress(format string, data ...interface{}) {
+	if !globalQuiet {
+		console.Printf(format, data...)
+	}
+}
+
+// migrateFSToXL - copies all buckets, objects, incomplete uploads and
+// bucket policies of an FS export into XL disks.
+func migrateFSToXL(srcPath string, dstDisks []string, targetConfigDir string) (migrateStats, error) {
+	for _, disk := range dstDisks {
+		if filepath.Clean(disk) == filepath.Clean(srcPath) {
+			return migrateStats{}, fmt.Errorf("FS export %s cannot be one of the XL disks", srcPath)
+		}
+	}
+
+	// Verify the source is an FS export before touching it.
+	storage, err := newStorageAPI(srcPath)
+	if err != nil {
+		return migrateStats{}, err
+	}
+	format, err := loadFormatFS(storage)
+	if err == errFileNotFound || err == errVolumeNotFound || err == nil && format.Format != "fs" {
+		return migrateStats{}, fmt.Errorf("%s is not an FS export", srcPath)
+	}
+	if err != nil {
+		return migrateStats{}, err
+	}
+
+	srcAPI, err := newFSObjects(srcPath)
+	if err != nil {
+		return migrateStats{}, err
+	}
+	dstAPI, err := newXLObjects(dstDisks)
+	if err != nil {
+		return migrateStats{}, err
+	}
+
+	m := &fsMigration{
+		src:             srcAPI.(fsObjects),
+		dst:             dstAPI,
+		targetConfigDir: targetConfigDir,
+	}
+	if err = m.loadJournal(dstDisks); err != nil {
+		return migrateStats{}, err
+	}
+
+	buckets, err := m.src.ListBuckets()
+	if err != nil {
+		return migrateStats{}, err
+	}
+	for _, bucket := range buckets {
+		if err = m.migrateBucket(bucket.Name); err != nil {
+			return m.stats, err
+		}
+	}
+	return m.stats, nil
+}
+
+// loadJournal - loads the journal of an interrupted migration, starts
+// a new one if there is none.
+func (m *fsMigration) loadJournal(disks []string) error {
+	buf, err := m.src.storage.ReadAll(minioMetaBucket, migrateJournalFile)
+	if err == errFileNotFound {
+		// Objects already present on XL are never overwritten by a
+		// new migration.
+		buckets, lErr := m.dst.ListBuckets()
+		if lErr != nil {
+			return lErr
+		}
+		if len(buckets) > 0 {
+			return errMigrateDstNotEmpty
+		}
+		m.journal = migrateJournal{
+			Version: "1",
+			Disks:   disks,
+			Objects: make(map[string]string),
+			Uploads: make(map[string]string),
+		}
+		return m.saveJournal()
+	}
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(buf, &m.journal); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(m.journal.Disks, disks) {
+		return fmt.Errorf("An earlier migration of this export was into %s", strings.Join(m.journal.Disks, " "))
+	}
+	if m.journal.Objects == nil {
+		m.journal.Objects = make(map[string]string)
+	}
+	if m.journal.Uploads == nil {
+		m.journal.Uploads = make(map[string]string)
+	}
+	return nil
+}
+
+// saveJournal - saves the journal to a temporary location and renames
+// it to the actual location.
+func (m *fsMigration) saveJournal() error {
+	journalBytes, err := json.Marshal(m.journal)
+	if err != nil {
+		return err
+	}
+	tempJournalPath := path.Join(tmpMetaPrefix, getUUID())
+	if err = m.src.storage.AppendFile(minioMetaBucket, tempJournalPath, journalBytes); err != nil {
+		return err
+	}
+	if err = m.src.storage.RenameFile(minioMetaBucket, tempJournalPath, minioMetaBucket, migrateJournalFile); err != nil {
+		m.src.storage.DeleteFile(minioMetaBucket, tempJournalPath)
+		return err
+	}
+	return nil
+}
+
+// migrateBucket - migrates a bucket, its config, objects and
+// incomplete uploads.
+func (m *fsMigration) migrateBucket(bucket string) error {
+	if err := m.dst.MakeBucket(bucket); err != nil {
+		if _, ok := err.(BucketExists); !ok {
+			return err
+		}
+	}
+	m.stats.Buckets++
+	printMigrateProgress("Migrating bucket %s\n", bucket)
+
+	if err := m.migrateBucketConfig(bucket); err != nil {
+		return err
+	}
+
+	marker := ""
+	for {
+		result, err := m.src.ListObjects(bucket, "", marker, "", maxObjectList)
+		if err != nil {
+			return err
+		}
+		for _, objInfo := range result.Objects {
+			if err = m.migrateObject(bucket, objInfo.Name); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextMarker
+	}
+
+	keyMarker, uploadIDMarker := "", ""
+	for {
+		result, err := m.src.ListMultipartUploads(bucket, "", keyMarker, uploadIDMarker, "", maxUploadsList)
+		if err != nil {
+			return err
+		}
+		for _, upload := range result.Uploads {
+			if err = m.migrateUpload(bucket, upload.Object, upload.UploadID); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated {
+			break
+		}
+		keyMarker, uploadIDMarker = result.NextKeyMarker, result.NextUploadIDMarker
+	}
+	return nil
+}
+
+// migrateBucketConfig - copies all files of the bucket config folder,
+// i.e policy, object lock, quota, usage and replication config, into
+// the target config folder if set. Bucket config is saved in the
+// config folder, a server of the XL disks sharing the config folder
+// uses it as it is. Only the policy is validated, an invalid policy is
+// not migrated.
+func (m *fsMigration) migrateBucketConfig(bucket string) error {
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(bucketConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		configBytes, err := ioutil.ReadFile(filepath.Join(bucketConfigPath, file.Name()))
+		if err != nil {
+			return err
+		}
+		if file.Name() == "access-policy.json" {
+			if _, err = parseBucketPolicy(configBytes); err != nil {
+				errorIf(err, "Invalid policy of bucket %s is not migrated.", bucket)
+				continue
+			}
+			m.stats.Policies++
+		}
+		if m.targetConfigDir == "" {
+			continue
+		}
+		targetConfigPath := filepath.Join(m.targetConfigDir, "buckets", bucket)
+		if err = os.MkdirAll(targetConfigPath, 0700); err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(filepath.Join(targetConfigPath, file.Name()), configBytes, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateObject - copies an object with its metadata unless it was
+// already copied, and verifies the copy. Multipart objects are copied
+// part by part, so that they keep their parts and md5sum.
+func (m *fsMigration) migrateObject(bucket, object string) error {
+	srcInfo, err := m.src.GetObjectInfo(bucket, object)
+	if err != nil {
+		return err
+	}
+	key := path.Join(bucket, object)
+
+	// Copy of a multipart object was interrupted, start it over.
+	if uploadID, ok := m.journal.Objects[key]; ok {
+		m.dst.AbortMultipartUpload(bucket, object, uploadID)
+		delete(m.journal.Objects, key)
+		if err = m.saveJournal(); err != nil {
+			return err
+		}
+	}
+
+	md5Hex := srcInfo.MD5Sum
+	isMultipart := isMultipartMD5(md5Hex) && len(srcInfo.Parts) > 0
+	if md5Hex == "" || isMultipartMD5(md5Hex) && !isMultipart {
+		// Objects saved before metadata was kept in `fs.json` have
+		// no md5sum or parts, they are copied as a single part.
+		if md5Hex, err = objectMD5(m.src, bucket, object, srcInfo.Size); err != nil {
+			return err
+		}
+	}
+
+	if dstInfo, dErr := m.dst.GetObjectInfo(bucket, object); dErr == nil && dstInfo.Size == srcInfo.Size && dstInfo.MD5Sum == md5Hex {
+		m.stats.Skipped++
+		return nil
+	}
+
+	printMigrateProgress("Copying %s (%s)\n", key, humanize.IBytes(uint64(srcInfo.Size)))
+	var srcMD5Hex string
+	if isMultipart {
+		srcMD5Hex, err = m.copyMultipartObject(srcInfo)
+	} else {
+		srcMD5Hex, err = m.copyObject(srcInfo, md5Hex)
+	}
+	if err != nil {
+		return toObjectErr(err, bucket, object)
+	}
+
+	// Read back the copy, verifies it was saved as it was read.
+	dstMD5Hex, err := objectMD5(m.dst, bucket, object, srcInfo.Size)
+	if err != nil {
+		return err
+	}
+	if dstMD5Hex != srcMD5Hex {
+		return fmt.Errorf("Checksum of %s is %s after migration, expected %s", key, dstMD5Hex, srcMD5Hex)
+	}
+	m.stats.Objects++
+	m.stats.Bytes += srcInfo.Size
+	return nil
+}
+
+// copyObject - copies an object in a single part, returns the md5sum
+// of the data read.
+func (m *fsMigration) copyObject(srcInfo ObjectInfo, md5Hex string) (string, error) {
+	metadata := getMigrateMetadata(srcInfo.UserDefined)
+	// XL verifies the data against the md5sum.
+	metadata["md5Sum"] = md5Hex
+
+	reader := newObjectReader(m.src, srcInfo.Bucket, srcInfo.Name, 0, srcInfo.Size)
+	md5Writer := md5.New()
+	_, err := m.dst.PutObject(srcInfo.Bucket, srcInfo.Name, srcInfo.Size, io.TeeReader(reader, md5Writer), metadata)
+	reader.CloseWithError(err)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(md5Writer.Sum(nil)), nil
+}
+
+// copyMultipartObject - copies a multipart object part by part,
+// returns the md5sum of the data read.
+func (m *fsMigration) copyMultipartObject(srcInfo ObjectInfo) (string, error) {
+	bucket, object := srcInfo.Bucket, srcInfo.Name
+	uploadID, err := m.dst.NewMultipartUpload(bucket, object, getMigrateMetadata(srcInfo.UserDefined))
+	if err != nil {
+		return "", err
+	}
+	m.journal.Objects[path.Join(bucket, object)] = uploadID
+	if err = m.saveJournal(); err != nil {
+		return "", err
+	}
+
+	md5Writer := md5.New()
+	completeParts := make([]completePart, len(srcInfo.Parts))
+	var offset int64
+	for i, part := range srcInfo.Parts {
+		reader := newObjectReader(m.src, bucket, object, offset, part.Size)
+		// XL verifies each part against its md5sum.
+		_, err = m.dst.PutObjectPart(bucket, object, uploadID, part.Number, part.Size, io.TeeReader(reader, md5Writer), part.ETag)
+		reader.CloseWithError(err)
+		if err != nil {
+			m.dst.AbortMultipartUpload(bucket, object, uploadID)
+			return "", err
+		}
+		completeParts[i] = completePart{PartNumber: part.Number, ETag: part.ETag}
+		offset += part.Size
+	}
+	md5Hex, err := m.dst.CompleteMultipartUpload(bucket, object, uploadID, completeParts)
+	if err != nil {
+		m.dst.AbortMultipartUpload(bucket, object, uploadID)
+		return "", err
+	}
+	if md5Hex != srcInfo.MD5Sum {
+		return "", BadDigest{srcInfo.MD5Sum, md5Hex}
+	}
+
+	delete(m.journal.Objects, path.Join(bucket, object))
+	if err = m.saveJournal(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(md5Writer.Sum(nil)), nil
+}
+
+// migrateUpload - recreates an incomplete upload on XL with all of its
+// parts. Uploads get a new upload id on XL, the mapping is printed and
+// kept in the journal.
+func (m *fsMigration) migrateUpload(bucket, object, srcUploadID string) error {
+	uploadIDPath := path.Join(mpartMetaPrefix, bucket, object, srcUploadID)
+	fsMeta, err := readFSMetadata(m.src.storage, minioMetaBucket, uploadIDPath)
+	if err != nil {
+		return toObjectErr(err, minioMetaBucket, uploadIDPath)
+	}
+	key := path.Join(bucket, object, srcUploadID)
+
+	// Parts copied before an interruption are not copied again.
+	dstParts := make(map[int]string)
+	uploadID, ok := m.journal.Uploads[key]
+	if ok {
+		partNumberMarker := 0
+		for {
+			result, lErr := m.dst.ListObjectParts(bucket, object, uploadID, partNumberMarker, maxPartsList)
+			if lErr != nil {
+				return lErr
+			}
+			for _, part := range result.Parts {
+				dstParts[part.PartNumber] = part.ETag
+			}
+			if !result.IsTruncated {
+				break
+			}
+			partNumberMarker = result.NextPartNumberMarker
+		}
+	} else {
+		if uploadID, err = m.dst.NewMultipartUpload(bucket, object, getMigrateMetadata(fsMeta.Meta)); err != nil {
+			return err
+		}
+		m.journal.Uploads[key] = uploadID
+		if err = m.saveJournal(); err != nil {
+			return err
+		}
+		printMigrateProgress("Upload %s of %s continues as %s\n", srcUploadID, path.Join(bucket, object), uploadID)
+	}
+
+	for _, part := range fsMeta.Parts {
+		if dstParts[part.Number] == part.ETag {
+			continue
+		}
+		reader, err := newStorageReader(m.src.storage, minioMetaBucket, path.Join(uploadIDPath, part.Name))
+		if err != nil {
+			return toObjectErr(err, minioMetaBucket, uploadIDPath)
+		}
+		if _, err = m.dst.PutObjectPart(bucket, object, uploadID, part.Number, part.Size, reader, part.ETag); err != nil {
+			return err
+		}
+		m.stats.Parts++
+	}
+	m.stats.Uploads++
+	return nil
+}
+
+// getMigrateMetadata - returns a copy of object metadata without its
+// md5sum, which is computed again by XL.
+func getMigrateMetadata(metadata map[string]string) map[string]string {
+	meta := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		if key != "md5Sum" {
+			meta[key] = value
+		}
+	}
+	return meta
+}
+
+// newObjectReader - returns a reader of length bytes of an object from
+// offset.
+func newObjectReader(objAPI ObjectLayer, bucket, object string, offset, length int64) *io.PipeReader {
+	pipeReader, pipeWriter := io.Pipe()
+	// Empty objects have no range to read.
+	if length == 0 {
+		pipeWriter.Close()
+		return pipeReader
+	}
+	go func() {
+		pipeWriter.CloseWithError(objAPI.GetObject(bucket, object, offset, length, pipeWriter))
+	}()
+	return pipeReader
+}
+
+// objectMD5 - returns md5sum of the content of an object.
+func objectMD5(objAPI ObjectLayer, bucket, object string, size int64) (string, error) {
+	reader := newObjectReader(objAPI, bucket, object, 0, size)
+	defer reader.Close()
+	md5Writer := md5.New()
+	if _, err := io.Copy(md5Writer, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(md5Writer.Sum(nil)), nil
+}
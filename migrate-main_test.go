@@ -0,0 +1,227 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests migration of an FS export into XL, including resuming it.
+func TestMigrateFSToXL(t *testing.T) {
+	globalQuiet = true
+	defer func() { globalQuiet = false }()
+
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to initialize config: <ERROR> %s", err)
+	}
+	defer removeAll(rootPath)
+
+	obj, fsDir, err := getSingleNodeObjectLayer()
+	if err != nil {
+		t.Fatalf("Unable to initialize FS: <ERROR> %s", err)
+	}
+	defer removeAll(fsDir)
+
+	var disks []string
+	for i := 0; i < 8; i++ {
+		disk, dErr := ioutil.TempDir(os.TempDir(), "minio-")
+		if dErr != nil {
+			t.Fatalf("Unable to create disk: <ERROR> %s", dErr)
+		}
+		disks = append(disks, disk)
+	}
+	defer removeRoots(disks)
+
+	bucket := "bucket"
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if err = obj.MakeBucket("empty"); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+
+	// Objects of a single part.
+	objects := map[string][]byte{
+		"object.txt":       []byte("hello, world"),
+		"dir/object":       bytes.Repeat([]byte("a"), 1024*1024),
+		"dir/empty-object": {},
+	}
+	for object, data := range objects {
+		_, err = obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), map[string]string{"X-Amz-Meta-Color": "blue"})
+		if err != nil {
+			t.Fatalf("Unexpected error: <ERROR> %s", err)
+		}
+	}
+
+	// Multipart object.
+	part1, part2 := bytes.Repeat([]byte("b"), 5*1024*1024), []byte("last part")
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", map[string]string{"content-type": "application/custom"})
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	var completeParts []completePart
+	for i, part := range [][]byte{part1, part2} {
+		md5Hex, pErr := obj.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(len(part)), bytes.NewReader(part), "")
+		if pErr != nil {
+			t.Fatalf("Unexpected error: <ERROR> %s", pErr)
+		}
+		completeParts = append(completeParts, completePart{PartNumber: i + 1, ETag: md5Hex})
+	}
+	multipartMD5, err := obj.CompleteMultipartUpload(bucket, "multipart", uploadID, completeParts)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	objects["multipart"] = append(append([]byte{}, part1...), part2...)
+
+	// Incomplete upload.
+	incompleteID, err := obj.NewMultipartUpload(bucket, "incomplete", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	incompleteMD5, err := obj.PutObjectPart(bucket, "incomplete", incompleteID, 1, int64(len(part1)), bytes.NewReader(part1), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+
+	// Bucket policy.
+	policyBytes, err := json.Marshal(BucketPolicy{Version: "1.0", Statements: getReadOnlyStatement(bucket, "")})
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if err = writeBucketPolicy(bucket, policyBytes); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	// Bucket quota and usage.
+	if err = globalBucketQuotas.set(bucket, bucketQuota{Size: 1 << 30}, bucketUsage{Size: 10, Objects: 1}); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	defer globalBucketQuotas.remove(bucket)
+	targetConfigDir, err := getTestRoot()
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	defer removeAll(targetConfigDir)
+
+	// FS export cannot be migrated into itself.
+	if _, err = migrateFSToXL(fsDir, append([]string{fsDir}, disks[1:]...), ""); err == nil {
+		t.Fatal("Expected migration into the FS export to fail")
+	}
+
+	stats, err := migrateFSToXL(fsDir, disks, targetConfigDir)
+	if err != nil {
+		t.Fatalf("Unable to migrate: <ERROR> %s", err)
+	}
+	if stats.Buckets != 2 || stats.Objects != len(objects) || stats.Skipped != 0 || stats.Uploads != 1 || stats.Parts != 1 || stats.Policies != 1 {
+		t.Errorf("Unexpected migration stats %#v", stats)
+	}
+
+	xl, err := newXLObjects(disks)
+	if err != nil {
+		t.Fatalf("Unable to initialize XL: <ERROR> %s", err)
+	}
+	for object, data := range objects {
+		var buffer bytes.Buffer
+		if len(data) > 0 {
+			err = xl.GetObject(bucket, object, 0, int64(len(data)), &buffer)
+		}
+		if err != nil {
+			t.Fatalf("Unable to read %s: <ERROR> %s", object, err)
+		}
+		if !bytes.Equal(buffer.Bytes(), data) {
+			t.Errorf("Content of %s differs after migration", object)
+		}
+		srcInfo, _ := obj.GetObjectInfo(bucket, object)
+		dstInfo, _ := xl.GetObjectInfo(bucket, object)
+		if dstInfo.MD5Sum != srcInfo.MD5Sum || dstInfo.ContentType != srcInfo.ContentType || dstInfo.UserDefined["X-Amz-Meta-Color"] != srcInfo.UserDefined["X-Amz-Meta-Color"] {
+			t.Errorf("Metadata of %s differs after migration, expected %#v, found %#v", object, srcInfo, dstInfo)
+		}
+	}
+	if dstInfo, _ := xl.GetObjectInfo(bucket, "multipart"); dstInfo.MD5Sum != multipartMD5 || len(dstInfo.Parts) != 2 {
+		t.Errorf("Expected multipart object with md5sum %s and 2 parts, found %#v", multipartMD5, dstInfo)
+	}
+
+	uploads, err := xl.ListMultipartUploads(bucket, "", "", "", "", maxUploadsList)
+	if err != nil || len(uploads.Uploads) != 1 || uploads.Uploads[0].Object != "incomplete" {
+		t.Fatalf("Expected incomplete upload after migration, found %#v, <ERROR> %v", uploads, err)
+	}
+	parts, err := xl.ListObjectParts(bucket, "incomplete", uploads.Uploads[0].UploadID, 0, maxPartsList)
+	if err != nil || len(parts.Parts) != 1 || parts.Parts[0].ETag != incompleteMD5 {
+		t.Errorf("Expected part %s of incomplete upload, found %#v, <ERROR> %v", incompleteMD5, parts, err)
+	}
+
+	migratedPolicy, err := ioutil.ReadFile(filepath.Join(targetConfigDir, "buckets", bucket, "access-policy.json"))
+	if err != nil || !bytes.Equal(migratedPolicy, policyBytes) {
+		t.Errorf("Expected bucket policy in target config folder, <ERROR> %v", err)
+	}
+	srcQuota, _ := readBucketConfigFile(bucket, bucketQuotaConfigFile)
+	migratedQuota, err := ioutil.ReadFile(filepath.Join(targetConfigDir, "buckets", bucket, bucketQuotaConfigFile))
+	if err != nil || !bytes.Equal(migratedQuota, srcQuota) {
+		t.Errorf("Expected bucket quota in target config folder, <ERROR> %v", err)
+	}
+	// Usage is shared with the XL disks here, it changes during migration.
+	if _, err = os.Stat(filepath.Join(targetConfigDir, "buckets", bucket, bucketUsageConfigFile)); err != nil {
+		t.Errorf("Expected bucket usage in target config folder, <ERROR> %v", err)
+	}
+
+	// Interrupted migration resumes, objects already migrated are skipped.
+	if err = xl.DeleteObject(bucket, "object.txt"); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	stats, err = migrateFSToXL(fsDir, disks, targetConfigDir)
+	if err != nil {
+		t.Fatalf("Unable to resume migration: <ERROR> %s", err)
+	}
+	if stats.Objects != 1 || stats.Skipped != len(objects)-1 || stats.Uploads != 1 || stats.Parts != 0 {
+		t.Errorf("Unexpected stats of resumed migration %#v", stats)
+	}
+
+	// Migration into other disks is refused while a journal exists.
+	if _, err = migrateFSToXL(fsDir, disks[:6], ""); err == nil {
+		t.Error("Expected migration into other disks to fail")
+	}
+}
+
+// Tests migration refuses XL disks with data.
+func TestMigrateFSToXLNotEmpty(t *testing.T) {
+	_, fsDir, err := getSingleNodeObjectLayer()
+	if err != nil {
+		t.Fatalf("Unable to initialize FS: <ERROR> %s", err)
+	}
+	defer removeAll(fsDir)
+
+	xl, disks, err := getXLObjectLayer()
+	if err != nil {
+		t.Fatalf("Unable to initialize XL: <ERROR> %s", err)
+	}
+	defer removeRoots(disks)
+	if err = xl.MakeBucket("bucket"); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+
+	if _, err = migrateFSToXL(fsDir, disks, ""); err != errMigrateDstNotEmpty {
+		t.Errorf("Expected %s, but found %v", errMigrateDstNotEmpty, err)
+	}
+	if _, err = migrateFSToXL(disks[0], disks[1:15], ""); err == nil {
+		t.Error("Expected migration of an XL disk to fail")
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Wrapper for calling CopyObject tests for both XL multiple disks and single node setup.
+func TestObjectAPICopyObject(t *testing.T) {
+	ExecObjectLayerTest(t, testObjectAPICopyObject)
+}
+
+// Tests validate correctness of CopyObject.
+func testObjectAPICopyObject(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "minio-bucket"
+	object := "minio-object"
+	data := bytes.Repeat([]byte("abcdefghijklmnop"), 64*1024)
+
+	// Create bucket.
+	if err := obj.MakeBucket(bucket); err != nil {
+		// Failed to create newbucket, abort.
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	md5Hex, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), nil)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+
+	testCases := []struct {
+		srcBucket string
+		srcObject string
+		dstBucket string
+		dstObject string
+		// flag indicating whether the test should pass.
+		shouldPass bool
+	}{
+		// Test case - 1.
+		// Invalid source bucket name.
+		{".test", object, bucket, "copy", false},
+		// Test case - 2.
+		// Invalid destination object name.
+		{bucket, object, bucket, "", false},
+		// Test case - 3.
+		// Non-existent source object.
+		{bucket, "missing", bucket, "copy", false},
+		// Test case - 4.
+		// Copy to a new object.
+		{bucket, object, bucket, "copy", true},
+		// Test case - 5.
+		// Copy over an existing object.
+		{bucket, object, bucket, "copy", true},
+	}
+	for i, testCase := range testCases {
+		copyMD5, cErr := obj.CopyObject(testCase.srcBucket, testCase.srcObject, testCase.dstBucket, testCase.dstObject, map[string]string{"content-type": "application/octet-stream"})
+		if cErr != nil && testCase.shouldPass {
+			t.Errorf("Test %d: %s: Expected to pass, but failed with: <ERROR> %s", i+1, instanceType, cErr)
+		}
+		if cErr == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: %s: Expected to fail, but passed", i+1, instanceType)
+		}
+		if cErr != nil || !testCase.shouldPass {
+			continue
+		}
+		if copyMD5 != md5Hex {
+			t.Errorf("Test %d: %s: Expected md5sum %s, but found %s", i+1, instanceType, md5Hex, copyMD5)
+		}
+		var buffer bytes.Buffer
+		if err = obj.GetObject(testCase.dstBucket, testCase.dstObject, 0, int64(len(data)), &buffer); err != nil {
+			t.Fatalf("Test %d: %s: <ERROR> %s", i+1, instanceType, err)
+		}
+		if !bytes.Equal(buffer.Bytes(), data) {
+			t.Errorf("Test %d: %s: Expected copied data to match the source", i+1, instanceType)
+		}
+	}
+
+	// Copying an object onto itself only replaces its metadata.
+	if _, err = obj.CopyObject(bucket, object, bucket, object, map[string]string{"content-type": "text/plain"}); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objInfo, err := obj.GetObjectInfo(bucket, object)
+	if err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	if instanceType == xLTestStr {
+		if objInfo.ContentType != "text/plain" {
+			t.Errorf("%s: Expected content type text/plain, but found %s", instanceType, objInfo.ContentType)
+		}
+		if objInfo.MD5Sum != md5Hex {
+			t.Errorf("%s: Expected md5sum %s to be retained, but found %s", instanceType, md5Hex, objInfo.MD5Sum)
+		}
+	}
+	if objInfo.Size != int64(len(data)) {
+		t.Errorf("%s: Expected size %d, but found %d", instanceType, len(data), objInfo.Size)
+	}
+}
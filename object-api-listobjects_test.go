@@ -445,10 +445,6 @@ func testListObjects(obj ObjectLayer, instanceType string, t *testing.T) {
 		{"volatile-bucket-1", "", "", "", 0, ListObjectsInfo{}, BucketNotFound{Bucket: "volatile-bucket-1"}, false},
 		{"volatile-bucket-2", "", "", "", 0, ListObjectsInfo{}, BucketNotFound{Bucket: "volatile-bucket-2"}, false},
 		{"volatile-bucket-3", "", "", "", 0, ListObjectsInfo{}, BucketNotFound{Bucket: "volatile-bucket-3"}, false},
-		// Valid, existing bucket, but sending invalid delimeter values (9-10).
-		// Empty string < "" > and forward slash < / > are the ony two valid arguments for delimeter.
-		{"test-bucket-list-object", "", "", "*", 0, ListObjectsInfo{}, fmt.Errorf("delimiter '%s' is not supported", "*"), false},
-		{"test-bucket-list-object", "", "", "-", 0, ListObjectsInfo{}, fmt.Errorf("delimiter '%s' is not supported", "-"), false},
 		// Testing for failure cases with both perfix and marker (13).
 		// The prefix and marker combination to be valid it should satisy strings.HasPrefix(marker, prefix).
 		{"test-bucket-list-object", "asia", "europe-object", "", 0, ListObjectsInfo{}, fmt.Errorf("Invalid combination of marker '%s' and prefix '%s'", "europe-object", "asia"), false},
@@ -571,6 +567,135 @@ func testListObjects(obj ObjectLayer, instanceType string, t *testing.T) {
 
 }
 
+// Wrapper for calling ListObjects tests with delimiters other than '/' for both XL multiple disks and single node setup.
+func TestListObjectsDelimiter(t *testing.T) {
+	ExecObjectLayerTest(t, testListObjectsDelimiter)
+}
+
+// Tests validate grouping of keys into common prefixes by any delimiter,
+// along with pagination over them.
+func testListObjectsDelimiter(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "test-bucket-list-delimiter"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s : %s", instanceType, err.Error())
+	}
+	objects := []string{
+		"a#1", "a#2", "a#b#1", "a-1", "b", "c/d#1", "c/d#2", "c/e", "d##1", "e",
+	}
+	for _, object := range objects {
+		if _, err := obj.PutObject(bucket, object, int64(len(object)), bytes.NewBufferString(object), nil); err != nil {
+			t.Fatalf("%s : %s", instanceType, err.Error())
+		}
+	}
+
+	testCases := []struct {
+		prefix    string
+		marker    string
+		delimiter string
+		maxKeys   int
+		// expected objects and common prefixes.
+		objects     []string
+		prefixes    []string
+		isTruncated bool
+		nextMarker  string
+	}{
+		// Test case - 1.
+		// Delimiter without prefix.
+		{"", "", "#", 10, []string{"a-1", "b", "c/e", "e"}, []string{"a#", "c/d#", "d#"}, false, "e"},
+		// Test case - 2.
+		// Delimiter with prefix.
+		{"a#", "", "#", 10, []string{"a#1", "a#2"}, []string{"a#b#"}, false, "a#b#"},
+		// Test case - 3.
+		// Multiple character delimiter.
+		{"", "", "##", 10, []string{"a#1", "a#2", "a#b#1", "a-1", "b", "c/d#1", "c/d#2", "c/e", "e"}, []string{"d##"}, false, "e"},
+		// Test case - 4.
+		// Truncated after a common prefix.
+		{"", "", "#", 2, []string{"a-1"}, []string{"a#"}, true, "a-1"},
+		// Test case - 5.
+		// Resumes after the common prefix.
+		{"", "a#", "#", 1, []string{"a-1"}, nil, true, "a-1"},
+		// Test case - 6.
+		// Truncated at a common prefix.
+		{"", "a-1", "#", 2, []string{"b"}, []string{"c/d#"}, true, "c/d#"},
+		// Test case - 7.
+		// Resumes after a common prefix including '/'.
+		{"", "c/d#", "#", 3, []string{"c/e", "e"}, []string{"d#"}, false, "e"},
+		// Test case - 8.
+		// Marker within a common prefix.
+		{"", "a#1", "#", 10, []string{"a-1", "b", "c/e", "e"}, []string{"a#", "c/d#", "d#"}, false, "e"},
+		// Test case - 9.
+		// Max keys of zero returns nothing and is not truncated.
+		{"", "", "#", 0, nil, nil, false, ""},
+		// Test case - 10.
+		// Common prefix spanning multiple listings of keys.
+		{"", "", "#", 1, nil, []string{"a#"}, true, "a#"},
+	}
+	for i, testCase := range testCases {
+		result, err := obj.ListObjects(bucket, testCase.prefix, testCase.marker, testCase.delimiter, testCase.maxKeys)
+		if err != nil {
+			t.Fatalf("Test %d: %s: <ERROR> %s", i+1, instanceType, err)
+		}
+		var objects []string
+		for _, objInfo := range result.Objects {
+			objects = append(objects, objInfo.Name)
+		}
+		if strings.Join(objects, ",") != strings.Join(testCase.objects, ",") {
+			t.Errorf("Test %d: %s: Expected objects %v, but found %v", i+1, instanceType, testCase.objects, objects)
+		}
+		if strings.Join(result.Prefixes, ",") != strings.Join(testCase.prefixes, ",") {
+			t.Errorf("Test %d: %s: Expected prefixes %v, but found %v", i+1, instanceType, testCase.prefixes, result.Prefixes)
+		}
+		if result.IsTruncated != testCase.isTruncated {
+			t.Errorf("Test %d: %s: Expected IsTruncated flag to be %v, but found %v", i+1, instanceType, testCase.isTruncated, result.IsTruncated)
+		}
+		if result.NextMarker != testCase.nextMarker {
+			t.Errorf("Test %d: %s: Expected NextMarker %s, but found %s", i+1, instanceType, testCase.nextMarker, result.NextMarker)
+		}
+	}
+}
+
+// Tests validate listings with delimiters resume after all the keys of
+// a listed common prefix, and max keys of zero lists nothing.
+func TestListObjectsDelimitedMarkers(t *testing.T) {
+	keys := []string{"a#1", "a#2", "a#3", "a#4", "a#5", "a#6", "b"}
+	var markers []string
+	listObjects := func(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+		markers = append(markers, marker)
+		result := ListObjectsInfo{}
+		for _, key := range keys {
+			if key <= marker {
+				continue
+			}
+			if len(result.Objects) == maxKeys {
+				result.IsTruncated = true
+				break
+			}
+			result.Objects = append(result.Objects, ObjectInfo{Name: key})
+		}
+		return result, nil
+	}
+
+	result, err := listObjectsDelimited(listObjects, "bucket", "", "", "#", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if result.IsTruncated || len(result.Objects) != 0 || len(result.Prefixes) != 0 || len(markers) != 0 {
+		t.Errorf("Expected max keys of zero to list nothing, but found %v after listing %v", result, markers)
+	}
+
+	result, err = listObjectsDelimited(listObjects, "bucket", "", "", "#", 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if strings.Join(result.Prefixes, ",") != "a#" || len(result.Objects) != 1 || result.Objects[0].Name != "b" {
+		t.Errorf("Expected prefix a# and object b, but found %v", result)
+	}
+	// Keys of the common prefix a# are not listed again.
+	if strings.Join(markers, ",") != ",a#\xff" {
+		t.Errorf("Expected listing to resume after common prefix a#, but found markers %q", markers)
+	}
+}
+
 func BenchmarkListObjects(b *testing.B) {
 	// Make a temporary directory to use as the obj.
 	directory, err := ioutil.TempDir("", "minio-list-benchmark")
@@ -0,0 +1,468 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"container/list"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio/pkg/disk"
+)
+
+const (
+	// Cached object data file name.
+	cacheDataFile = "data"
+
+	// Cached object metadata file name.
+	cacheMetaFile = "cache.json"
+
+	// Temporary directory of objects being cached.
+	cacheTmpDir = ".tmp"
+
+	// Cache metadata version.
+	cacheMetaVersion = "1"
+
+	// Default cache drive usage in percent above which eviction starts.
+	cacheDefaultHighWatermark = 90
+
+	// Default cache drive usage in percent at which eviction stops.
+	cacheDefaultLowWatermark = 70
+)
+
+// cacheConfig - cache drives and watermarks.
+type cacheConfig struct {
+	drives        []string
+	highWatermark int
+	lowWatermark  int
+}
+
+// Global cache config, initialized from environment variables.
+var globalCacheConfig cacheConfig
+
+// parseCacheConfig - parses comma separated cache drives and the
+// optional high and low watermarks.
+func parseCacheConfig(drives, highWatermark, lowWatermark string) (config cacheConfig, err error) {
+	for _, drive := range strings.Split(drives, ",") {
+		if drive = strings.TrimSpace(drive); drive != "" {
+			config.drives = append(config.drives, drive)
+		}
+	}
+	if len(config.drives) == 0 {
+		return cacheConfig{}, fmt.Errorf("Invalid cache drives '%s'", drives)
+	}
+	config.highWatermark = cacheDefaultHighWatermark
+	if highWatermark != "" {
+		if config.highWatermark, err = strconv.Atoi(highWatermark); err != nil {
+			return cacheConfig{}, err
+		}
+	}
+	config.lowWatermark = cacheDefaultLowWatermark
+	if lowWatermark != "" {
+		if config.lowWatermark, err = strconv.Atoi(lowWatermark); err != nil {
+			return cacheConfig{}, err
+		}
+	}
+	if config.lowWatermark <= 0 || config.lowWatermark >= config.highWatermark || config.highWatermark > 100 {
+		return cacheConfig{}, fmt.Errorf("Invalid cache watermarks high %d, low %d", config.highWatermark, config.lowWatermark)
+	}
+	return config, nil
+}
+
+// cacheMeta - metadata of a cached object, used to validate cached
+// object against the backend.
+type cacheMeta struct {
+	Version         string            `json:"version"`
+	Bucket          string            `json:"bucket"`
+	Object          string            `json:"object"`
+	Size            int64             `json:"size"`
+	ModTime         time.Time         `json:"modTime"`
+	MD5Sum          string            `json:"md5Sum"`
+	ContentType     string            `json:"contentType,omitempty"`
+	ContentEncoding string            `json:"contentEncoding,omitempty"`
+	UserDefined     map[string]string `json:"meta,omitempty"`
+}
+
+// isValid - verifies if cached object is the same as the backend object.
+func (m cacheMeta) isValid(objInfo ObjectInfo) bool {
+	return m.Size == objInfo.Size && m.ModTime.Equal(objInfo.ModTime) && m.MD5Sum == objInfo.MD5Sum
+}
+
+// cacheEntry - cached object.
+type cacheEntry struct {
+	key  string
+	meta cacheMeta
+}
+
+// cacheDrive - objects cached on a single drive, least recently used
+// objects are evicted once usage of the drive crosses the high
+// watermark until usage drops below the low watermark. Usage is
+// reported by the file system, data of other users of the drive is
+// accounted as well.
+type cacheDrive struct {
+	dir           string
+	capacity      int64
+	highWatermark int
+	lowWatermark  int
+	getDiskInfo   func(path string) (disk.Info, error)
+
+	mutex *sync.Mutex
+	// Total size of cached objects.
+	size int64
+	// Cached objects, most recently used first.
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+// newCacheDrive - initialize a cache drive, loads previously cached
+// objects. Usage of the drive is reported by getDiskInfo.
+func newCacheDrive(dir string, getDiskInfo func(path string) (disk.Info, error), highWatermark, lowWatermark int) (*cacheDrive, error) {
+	info, err := getDiskInfo(dir)
+	if err != nil {
+		return nil, err
+	}
+	d := &cacheDrive{
+		dir:           dir,
+		capacity:      info.Total,
+		highWatermark: highWatermark,
+		lowWatermark:  lowWatermark,
+		getDiskInfo:   getDiskInfo,
+		mutex:         &sync.Mutex{},
+		lru:           list.New(),
+		entries:       make(map[string]*list.Element),
+	}
+	// Remove objects which were being cached.
+	if err := os.RemoveAll(filepath.Join(dir, cacheTmpDir)); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(dir, cacheTmpDir), 0700); err != nil {
+		return nil, err
+	}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// load - loads cached objects, recently cached objects are treated
+// as recently used.
+func (d *cacheDrive) load() error {
+	dirEntries, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	var entries []cacheEntry
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || dirEntry.Name() == cacheTmpDir {
+			continue
+		}
+		key := dirEntry.Name()
+		buf, err := ioutil.ReadFile(filepath.Join(d.dir, key, cacheMetaFile))
+		if err == nil {
+			var meta cacheMeta
+			if err = json.Unmarshal(buf, &meta); err == nil && meta.Version == cacheMetaVersion {
+				entries = append(entries, cacheEntry{key: key, meta: meta})
+				continue
+			}
+		}
+		// Remove incomplete or corrupted entries.
+		os.RemoveAll(filepath.Join(d.dir, key))
+	}
+	sort.Sort(byCacheModTime(entries))
+	for _, entry := range entries {
+		d.entries[entry.key] = d.lru.PushFront(entry)
+		d.size += entry.meta.Size
+	}
+	d.evict()
+	return nil
+}
+
+// byCacheModTime - sorts cache entries by object modification time.
+type byCacheModTime []cacheEntry
+
+func (e byCacheModTime) Len() int           { return len(e) }
+func (e byCacheModTime) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byCacheModTime) Less(i, j int) bool { return e[i].meta.ModTime.Before(e[j].meta.ModTime) }
+
+// cacheKey - directory name of a cached object.
+func cacheKey(bucket, object string) string {
+	return hex.EncodeToString(sum256([]byte(pathJoin(bucket, object))))
+}
+
+// diskUsage - returns used space and capacity of the drive, falls
+// back to the size of cached objects if the file system can't report
+// its usage. Caller must hold the lock.
+func (d *cacheDrive) diskUsage() (used, total int64) {
+	info, err := d.getDiskInfo(d.dir)
+	if err != nil || info.Total <= 0 {
+		errorIf(err, "Unable to get usage of cache drive %s.", d.dir)
+		return d.size, d.capacity
+	}
+	return info.Total - info.Free, info.Total
+}
+
+// fits - verifies if an object of size can be cached without crossing
+// the high watermark, along with data on the drive which is not cached
+// objects and can't be evicted.
+func (d *cacheDrive) fits(size int64) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	used, total := d.diskUsage()
+	return (used-d.size+size)*100 <= total*int64(d.highWatermark)
+}
+
+// open - opens a cached object if it is valid, invalid objects are
+// removed from the cache.
+func (d *cacheDrive) open(bucket, object string, objInfo ObjectInfo) (*os.File, bool) {
+	key := cacheKey(bucket, object)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	elem, ok := d.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !elem.Value.(cacheEntry).meta.isValid(objInfo) {
+		d.removeElement(elem)
+		return nil, false
+	}
+	file, err := os.Open(filepath.Join(d.dir, key, cacheDataFile))
+	if err != nil {
+		d.removeElement(elem)
+		return nil, false
+	}
+	d.lru.MoveToFront(elem)
+	return file, true
+}
+
+// tempFile - creates a new temporary file to cache an object.
+func (d *cacheDrive) tempFile() (*os.File, error) {
+	return ioutil.TempFile(filepath.Join(d.dir, cacheTmpDir), "object-")
+}
+
+// commit - adds a completely written temporary file to the cache.
+func (d *cacheDrive) commit(objInfo ObjectInfo, tmpPath string) error {
+	key := cacheKey(objInfo.Bucket, objInfo.Name)
+	meta := cacheMeta{
+		Version:         cacheMetaVersion,
+		Bucket:          objInfo.Bucket,
+		Object:          objInfo.Name,
+		Size:            objInfo.Size,
+		ModTime:         objInfo.ModTime,
+		MD5Sum:          objInfo.MD5Sum,
+		ContentType:     objInfo.ContentType,
+		ContentEncoding: objInfo.ContentEncoding,
+		UserDefined:     objInfo.UserDefined,
+	}
+	buf, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if elem, ok := d.entries[key]; ok {
+		d.removeElement(elem)
+	}
+	entryDir := filepath.Join(d.dir, key)
+	if err = os.MkdirAll(entryDir, 0700); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, filepath.Join(entryDir, cacheDataFile)); err != nil {
+		os.RemoveAll(entryDir)
+		return err
+	}
+	if err = ioutil.WriteFile(filepath.Join(entryDir, cacheMetaFile), buf, 0600); err != nil {
+		os.RemoveAll(entryDir)
+		return err
+	}
+	d.entries[key] = d.lru.PushFront(cacheEntry{key: key, meta: meta})
+	d.size += meta.Size
+	d.evict()
+	return nil
+}
+
+// remove - removes an object from the cache.
+func (d *cacheDrive) remove(bucket, object string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if elem, ok := d.entries[cacheKey(bucket, object)]; ok {
+		d.removeElement(elem)
+	}
+}
+
+// removeElement - removes a cached object, caller must hold the lock.
+func (d *cacheDrive) removeElement(elem *list.Element) {
+	entry := elem.Value.(cacheEntry)
+	d.lru.Remove(elem)
+	delete(d.entries, entry.key)
+	d.size -= entry.meta.Size
+	errorIf(os.RemoveAll(filepath.Join(d.dir, entry.key)), "Unable to remove cached object %s.", pathJoin(entry.meta.Bucket, entry.meta.Object))
+}
+
+// evict - evicts least recently used objects once usage crosses the
+// high watermark, caller must hold the lock.
+func (d *cacheDrive) evict() {
+	used, total := d.diskUsage()
+	if used*100 <= total*int64(d.highWatermark) {
+		return
+	}
+	for d.lru.Len() > 0 && used*100 > total*int64(d.lowWatermark) {
+		d.removeElement(d.lru.Back())
+		used, total = d.diskUsage()
+	}
+}
+
+// cacheTeeWriter - writes to the client and the cache file, failing
+// cache writes never fail the client.
+type cacheTeeWriter struct {
+	writer io.Writer
+	file   *os.File
+	err    error
+}
+
+func (w *cacheTeeWriter) Write(p []byte) (int, error) {
+	if w.err == nil {
+		_, w.err = w.file.Write(p)
+	}
+	return w.writer.Write(p)
+}
+
+// cacheObjects - implements ObjectLayer by caching recently read
+// objects of another ObjectLayer on local drives. Cached objects are
+// validated against the backend on every read, and invalidated by
+// writes and deletes through the cache.
+type cacheObjects struct {
+	ObjectLayer
+	drives []*cacheDrive
+}
+
+// newCacheObjects - initialize new cache object layer in front of objAPI.
+func newCacheObjects(objAPI ObjectLayer, config cacheConfig) (ObjectLayer, error) {
+	c := cacheObjects{ObjectLayer: objAPI}
+	for _, dir := range config.drives {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+		drive, err := newCacheDrive(dir, disk.GetInfo, config.highWatermark, config.lowWatermark)
+		if err != nil {
+			return nil, err
+		}
+		c.drives = append(c.drives, drive)
+	}
+	return c, nil
+}
+
+// drive - returns the cache drive of an object.
+func (c cacheObjects) drive(bucket, object string) *cacheDrive {
+	h := fnv.New32a()
+	h.Write([]byte(pathJoin(bucket, object)))
+	return c.drives[h.Sum32()%uint32(len(c.drives))]
+}
+
+// GetObject - serves the object from cache if the cached object is
+// still valid, complete reads of uncached objects are cached.
+func (c cacheObjects) GetObject(bucket, object string, startOffset int64, length int64, writer io.Writer) error {
+	drive := c.drive(bucket, object)
+	objInfo, err := c.ObjectLayer.GetObjectInfo(bucket, object)
+	if err != nil {
+		if _, ok := err.(ObjectNotFound); ok {
+			drive.remove(bucket, object)
+		}
+		return err
+	}
+
+	if file, ok := drive.open(bucket, object, objInfo); ok {
+		defer file.Close()
+		if _, err = file.Seek(startOffset, 0); err != nil {
+			return err
+		}
+		_, err = io.CopyN(writer, file, length)
+		return err
+	}
+
+	// Only complete reads of objects which fit the cache are cached.
+	if startOffset != 0 || length != objInfo.Size || objInfo.Size == 0 || !drive.fits(objInfo.Size) {
+		return c.ObjectLayer.GetObject(bucket, object, startOffset, length, writer)
+	}
+	file, err := drive.tempFile()
+	if err != nil {
+		errorIf(err, "Unable to create cache file.")
+		return c.ObjectLayer.GetObject(bucket, object, startOffset, length, writer)
+	}
+	defer os.Remove(file.Name())
+
+	teeWriter := &cacheTeeWriter{writer: writer, file: file}
+	err = c.ObjectLayer.GetObject(bucket, object, startOffset, length, teeWriter)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	if teeWriter.err != nil {
+		errorIf(teeWriter.err, "Unable to write cache file.")
+		return nil
+	}
+	errorIf(drive.commit(objInfo, file.Name()), "Unable to cache object %s.", pathJoin(bucket, object))
+	return nil
+}
+
+// PutObject - creates an object and invalidates the cached object.
+func (c cacheObjects) PutObject(bucket, object string, size int64, data io.Reader, metadata map[string]string) (string, error) {
+	defer c.drive(bucket, object).remove(bucket, object)
+	return c.ObjectLayer.PutObject(bucket, object, size, data, metadata)
+}
+
+// CopyObject - copies an object and invalidates the cached object.
+func (c cacheObjects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (string, error) {
+	defer c.drive(dstBucket, dstObject).remove(dstBucket, dstObject)
+	return c.ObjectLayer.CopyObject(srcBucket, srcObject, dstBucket, dstObject, metadata)
+}
+
+// SetObjectLock - updates object lock metadata of an object and
+// invalidates the cached object.
+func (c cacheObjects) SetObjectLock(bucket, object string, lockMeta map[string]string) error {
+	defer c.drive(bucket, object).remove(bucket, object)
+	return c.ObjectLayer.SetObjectLock(bucket, object, lockMeta)
+}
+
+// DeleteObject - deletes an object and invalidates the cached object.
+func (c cacheObjects) DeleteObject(bucket, object string) error {
+	defer c.drive(bucket, object).remove(bucket, object)
+	return c.ObjectLayer.DeleteObject(bucket, object)
+}
+
+// CompleteMultipartUpload - completes a multipart upload and
+// invalidates the cached object.
+func (c cacheObjects) CompleteMultipartUpload(bucket, object, uploadID string, uploadedParts []completePart) (string, error) {
+	defer c.drive(bucket, object).remove(bucket, object)
+	return c.ObjectLayer.CompleteMultipartUpload(bucket, object, uploadID, uploadedParts)
+}
@@ -0,0 +1,255 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minio/minio/pkg/disk"
+)
+
+// Tests validate parsing of cache configuration.
+func TestParseCacheConfig(t *testing.T) {
+	testCases := []struct {
+		drives        string
+		highWatermark string
+		lowWatermark  string
+		expectedCount int
+		shouldPass    bool
+	}{
+		// Test case - 1.
+		// Default watermarks.
+		{"/mnt/cache1, /mnt/cache2", "", "", 2, true},
+		// Test case - 2.
+		// Custom watermarks.
+		{"/mnt/cache1", "80", "50", 1, true},
+		// Test case - 3.
+		// No drives.
+		{" , ", "", "", 0, false},
+		// Test case - 4.
+		// Low watermark above high watermark.
+		{"/mnt/cache1", "50", "80", 0, false},
+		// Test case - 5.
+		// High watermark above 100.
+		{"/mnt/cache1", "120", "", 0, false},
+		// Test case - 6.
+		// Invalid watermark.
+		{"/mnt/cache1", "high", "", 0, false},
+	}
+	for i, testCase := range testCases {
+		config, err := parseCacheConfig(testCase.drives, testCase.highWatermark, testCase.lowWatermark)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: Expected to pass, but failed with: <ERROR> %s", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: Expected to fail, but passed instead", i+1)
+		}
+		if err == nil && len(config.drives) != testCase.expectedCount {
+			t.Errorf("Test %d: Expected %d drives, but found %d", i+1, testCase.expectedCount, len(config.drives))
+		}
+	}
+}
+
+// Tests validate least recently used objects are evicted between watermarks.
+func TestCacheDriveEviction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minio-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeAll(dir)
+
+	// Drive of 100 bytes, used by cached objects and by other data.
+	var otherData int64
+	getDiskInfo := func(dir string) (disk.Info, error) {
+		used := otherData
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && info.Name() == cacheDataFile {
+				used += info.Size()
+			}
+			return err
+		})
+		return disk.Info{Total: 100, Free: 100 - used}, err
+	}
+	drive, err := newCacheDrive(dir, getDiskInfo, 80, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("a"), 25)
+	modTime := time.Now().UTC()
+	objInfo := func(object string) ObjectInfo {
+		return ObjectInfo{Bucket: "bucket", Name: object, Size: int64(len(data)), ModTime: modTime}
+	}
+	cacheObject := func(object string) {
+		file, cErr := drive.tempFile()
+		if cErr != nil {
+			t.Fatal(cErr)
+		}
+		file.Write(data)
+		file.Close()
+		if cErr = drive.commit(objInfo(object), file.Name()); cErr != nil {
+			t.Fatal(cErr)
+		}
+	}
+	isCached := func(object string) bool {
+		file, ok := drive.open("bucket", object, objInfo(object))
+		if ok {
+			file.Close()
+		}
+		return ok
+	}
+
+	for _, object := range []string{"a", "b", "c"} {
+		cacheObject(object)
+	}
+	// Usage is below high watermark, "a" is now most recently used.
+	if !isCached("a") {
+		t.Fatal("Expected 'a' to be cached")
+	}
+	// Crossing high watermark evicts "b" and "c" to reach low watermark.
+	cacheObject("d")
+	for object, cached := range map[string]bool{"a": true, "b": false, "c": false, "d": true} {
+		if isCached(object) != cached {
+			t.Errorf("Expected '%s' cached to be '%v'", object, cached)
+		}
+	}
+	if drive.size != 50 {
+		t.Errorf("Expected cache size 50, but found %d", drive.size)
+	}
+
+	// Cached objects are loaded back.
+	drive, err = newCacheDrive(dir, getDiskInfo, 80, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isCached("a") || !isCached("d") || drive.size != 50 {
+		t.Errorf("Expected cached objects to be loaded, but found size %d", drive.size)
+	}
+
+	// Other data on the drive counts towards the watermarks.
+	otherData = 60
+	if drive.fits(25) {
+		t.Error("Expected object not to fit along with other data")
+	}
+	if !drive.fits(10) {
+		t.Error("Expected object to fit along with other data")
+	}
+	otherData = 35
+	drive.mutex.Lock()
+	drive.evict()
+	drive.mutex.Unlock()
+	if drive.size != 25 {
+		t.Errorf("Expected cache size 25 after other data crossed high watermark, but found %d", drive.size)
+	}
+	otherData = 0
+
+	// Stale objects are invalidated.
+	staleInfo := objInfo("a")
+	staleInfo.ModTime = modTime.Add(time.Second)
+	if _, ok := drive.open("bucket", "a", staleInfo); ok {
+		t.Error("Expected stale object not to be served")
+	}
+	if isCached("a") {
+		t.Error("Expected stale object to be removed")
+	}
+}
+
+// Wrapper for calling cache tests for both XL multiple disks and single node setup.
+func TestCacheObjects(t *testing.T) {
+	ExecObjectLayerTest(t, testCacheObjects)
+}
+
+// Tests validate objects are cached on read, and invalidated by writes
+// through the cache and by changes in the backend.
+func testCacheObjects(obj ObjectLayer, instanceType string, t *testing.T) {
+	dir, err := ioutil.TempDir("", "minio-cache-")
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	defer removeAll(dir)
+
+	cache, err := newCacheObjects(obj, cacheConfig{drives: []string{dir}, highWatermark: 90, lowWatermark: 70})
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	bucket := getRandomBucketName()
+	if err = cache.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	getObject := func(offset, length int64) string {
+		var buffer bytes.Buffer
+		if gErr := cache.GetObject(bucket, "object", offset, length, &buffer); gErr != nil {
+			t.Fatalf("%s: <ERROR> %s", instanceType, gErr)
+		}
+		return buffer.String()
+	}
+
+	if _, err = cache.PutObject(bucket, "object", 12, bytes.NewReader([]byte("hello, world")), nil); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	// Partial reads are not cached.
+	if data := getObject(7, 5); data != "world" {
+		t.Errorf("%s: Expected 'world', but found '%s'", instanceType, data)
+	}
+	dataPath := filepath.Join(dir, cacheKey(bucket, "object"), cacheDataFile)
+	if _, err = os.Stat(dataPath); !os.IsNotExist(err) {
+		t.Errorf("%s: Expected partial read not to be cached", instanceType)
+	}
+	// Complete reads are cached.
+	if data := getObject(0, 12); data != "hello, world" {
+		t.Errorf("%s: Expected 'hello, world', but found '%s'", instanceType, data)
+	}
+	// Cached object is served, changing cached data shows up in reads.
+	if err = ioutil.WriteFile(dataPath, []byte("HELLO, WORLD"), 0600); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if data := getObject(7, 5); data != "WORLD" {
+		t.Errorf("%s: Expected cached 'WORLD', but found '%s'", instanceType, data)
+	}
+
+	// Writes through the cache invalidate the cached object.
+	if _, err = cache.PutObject(bucket, "object", 5, bytes.NewReader([]byte("hello")), nil); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if data := getObject(0, 5); data != "hello" {
+		t.Errorf("%s: Expected 'hello', but found '%s'", instanceType, data)
+	}
+
+	// Writes bypassing the cache are detected by validation.
+	if _, err = obj.PutObject(bucket, "object", 3, bytes.NewReader([]byte("bye")), nil); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if data := getObject(0, 3); data != "bye" {
+		t.Errorf("%s: Expected 'bye', but found '%s'", instanceType, data)
+	}
+
+	// Deletes through the cache invalidate the cached object.
+	if err = cache.DeleteObject(bucket, "object"); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if _, err = os.Stat(dataPath); !os.IsNotExist(err) {
+		t.Errorf("%s: Expected deleted object to be removed from cache", instanceType)
+	}
+	var buffer bytes.Buffer
+	if err = cache.GetObject(bucket, "object", 0, 3, &buffer); err == nil {
+		t.Errorf("%s: Expected deleted object read to fail, but passed instead", instanceType)
+	}
+}
@@ -0,0 +1,409 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Metadata key of the checksum algorithm of an object or an
+	// upload, checksums are saved with the key of their header.
+	checksumAlgorithmKey = "x-amz-checksum-algorithm"
+	// Prefix of checksum headers.
+	checksumHeaderPrefix = "x-amz-checksum-"
+)
+
+// Supported checksum algorithms.
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"CRC32":  func() hash.Hash { return crc32.NewIEEE() },
+	"CRC32C": func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) },
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+}
+
+// checksumKey - returns header and metadata key of the checksum of an
+// algorithm.
+func checksumKey(algorithm string) string {
+	return checksumHeaderPrefix + strings.ToLower(algorithm)
+}
+
+// newChecksumHash - returns a new hash of the checksum algorithm of
+// the metadata, nil if there is none.
+func newChecksumHash(metadata map[string]string) hash.Hash {
+	newHash, ok := checksumAlgorithms[metadata[checksumAlgorithmKey]]
+	if !ok {
+		return nil
+	}
+	return newHash()
+}
+
+// newChecksumWriter - returns a writer updating md5 and the checksum,
+// if any.
+func newChecksumWriter(md5Writer io.Writer, checksumHash hash.Hash) io.Writer {
+	if checksumHash == nil {
+		return md5Writer
+	}
+	return io.MultiWriter(md5Writer, checksumHash)
+}
+
+// encodeChecksum - returns the base64 encoded checksum of a hash, ""
+// if there is no hash.
+func encodeChecksum(checksumHash hash.Hash) string {
+	if checksumHash == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(checksumHash.Sum(nil))
+}
+
+// updateChecksum - saves the calculated checksum in metadata if not
+// set, otherwise verifies it.
+func updateChecksum(metadata map[string]string, checksumHash hash.Hash) error {
+	if checksumHash == nil {
+		return nil
+	}
+	algorithm := metadata[checksumAlgorithmKey]
+	checksum := encodeChecksum(checksumHash)
+	if expected := metadata[checksumKey(algorithm)]; expected != "" && expected != checksum {
+		return BadChecksum{Algorithm: algorithm, ExpectedChecksum: expected, CalculatedChecksum: checksum}
+	}
+	metadata[checksumKey(algorithm)] = checksum
+	return nil
+}
+
+// completeMultipartChecksum - saves the checksum of a multipart object
+// in metadata. It is the checksum of the concatenated checksums of the
+// parts followed by the number of parts. Part checksums sent on
+// completion are verified against the checksums of the uploaded parts.
+func completeMultipartChecksum(metadata map[string]string, uploadedParts []completePart, parts []objectPartInfo) error {
+	checksumHash := newChecksumHash(metadata)
+	if checksumHash == nil {
+		return nil
+	}
+	algorithm := metadata[checksumAlgorithmKey]
+	for i, part := range parts {
+		if checksum := uploadedParts[i].checksum(algorithm); checksum != "" && checksum != part.Checksum {
+			return InvalidPart{}
+		}
+		sum, err := base64.StdEncoding.DecodeString(part.Checksum)
+		if err != nil || part.Checksum == "" {
+			return InvalidPart{}
+		}
+		checksumHash.Write(sum)
+	}
+	metadata[checksumKey(algorithm)] = fmt.Sprintf("%s-%d", encodeChecksum(checksumHash), len(parts))
+	return nil
+}
+
+// checksum - returns the checksum of a completed part of an algorithm.
+func (p completePart) checksum(algorithm string) string {
+	switch algorithm {
+	case "CRC32":
+		return p.ChecksumCRC32
+	case "CRC32C":
+		return p.ChecksumCRC32C
+	case "SHA1":
+		return p.ChecksumSHA1
+	case "SHA256":
+		return p.ChecksumSHA256
+	}
+	return ""
+}
+
+// isValidChecksum - verifies a checksum is the base64 encoded sum of
+// its algorithm.
+func isValidChecksum(algorithm, checksum string) bool {
+	sum, err := base64.StdEncoding.DecodeString(checksum)
+	return err == nil && len(sum) == checksumAlgorithms[algorithm]().Size()
+}
+
+// checksumRequest - checksum of a request body, sent as a header or as
+// a trailer of an aws-chunked body.
+type checksumRequest struct {
+	algorithm string
+	checksum  string
+	trailing  bool
+}
+
+// parseChecksumAlgorithm - parses a checksum algorithm header.
+func parseChecksumAlgorithm(value string) (string, APIErrorCode) {
+	algorithm := strings.ToUpper(value)
+	if _, ok := checksumAlgorithms[algorithm]; !ok {
+		return "", ErrInvalidChecksumAlgorithm
+	}
+	return algorithm, ErrNone
+}
+
+// parseChecksumRequest - parses the checksum header or trailer of a
+// request, returns an empty checksumRequest if there is none.
+func parseChecksumRequest(r *http.Request) (checksumRequest, APIErrorCode) {
+	var c checksumRequest
+	for algorithm := range checksumAlgorithms {
+		value := r.Header.Get(checksumKey(algorithm))
+		if value == "" {
+			continue
+		}
+		if c.algorithm != "" {
+			return checksumRequest{}, ErrMultipleChecksums
+		}
+		if !isValidChecksum(algorithm, value) {
+			return checksumRequest{}, ErrInvalidChecksum
+		}
+		c.algorithm, c.checksum = algorithm, value
+	}
+
+	trailer := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Amz-Trailer")))
+	if trailer == "" {
+		// An aws-chunked body cannot be saved as it is, it is only
+		// decoded along with its checksum trailer.
+		if r.Header.Get("X-Amz-Content-Sha256") == unsignedPayloadTrailer {
+			return checksumRequest{}, ErrInvalidRequest
+		}
+		return c, ErrNone
+	}
+	// Only unsigned aws-chunked bodies carry trailers.
+	if c.algorithm != "" || !strings.HasPrefix(trailer, checksumHeaderPrefix) {
+		return checksumRequest{}, ErrInvalidChecksum
+	}
+	if r.Header.Get("X-Amz-Content-Sha256") != unsignedPayloadTrailer {
+		return checksumRequest{}, ErrNotImplemented
+	}
+	algorithm, s3Error := parseChecksumAlgorithm(strings.TrimPrefix(trailer, checksumHeaderPrefix))
+	if s3Error != ErrNone {
+		return checksumRequest{}, s3Error
+	}
+	return checksumRequest{algorithm: algorithm, trailing: true}, ErrNone
+}
+
+// getDecodedContentLength - returns the size of the data of a request
+// body, aws-chunked bodies send it separately.
+func (c checksumRequest) getDecodedContentLength(r *http.Request) int64 {
+	if !c.trailing {
+		return r.ContentLength
+	}
+	size, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+	if err != nil || size < 0 {
+		return -1
+	}
+	return size
+}
+
+// newReader - returns a reader of the request body of size bytes which
+// verifies the checksum once the body is read, aws-chunked bodies are
+// decoded.
+func (c checksumRequest) newReader(body io.Reader, size int64) *checksumReader {
+	reader := &checksumReader{
+		reader:    body,
+		size:      size,
+		algorithm: c.algorithm,
+		hash:      checksumAlgorithms[c.algorithm](),
+		checksum:  c.checksum,
+	}
+	if c.trailing {
+		reader.chunked = newAWSChunkedReader(body)
+		reader.reader = reader.chunked
+	}
+	return reader
+}
+
+// checksumReader - verifies the checksum of a request body while it is
+// read, reading fails with BadChecksum on a mismatch. The checksum is
+// verified once size bytes are read, so that readers which do not read
+// until EOF verify it as well.
+type checksumReader struct {
+	reader    io.Reader
+	chunked   *awsChunkedReader
+	size      int64
+	read      int64
+	algorithm string
+	hash      hash.Hash
+	checksum  string
+	verified  bool
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	if c.verified {
+		return 0, io.EOF
+	}
+	n, err := c.reader.Read(p)
+	c.hash.Write(p[:n])
+	c.read += int64(n)
+	if err != nil && err != io.EOF {
+		return n, err
+	}
+	if err == io.EOF || c.size >= 0 && c.read >= c.size {
+		// Data of a mismatching body is not returned, readers may
+		// consume it before they see the error.
+		if vErr := c.verify(); vErr != nil {
+			return 0, vErr
+		}
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// verify - verifies the body ends and matches its checksum.
+func (c *checksumReader) verify() error {
+	// Trailers follow the data.
+	var buf [1]byte
+	if n, err := c.reader.Read(buf[:]); n > 0 {
+		return IncompleteBody{}
+	} else if err != io.EOF {
+		return err
+	}
+	if c.size >= 0 && c.read != c.size {
+		return IncompleteBody{}
+	}
+	expected := c.checksum
+	if c.chunked != nil {
+		expected = c.chunked.trailers.Get(checksumKey(c.algorithm))
+		if !isValidChecksum(c.algorithm, expected) {
+			return errMalformedTrailer
+		}
+	}
+	checksum := encodeChecksum(c.hash)
+	if checksum != expected {
+		return BadChecksum{Algorithm: c.algorithm, ExpectedChecksum: expected, CalculatedChecksum: checksum}
+	}
+	c.verified = true
+	return nil
+}
+
+// Checksum - returns the verified checksum, "" until the body is read.
+func (c *checksumReader) Checksum() string {
+	if !c.verified {
+		return ""
+	}
+	return encodeChecksum(c.hash)
+}
+
+// Maximum length of a chunk header or trailer line.
+const awsChunkedMaxLineSize = 4096
+
+// awsChunkedReader - decodes an aws-chunked body of unsigned payload,
+// chunks are a hex size line followed by data, the last chunk is empty
+// and followed by trailers.
+//
+//	<hex size>\r\n<data>\r\n ... 0\r\n<name>:<value>\r\n\r\n
+type awsChunkedReader struct {
+	reader   *bufio.Reader
+	left     int64
+	trailers http.Header
+	err      error
+}
+
+func newAWSChunkedReader(body io.Reader) *awsChunkedReader {
+	return &awsChunkedReader{
+		reader:   bufio.NewReaderSize(body, awsChunkedMaxLineSize),
+		trailers: make(http.Header),
+	}
+}
+
+// readLine - reads a line without its CRLF.
+func (c *awsChunkedReader) readLine() ([]byte, error) {
+	line, err := c.reader.ReadSlice('\n')
+	if err != nil {
+		if err == io.EOF || err == bufio.ErrBufferFull {
+			return nil, errMalformedTrailer
+		}
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// readChunkSize - reads the size line of the next chunk, reads the
+// trailers after the last chunk.
+func (c *awsChunkedReader) readChunkSize() error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if i := bytes.IndexByte(line, ';'); i >= 0 {
+		line = line[:i]
+	}
+	size, err := strconv.ParseInt(string(bytes.TrimSpace(line)), 16, 64)
+	if err != nil || size < 0 {
+		return errMalformedTrailer
+	}
+	if size > 0 {
+		c.left = size
+		return nil
+	}
+	for {
+		line, err = c.readLine()
+		if err != nil {
+			return err
+		}
+		if len(line) == 0 {
+			return io.EOF
+		}
+		i := bytes.IndexByte(line, ':')
+		if i <= 0 {
+			return errMalformedTrailer
+		}
+		c.trailers.Add(string(bytes.TrimSpace(line[:i])), string(bytes.TrimSpace(line[i+1:])))
+	}
+}
+
+func (c *awsChunkedReader) Read(p []byte) (int, error) {
+	if c.err == nil && c.left == 0 {
+		c.err = c.readChunkSize()
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	if int64(len(p)) > c.left {
+		p = p[:c.left]
+	}
+	n, err := c.reader.Read(p)
+	c.left -= int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil && c.left == 0 {
+		// Chunk data is followed by CRLF.
+		var line []byte
+		if line, err = c.readLine(); err == nil && len(line) != 0 {
+			err = errMalformedTrailer
+		}
+	}
+	c.err = err
+	return n, err
+}
+
+// trimAWSChunkedEncoding - removes aws-chunked from a content encoding,
+// it only encodes the request body and is not saved.
+func trimAWSChunkedEncoding(encoding string) string {
+	var encodings []string
+	for _, value := range strings.Split(encoding, ",") {
+		if value = strings.TrimSpace(value); value != "" && !strings.EqualFold(value, "aws-chunked") {
+			encodings = append(encodings, value)
+		}
+	}
+	return strings.Join(encodings, ",")
+}
@@ -0,0 +1,223 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Tests validate reading of aws-chunked bodies and verification of
+// their checksum trailers.
+func TestChecksumReader(t *testing.T) {
+	data := "hello, world"
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(data))
+	checksum := base64.StdEncoding.EncodeToString(crc.Sum(nil))
+
+	testCases := []struct {
+		body       string
+		size       int64
+		shouldPass bool
+	}{
+		// Test case - 1.
+		// Valid body of two chunks.
+		{"5\r\nhello\r\n7\r\n, world\r\n0\r\nx-amz-checksum-crc32:" + checksum + "\r\n\r\n", 12, true},
+		// Test case - 2.
+		// Valid body of unknown size with chunk extensions.
+		{"c;ext=1\r\nhello, world\r\n0\r\nx-amz-checksum-crc32: " + checksum + "\r\n\r\n", -1, true},
+		// Test case - 3.
+		// Checksum mismatch.
+		{"5\r\nhello\r\n7\r\n, worlD\r\n0\r\nx-amz-checksum-crc32:" + checksum + "\r\n\r\n", 12, false},
+		// Test case - 4.
+		// Missing trailer.
+		{"c\r\nhello, world\r\n0\r\n\r\n", 12, false},
+		// Test case - 5.
+		// Malformed trailer.
+		{"c\r\nhello, world\r\n0\r\nx-amz-checksum-crc32\r\n\r\n", 12, false},
+		// Test case - 6.
+		// Missing CRLF after chunk data.
+		{"c\r\nhello, worldX0\r\nx-amz-checksum-crc32:" + checksum + "\r\n\r\n", 12, false},
+		// Test case - 7.
+		// Invalid chunk size.
+		{"z\r\nhello, world\r\n0\r\nx-amz-checksum-crc32:" + checksum + "\r\n\r\n", 12, false},
+		// Test case - 8.
+		// Truncated body.
+		{"c\r\nhello", 12, false},
+		// Test case - 9.
+		// Body longer than its decoded length.
+		{"c\r\nhello, world\r\n1\r\n!\r\n0\r\nx-amz-checksum-crc32:" + checksum + "\r\n\r\n", 12, false},
+	}
+	for i, testCase := range testCases {
+		reader := checksumRequest{algorithm: "CRC32", trailing: true}.newReader(strings.NewReader(testCase.body), testCase.size)
+		readData, err := ioutil.ReadAll(reader)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: Expected to pass, but failed with: <ERROR> %s", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: Expected to fail, but passed instead", i+1)
+		}
+		if err == nil && (string(readData) != data || reader.Checksum() != checksum) {
+			t.Errorf("Test %d: Expected %s with checksum %s, found %s with checksum %s", i+1, data, checksum, readData, reader.Checksum())
+		}
+	}
+
+	// Checksum sent as a header.
+	reader := checksumRequest{algorithm: "CRC32", checksum: checksum}.newReader(strings.NewReader(data), int64(len(data)))
+	if _, err := ioutil.ReadAll(reader); err != nil {
+		t.Errorf("Expected checksum header to match, but failed with: <ERROR> %s", err)
+	}
+	reader = checksumRequest{algorithm: "SHA256", checksum: checksum}.newReader(strings.NewReader(data), int64(len(data)))
+	if _, err := ioutil.ReadAll(reader); err == nil {
+		t.Error("Expected checksum mismatch, but passed instead")
+	} else if _, ok := err.(BadChecksum); !ok {
+		t.Errorf("Expected BadChecksum, but failed with: <ERROR> %s", err)
+	}
+}
+
+// Tests validate parsing of checksum headers and trailers of requests.
+func TestParseChecksumRequest(t *testing.T) {
+	checksum := base64.StdEncoding.EncodeToString(make([]byte, crc32.Size))
+	testCases := []struct {
+		headers  map[string]string
+		expected checksumRequest
+		s3Error  APIErrorCode
+	}{
+		// Test case - 1.
+		// Request without checksum.
+		{map[string]string{}, checksumRequest{}, ErrNone},
+		// Test case - 2.
+		// Checksum header.
+		{map[string]string{"X-Amz-Checksum-Crc32": checksum}, checksumRequest{algorithm: "CRC32", checksum: checksum}, ErrNone},
+		// Test case - 3.
+		// Checksum trailer of an aws-chunked body.
+		{map[string]string{"X-Amz-Trailer": "x-amz-checksum-crc32", "X-Amz-Content-Sha256": unsignedPayloadTrailer}, checksumRequest{algorithm: "CRC32", trailing: true}, ErrNone},
+		// Test case - 4.
+		// Aws-chunked body without trailer.
+		{map[string]string{"X-Amz-Content-Sha256": unsignedPayloadTrailer}, checksumRequest{}, ErrInvalidRequest},
+		// Test case - 5.
+		// Trailer of a signed body.
+		{map[string]string{"X-Amz-Trailer": "x-amz-checksum-crc32"}, checksumRequest{}, ErrNotImplemented},
+		// Test case - 6.
+		// Invalid checksum header.
+		{map[string]string{"X-Amz-Checksum-Sha256": checksum}, checksumRequest{}, ErrInvalidChecksum},
+	}
+	for i, testCase := range testCases {
+		r, err := http.NewRequest("PUT", "http://localhost:9000/bucket/object", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Unexpected error: <ERROR> %s", i+1, err)
+		}
+		for key, value := range testCase.headers {
+			r.Header.Set(key, value)
+		}
+		c, s3Error := parseChecksumRequest(r)
+		if s3Error != testCase.s3Error || c != testCase.expected {
+			t.Errorf("Test %d: Expected %#v with error %d, found %#v with error %d", i+1, testCase.expected, testCase.s3Error, c, s3Error)
+		}
+	}
+}
+
+// Wrapper for calling checksum tests for both XL multiple disks and single node setup.
+func TestObjectChecksums(t *testing.T) {
+	ExecObjectLayerTest(t, testObjectChecksums)
+}
+
+// Tests validate checksums of objects and multipart uploads.
+func testObjectChecksums(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := "bucket"
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+	}
+
+	// Checksum of a single object is saved and verified.
+	data := []byte("hello, world")
+	sum := sha256.Sum256(data)
+	checksum := base64.StdEncoding.EncodeToString(sum[:])
+	_, err := obj.PutObject(bucket, "object", int64(len(data)), bytes.NewReader(data), map[string]string{checksumAlgorithmKey: "SHA256"})
+	if err != nil {
+		t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+	}
+	objInfo, err := obj.GetObjectInfo(bucket, "object")
+	if err != nil {
+		t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+	}
+	if objInfo.UserDefined[checksumKey("SHA256")] != checksum {
+		t.Errorf("%s: Expected checksum %s, found %s", instanceType, checksum, objInfo.UserDefined[checksumKey("SHA256")])
+	}
+	metadata := map[string]string{checksumAlgorithmKey: "SHA256", checksumKey("SHA256"): checksum}
+	if _, err = obj.PutObject(bucket, "bad-object", 3, bytes.NewReader([]byte("bad")), metadata); err == nil {
+		t.Errorf("%s: Expected checksum mismatch, but passed instead", instanceType)
+	} else if _, ok := err.(BadChecksum); !ok {
+		t.Errorf("%s: Expected BadChecksum, but failed with: <ERROR> %s", instanceType, err)
+	}
+	if _, err = obj.GetObjectInfo(bucket, "bad-object"); err == nil {
+		t.Errorf("%s: Expected object with bad checksum to be removed", instanceType)
+	}
+
+	// Checksum of a multipart object is the checksum of its part checksums.
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", map[string]string{checksumAlgorithmKey: "CRC32"})
+	if err != nil {
+		t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+	}
+	parts := [][]byte{bytes.Repeat([]byte("a"), 5*1024*1024), []byte("last part")}
+	var completeParts []completePart
+	composite := crc32.NewIEEE()
+	for i, part := range parts {
+		md5Hex, pErr := obj.PutObjectPart(bucket, "multipart", uploadID, i+1, int64(len(part)), bytes.NewReader(part), "")
+		if pErr != nil {
+			t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, pErr)
+		}
+		partCRC := crc32.NewIEEE()
+		partCRC.Write(part)
+		composite.Write(partCRC.Sum(nil))
+		completeParts = append(completeParts, completePart{PartNumber: i + 1, ETag: md5Hex, ChecksumCRC32: base64.StdEncoding.EncodeToString(partCRC.Sum(nil))})
+	}
+	partsInfo, err := obj.ListObjectParts(bucket, "multipart", uploadID, 0, maxPartsList)
+	if err != nil {
+		t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+	}
+	if partsInfo.ChecksumAlgorithm != "CRC32" || len(partsInfo.Parts) != 2 || partsInfo.Parts[0].Checksum != completeParts[0].ChecksumCRC32 {
+		t.Errorf("%s: Expected part checksums in listing, found %#v", instanceType, partsInfo)
+	}
+
+	// Mismatching part checksums fail completion.
+	badParts := append([]completePart{}, completeParts...)
+	badParts[1].ChecksumCRC32 = completeParts[0].ChecksumCRC32
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, badParts); err == nil {
+		t.Errorf("%s: Expected completion with bad part checksum to fail", instanceType)
+	} else if _, ok := err.(InvalidPart); !ok {
+		t.Errorf("%s: Expected InvalidPart, but failed with: <ERROR> %s", instanceType, err)
+	}
+
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, completeParts); err != nil {
+		t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+	}
+	objInfo, err = obj.GetObjectInfo(bucket, "multipart")
+	if err != nil {
+		t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+	}
+	expected := base64.StdEncoding.EncodeToString(composite.Sum(nil)) + "-2"
+	if objInfo.UserDefined[checksumKey("CRC32")] != expected {
+		t.Errorf("%s: Expected checksum %s, found %s", instanceType, expected, objInfo.UserDefined[checksumKey("CRC32")])
+	}
+}
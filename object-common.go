@@ -55,7 +55,7 @@ func fsHouseKeeping(storageDisk StorageAPI) error {
 
 // Depending on the disk type network or local, initialize storage API.
 func newStorageAPI(disk string) (storage StorageAPI, err error) {
-	if !strings.ContainsRune(disk, ':') || filepath.VolumeName(disk) != "" {
+	if !isRemoteDisk(disk) {
 		// Initialize filesystem storage API.
 		return newPosix(disk)
 	}
@@ -63,6 +63,23 @@ func newStorageAPI(disk string) (storage StorageAPI, err error) {
 	return newRPCClient(disk)
 }
 
+// isRemoteDisk - returns true if the disk is served by another node
+// over the storage RPC, i.e of the form "host:/path".
+func isRemoteDisk(disk string) bool {
+	return strings.ContainsRune(disk, ':') && filepath.VolumeName(disk) == ""
+}
+
+// isDistributedSetup - returns true if any of the disks is served by
+// another node.
+func isDistributedSetup(disks []string) bool {
+	for _, disk := range disks {
+		if isRemoteDisk(disk) {
+			return true
+		}
+	}
+	return false
+}
+
 // Initializes meta volume on all input storage disks.
 func initMetaVolume(storageDisks []StorageAPI) error {
 	// This happens for the first time, but keep this here since this
@@ -0,0 +1,431 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/minio/minio/pkg/mimedb"
+)
+
+const (
+	// Metadata key of the compression format of an object.
+	compressionKey = "x-minio-internal-compression"
+
+	// Metadata key of the uncompressed size of an object.
+	compressionActualSizeKey = "x-minio-internal-actual-size"
+
+	// Metadata key of the md5sum of the uncompressed data, used as
+	// the ETag of an object.
+	compressionActualMD5Key = "x-minio-internal-actual-md5"
+
+	// Compression format, independently compressed flate blocks
+	// followed by an index of block offsets.
+	compressionFormatV1 = "flate/v1"
+
+	// Uncompressed size of each block, every block except the
+	// last one is of this size.
+	compressBlockSize = 1 * 1024 * 1024
+
+	// Size of the compressed length header of each block.
+	compressBlockHeaderSize = 4
+
+	// Size of each block offset in the index.
+	compressIndexEntrySize = 8
+)
+
+// Extensions and content types compressed by default.
+var (
+	compressDefaultExtensions = []string{".txt", ".log", ".csv", ".json", ".xml"}
+	compressDefaultMimeTypes  = []string{"text/*", "application/json", "application/xml"}
+)
+
+// compressConfig - extensions and content types of objects to compress.
+type compressConfig struct {
+	enabled    bool
+	extensions []string
+	mimeTypes  []string
+}
+
+// Global compression config, initialized from environment variables.
+var globalCompressConfig compressConfig
+
+// parseCompressConfig - parses comma separated extensions and content
+// types of objects to compress, defaults are used if empty.
+func parseCompressConfig(extensions, mimeTypes string) (config compressConfig, err error) {
+	config.enabled = true
+	for _, ext := range strings.Split(extensions, ",") {
+		if ext = strings.ToLower(strings.TrimSpace(ext)); ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") || len(ext) == 1 {
+			return compressConfig{}, fmt.Errorf("Invalid extension '%s'", ext)
+		}
+		config.extensions = append(config.extensions, ext)
+	}
+	for _, mimeType := range strings.Split(mimeTypes, ",") {
+		if mimeType = strings.ToLower(strings.TrimSpace(mimeType)); mimeType == "" {
+			continue
+		}
+		if !strings.Contains(mimeType, "/") {
+			return compressConfig{}, fmt.Errorf("Invalid content type '%s'", mimeType)
+		}
+		config.mimeTypes = append(config.mimeTypes, mimeType)
+	}
+	if len(config.extensions) == 0 {
+		config.extensions = compressDefaultExtensions
+	}
+	if len(config.mimeTypes) == 0 {
+		config.mimeTypes = compressDefaultMimeTypes
+	}
+	return config, nil
+}
+
+// isCompressible - verifies if an object should be compressed, either
+// its extension or its content type should match. Objects with a
+// content encoding are already compressed, objects with a checksum
+// are not compressed since the checksum covers the stored data.
+func (config compressConfig) isCompressible(object string, metadata map[string]string) bool {
+	if !config.enabled || metadata["content-encoding"] != "" || metadata[checksumAlgorithmKey] != "" {
+		return false
+	}
+	objectExt := strings.ToLower(path.Ext(object))
+	for _, ext := range config.extensions {
+		if ext == objectExt {
+			return true
+		}
+	}
+	// Guess content-type from the extension if not specified.
+	contentType := metadata["content-type"]
+	if contentType == "" && objectExt != "" {
+		if content, ok := mimedb.DB[strings.TrimPrefix(objectExt, ".")]; ok {
+			contentType = content.ContentType
+		}
+	}
+	// Ignore content type parameters like charset.
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if contentType == "" {
+		return false
+	}
+	for _, mimeType := range config.mimeTypes {
+		if mimeType == contentType {
+			return true
+		}
+		if strings.HasSuffix(mimeType, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(mimeType, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// getCompressedActualSize - returns the uncompressed size of an
+// object, ok is false if the object is not compressed.
+func getCompressedActualSize(objInfo ObjectInfo) (size int64, ok bool) {
+	return getMetadataActualSize(objInfo.UserDefined)
+}
+
+// getMetadataActualSize - returns the uncompressed size saved in
+// object metadata, ok is false if the object is not compressed.
+func getMetadataActualSize(metadata map[string]string) (size int64, ok bool) {
+	if metadata[compressionKey] != compressionFormatV1 {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(metadata[compressionActualSizeKey], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return size, true
+}
+
+// getQuotaSize - returns the size of an object accounted in bucket
+// quota given its stored size, compressed objects are accounted with
+// their uncompressed size so that usage matches listed object sizes.
+func getQuotaSize(metadata map[string]string, size int64) int64 {
+	if actualSize, ok := getMetadataActualSize(metadata); ok {
+		return actualSize
+	}
+	return size
+}
+
+// toCompressedObjectInfo - replaces size and md5sum of a compressed
+// object with the ones of its uncompressed data.
+func toCompressedObjectInfo(objInfo ObjectInfo) ObjectInfo {
+	size, ok := getCompressedActualSize(objInfo)
+	if !ok {
+		return objInfo
+	}
+	objInfo.Size = size
+	// Objects compressed before the md5sum was saved keep the
+	// md5sum of the compressed data.
+	if md5Sum := objInfo.UserDefined[compressionActualMD5Key]; md5Sum != "" {
+		objInfo.MD5Sum = md5Sum
+	}
+	return objInfo
+}
+
+// compressBlocks - compresses size bytes of reader into independently
+// compressed blocks, each prefixed with its compressed length,
+// followed by the offsets of all the blocks.
+func compressBlocks(writer io.Writer, reader io.Reader, size int64) error {
+	var index []byte
+	var offset int64
+	var compressed bytes.Buffer
+	flateWriter, err := flate.NewWriter(&compressed, flate.BestSpeed)
+	if err != nil {
+		return err
+	}
+	block := make([]byte, compressBlockSize)
+	for remaining := size; remaining > 0; {
+		n := int64(compressBlockSize)
+		if remaining < n {
+			n = remaining
+		}
+		if _, err = io.ReadFull(reader, block[:n]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return IncompleteBody{}
+			}
+			return err
+		}
+		remaining -= n
+
+		compressed.Reset()
+		compressed.Write(make([]byte, compressBlockHeaderSize))
+		flateWriter.Reset(&compressed)
+		if _, err = flateWriter.Write(block[:n]); err != nil {
+			return err
+		}
+		if err = flateWriter.Close(); err != nil {
+			return err
+		}
+		buf := compressed.Bytes()
+		binary.BigEndian.PutUint32(buf, uint32(len(buf)-compressBlockHeaderSize))
+		if _, err = writer.Write(buf); err != nil {
+			return err
+		}
+
+		var entry [compressIndexEntrySize]byte
+		binary.BigEndian.PutUint64(entry[:], uint64(offset))
+		index = append(index, entry[:]...)
+		offset += int64(len(buf))
+	}
+	_, err = writer.Write(index)
+	return err
+}
+
+// decompressBlocks - decompresses blocks from reader, skips the first
+// offset bytes of the first block and writes length bytes to writer.
+func decompressBlocks(writer io.Writer, reader io.Reader, offset, length int64) error {
+	var header [compressBlockHeaderSize]byte
+	var compressed []byte
+	flateReader := flate.NewReader(nil)
+	for length > 0 {
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return err
+		}
+		n := int(binary.BigEndian.Uint32(header[:]))
+		if cap(compressed) < n {
+			compressed = make([]byte, n)
+		}
+		compressed = compressed[:n]
+		if _, err := io.ReadFull(reader, compressed); err != nil {
+			return err
+		}
+		if err := flateReader.(flate.Resetter).Reset(bytes.NewReader(compressed), nil); err != nil {
+			return err
+		}
+		if offset > 0 {
+			if _, err := io.CopyN(ioutil.Discard, flateReader, offset); err != nil {
+				return err
+			}
+			offset = 0
+		}
+		written, err := io.CopyN(writer, flateReader, length)
+		length -= written
+		if err != nil && err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
+
+// compressObjects - implements ObjectLayer by transparently
+// compressing objects of another ObjectLayer. Objects are compressed
+// in blocks so that range reads only decompress the blocks in range,
+// the uncompressed size is saved in object metadata.
+type compressObjects struct {
+	ObjectLayer
+	config compressConfig
+}
+
+// newCompressObjects - initialize new compression object layer in
+// front of objAPI, compressed objects are readable even if new
+// objects are not compressed.
+func newCompressObjects(objAPI ObjectLayer, config compressConfig) ObjectLayer {
+	return compressObjects{ObjectLayer: objAPI, config: config}
+}
+
+// GetObjectInfo - returns object info with the uncompressed size and
+// md5sum.
+func (c compressObjects) GetObjectInfo(bucket, object string) (ObjectInfo, error) {
+	objInfo, err := c.ObjectLayer.GetObjectInfo(bucket, object)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return toCompressedObjectInfo(objInfo), nil
+}
+
+// ListObjects - lists objects with their uncompressed sizes and md5sums.
+func (c compressObjects) ListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+	result, err := c.ObjectLayer.ListObjects(bucket, prefix, marker, delimiter, maxKeys)
+	if err != nil {
+		return ListObjectsInfo{}, err
+	}
+	for i := range result.Objects {
+		result.Objects[i] = toCompressedObjectInfo(result.Objects[i])
+	}
+	return result, nil
+}
+
+// GetObject - reads the object, only the compressed blocks in range
+// are read and decompressed for compressed objects.
+func (c compressObjects) GetObject(bucket, object string, startOffset int64, length int64, writer io.Writer) error {
+	objInfo, err := c.ObjectLayer.GetObjectInfo(bucket, object)
+	if err != nil {
+		return err
+	}
+	size, ok := getCompressedActualSize(objInfo)
+	if !ok {
+		return c.ObjectLayer.GetObject(bucket, object, startOffset, length, writer)
+	}
+	if startOffset < 0 || length < 0 || startOffset+length > size {
+		return InvalidRange{}
+	}
+	if length == 0 {
+		return nil
+	}
+
+	// Blocks to read, the index is only read for partial reads.
+	blocks := (size + compressBlockSize - 1) / compressBlockSize
+	indexOffset := objInfo.Size - blocks*compressIndexEntrySize
+	firstBlock := startOffset / compressBlockSize
+	lastBlock := (startOffset + length - 1) / compressBlockSize
+	blockStart, blockEnd := int64(0), indexOffset
+	if firstBlock > 0 || lastBlock < blocks-1 {
+		var index bytes.Buffer
+		if err = c.ObjectLayer.GetObject(bucket, object, indexOffset, objInfo.Size-indexOffset, &index); err != nil {
+			return err
+		}
+		offsets := index.Bytes()
+		blockStart = int64(binary.BigEndian.Uint64(offsets[firstBlock*compressIndexEntrySize:]))
+		if lastBlock < blocks-1 {
+			blockEnd = int64(binary.BigEndian.Uint64(offsets[(lastBlock+1)*compressIndexEntrySize:]))
+		}
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		gErr := c.ObjectLayer.GetObject(bucket, object, blockStart, blockEnd-blockStart, pipeWriter)
+		pipeWriter.CloseWithError(gErr)
+	}()
+	defer pipeReader.Close()
+	return decompressBlocks(writer, pipeReader, startOffset-firstBlock*compressBlockSize, length)
+}
+
+// PutObject - creates an object, compressed if its extension or
+// content type is configured for compression. Objects of unknown size
+// are not compressed. The returned md5sum is of the uncompressed data.
+func (c compressObjects) PutObject(bucket, object string, size int64, data io.Reader, metadata map[string]string) (string, error) {
+	if size <= 0 || !c.config.isCompressible(object, metadata) {
+		return c.ObjectLayer.PutObject(bucket, object, size, data, metadata)
+	}
+
+	// Content-MD5 is verified on the uncompressed data.
+	compressMetadata := make(map[string]string)
+	for key, value := range metadata {
+		if key != "md5Sum" {
+			compressMetadata[key] = value
+		}
+	}
+	compressMetadata[compressionKey] = compressionFormatV1
+	compressMetadata[compressionActualSizeKey] = strconv.FormatInt(size, 10)
+
+	pipeReader, pipeWriter := io.Pipe()
+	errCh := make(chan error, 1)
+	go func() {
+		md5Writer := md5.New()
+		err := compressBlocks(pipeWriter, io.TeeReader(data, md5Writer), size)
+		md5Hex := hex.EncodeToString(md5Writer.Sum(nil))
+		if err == nil && metadata["md5Sum"] != "" && md5Hex != metadata["md5Sum"] {
+			err = BadDigest{metadata["md5Sum"], md5Hex}
+		}
+		if err == nil {
+			// Backends save metadata only once the data is read till
+			// EOF, which happens after the pipe is closed below.
+			compressMetadata[compressionActualMD5Key] = md5Hex
+		}
+		// Failing the reader makes sure the object is not created.
+		errCh <- err
+		pipeWriter.CloseWithError(err)
+	}()
+
+	_, err := c.ObjectLayer.PutObject(bucket, object, -1, pipeReader, compressMetadata)
+	pipeReader.Close()
+	if cErr := <-errCh; cErr != nil && cErr != io.ErrClosedPipe {
+		return "", cErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return compressMetadata[compressionActualMD5Key], nil
+}
+
+// CopyObject - copies an object, compressed objects are copied as is
+// and stay compressed.
+func (c compressObjects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (string, error) {
+	objInfo, err := c.ObjectLayer.GetObjectInfo(srcBucket, srcObject)
+	if err != nil {
+		return "", err
+	}
+	size, ok := getCompressedActualSize(objInfo)
+	if !ok {
+		return c.ObjectLayer.CopyObject(srcBucket, srcObject, dstBucket, dstObject, metadata)
+	}
+	compressMetadata := make(map[string]string)
+	for key, value := range metadata {
+		compressMetadata[key] = value
+	}
+	compressMetadata[compressionKey] = compressionFormatV1
+	compressMetadata[compressionActualSizeKey] = strconv.FormatInt(size, 10)
+	if md5Sum := objInfo.UserDefined[compressionActualMD5Key]; md5Sum != "" {
+		compressMetadata[compressionActualMD5Key] = md5Sum
+	}
+	md5Sum, err := c.ObjectLayer.CopyObject(srcBucket, srcObject, dstBucket, dstObject, compressMetadata)
+	if err != nil {
+		return "", err
+	}
+	return toCompressedObjectInfo(ObjectInfo{MD5Sum: md5Sum, UserDefined: compressMetadata}).MD5Sum, nil
+}
@@ -0,0 +1,241 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+// Tests validate parsing of compression configuration and selection
+// of objects to compress.
+func TestCompressConfig(t *testing.T) {
+	if _, err := parseCompressConfig("txt", ""); err == nil {
+		t.Errorf("Expected extension without '.' to fail")
+	}
+	if _, err := parseCompressConfig("", "text"); err == nil {
+		t.Errorf("Expected content type without '/' to fail")
+	}
+	config, err := parseCompressConfig(".LOG, .ndjson", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+
+	testCases := []struct {
+		object     string
+		metadata   map[string]string
+		compressed bool
+	}{
+		// Test case - 1.
+		// Configured extension.
+		{"app.log", nil, true},
+		// Test case - 2.
+		// Configured extension, case insensitive.
+		{"events.NDJSON", nil, true},
+		// Test case - 3.
+		// Default content type with parameters.
+		{"object", map[string]string{"content-type": "text/plain; charset=utf-8"}, true},
+		// Test case - 4.
+		// Content type guessed from the extension.
+		{"data.json", nil, true},
+		// Test case - 5.
+		// Already encoded objects are not compressed.
+		{"app.log", map[string]string{"content-encoding": "gzip"}, false},
+		// Test case - 6.
+		// Binary objects are not compressed.
+		{"image.png", nil, false},
+		// Test case - 7.
+		// Unknown content type.
+		{"object", nil, false},
+	}
+	for i, testCase := range testCases {
+		if compressed := config.isCompressible(testCase.object, testCase.metadata); compressed != testCase.compressed {
+			t.Errorf("Test %d: Expected compressed to be %v, but found %v", i+1, testCase.compressed, compressed)
+		}
+	}
+
+	// Nothing is compressed unless enabled.
+	if (compressConfig{}).isCompressible("app.log", nil) {
+		t.Errorf("Expected disabled compression not to compress")
+	}
+}
+
+// Wrapper for calling CompressObjects tests for both XL multiple disks and single node setup.
+func TestCompressObjects(t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Init Test config failed")
+	}
+	defer removeAll(rootPath)
+
+	ExecObjectLayerTest(t, testCompressObjects)
+}
+
+// Tests validate objects are compressed in the backend, reported with
+// their uncompressed size and md5sum and readable at any range.
+func testCompressObjects(obj ObjectLayer, instanceType string, t *testing.T) {
+	config, err := parseCompressConfig("", "")
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	compress := newCompressObjects(obj, config)
+	bucket := getRandomBucketName()
+	if err = compress.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+
+	// Spans multiple blocks with a partial last block.
+	var data bytes.Buffer
+	for i := 0; data.Len() < 3*compressBlockSize+100; i++ {
+		fmt.Fprintf(&data, "{\"line\": %d, \"message\": \"request completed\"}\n", i)
+	}
+	size := int64(data.Len())
+	md5Bytes := md5.Sum(data.Bytes())
+	md5Hex := hex.EncodeToString(md5Bytes[:])
+	metadata := map[string]string{"md5Sum": md5Hex}
+	md5Sum, err := compress.PutObject(bucket, "object.json", size, bytes.NewReader(data.Bytes()), metadata)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if md5Sum != md5Hex {
+		t.Errorf("%s: Expected md5sum %s of uncompressed data, but found %s", instanceType, md5Hex, md5Sum)
+	}
+
+	objInfo, err := obj.GetObjectInfo(bucket, "object.json")
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if objInfo.Size >= size/2 {
+		t.Errorf("%s: Expected object to be compressed, but found size %d of %d", instanceType, objInfo.Size, size)
+	}
+	if objInfo, err = compress.GetObjectInfo(bucket, "object.json"); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if objInfo.Size != size || objInfo.MD5Sum != md5Hex {
+		t.Errorf("%s: Expected size %d and md5sum %s, but found %d and %s", instanceType, size, md5Hex, objInfo.Size, objInfo.MD5Sum)
+	}
+	listInfo, err := compress.ListObjects(bucket, "", "", "", 10)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if len(listInfo.Objects) != 1 || listInfo.Objects[0].Size != size || listInfo.Objects[0].MD5Sum != md5Hex {
+		t.Errorf("%s: Expected listed size %d and md5sum %s, but found %v", instanceType, size, md5Hex, listInfo.Objects)
+	}
+
+	// Copies stay compressed and keep the md5sum of uncompressed data.
+	if md5Sum, err = compress.CopyObject(bucket, "object.json", bucket, "copy.json", nil); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if md5Sum != md5Hex {
+		t.Errorf("%s: Expected copy md5sum %s, but found %s", instanceType, md5Hex, md5Sum)
+	}
+	if objInfo, err = compress.GetObjectInfo(bucket, "copy.json"); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if objInfo.Size != size || objInfo.MD5Sum != md5Hex {
+		t.Errorf("%s: Expected copy size %d and md5sum %s, but found %d and %s", instanceType, size, md5Hex, objInfo.Size, objInfo.MD5Sum)
+	}
+
+	testCases := []struct {
+		offset int64
+		length int64
+	}{
+		// Test case - 1.
+		// Complete object.
+		{0, size},
+		// Test case - 2.
+		// Within the first block.
+		{10, 100},
+		// Test case - 3.
+		// Across blocks.
+		{compressBlockSize - 10, compressBlockSize + 20},
+		// Test case - 4.
+		// Last partial block.
+		{3 * compressBlockSize, size - 3*compressBlockSize},
+		// Test case - 5.
+		// Empty read.
+		{size, 0},
+	}
+	for i, testCase := range testCases {
+		var buffer bytes.Buffer
+		if err = compress.GetObject(bucket, "object.json", testCase.offset, testCase.length, &buffer); err != nil {
+			t.Fatalf("%s: Test %d: <ERROR> %s", instanceType, i+1, err)
+		}
+		if !bytes.Equal(buffer.Bytes(), data.Bytes()[testCase.offset:testCase.offset+testCase.length]) {
+			t.Errorf("%s: Test %d: Expected data at offset %d of length %d to match", instanceType, i+1, testCase.offset, testCase.length)
+		}
+	}
+	var buffer bytes.Buffer
+	if err = compress.GetObject(bucket, "object.json", size-10, 20, &buffer); err == nil {
+		t.Errorf("%s: Expected read beyond the object to fail", instanceType)
+	}
+
+	// Content-MD5 is verified on uncompressed data, object is not created on mismatch.
+	metadata = map[string]string{"md5Sum": "d41d8cd98f00b204e9800998ecf8427e"}
+	_, err = compress.PutObject(bucket, "bad.json", size, bytes.NewReader(data.Bytes()), metadata)
+	if _, ok := err.(BadDigest); !ok {
+		t.Errorf("%s: Expected BadDigest, but found %v", instanceType, err)
+	}
+	if _, err = compress.GetObjectInfo(bucket, "bad.json"); err == nil {
+		t.Errorf("%s: Expected object with bad digest not to be created", instanceType)
+	}
+	// Incomplete body.
+	_, err = compress.PutObject(bucket, "short.json", size+1, bytes.NewReader(data.Bytes()), nil)
+	if _, ok := err.(IncompleteBody); !ok {
+		t.Errorf("%s: Expected IncompleteBody, but found %v", instanceType, err)
+	}
+
+	// Other objects are stored as is.
+	if _, err = compress.PutObject(bucket, "object.bin", 5, bytes.NewReader([]byte("hello")), nil); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if objInfo, err = obj.GetObjectInfo(bucket, "object.bin"); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if objInfo.Size != 5 {
+		t.Errorf("%s: Expected object not to be compressed, but found size %d", instanceType, objInfo.Size)
+	}
+
+	// Bucket quota accounts for the uncompressed size, same as listing.
+	usage, err := getBucketUsage(compress, bucket)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if err = globalBucketQuotas.set(bucket, bucketQuota{Size: usage.Size + size}, usage); err != nil {
+		t.Fatalf("%s: Unable to set bucket quota: <ERROR> %s", instanceType, err)
+	}
+	defer globalBucketQuotas.remove(bucket)
+	if _, err = compress.PutObject(bucket, "quota.json", size+1, bytes.NewReader(append(data.Bytes(), '\n')), nil); err == nil {
+		t.Errorf("%s: Expected object exceeding quota with its uncompressed size to fail", instanceType)
+	}
+	if _, err = compress.PutObject(bucket, "quota.json", size, bytes.NewReader(data.Bytes()), nil); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if err = compress.DeleteObject(bucket, "object.json"); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	info, err := globalBucketQuotas.get(bucket)
+	if err != nil {
+		t.Fatalf("%s: Unable to get bucket quota: <ERROR> %s", instanceType, err)
+	}
+	if expected := (bucketUsage{usage.Size, usage.Objects}); info.Usage != expected {
+		t.Errorf("%s: Expected usage %v, but found %v", instanceType, expected, info.Usage)
+	}
+}
@@ -24,6 +24,26 @@ type StorageInfo struct {
 	Total int64
 	// Free available disk space.
 	Free int64
+	// Health of each disk, only for XL.
+	Disks []DiskHealth
+}
+
+// DiskHealth - represents health of a disk.
+type DiskHealth struct {
+	// Path of the disk.
+	Path string
+	// Online is false while the disk is skipped after repeated faults.
+	Online bool
+	// Number of calls made to the disk.
+	Calls int64
+	// Number of calls failed by faults of the disk.
+	Errors int64
+	// Moving average of call latency.
+	Latency time.Duration
+	// Last fault of the disk.
+	LastError string
+	// Date and time when the disk went offline.
+	OfflineSince time.Time
 }
 
 // BucketInfo - represents bucket metadata.
@@ -62,6 +82,13 @@ type ObjectInfo struct {
 	// what decoding mechanisms must be applied to obtain the object referenced
 	// by the Content-Type header field.
 	ContentEncoding string
+
+	// User-Defined metadata saved along with the object.
+	UserDefined map[string]string
+
+	// Parts of a multipart object in order, empty for objects
+	// created with a single PUT.
+	Parts []objectPartInfo
 }
 
 // ListPartsInfo - represents list of all parts.
@@ -95,6 +122,9 @@ type ListPartsInfo struct {
 	// List of all parts.
 	Parts []partInfo
 
+	// Checksum algorithm of the parts, if any.
+	ChecksumAlgorithm string
+
 	EncodingType string // Not supported yet.
 }
 
@@ -182,6 +212,10 @@ type partInfo struct {
 
 	// Size in bytes of the part.
 	Size int64
+
+	// Base64 encoded checksum of the part, if the upload has a
+	// checksum algorithm.
+	Checksum string
 }
 
 // uploadMetadata - represents metadata in progress multipart upload.
@@ -206,6 +240,12 @@ type completePart struct {
 
 	// Entity tag returned when the part was uploaded.
 	ETag string
+
+	// Checksums returned when the part was uploaded, if any.
+	ChecksumCRC32  string
+	ChecksumCRC32C string
+	ChecksumSHA1   string
+	ChecksumSHA256 string
 }
 
 // completedParts - is a collection satisfying sort.Interface.
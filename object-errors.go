@@ -126,6 +126,20 @@ func (e ObjectExistsAsDirectory) Error() string {
 	return "Object exists on : " + e.Bucket + " as directory " + e.Object
 }
 
+// ObjectLocked object is under retention or legal hold.
+type ObjectLocked GenericError
+
+func (e ObjectLocked) Error() string {
+	return "Object is WORM protected and cannot be overwritten or deleted: " + e.Bucket + "/" + e.Object
+}
+
+// PrefixAccessDenied object access is denied.
+type PrefixAccessDenied GenericError
+
+func (e PrefixAccessDenied) Error() string {
+	return "Prefix access is denied: " + e.Bucket + "/" + e.Object
+}
+
 // BucketExists bucket exists.
 type BucketExists GenericError
 
@@ -143,6 +157,17 @@ func (e BadDigest) Error() string {
 	return "Bad digest: Expected " + e.ExpectedMD5 + " is not valid with what we calculated " + e.CalculatedMD5
 }
 
+// BadChecksum - checksum you specified did not match what we received.
+type BadChecksum struct {
+	Algorithm          string
+	ExpectedChecksum   string
+	CalculatedChecksum string
+}
+
+func (e BadChecksum) Error() string {
+	return "Bad checksum: Expected " + e.Algorithm + " " + e.ExpectedChecksum + " is not valid with what we calculated " + e.CalculatedChecksum
+}
+
 // UnsupportedDelimiter - unsupported delimiter.
 type UnsupportedDelimiter struct {
 	Delimiter string
@@ -177,6 +202,34 @@ func (e BucketPolicyNotFound) Error() string {
 	return "No bucket policy found for bucket: " + e.Bucket
 }
 
+// BucketObjectLockConfigNotFound - no object lock configuration found.
+type BucketObjectLockConfigNotFound GenericError
+
+func (e BucketObjectLockConfigNotFound) Error() string {
+	return "No object lock configuration found for bucket: " + e.Bucket
+}
+
+// BucketQuotaNotFound - no bucket quota found.
+type BucketQuotaNotFound GenericError
+
+func (e BucketQuotaNotFound) Error() string {
+	return "No bucket quota found for bucket: " + e.Bucket
+}
+
+// BucketQuotaExceeded - bucket quota exceeded.
+type BucketQuotaExceeded GenericError
+
+func (e BucketQuotaExceeded) Error() string {
+	return "Bucket quota exceeded for bucket: " + e.Bucket
+}
+
+// BucketReplicationConfigNotFound - no replication configuration found.
+type BucketReplicationConfigNotFound GenericError
+
+func (e BucketReplicationConfigNotFound) Error() string {
+	return "No replication configuration found for bucket: " + e.Bucket
+}
+
 /// Bucket related errors.
 
 // BucketNameInvalid - bucketname provided is invalid.
@@ -241,6 +294,13 @@ func (e InvalidPart) Error() string {
 	return "One or more of the specified parts could not be found"
 }
 
+// NotImplemented operation is not supported by the object layer.
+type NotImplemented struct{}
+
+func (e NotImplemented) Error() string {
+	return "Not Implemented"
+}
+
 // InvalidPartOrder parts are not ordered as Requested
 type InvalidPartOrder struct {
 	UploadID string
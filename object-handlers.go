@@ -17,13 +17,16 @@
 package main
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"sort"
 	"strconv"
@@ -51,6 +54,110 @@ func setGetRespHeaders(w http.ResponseWriter, reqParams url.Values) {
 	}
 }
 
+// getObjectPartRange - returns the byte range of the part requested
+// with the partNumber query parameter, nil if no part is requested.
+// Objects created with a single PUT only have part 1, which is the
+// whole object, same as S3.
+func getObjectPartRange(r *http.Request, objInfo ObjectInfo) (*httpRange, APIErrorCode) {
+	partNumberStr := r.URL.Query().Get("partNumber")
+	if partNumberStr == "" {
+		return nil, ErrNone
+	}
+	partNumber, err := strconv.Atoi(partNumberStr)
+	if err != nil || partNumber < 1 || isMaxPartID(partNumber) {
+		return nil, ErrInvalidPartNumber
+	}
+	if r.Header.Get("Range") != "" {
+		return nil, ErrInvalidPartNumberRange
+	}
+	if len(objInfo.Parts) == 0 {
+		if partNumber != 1 {
+			return nil, ErrPartNumberNotSatisfiable
+		}
+		return &httpRange{start: 0, length: objInfo.Size, size: objInfo.Size}, ErrNone
+	}
+	if partNumber > len(objInfo.Parts) {
+		return nil, ErrPartNumberNotSatisfiable
+	}
+	var start int64
+	for _, part := range objInfo.Parts[:partNumber-1] {
+		start += part.Size
+	}
+	length := objInfo.Parts[partNumber-1].Size
+	return &httpRange{start: start, length: length, size: objInfo.Size}, ErrNone
+}
+
+// setPartsCountHeader - sets x-amz-mp-parts-count for requests of
+// a part of a multipart object.
+func setPartsCountHeader(w http.ResponseWriter, r *http.Request, objInfo ObjectInfo) {
+	if r.URL.Query().Get("partNumber") != "" && len(objInfo.Parts) > 0 {
+		w.Header().Set("x-amz-mp-parts-count", strconv.Itoa(len(objInfo.Parts)))
+	}
+}
+
+// setChecksumHeaders - sets the checksum of an object if requested with
+// x-amz-checksum-mode, only for requests of the whole object.
+func setChecksumHeaders(w http.ResponseWriter, r *http.Request, objInfo ObjectInfo) {
+	if !strings.EqualFold(r.Header.Get("X-Amz-Checksum-Mode"), "ENABLED") {
+		return
+	}
+	if r.Header.Get("Range") != "" || r.URL.Query().Get("partNumber") != "" {
+		return
+	}
+	algorithm := objInfo.UserDefined[checksumAlgorithmKey]
+	if checksum := objInfo.UserDefined[checksumKey(algorithm)]; algorithm != "" && checksum != "" {
+		w.Header().Set(checksumKey(algorithm), checksum)
+	}
+}
+
+// writeObjectRanges - writes multiple ranges of an object as a
+// multipart/byteranges response, each range is read with its own
+// GetObject call.
+func writeObjectRanges(w http.ResponseWriter, objAPI ObjectLayer, bucket, object string, hranges []*httpRange) error {
+	contentType := w.Header().Get("Content-Type")
+	partHeader := func(hrange *httpRange) textproto.MIMEHeader {
+		return textproto.MIMEHeader{
+			"Content-Type":  {contentType},
+			"Content-Range": {hrange.String()},
+		}
+	}
+
+	// Content-Length is the length of the ranges along with the
+	// multipart headers, which are generated ahead without data.
+	var headers bytes.Buffer
+	mw := multipart.NewWriter(&headers)
+	contentLength := int64(0)
+	for _, hrange := range hranges {
+		if _, err := mw.CreatePart(partHeader(hrange)); err != nil {
+			return err
+		}
+		contentLength += hrange.length
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	contentLength += int64(headers.Len())
+
+	w.Header().Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	w.Header().Set("Content-Length", strconv.FormatInt(contentLength, 10))
+	w.WriteHeader(http.StatusPartialContent)
+
+	pw := multipart.NewWriter(w)
+	if err := pw.SetBoundary(mw.Boundary()); err != nil {
+		return err
+	}
+	for _, hrange := range hranges {
+		part, err := pw.CreatePart(partHeader(hrange))
+		if err != nil {
+			return err
+		}
+		if err = objAPI.GetObject(bucket, object, hrange.start, hrange.length, part); err != nil {
+			return err
+		}
+	}
+	return pw.Close()
+}
+
 // http Header "x-amz-content-sha256" == "UNSIGNED-PAYLOAD" indicates that the
 // client did not calculate sha256 of the payload. Hence we skip calculating sha256.
 // We also skip calculating sha256 for presigned requests without "x-amz-content-sha256" header.
@@ -68,14 +175,29 @@ func errAllowableObjectNotFound(bucket string, r *http.Request) APIErrorCode {
 		//we care about the bucket as a whole, not a particular resource
 		url := *r.URL
 		url.Path = "/" + bucket
+		bucketReq := *r
+		bucketReq.URL = &url
 
-		if s3Error := enforceBucketPolicy("s3:ListBucket", bucket, &url); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:ListBucket", bucket, &bucketReq); s3Error != ErrNone {
 			return ErrAccessDenied
 		}
 	}
 	return ErrNoSuchKey
 }
 
+// enforceCopySourcePolicy - anonymous copy requests need to be allowed
+// to read the source object in addition to writing the destination.
+func enforceCopySourcePolicy(sourceBucket, sourceObject string, r *http.Request) APIErrorCode {
+	if getRequestAuthType(r) != authTypeAnonymous {
+		return ErrNone
+	}
+	url := *r.URL
+	url.Path = "/" + sourceBucket + "/" + sourceObject
+	sourceReq := *r
+	sourceReq.URL = &url
+	return enforceBucketPolicy("s3:GetObject", sourceBucket, &sourceReq)
+}
+
 // GetObjectHandler - GET Object
 // ----------
 // This implementation of the GET operation retrieves object. To use GET,
@@ -93,7 +215,7 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
-		if s3Error := enforceBucketPolicy("s3:GetObject", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:GetObject", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -115,15 +237,34 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	var hrange *httpRange
-	hrange, err = getRequestedRange(r.Header.Get("Range"), objInfo.Size)
-	if err != nil {
-		writeErrorResponse(w, r, ErrInvalidRange, r.URL.Path)
+	// Get the requested part, or ranges of the object.
+	hrange, s3Error := getObjectPartRange(r, objInfo)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
 		return
 	}
+	var hranges []*httpRange
+	if hrange == nil {
+		hranges, err = getRequestedRanges(r.Header.Get("Range"), objInfo.Size)
+		if err != nil {
+			writeErrorResponse(w, r, ErrInvalidRange, r.URL.Path)
+			return
+		}
+		hrange = &httpRange{size: objInfo.Size}
+		if len(hranges) == 1 {
+			hrange = hranges[0]
+		}
+	}
 
-	// Set standard object headers.
-	setObjectHeaders(w, objInfo, hrange)
+	// Set standard object headers, multiple ranges are sent with
+	// their own headers.
+	setPartsCountHeader(w, r, objInfo)
+	setChecksumHeaders(w, r, objInfo)
+	if len(hranges) > 1 {
+		setObjectHeaders(w, objInfo, nil)
+	} else {
+		setObjectHeaders(w, objInfo, hrange)
+	}
 
 	// Set any additional requested response headers.
 	setGetRespHeaders(w, r.URL.Query())
@@ -138,6 +279,19 @@ func (api objectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Get multiple ranges of the object.
+	if len(hranges) > 1 {
+		if err := writeObjectRanges(w, api.ObjectAPI, bucket, object, hranges); err != nil {
+			errorIf(err, "Writing to client failed.")
+		}
+		return
+	}
+
+	// Preconditions are verified, reply with partial content for ranges.
+	if isPartialContent(hrange) {
+		w.WriteHeader(http.StatusPartialContent)
+	}
+
 	// Get the object.
 	startOffset := hrange.start
 	length := hrange.length
@@ -273,7 +427,7 @@ func (api objectAPIHandlers) HeadObjectHandler(w http.ResponseWriter, r *http.Re
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
-		if s3Error := enforceBucketPolicy("s3:GetObject", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:GetObject", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -295,8 +449,17 @@ func (api objectAPIHandlers) HeadObjectHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// Get the requested part of the object, if any.
+	hrange, s3Error := getObjectPartRange(r, objInfo)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
 	// Set standard object headers.
-	setObjectHeaders(w, objInfo, nil)
+	setPartsCountHeader(w, r, objInfo)
+	setChecksumHeaders(w, r, objInfo)
+	setObjectHeaders(w, objInfo, hrange)
 
 	// Verify 'If-Modified-Since' and 'If-Unmodified-Since'.
 	lastModified := objInfo.ModTime
@@ -310,7 +473,11 @@ func (api objectAPIHandlers) HeadObjectHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	// Successfull response.
-	w.WriteHeader(http.StatusOK)
+	if isPartialContent(hrange) {
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
 }
 
 // CopyObjectHandler - Copy Object
@@ -329,7 +496,7 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
-		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -345,27 +512,27 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 
 	// objectSource
 	objectSource := r.Header.Get("X-Amz-Copy-Source")
-
-	// Skip the first element if it is '/', split the rest.
-	if strings.HasPrefix(objectSource, "/") {
-		objectSource = objectSource[1:]
-	}
-	splits := strings.SplitN(objectSource, "/", 2)
-
-	// Save sourceBucket and sourceObject extracted from url Path.
-	var sourceBucket, sourceObject string
-	if len(splits) == 2 {
-		sourceBucket = splits[0]
-		sourceObject = splits[1]
-	}
+	sourceBucket, sourceObject := getCopySource(objectSource)
 	// If source object is empty, reply back error.
 	if sourceObject == "" {
 		writeErrorResponse(w, r, ErrInvalidCopySource, r.URL.Path)
 		return
 	}
+	if s3Error := enforceCopySourcePolicy(sourceBucket, sourceObject, r); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, objectSource)
+		return
+	}
+
+	// Metadata is copied from the source unless it is replaced.
+	metadataDirective := r.Header.Get("X-Amz-Metadata-Directive")
+	if metadataDirective != "" && metadataDirective != "COPY" && metadataDirective != "REPLACE" {
+		writeErrorResponse(w, r, ErrInvalidMetadataDirective, r.URL.Path)
+		return
+	}
 
-	// Source and destination objects cannot be same, reply back error.
-	if sourceObject == object && sourceBucket == bucket {
+	// Source and destination objects cannot be same unless the
+	// metadata is replaced, reply back error.
+	if sourceObject == object && sourceBucket == bucket && metadataDirective != "REPLACE" {
 		writeErrorResponse(w, r, ErrInvalidCopyDest, r.URL.Path)
 		return
 	}
@@ -387,7 +554,7 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 
 	// Verify x-amz-copy-source-if-match and
 	// x-amz-copy-source-if-none-match.
-	if checkCopySourceETag(w, r) {
+	if checkCopySourceETag(w, r, objInfo.MD5Sum) {
 		return
 	}
 
@@ -397,38 +564,53 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	pipeReader, pipeWriter := io.Pipe()
-	go func() {
-		startOffset := int64(0) // Read the whole file.
-		// Get the object.
-		gErr := api.ObjectAPI.GetObject(sourceBucket, sourceObject, startOffset, objInfo.Size, pipeWriter)
-		if gErr != nil {
-			errorIf(gErr, "Unable to read an object.")
-			pipeWriter.CloseWithError(gErr)
-			return
+	// Save metadata.
+	var metadata map[string]string
+	if metadataDirective == "REPLACE" {
+		metadata = extractMetadataFromHeader(r.Header)
+	} else {
+		metadata = make(map[string]string)
+		metadata["content-type"] = objInfo.ContentType
+		metadata["content-encoding"] = objInfo.ContentEncoding
+		for _, key := range objectContentHeaders {
+			if value, ok := objInfo.UserDefined[strings.ToLower(key)]; ok {
+				metadata[strings.ToLower(key)] = value
+			}
 		}
-		pipeWriter.Close() // Close.
-	}()
-
-	// Size of object.
-	size := objInfo.Size
+		for key, value := range objInfo.UserDefined {
+			if isUserMetadataKey(key) {
+				metadata[key] = value
+			}
+		}
+	}
+	// Do not set `md5sum`, the object layer retains the md5sum of
+	// the copied data.
 
-	// Save metadata.
-	metadata := make(map[string]string)
-	// Save other metadata if available.
-	metadata["content-type"] = objInfo.ContentType
-	metadata["content-encoding"] = objInfo.ContentEncoding
-	// Do not set `md5sum` as CopyObject will not keep the
-	// same md5sum as the source.
+	// Apply object lock retention and legal hold, if any.
+	lockMetadata, s3Error := parseObjectLockHeaders(bucket, r.Header)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+	for key, value := range lockMetadata {
+		metadata[key] = value
+	}
+	// Mark the object for replication, if any.
+	for key, value := range getReplicationMetadata(bucket, object, r) {
+		metadata[key] = value
+	}
 
-	// Create the object.
-	md5Sum, err := api.ObjectAPI.PutObject(bucket, object, size, pipeReader, metadata)
+	// Copy the object.
+	md5Sum, err := api.ObjectAPI.CopyObject(sourceBucket, sourceObject, bucket, object, metadata)
 	if err != nil {
-		errorIf(err, "Unable to create an object.")
+		errorIf(err, "Unable to copy an object.")
 		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
 		return
 	}
 
+	// Queue the new object for replication.
+	queueReplication(bucket, object, r)
+
 	objInfo, err = api.ObjectAPI.GetObjectInfo(bucket, object)
 	if err != nil {
 		errorIf(err, "Unable to fetch object info.")
@@ -442,8 +624,59 @@ func (api objectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 	setCommonHeaders(w)
 	// write success response.
 	writeSuccessResponse(w, encodedSuccessResponse)
-	// Explicitly close the reader, to avoid fd leaks.
-	pipeReader.Close()
+}
+
+// Standard headers saved along with the object and returned on
+// GET and HEAD, in addition to content type and encoding.
+var objectContentHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Language",
+	"Expires",
+}
+
+// extractMetadataFromHeader - returns the content headers and user
+// metadata of an object from request headers.
+func extractMetadataFromHeader(header http.Header) map[string]string {
+	metadata := make(map[string]string)
+	// Save other metadata if available.
+	metadata["content-type"] = header.Get("Content-Type")
+	metadata["content-encoding"] = trimAWSChunkedEncoding(header.Get("Content-Encoding"))
+	for _, key := range objectContentHeaders {
+		if value := header.Get(key); value != "" {
+			metadata[strings.ToLower(key)] = value
+		}
+	}
+	for key := range header {
+		cKey := http.CanonicalHeaderKey(key)
+		if isUserMetadataKey(cKey) {
+			metadata[cKey] = header.Get(cKey)
+		}
+	}
+	return metadata
+}
+
+// isUserMetadataKey - returns true for canonical keys of user
+// defined metadata headers.
+func isUserMetadataKey(cKey string) bool {
+	return strings.HasPrefix(cKey, "X-Amz-Meta-") || strings.HasPrefix(cKey, "X-Minio-Meta-")
+}
+
+// getCopySource - returns the source bucket and object of
+// x-amz-copy-source, object is empty if the source is not valid.
+func getCopySource(objectSource string) (sourceBucket, sourceObject string) {
+	// Skip the first element if it is '/', split the rest.
+	if strings.HasPrefix(objectSource, "/") {
+		objectSource = objectSource[1:]
+	}
+	splits := strings.SplitN(objectSource, "/", 2)
+
+	// Save sourceBucket and sourceObject extracted from url Path.
+	if len(splits) == 2 {
+		sourceBucket = splits[0]
+		sourceObject = splits[1]
+	}
+	return sourceBucket, sourceObject
 }
 
 // checkCopySource implements x-amz-copy-source-if-modified-since and
@@ -460,7 +693,7 @@ func checkCopySourceLastModified(w http.ResponseWriter, r *http.Request, modtime
 	}
 	// The Date-Modified header truncates sub-second precision, so
 	// use mtime < t+1s instead of mtime <= t to check for unmodified.
-	if _, ok := r.Header["x-amz-copy-source-if-modified-since"]; ok {
+	if _, ok := r.Header["X-Amz-Copy-Source-If-Modified-Since"]; ok {
 		// Return the object only if it has been modified since the
 		// specified time, otherwise return a 304 error (not modified).
 		t, err := time.Parse(http.TimeFormat, r.Header.Get("x-amz-copy-source-if-modified-since"))
@@ -473,7 +706,7 @@ func checkCopySourceLastModified(w http.ResponseWriter, r *http.Request, modtime
 			w.WriteHeader(http.StatusNotModified)
 			return true
 		}
-	} else if _, ok := r.Header["x-amz-copy-source-if-unmodified-since"]; ok {
+	} else if _, ok := r.Header["X-Amz-Copy-Source-If-Unmodified-Since"]; ok {
 		// Return the object only if it has not been modified since the
 		// specified time, otherwise return a 412 error (precondition failed).
 		t, err := time.Parse(http.TimeFormat, r.Header.Get("x-amz-copy-source-if-unmodified-since"))
@@ -494,15 +727,14 @@ func checkCopySourceLastModified(w http.ResponseWriter, r *http.Request, modtime
 // checkCopySourceETag implements x-amz-copy-source-if-match and
 // x-amz-copy-source-if-none-match checks.
 //
-// The ETag must have been previously set in the ResponseWriter's
-// headers. The return value is whether this request is now considered
-// complete.
-func checkCopySourceETag(w http.ResponseWriter, r *http.Request) bool {
-	etag := w.Header().Get("ETag")
+// etag is the ETag of the copy source. The return value is whether
+// this request is now considered complete.
+func checkCopySourceETag(w http.ResponseWriter, r *http.Request, etag string) bool {
 	// Tag must be provided...
 	if etag == "" {
 		return false
 	}
+	etag = "\"" + etag + "\""
 	if inm := r.Header.Get("x-amz-copy-source-if-none-match"); inm != "" {
 		// Return the object only if its entity tag (ETag) is different
 		// from the one specified; otherwise, return a 304 (not modified).
@@ -559,8 +791,14 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		writeErrorResponse(w, r, ErrInvalidDigest, r.URL.Path)
 		return
 	}
+	// Get additional checksum sent by client, if any.
+	checksumReq, s3Error := parseChecksumRequest(r)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
 	/// if Content-Length is unknown/missing, deny the request
-	size := r.ContentLength
+	size := checksumReq.getDecodedContentLength(r)
 	if size == -1 && !contains(r.TransferEncoding, "chunked") {
 		writeErrorResponse(w, r, ErrMissingContentLength, r.URL.Path)
 		return
@@ -572,19 +810,28 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// Save metadata.
-	metadata := make(map[string]string)
+	metadata := extractMetadataFromHeader(r.Header)
 	// Make sure we hex encode md5sum here.
 	metadata["md5Sum"] = hex.EncodeToString(md5Bytes)
-	// Save other metadata if available.
-	metadata["content-type"] = r.Header.Get("Content-Type")
-	metadata["content-encoding"] = r.Header.Get("Content-Encoding")
-	for key := range r.Header {
-		cKey := http.CanonicalHeaderKey(key)
-		if strings.HasPrefix(cKey, "x-amz-meta-") {
-			metadata[cKey] = r.Header.Get(cKey)
-		} else if strings.HasPrefix(key, "x-minio-meta-") {
-			metadata[cKey] = r.Header.Get(cKey)
-		}
+	// Apply object lock retention and legal hold, if any.
+	lockMetadata, s3Error := parseObjectLockHeaders(bucket, r.Header)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+	for key, value := range lockMetadata {
+		metadata[key] = value
+	}
+	// Mark the object for replication, if any.
+	for key, value := range getReplicationMetadata(bucket, object, r) {
+		metadata[key] = value
+	}
+	// Verify the additional checksum while the body is read.
+	var checksumBody *checksumReader
+	if checksumReq.algorithm != "" {
+		metadata[checksumAlgorithmKey] = checksumReq.algorithm
+		checksumBody = checksumReq.newReader(r.Body, size)
+		r.Body = ioutil.NopCloser(checksumBody)
 	}
 
 	var md5Sum string
@@ -595,7 +842,7 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
-		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -609,9 +856,9 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 			// request without sha256-header.
 			var s3Error APIErrorCode
 			if isRequestSignatureV4(r) {
-				s3Error = doesSignatureMatch(unsignedPayload, r, validateRegion)
+				s3Error = doesSignatureMatch(getUnsignedPayload(r), r, validateRegion)
 			} else if isRequestPresignedSignatureV4(r) {
-				s3Error = doesPresignedSignatureMatch(unsignedPayload, r, validateRegion)
+				s3Error = doesPresignedSignatureMatch(getUnsignedPayload(r), r, validateRegion)
 			}
 			if s3Error != ErrNone {
 				if s3Error == ErrSignatureDoesNotMatch {
@@ -675,9 +922,14 @@ func (api objectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
 		return
 	}
+	// Queue the new object for replication.
+	queueReplication(bucket, object, r)
 	if md5Sum != "" {
 		w.Header().Set("ETag", "\""+md5Sum+"\"")
 	}
+	if checksumBody != nil {
+		w.Header().Set(checksumKey(checksumReq.algorithm), checksumBody.Checksum())
+	}
 	writeSuccessResponse(w, nil)
 }
 
@@ -697,7 +949,7 @@ func (api objectAPIHandlers) NewMultipartUploadHandler(w http.ResponseWriter, r
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/mpuAndPermissions.html
-		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -709,17 +961,29 @@ func (api objectAPIHandlers) NewMultipartUploadHandler(w http.ResponseWriter, r
 	}
 
 	// Save metadata.
-	metadata := make(map[string]string)
-	// Save other metadata if available.
-	metadata["content-type"] = r.Header.Get("Content-Type")
-	metadata["content-encoding"] = r.Header.Get("Content-Encoding")
-	for key := range r.Header {
-		cKey := http.CanonicalHeaderKey(key)
-		if strings.HasPrefix(cKey, "x-amz-meta-") {
-			metadata[cKey] = r.Header.Get(cKey)
-		} else if strings.HasPrefix(key, "x-minio-meta-") {
-			metadata[cKey] = r.Header.Get(cKey)
+	metadata := extractMetadataFromHeader(r.Header)
+	// Apply object lock retention and legal hold, if any.
+	lockMetadata, s3Error := parseObjectLockHeaders(bucket, r.Header)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+	for key, value := range lockMetadata {
+		metadata[key] = value
+	}
+	// Mark the object for replication, if any.
+	for key, value := range getReplicationMetadata(bucket, object, r) {
+		metadata[key] = value
+	}
+	// Save the checksum algorithm of the parts, if any.
+	var checksumAlgorithm string
+	if value := r.Header.Get(checksumAlgorithmKey); value != "" {
+		checksumAlgorithm, s3Error = parseChecksumAlgorithm(value)
+		if s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
 		}
+		metadata[checksumAlgorithmKey] = checksumAlgorithm
 	}
 
 	uploadID, err := api.ObjectAPI.NewMultipartUpload(bucket, object, metadata)
@@ -733,6 +997,9 @@ func (api objectAPIHandlers) NewMultipartUploadHandler(w http.ResponseWriter, r
 	encodedSuccessResponse := encodeResponse(response)
 	// write headers
 	setCommonHeaders(w)
+	if checksumAlgorithm != "" {
+		w.Header().Set(checksumAlgorithmKey, checksumAlgorithm)
+	}
 	// write success response.
 	writeSuccessResponse(w, encodedSuccessResponse)
 }
@@ -750,8 +1017,15 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	// Get additional checksum sent by client, if any.
+	checksumReq, s3Error := parseChecksumRequest(r)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
 	/// if Content-Length is unknown/missing, throw away
-	size := r.ContentLength
+	size := checksumReq.getDecodedContentLength(r)
 	if size == -1 {
 		writeErrorResponse(w, r, ErrMissingContentLength, r.URL.Path)
 		return
@@ -778,6 +1052,13 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	// Verify the additional checksum while the body is read.
+	var checksumBody *checksumReader
+	if checksumReq.algorithm != "" {
+		checksumBody = checksumReq.newReader(r.Body, size)
+		r.Body = ioutil.NopCloser(checksumBody)
+	}
+
 	var partMD5 string
 	switch getRequestAuthType(r) {
 	default:
@@ -786,7 +1067,7 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/mpuAndPermissions.html
-		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -802,9 +1083,9 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 			// request without sha256-header.
 			var s3Error APIErrorCode
 			if isRequestSignatureV4(r) {
-				s3Error = doesSignatureMatch(unsignedPayload, r, validateRegion)
+				s3Error = doesSignatureMatch(getUnsignedPayload(r), r, validateRegion)
 			} else if isRequestPresignedSignatureV4(r) {
-				s3Error = doesPresignedSignatureMatch(unsignedPayload, r, validateRegion)
+				s3Error = doesPresignedSignatureMatch(getUnsignedPayload(r), r, validateRegion)
 			}
 			if s3Error != ErrNone {
 				if s3Error == ErrSignatureDoesNotMatch {
@@ -871,9 +1152,132 @@ func (api objectAPIHandlers) PutObjectPartHandler(w http.ResponseWriter, r *http
 	if partMD5 != "" {
 		w.Header().Set("ETag", "\""+partMD5+"\"")
 	}
+	if checksumBody != nil {
+		w.Header().Set(checksumKey(checksumReq.algorithm), checksumBody.Checksum())
+	}
 	writeSuccessResponse(w, nil)
 }
 
+// CopyObjectPartHandler - Upload part copy
+// ----------
+// This implementation of the PUT operation uploads a part of a
+// multipart upload by reading a range of another object.
+func (api objectAPIHandlers) CopyObjectPartHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypeAnonymous:
+		// http://docs.aws.amazon.com/AmazonS3/latest/dev/mpuAndPermissions.html
+		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	uploadID := r.URL.Query().Get("uploadId")
+	partID, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil {
+		writeErrorResponse(w, r, ErrInvalidPart, r.URL.Path)
+		return
+	}
+
+	// check partID with maximum part ID for multipart objects
+	if isMaxPartID(partID) {
+		writeErrorResponse(w, r, ErrInvalidMaxParts, r.URL.Path)
+		return
+	}
+
+	objectSource := r.Header.Get("X-Amz-Copy-Source")
+	sourceBucket, sourceObject := getCopySource(objectSource)
+	// If source object is empty, reply back error.
+	if sourceObject == "" {
+		writeErrorResponse(w, r, ErrInvalidCopySource, r.URL.Path)
+		return
+	}
+	if s3Error := enforceCopySourcePolicy(sourceBucket, sourceObject, r); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, objectSource)
+		return
+	}
+
+	objInfo, err := api.ObjectAPI.GetObjectInfo(sourceBucket, sourceObject)
+	if err != nil {
+		errorIf(err, "Unable to fetch object info.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), objectSource)
+		return
+	}
+
+	// Verify x-amz-copy-source-if-modified-since and
+	// x-amz-copy-source-if-unmodified-since.
+	if checkCopySourceLastModified(w, r, objInfo.ModTime) {
+		return
+	}
+
+	// Verify x-amz-copy-source-if-match and
+	// x-amz-copy-source-if-none-match.
+	if checkCopySourceETag(w, r, objInfo.MD5Sum) {
+		return
+	}
+
+	// Copy the whole object unless x-amz-copy-source-range is set.
+	startOffset, length := int64(0), objInfo.Size
+	if rangeHeader := r.Header.Get("X-Amz-Copy-Source-Range"); rangeHeader != "" {
+		hrange, rErr := parseCopyPartRange(rangeHeader, objInfo.Size)
+		if rErr != nil {
+			if _, ok := rErr.(InvalidRange); ok {
+				writeErrorResponse(w, r, ErrInvalidRange, objectSource)
+				return
+			}
+			writeErrorResponse(w, r, ErrInvalidCopyPartRange, r.URL.Path)
+			return
+		}
+		startOffset, length = hrange.start, hrange.length
+	}
+
+	/// maximum Upload size for multipart objects in a single operation
+	if isMaxObjectSize(length) {
+		writeErrorResponse(w, r, ErrEntityTooLarge, objectSource)
+		return
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		gErr := api.ObjectAPI.GetObject(sourceBucket, sourceObject, startOffset, length, pipeWriter)
+		if gErr != nil {
+			errorIf(gErr, "Unable to read an object.")
+			pipeWriter.CloseWithError(gErr)
+			return
+		}
+		pipeWriter.Close() // Close.
+	}()
+
+	partMD5, err := api.ObjectAPI.PutObjectPart(bucket, object, uploadID, partID, length, pipeReader, "")
+	// Explicitly close the reader, to avoid fd leaks.
+	pipeReader.Close()
+	if err != nil {
+		errorIf(err, "Unable to create object part.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+
+	response := generateCopyObjectPartResponse(partMD5, time.Now().UTC())
+	encodedSuccessResponse := encodeResponse(response)
+	// write headers
+	setCommonHeaders(w)
+	// write success response.
+	writeSuccessResponse(w, encodedSuccessResponse)
+}
+
 // AbortMultipartUploadHandler - Abort multipart upload
 func (api objectAPIHandlers) AbortMultipartUploadHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -887,7 +1291,7 @@ func (api objectAPIHandlers) AbortMultipartUploadHandler(w http.ResponseWriter,
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/mpuAndPermissions.html
-		if s3Error := enforceBucketPolicy("s3:AbortMultipartUpload", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:AbortMultipartUpload", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -933,7 +1337,7 @@ func (api objectAPIHandlers) ListObjectPartsHandler(w http.ResponseWriter, r *ht
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/mpuAndPermissions.html
-		if s3Error := enforceBucketPolicy("s3:ListMultipartUploadParts", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:ListMultipartUploadParts", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -985,7 +1389,7 @@ func (api objectAPIHandlers) CompleteMultipartUploadHandler(w http.ResponseWrite
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/mpuAndPermissions.html
-		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:PutObject", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -1057,6 +1461,9 @@ func (api objectAPIHandlers) CompleteMultipartUploadHandler(w http.ResponseWrite
 		return
 	}
 
+	// Queue the new object for replication.
+	queueReplication(bucket, object, r)
+
 	// Get object location.
 	location := getLocation(r)
 	// Generate complete multipart response.
@@ -1087,7 +1494,7 @@ func (api objectAPIHandlers) DeleteObjectHandler(w http.ResponseWriter, r *http.
 		return
 	case authTypeAnonymous:
 		// http://docs.aws.amazon.com/AmazonS3/latest/dev/using-with-s3-actions.html
-		if s3Error := enforceBucketPolicy("s3:DeleteObject", bucket, r.URL); s3Error != ErrNone {
+		if s3Error := enforceBucketPolicy("s3:DeleteObject", bucket, r); s3Error != ErrNone {
 			writeErrorResponse(w, r, s3Error, r.URL.Path)
 			return
 		}
@@ -1097,9 +1504,25 @@ func (api objectAPIHandlers) DeleteObjectHandler(w http.ResponseWriter, r *http.
 			return
 		}
 	}
+	// Owner can delete objects under governance retention.
+	if isBypassGovernanceRequest(r) {
+		if err := api.removeGovernanceRetention(bucket, object); err != nil {
+			errorIf(err, "Unable to remove governance retention.")
+			writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+			return
+		}
+	}
 	/// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectDELETE.html
 	/// Ignore delete object errors, since we are suppposed to reply
-	/// only 204.
-	api.ObjectAPI.DeleteObject(bucket, object)
+	/// only 204. Objects protected by object lock are an exception.
+	if err := api.ObjectAPI.DeleteObject(bucket, object); err != nil {
+		if _, ok := err.(ObjectLocked); ok {
+			writeErrorResponse(w, r, ErrObjectLocked, r.URL.Path)
+			return
+		}
+	} else {
+		// Queue the delete for replication.
+		queueReplication(bucket, object, r)
+	}
 	writeSuccessNoContent(w)
 }
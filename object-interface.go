@@ -34,7 +34,9 @@ type ObjectLayer interface {
 	GetObject(bucket, object string, startOffset int64, length int64, writer io.Writer) (err error)
 	GetObjectInfo(bucket, object string) (objInfo ObjectInfo, err error)
 	PutObject(bucket, object string, size int64, data io.Reader, metadata map[string]string) (md5 string, err error)
+	CopyObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (md5 string, err error)
 	DeleteObject(bucket, object string) error
+	SetObjectLock(bucket, object string, lockMeta map[string]string) error
 
 	// Multipart operations.
 	ListMultipartUploads(bucket, prefix, keyMarker, uploadIDMarker, delimiter string, maxUploads int) (result ListMultipartsInfo, err error)
@@ -0,0 +1,86 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "strings"
+
+// listObjectsDelimited - lists objects at prefix grouping keys into
+// common prefixes by a delimiter other than '/', which tree walks do
+// not know about. Keys are listed recursively with listObjects and
+// each listing resumes after the last listed key, so that the tree
+// walk saved by the previous listing is reused, or after all the keys
+// of the last listed common prefix.
+func listObjectsDelimited(listObjects func(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error), bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+	// Returns the common prefix of an object, empty if the object is
+	// not grouped.
+	commonPrefix := func(object string) string {
+		if i := strings.Index(object[len(prefix):], delimiter); i >= 0 {
+			return object[:len(prefix)+i+len(delimiter)]
+		}
+		return ""
+	}
+	// With max keys of zero we have reached eof, return right here.
+	if maxKeys == 0 {
+		return ListObjectsInfo{}, nil
+	}
+
+	// Marker is a common prefix returned by a previous listing, the
+	// walk starts after all its keys, valid UTF-8 object names never
+	// contain the byte 0xff.
+	walkMarker := marker
+	if marker != "" && commonPrefix(marker) == marker {
+		walkMarker = marker + "\xff"
+	}
+
+	result := ListObjectsInfo{}
+	var lastPrefix string
+	var count int
+	for {
+		// One more key than needed tells if the result is truncated.
+		listInfo, err := listObjects(bucket, prefix, walkMarker, "", maxKeys-count+1)
+		if err != nil {
+			return ListObjectsInfo{}, err
+		}
+		for _, objInfo := range listInfo.Objects {
+			objPrefix := commonPrefix(objInfo.Name)
+			if objPrefix != "" && objPrefix == lastPrefix {
+				continue
+			}
+			if count == maxKeys {
+				result.IsTruncated = true
+				return result, nil
+			}
+			count++
+			result.NextMarker = objInfo.Name
+			if objPrefix != "" {
+				result.Prefixes = append(result.Prefixes, objPrefix)
+				result.NextMarker = objPrefix
+				lastPrefix = objPrefix
+				continue
+			}
+			result.Objects = append(result.Objects, objInfo)
+		}
+		if !listInfo.IsTruncated || len(listInfo.Objects) == 0 {
+			return result, nil
+		}
+		walkMarker = listInfo.Objects[len(listInfo.Objects)-1].Name
+		if lastPrefix != "" && commonPrefix(walkMarker) == lastPrefix {
+			// Skip the remaining keys of the common prefix.
+			walkMarker = lastPrefix + "\xff"
+		}
+	}
+}
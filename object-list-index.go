@@ -0,0 +1,1022 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Prefix of list indexes in minioMetaBucket, the index of a
+	// bucket is saved under list-index/<bucket>/.
+	listIndexMetaPrefix = "list-index"
+
+	// Sorted entries of an index, one JSON document per line after
+	// a header line with the sequence number of the snapshot.
+	listIndexSnapshotFile = "snapshot.json"
+
+	// Changes since the snapshot, one JSON record per line. Records
+	// start with a newline, so a record appended after an incomplete
+	// one starts on a new line.
+	listIndexJournalFile = "journal.json"
+
+	// Minimum number of journal records before the journal is
+	// compacted into a new snapshot.
+	listIndexCompactRecords = 10000
+
+	// Number of entries per in-memory block, blocks are split at
+	// twice this size.
+	listIndexBlockSize = 512
+
+	// Size of reads and writes of index files.
+	listIndexIOSize = 1 * 1024 * 1024
+
+	// Interval between attempts to load an index which failed to load.
+	listIndexRetryInterval = 1 * time.Minute
+)
+
+// Journal record operations.
+const (
+	// Object is about to be modified, its state is unknown until a
+	// put or delete record follows.
+	listIndexOpPending = "pending"
+	listIndexOpPut     = "put"
+	listIndexOpDelete  = "delete"
+)
+
+// errListIndexNotReady - index is being loaded or built.
+var errListIndexNotReady = errors.New("list index is not ready")
+
+// Global list of buckets with a list index, initialized from
+// environment variables.
+var globalListIndexBuckets []string
+
+// parseListIndexBuckets - parses comma separated bucket names.
+func parseListIndexBuckets(value string) (buckets []string, err error) {
+	for _, bucket := range strings.Split(value, ",") {
+		if bucket = strings.TrimSpace(bucket); bucket == "" {
+			continue
+		}
+		if !IsValidBucketName(bucket) {
+			return nil, fmt.Errorf("Invalid bucket name %s", bucket)
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
+
+// listIndexEntry - listed stat data of an object.
+type listIndexEntry struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	MD5Sum  string    `json:"md5Sum,omitempty"`
+}
+
+// listIndexHeader - first line of a snapshot.
+type listIndexHeader struct {
+	Seq uint64 `json:"seq"`
+}
+
+// listIndexRecord - journal record of a change to an object.
+type listIndexRecord struct {
+	Seq   uint64         `json:"seq"`
+	Op    string         `json:"op"`
+	Entry listIndexEntry `json:"entry"`
+}
+
+// listIndexBlocks - entries sorted by name in blocks, so inserts and
+// deletes only move the entries of a single block.
+type listIndexBlocks struct {
+	blocks [][]listIndexEntry
+	count  int
+}
+
+// search - returns the block and the position in the block of the
+// first entry not less than name.
+func (b *listIndexBlocks) search(name string) (int, int) {
+	i := sort.Search(len(b.blocks), func(i int) bool {
+		block := b.blocks[i]
+		return block[len(block)-1].Name >= name
+	})
+	if i == len(b.blocks) {
+		return i, 0
+	}
+	block := b.blocks[i]
+	return i, sort.Search(len(block), func(j int) bool {
+		return block[j].Name >= name
+	})
+}
+
+// put - inserts or replaces an entry.
+func (b *listIndexBlocks) put(entry listIndexEntry) {
+	i, j := b.search(entry.Name)
+	if i == len(b.blocks) {
+		if i == 0 {
+			b.blocks = append(b.blocks, []listIndexEntry{entry})
+			b.count++
+			return
+		}
+		// Largest name so far, append to the last block.
+		i--
+		j = len(b.blocks[i])
+	}
+	block := b.blocks[i]
+	if j < len(block) && block[j].Name == entry.Name {
+		block[j] = entry
+		return
+	}
+	block = append(block, listIndexEntry{})
+	copy(block[j+1:], block[j:])
+	block[j] = entry
+	b.count++
+	if len(block) <= 2*listIndexBlockSize {
+		b.blocks[i] = block
+		return
+	}
+	// Split the block in halves.
+	half := len(block) / 2
+	left := append([]listIndexEntry(nil), block[:half]...)
+	right := append([]listIndexEntry(nil), block[half:]...)
+	b.blocks = append(b.blocks, nil)
+	copy(b.blocks[i+2:], b.blocks[i+1:])
+	b.blocks[i] = left
+	b.blocks[i+1] = right
+}
+
+// delete - removes an entry if present.
+func (b *listIndexBlocks) delete(name string) {
+	i, j := b.search(name)
+	if i == len(b.blocks) || b.blocks[i][j].Name != name {
+		return
+	}
+	block := b.blocks[i]
+	copy(block[j:], block[j+1:])
+	block = block[:len(block)-1]
+	b.count--
+	if len(block) == 0 {
+		b.blocks = append(b.blocks[:i], b.blocks[i+1:]...)
+		return
+	}
+	b.blocks[i] = block
+}
+
+// walk - calls fn on entries in order starting at the first entry
+// not less than name, until fn returns false.
+func (b *listIndexBlocks) walk(name string, fn func(entry listIndexEntry) bool) {
+	i, j := b.search(name)
+	for ; i < len(b.blocks); i++ {
+		for _, entry := range b.blocks[i][j:] {
+			if !fn(entry) {
+				return
+			}
+		}
+		j = 0
+	}
+}
+
+// clone - returns a copy of all entries, which is not changed by
+// changes of the original entries.
+func (b *listIndexBlocks) clone() listIndexBlocks {
+	blocks := make([][]listIndexEntry, len(b.blocks))
+	for i, block := range b.blocks {
+		blocks[i] = append([]listIndexEntry(nil), block...)
+	}
+	return listIndexBlocks{blocks: blocks, count: b.count}
+}
+
+// apply - applies a put or delete record.
+func (b *listIndexBlocks) apply(record listIndexRecord) {
+	switch record.Op {
+	case listIndexOpPut:
+		b.put(record.Entry)
+	case listIndexOpDelete:
+		b.delete(record.Entry.Name)
+	}
+}
+
+// listIndex - list index of a bucket. An index is ready once loaded
+// or built, objects changed while it is not ready are collected in
+// dirty and looked up once it is.
+type listIndex struct {
+	mutex    sync.Mutex
+	bucket   string
+	gen      uint64 // Incremented every time the index is opened or dropped.
+	seq      uint64 // Sequence number of the last record.
+	records  int    // Journal records since the snapshot.
+	entries  listIndexBlocks
+	ready    bool
+	building bool
+	failed   time.Time // Time of the last failure to load.
+	dirty    map[string]struct{}
+
+	// Serializes changes of the index files, held without mutex while
+	// writing so that listings are not blocked by disk I/O. Should be
+	// locked before mutex.
+	journalMutex sync.Mutex
+	compacting   bool   // Snapshot is being written.
+	tail         []byte // Records appended while compacting.
+}
+
+// listObjects - lists objects at prefix after marker recursively,
+// signature matches ObjectLayer.ListObjects for listObjectsDelimited.
+func (idx *listIndex) listObjects(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if !idx.ready {
+		return ListObjectsInfo{}, errListIndexNotReady
+	}
+
+	start := marker
+	if prefix > start {
+		start = prefix
+	}
+	result := ListObjectsInfo{}
+	idx.entries.walk(start, func(entry listIndexEntry) bool {
+		if entry.Name == marker {
+			return true
+		}
+		if !strings.HasPrefix(entry.Name, prefix) {
+			return false
+		}
+		if len(result.Objects) == maxKeys {
+			result.IsTruncated = true
+			return false
+		}
+		result.Objects = append(result.Objects, ObjectInfo{
+			Bucket:  bucket,
+			Name:    entry.Name,
+			ModTime: entry.ModTime,
+			Size:    entry.Size,
+			MD5Sum:  entry.MD5Sum,
+		})
+		result.NextMarker = entry.Name
+		return true
+	})
+	return result, nil
+}
+
+// indexObjects - implements ObjectLayer by listing configured buckets
+// from a sorted index of object names instead of walking the tree of
+// another ObjectLayer. Indexes are saved in minioMetaBucket of all
+// disks and kept up to date by writes and deletes through the index.
+type indexObjects struct {
+	ObjectLayer
+	disks   []StorageAPI
+	indexes map[string]*listIndex
+}
+
+// newIndexObjects - initialize new index object layer in front of
+// objAPI, indexes are loaded or built in the background.
+func newIndexObjects(objAPI ObjectLayer, disks []StorageAPI, buckets []string) ObjectLayer {
+	o := indexObjects{
+		ObjectLayer: objAPI,
+		disks:       disks,
+		indexes:     make(map[string]*listIndex),
+	}
+	for _, bucket := range buckets {
+		idx := &listIndex{bucket: bucket}
+		o.indexes[bucket] = idx
+		idx.mutex.Lock()
+		o.open(idx)
+		idx.mutex.Unlock()
+	}
+	return o
+}
+
+// listIndexPath - returns the path of an index file in minioMetaBucket.
+func listIndexPath(bucket, file string) string {
+	return pathJoin(listIndexMetaPrefix, bucket, file)
+}
+
+// open - starts loading the index, or building it if it was never
+// saved. Must be called with the index locked.
+func (o indexObjects) open(idx *listIndex) {
+	idx.gen++
+	idx.ready = false
+	idx.building = true
+	idx.entries = listIndexBlocks{}
+	// Objects changed after a failed load are kept.
+	if idx.dirty == nil {
+		idx.dirty = make(map[string]struct{})
+	}
+	go o.load(idx)
+}
+
+// reload - opens an index which is neither ready nor being loaded,
+// a failed load is retried after listIndexRetryInterval. Must be
+// called with the index locked.
+func (o indexObjects) reload(idx *listIndex) {
+	if !idx.ready && !idx.building && time.Since(idx.failed) >= listIndexRetryInterval {
+		o.open(idx)
+	}
+}
+
+// load - loads the index from the disk with the latest changes,
+// objects with pending changes are looked up again. The index is
+// listed from the wrapped object layer until the next attempt if
+// loading fails.
+func (o indexObjects) load(idx *listIndex) {
+	entries, seq, records, err := o.readIndex(idx.bucket)
+	built := false
+	if err == errFileNotFound {
+		entries, err = o.scan(idx.bucket)
+		// Snapshot of a new index supersedes any stale journal.
+		seq, records, built = uint64(time.Now().UnixNano()), nil, true
+	}
+
+	// Records the failure, objects not looked up yet are kept dirty.
+	fail := func(err error, pending map[string]struct{}) {
+		errorIf(err, "Unable to load list index of %s.", idx.bucket)
+		idx.mutex.Lock()
+		defer idx.mutex.Unlock()
+		for object := range pending {
+			idx.dirty[object] = struct{}{}
+		}
+		idx.building = false
+		idx.failed = time.Now()
+	}
+
+	pending := make(map[string]struct{})
+	if err != nil {
+		fail(err, pending)
+		return
+	}
+	for _, record := range records {
+		if record.Op == listIndexOpPending {
+			pending[record.Entry.Name] = struct{}{}
+			continue
+		}
+		entries.apply(record)
+	}
+
+	// Objects changed while loading are looked up without holding the
+	// index locked, until no more objects are changed.
+	var resolved []listIndexRecord
+	for {
+		idx.journalMutex.Lock()
+		idx.mutex.Lock()
+		for object := range idx.dirty {
+			pending[object] = struct{}{}
+		}
+		idx.dirty = make(map[string]struct{})
+		if len(pending) == 0 {
+			break
+		}
+		idx.mutex.Unlock()
+		idx.journalMutex.Unlock()
+
+		for object := range pending {
+			record, sErr := o.stat(idx.bucket, object)
+			if sErr != nil {
+				for _, record = range resolved {
+					pending[record.Entry.Name] = struct{}{}
+				}
+				fail(sErr, pending)
+				return
+			}
+			entries.apply(record)
+			resolved = append(resolved, record)
+			delete(pending, object)
+		}
+	}
+
+	// Both locks are held, save the changes of looked up objects.
+	for i := range resolved {
+		seq++
+		resolved[i].Seq = seq
+	}
+	if err = o.appendJournal(idx, resolved...); err != nil {
+		idx.mutex.Unlock()
+		idx.journalMutex.Unlock()
+		for _, record := range resolved {
+			pending[record.Entry.Name] = struct{}{}
+		}
+		fail(err, pending)
+		return
+	}
+	idx.entries = entries
+	idx.seq = seq
+	idx.records = len(records) + len(resolved)
+	idx.ready = true
+	idx.building = false
+	idx.dirty = nil
+	idx.mutex.Unlock()
+	idx.journalMutex.Unlock()
+
+	// Save the snapshot of a new index.
+	if built {
+		o.compact(idx)
+	}
+}
+
+// readIndex - reads the snapshot and the journal records after it
+// from the disk with the latest records, returns errFileNotFound if
+// no disk has a snapshot.
+func (o indexObjects) readIndex(bucket string) (entries listIndexBlocks, lastSeq uint64, records []listIndexRecord, err error) {
+	var latest StorageAPI
+	var latestSeq uint64
+	for _, disk := range o.disks {
+		if disk == nil {
+			continue
+		}
+		header, hErr := readListIndexHeader(disk, bucket)
+		if hErr != nil {
+			if hErr != errFileNotFound {
+				errorIf(hErr, "Unable to read list index of %s.", bucket)
+			}
+			continue
+		}
+		diskRecords, jErr := readListIndexJournal(disk, bucket, header.Seq)
+		if jErr != nil {
+			errorIf(jErr, "Unable to read list index journal of %s.", bucket)
+			continue
+		}
+		lastSeq := header.Seq
+		if n := len(diskRecords); n > 0 {
+			lastSeq = diskRecords[n-1].Seq
+		}
+		if latest == nil || lastSeq > latestSeq {
+			latest, latestSeq, records = disk, lastSeq, diskRecords
+		}
+	}
+	if latest == nil {
+		return listIndexBlocks{}, 0, nil, errFileNotFound
+	}
+	if entries, err = readListIndexSnapshot(latest, bucket); err != nil {
+		return listIndexBlocks{}, 0, nil, err
+	}
+	return entries, latestSeq, records, nil
+}
+
+// scan - lists all objects of a bucket from the wrapped object layer,
+// a bucket which does not exist has an empty index.
+func (o indexObjects) scan(bucket string) (entries listIndexBlocks, err error) {
+	marker := ""
+	for {
+		result, lErr := o.ObjectLayer.ListObjects(bucket, "", marker, "", maxObjectList)
+		if lErr != nil {
+			if _, ok := lErr.(BucketNotFound); ok {
+				return listIndexBlocks{}, nil
+			}
+			return listIndexBlocks{}, lErr
+		}
+		for _, objInfo := range result.Objects {
+			entries.put(newListIndexEntry(objInfo))
+		}
+		if !result.IsTruncated || result.NextMarker == "" {
+			return entries, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+// newListIndexEntry - returns the listed stat data of an object.
+func newListIndexEntry(objInfo ObjectInfo) listIndexEntry {
+	return listIndexEntry{
+		Name:    objInfo.Name,
+		Size:    objInfo.Size,
+		ModTime: objInfo.ModTime,
+		MD5Sum:  objInfo.MD5Sum,
+	}
+}
+
+// stat - returns a put or delete record of the current state of an
+// object.
+func (o indexObjects) stat(bucket, object string) (listIndexRecord, error) {
+	objInfo, err := o.ObjectLayer.GetObjectInfo(bucket, object)
+	switch err.(type) {
+	case nil:
+		return listIndexRecord{Op: listIndexOpPut, Entry: newListIndexEntry(objInfo)}, nil
+	case ObjectNotFound, BucketNotFound:
+		return listIndexRecord{Op: listIndexOpDelete, Entry: listIndexEntry{Name: object}}, nil
+	}
+	return listIndexRecord{}, err
+}
+
+// begin - records an object is about to be modified. Changes of an
+// index which is not ready are looked up once it is.
+func (o indexObjects) begin(idx *listIndex, object string) {
+	idx.journalMutex.Lock()
+	defer idx.journalMutex.Unlock()
+
+	idx.mutex.Lock()
+	if !idx.ready {
+		o.reload(idx)
+		idx.dirty[object] = struct{}{}
+		idx.mutex.Unlock()
+		return
+	}
+	idx.seq++
+	record := listIndexRecord{Seq: idx.seq, Op: listIndexOpPending, Entry: listIndexEntry{Name: object}}
+	idx.mutex.Unlock()
+
+	err := o.appendJournal(idx, record)
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if err != nil {
+		errorIf(err, "Unable to update list index of %s.", idx.bucket)
+		o.open(idx)
+		idx.dirty[object] = struct{}{}
+		return
+	}
+	idx.records++
+}
+
+// end - records the state of an object after it was modified.
+func (o indexObjects) end(idx *listIndex, object string) {
+	idx.journalMutex.Lock()
+	defer idx.journalMutex.Unlock()
+
+	idx.mutex.Lock()
+	if !idx.ready {
+		o.reload(idx)
+		idx.dirty[object] = struct{}{}
+		idx.mutex.Unlock()
+		return
+	}
+	idx.mutex.Unlock()
+	o.commit(idx, object)
+}
+
+// commit - looks up an object, saves and applies its state. The index
+// is reopened on errors. Must be called with journalMutex of a ready
+// index locked, which keeps the records of an object in order.
+func (o indexObjects) commit(idx *listIndex, object string) {
+	record, err := o.stat(idx.bucket, object)
+	if err == nil {
+		idx.mutex.Lock()
+		idx.seq++
+		record.Seq = idx.seq
+		idx.mutex.Unlock()
+		err = o.appendJournal(idx, record)
+	}
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if err != nil {
+		errorIf(err, "Unable to update list index of %s.", idx.bucket)
+		o.open(idx)
+		idx.dirty[object] = struct{}{}
+		return
+	}
+	idx.records++
+	idx.entries.apply(record)
+
+	// Compact the journal once it is a large part of the index.
+	if idx.compacting || idx.records < listIndexCompactRecords || idx.records < idx.entries.count/2 {
+		return
+	}
+	go o.compact(idx)
+}
+
+// appendJournal - appends records to the journal on all disks,
+// succeeds if any disk succeeds. Index files of failed disks are
+// removed since their journal is missing a record. Must be called
+// with journalMutex locked.
+func (o indexObjects) appendJournal(idx *listIndex, records ...listIndexRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var buf []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, '\n')
+		buf = append(buf, line...)
+	}
+	err := errDiskNotFound
+	ok := false
+	for _, disk := range o.disks {
+		if disk == nil {
+			continue
+		}
+		if aErr := disk.AppendFile(minioMetaBucket, listIndexPath(idx.bucket, listIndexJournalFile), buf); aErr != nil {
+			err = aErr
+			deleteListIndex(disk, idx.bucket)
+			continue
+		}
+		ok = true
+	}
+	if !ok {
+		return err
+	}
+	// Records after the snapshot being written are kept in the
+	// journal which replaces the current one.
+	if idx.compacting {
+		idx.tail = append(idx.tail, buf...)
+	}
+	return nil
+}
+
+// compact - saves all entries as a new snapshot, which replaces the
+// journal. Changes are not blocked while the snapshot is written,
+// the index is reopened if it cannot be saved.
+func (o indexObjects) compact(idx *listIndex) {
+	idx.journalMutex.Lock()
+	idx.mutex.Lock()
+	if idx.compacting || !idx.ready {
+		idx.mutex.Unlock()
+		idx.journalMutex.Unlock()
+		return
+	}
+	idx.compacting = true
+	idx.tail = nil
+	gen, seq, records := idx.gen, idx.seq, idx.records
+	entries := idx.entries.clone()
+	idx.mutex.Unlock()
+	idx.journalMutex.Unlock()
+
+	tmpPath, disks, err := o.writeSnapshot(idx.bucket, entries, seq)
+
+	idx.journalMutex.Lock()
+	defer idx.journalMutex.Unlock()
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	idx.compacting = false
+	tail := idx.tail
+	idx.tail = nil
+	// Index was reopened or dropped while writing.
+	if idx.gen != gen {
+		for _, disk := range disks {
+			if disk != nil {
+				disk.DeleteFile(minioMetaBucket, tmpPath)
+			}
+		}
+		return
+	}
+	if err == nil {
+		err = o.renameSnapshot(idx.bucket, tmpPath, disks, tail)
+	}
+	if err != nil {
+		errorIf(err, "Unable to save list index of %s.", idx.bucket)
+		o.open(idx)
+		return
+	}
+	idx.records -= records
+}
+
+// writeSnapshot - writes entries as a new snapshot to a temporary file
+// of all disks, returns the disks which succeeded.
+func (o indexObjects) writeSnapshot(bucket string, entries listIndexBlocks, seq uint64) (string, []StorageAPI, error) {
+	tmpPath := pathJoin(tmpMetaPrefix, getUUID())
+	disks := make([]StorageAPI, len(o.disks))
+	copy(disks, o.disks)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	var err error
+	// Writes buffered lines to the temporary file of all disks.
+	flush := func() {
+		for i, disk := range disks {
+			if disk == nil {
+				continue
+			}
+			if aErr := disk.AppendFile(minioMetaBucket, tmpPath, buf.Bytes()); aErr != nil {
+				err = aErr
+				disks[i] = nil
+			}
+		}
+		buf.Reset()
+	}
+	if err = encoder.Encode(listIndexHeader{Seq: seq}); err != nil {
+		return "", nil, err
+	}
+	entries.walk("", func(entry listIndexEntry) bool {
+		if eErr := encoder.Encode(entry); eErr != nil {
+			err = eErr
+			return false
+		}
+		if buf.Len() >= listIndexIOSize {
+			flush()
+		}
+		return true
+	})
+	flush()
+	for _, disk := range disks {
+		if disk != nil {
+			return tmpPath, disks, nil
+		}
+	}
+	if err == nil {
+		err = errDiskNotFound
+	}
+	return "", nil, err
+}
+
+// renameSnapshot - replaces the snapshot on all disks by a snapshot
+// written by writeSnapshot, the journal is replaced by the records
+// appended since, succeeds if any disk succeeds. Must be called with
+// journalMutex locked.
+func (o indexObjects) renameSnapshot(bucket, tmpPath string, disks []StorageAPI, tail []byte) error {
+	err := errDiskNotFound
+	ok := false
+	for i, disk := range disks {
+		if disk == nil {
+			if o.disks[i] != nil {
+				deleteListIndex(o.disks[i], bucket)
+			}
+			continue
+		}
+		if rErr := disk.RenameFile(minioMetaBucket, tmpPath, minioMetaBucket, listIndexPath(bucket, listIndexSnapshotFile)); rErr != nil {
+			err = rErr
+			deleteListIndex(disk, bucket)
+			continue
+		}
+		// Records left behind on a crash are older than the snapshot.
+		if dErr := disk.DeleteFile(minioMetaBucket, listIndexPath(bucket, listIndexJournalFile)); dErr != nil && dErr != errFileNotFound {
+			err = dErr
+			deleteListIndex(disk, bucket)
+			continue
+		}
+		if len(tail) > 0 {
+			if aErr := disk.AppendFile(minioMetaBucket, listIndexPath(bucket, listIndexJournalFile), tail); aErr != nil {
+				err = aErr
+				deleteListIndex(disk, bucket)
+				continue
+			}
+		}
+		ok = true
+	}
+	if !ok {
+		return err
+	}
+	return nil
+}
+
+// deleteListIndex - removes the index files of a bucket from a disk.
+func deleteListIndex(disk StorageAPI, bucket string) {
+	for _, file := range []string{listIndexSnapshotFile, listIndexJournalFile} {
+		err := disk.DeleteFile(minioMetaBucket, listIndexPath(bucket, file))
+		if err != nil && err != errFileNotFound {
+			errorIf(err, "Unable to remove list index of %s.", bucket)
+		}
+	}
+}
+
+// storageReader - reads a file of a disk sequentially.
+type storageReader struct {
+	disk         StorageAPI
+	volume, path string
+	offset, size int64
+}
+
+// newStorageReader - returns a reader of a file, errFileNotFound if
+// it does not exist.
+func newStorageReader(disk StorageAPI, volume, path string) (*storageReader, error) {
+	fileInfo, err := disk.StatFile(volume, path)
+	if err != nil {
+		return nil, err
+	}
+	return &storageReader{disk: disk, volume: volume, path: path, size: fileInfo.Size}, nil
+}
+
+func (r *storageReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.size-r.offset {
+		p = p[:r.size-r.offset]
+	}
+	n, err := r.disk.ReadFile(r.volume, r.path, r.offset, p)
+	r.offset += n
+	return int(n), err
+}
+
+// newListIndexScanner - returns a line scanner of an index file.
+func newListIndexScanner(disk StorageAPI, bucket, file string) (*bufio.Scanner, error) {
+	reader, err := newStorageReader(disk, minioMetaBucket, listIndexPath(bucket, file))
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, listIndexIOSize), listIndexIOSize)
+	return scanner, nil
+}
+
+// readListIndexHeader - reads the header of a snapshot.
+func readListIndexHeader(disk StorageAPI, bucket string) (header listIndexHeader, err error) {
+	fileInfo, err := disk.StatFile(minioMetaBucket, listIndexPath(bucket, listIndexSnapshotFile))
+	if err != nil {
+		return listIndexHeader{}, err
+	}
+	// Header line is short, it always fits the first read.
+	buf := make([]byte, 4096)
+	if int64(len(buf)) > fileInfo.Size {
+		buf = buf[:fileInfo.Size]
+	}
+	n, err := disk.ReadFile(minioMetaBucket, listIndexPath(bucket, listIndexSnapshotFile), 0, buf)
+	if err != nil {
+		return listIndexHeader{}, err
+	}
+	line := buf[:n]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	err = json.Unmarshal(line, &header)
+	return header, err
+}
+
+// readListIndexSnapshot - reads all entries of a snapshot.
+func readListIndexSnapshot(disk StorageAPI, bucket string) (entries listIndexBlocks, err error) {
+	scanner, err := newListIndexScanner(disk, bucket, listIndexSnapshotFile)
+	if err != nil {
+		return listIndexBlocks{}, err
+	}
+	// Skip the header.
+	scanner.Scan()
+	for scanner.Scan() {
+		var entry listIndexEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return listIndexBlocks{}, err
+		}
+		entries.put(entry)
+	}
+	if err = scanner.Err(); err != nil {
+		return listIndexBlocks{}, err
+	}
+	return entries, nil
+}
+
+// readListIndexJournal - reads journal records after seq. Invalid
+// records are skipped, a crash may leave a record incomplete.
+func readListIndexJournal(disk StorageAPI, bucket string, seq uint64) (records []listIndexRecord, err error) {
+	scanner, err := newListIndexScanner(disk, bucket, listIndexJournalFile)
+	if err == errFileNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for scanner.Scan() {
+		var record listIndexRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			continue
+		}
+		if record.Seq > seq {
+			records = append(records, record)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// ListObjects - lists objects of indexed buckets from their index,
+// other buckets and indexes which are not ready yet are listed by the
+// wrapped object layer.
+func (o indexObjects) ListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
+	idx := o.indexes[bucket]
+	if idx == nil {
+		return o.ObjectLayer.ListObjects(bucket, prefix, marker, delimiter, maxKeys)
+	}
+	idx.mutex.Lock()
+	o.reload(idx)
+	ready := idx.ready
+	idx.mutex.Unlock()
+	if !ready {
+		return o.ObjectLayer.ListObjects(bucket, prefix, marker, delimiter, maxKeys)
+	}
+
+	// Verify if bucket exists.
+	if _, err := o.ObjectLayer.GetBucketInfo(bucket); err != nil {
+		return ListObjectsInfo{}, err
+	}
+	if !IsValidObjectPrefix(prefix) {
+		return ListObjectsInfo{}, ObjectNameInvalid{Bucket: bucket, Object: prefix}
+	}
+	// Verify if marker has prefix.
+	if marker != "" && !strings.HasPrefix(marker, prefix) {
+		return ListObjectsInfo{}, InvalidMarkerPrefixCombination{
+			Marker: marker,
+			Prefix: prefix,
+		}
+	}
+	// With max keys of zero we have reached eof, return right here.
+	if maxKeys == 0 {
+		return ListObjectsInfo{}, nil
+	}
+	// For delimiter and prefix as '/' we do not list anything at all.
+	if delimiter == slashSeparator && prefix == slashSeparator {
+		return ListObjectsInfo{}, nil
+	}
+	// Over flowing count - reset to maxObjectList.
+	if maxKeys < 0 || maxKeys > maxObjectList {
+		maxKeys = maxObjectList
+	}
+
+	var result ListObjectsInfo
+	var err error
+	if delimiter == "" {
+		result, err = idx.listObjects(bucket, prefix, marker, delimiter, maxKeys)
+	} else {
+		result, err = listObjectsDelimited(idx.listObjects, bucket, prefix, marker, delimiter, maxKeys)
+	}
+	if err == errListIndexNotReady {
+		return o.ObjectLayer.ListObjects(bucket, prefix, marker, delimiter, maxKeys)
+	}
+	return result, err
+}
+
+// DeleteBucket - deletes a bucket and drops its index, a bucket
+// created again with the same name starts with an empty index.
+func (o indexObjects) DeleteBucket(bucket string) error {
+	idx := o.indexes[bucket]
+	if idx == nil {
+		return o.ObjectLayer.DeleteBucket(bucket)
+	}
+	idx.journalMutex.Lock()
+	defer idx.journalMutex.Unlock()
+	if err := o.ObjectLayer.DeleteBucket(bucket); err != nil {
+		return err
+	}
+	for _, disk := range o.disks {
+		if disk != nil {
+			deleteListIndex(disk, bucket)
+		}
+	}
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	// Discards a snapshot being written.
+	idx.gen++
+	if idx.ready {
+		idx.entries = listIndexBlocks{}
+		idx.records = 0
+	}
+	return nil
+}
+
+// PutObject - creates an object and updates the index.
+func (o indexObjects) PutObject(bucket, object string, size int64, data io.Reader, metadata map[string]string) (string, error) {
+	if idx := o.index(bucket, object); idx != nil {
+		o.begin(idx, object)
+		defer o.end(idx, object)
+	}
+	return o.ObjectLayer.PutObject(bucket, object, size, data, metadata)
+}
+
+// CopyObject - copies an object and updates the index of the
+// destination.
+func (o indexObjects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (string, error) {
+	if idx := o.index(dstBucket, dstObject); idx != nil {
+		o.begin(idx, dstObject)
+		defer o.end(idx, dstObject)
+	}
+	return o.ObjectLayer.CopyObject(srcBucket, srcObject, dstBucket, dstObject, metadata)
+}
+
+// DeleteObject - deletes an object and updates the index.
+func (o indexObjects) DeleteObject(bucket, object string) error {
+	if idx := o.index(bucket, object); idx != nil {
+		o.begin(idx, object)
+		defer o.end(idx, object)
+	}
+	return o.ObjectLayer.DeleteObject(bucket, object)
+}
+
+// CompleteMultipartUpload - completes a multipart upload and updates
+// the index.
+func (o indexObjects) CompleteMultipartUpload(bucket, object, uploadID string, uploadedParts []completePart) (string, error) {
+	if idx := o.index(bucket, object); idx != nil {
+		o.begin(idx, object)
+		defer o.end(idx, object)
+	}
+	return o.ObjectLayer.CompleteMultipartUpload(bucket, object, uploadID, uploadedParts)
+}
+
+// index - returns the index of a bucket, nil if the bucket is not
+// indexed or the object name is invalid.
+func (o indexObjects) index(bucket, object string) *listIndex {
+	if !IsValidObjectName(object) {
+		return nil
+	}
+	return o.indexes[bucket]
+}
@@ -0,0 +1,289 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Tests validate entries stay sorted across block splits and deletes.
+func TestListIndexBlocks(t *testing.T) {
+	var entries listIndexBlocks
+	names := rand.Perm(5 * listIndexBlockSize)
+	for _, i := range names {
+		entries.put(listIndexEntry{Name: fmt.Sprintf("object-%05d", i)})
+	}
+	// Replacing an entry does not add one.
+	entries.put(listIndexEntry{Name: "object-00000", Size: 1})
+	for _, i := range names[:listIndexBlockSize] {
+		entries.delete(fmt.Sprintf("object-%05d", i))
+	}
+	entries.delete("missing")
+
+	if entries.count != 4*listIndexBlockSize {
+		t.Errorf("Expected %d entries, but found %d", 4*listIndexBlockSize, entries.count)
+	}
+	count := 0
+	last := ""
+	entries.walk("", func(entry listIndexEntry) bool {
+		if entry.Name <= last {
+			t.Fatalf("Expected %s to sort after %s", entry.Name, last)
+		}
+		last = entry.Name
+		count++
+		return true
+	})
+	if count != entries.count {
+		t.Errorf("Expected to walk %d entries, but walked %d", entries.count, count)
+	}
+	for _, block := range entries.blocks {
+		if len(block) == 0 || len(block) > 2*listIndexBlockSize {
+			t.Errorf("Expected block size between 1 and %d, but found %d", 2*listIndexBlockSize, len(block))
+		}
+	}
+}
+
+// waitListIndex - waits for the index of a bucket to be ready.
+func waitListIndex(objAPI ObjectLayer, bucket string) *listIndex {
+	idx := objAPI.(indexObjects).indexes[bucket]
+	for i := 0; i < 500; i++ {
+		idx.mutex.Lock()
+		ready := idx.ready
+		idx.mutex.Unlock()
+		if ready {
+			return idx
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+// Wrapper for calling ListIndex tests for both XL multiple disks and single node setup.
+func TestListIndex(t *testing.T) {
+	ExecObjectLayerTest(t, testListIndex)
+}
+
+// Tests validate listing from an index which is built, updated,
+// reloaded and recovered after a crash.
+func testListIndex(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := getRandomBucketName()
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	putObject := func(objAPI ObjectLayer, object string) {
+		if _, err := objAPI.PutObject(bucket, object, 5, bytes.NewReader([]byte("hello")), nil); err != nil {
+			t.Fatalf("%s: <ERROR> %s", instanceType, err)
+		}
+	}
+	listNames := func(objAPI ObjectLayer, delimiter string, maxKeys int) (names []string) {
+		result, err := objAPI.ListObjects(bucket, "", "", delimiter, maxKeys)
+		if err != nil {
+			t.Fatalf("%s: <ERROR> %s", instanceType, err)
+		}
+		names = append(names, result.Prefixes...)
+		for _, objInfo := range result.Objects {
+			names = append(names, objInfo.Name)
+		}
+		return names
+	}
+
+	// Objects created before the index are found by the build.
+	for _, object := range []string{"a/1", "a/2", "b", "c#d"} {
+		putObject(obj, object)
+	}
+	disks := getObjectLayerDisks(obj)
+	index := newIndexObjects(obj, disks, []string{bucket})
+	idx := waitListIndex(index, bucket)
+	if idx == nil {
+		t.Fatalf("%s: Expected list index to be ready", instanceType)
+	}
+
+	putObject(index, "d")
+	if err := index.DeleteObject(bucket, "b"); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+
+	testCases := []struct {
+		delimiter string
+		maxKeys   int
+		names     []string
+	}{
+		// Test case - 1.
+		// Recursive listing.
+		{"", 10, []string{"a/1", "a/2", "c#d", "d"}},
+		// Test case - 2.
+		// Listing grouped by '/'.
+		{"/", 10, []string{"a/", "c#d", "d"}},
+		// Test case - 3.
+		// Listing grouped by another delimiter.
+		{"#", 10, []string{"c#", "a/1", "a/2", "d"}},
+		// Test case - 4.
+		// Truncated listing.
+		{"", 2, []string{"a/1", "a/2"}},
+	}
+	for i, testCase := range testCases {
+		if names := listNames(index, testCase.delimiter, testCase.maxKeys); !reflect.DeepEqual(names, testCase.names) {
+			t.Errorf("%s: Test %d: Expected %v, but found %v", instanceType, i+1, testCase.names, names)
+		}
+	}
+	result, err := index.ListObjects(bucket, "a/", "a/1", "", 10)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if len(result.Objects) != 1 || result.Objects[0].Name != "a/2" || result.Objects[0].Size != 5 {
+		t.Errorf("%s: Expected a/2 after marker a/1, but found %v", instanceType, result.Objects)
+	}
+	if _, err = index.ListObjects("missing-bucket", "", "", "", 10); err == nil {
+		t.Errorf("%s: Expected listing of a missing bucket to fail", instanceType)
+	}
+
+	// Index is loaded from disk by a new server.
+	expected := []string{"a/1", "a/2", "c#d", "d"}
+	index = newIndexObjects(obj, disks, []string{bucket})
+	if idx = waitListIndex(index, bucket); idx == nil {
+		t.Fatalf("%s: Expected list index to be ready", instanceType)
+	}
+	if names := listNames(index, "", 10); !reflect.DeepEqual(names, expected) {
+		t.Errorf("%s: Expected %v after reload, but found %v", instanceType, expected, names)
+	}
+
+	// Crash after the pending record, with an incomplete record
+	// written after it.
+	putObject(obj, "e")
+	putObject(obj, "f")
+	journal := listIndexPath(bucket, listIndexJournalFile)
+	if err = disks[0].AppendFile(minioMetaBucket, journal, []byte(fmt.Sprintf("\n{\"seq\":%d,\"op\":\"pending\",\"entry\":{\"name\":\"e\"}}", idx.seq+1))); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if err = disks[0].AppendFile(minioMetaBucket, journal, []byte("\n{\"seq\":")); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if err = disks[0].AppendFile(minioMetaBucket, journal, []byte(fmt.Sprintf("\n{\"seq\":%d,\"op\":\"pending\",\"entry\":{\"name\":\"f\"}}", idx.seq+2))); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	index = newIndexObjects(obj, disks, []string{bucket})
+	if idx = waitListIndex(index, bucket); idx == nil {
+		t.Fatalf("%s: Expected list index to be ready", instanceType)
+	}
+	expected = append(expected, "e", "f")
+	if names := listNames(index, "", 10); !reflect.DeepEqual(names, expected) {
+		t.Errorf("%s: Expected %v after recovery, but found %v", instanceType, expected, names)
+	}
+
+	// Index is rebuilt if its files are lost.
+	for _, disk := range disks {
+		deleteListIndex(disk, bucket)
+	}
+	index = newIndexObjects(obj, disks, []string{bucket})
+	if idx = waitListIndex(index, bucket); idx == nil {
+		t.Fatalf("%s: Expected list index to be ready", instanceType)
+	}
+	if names := listNames(index, "", 10); !reflect.DeepEqual(names, expected) {
+		t.Errorf("%s: Expected %v after rebuild, but found %v", instanceType, expected, names)
+	}
+
+	// Journal is compacted into a new snapshot.
+	putObject(index, "g")
+	index.(indexObjects).compact(idx)
+	expected = append(expected, "g")
+	for _, disk := range disks {
+		header, hErr := readListIndexHeader(disk, bucket)
+		if hErr != nil {
+			t.Fatalf("%s: <ERROR> %s", instanceType, hErr)
+		}
+		if header.Seq != idx.seq {
+			t.Errorf("%s: Expected snapshot sequence %d, but found %d", instanceType, idx.seq, header.Seq)
+		}
+		if _, sErr := disk.StatFile(minioMetaBucket, journal); sErr != errFileNotFound {
+			t.Errorf("%s: Expected journal to be removed, but found %v", instanceType, sErr)
+		}
+	}
+	index = newIndexObjects(obj, disks, []string{bucket})
+	if idx = waitListIndex(index, bucket); idx == nil {
+		t.Fatalf("%s: Expected list index to be ready", instanceType)
+	}
+	if names := listNames(index, "", 10); !reflect.DeepEqual(names, expected) {
+		t.Errorf("%s: Expected %v after compaction, but found %v", instanceType, expected, names)
+	}
+
+	// Index which fails to load is listed from the backend, objects
+	// changed meanwhile are looked up once it is loaded again.
+	for _, disk := range disks {
+		if err = disk.AppendFile(minioMetaBucket, listIndexPath(bucket, listIndexSnapshotFile), []byte("{\"name\":")); err != nil {
+			t.Fatalf("%s: <ERROR> %s", instanceType, err)
+		}
+	}
+	index = newIndexObjects(obj, disks, []string{bucket})
+	idx = index.(indexObjects).indexes[bucket]
+	for i := 0; i < 500; i++ {
+		idx.mutex.Lock()
+		building := idx.building
+		idx.mutex.Unlock()
+		if !building {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	putObject(index, "h")
+	expected = append(expected, "h")
+	if names := listNames(index, "", 10); !reflect.DeepEqual(names, expected) {
+		t.Errorf("%s: Expected %v from the backend, but found %v", instanceType, expected, names)
+	}
+	idx.mutex.Lock()
+	if idx.ready || idx.building || idx.failed.IsZero() {
+		t.Errorf("%s: Expected failed load to be retried later", instanceType)
+	}
+	// Retry is due with the index files removed.
+	idx.failed = time.Time{}
+	idx.mutex.Unlock()
+	for _, disk := range disks {
+		deleteListIndex(disk, bucket)
+	}
+	listNames(index, "", 10)
+	if idx = waitListIndex(index, bucket); idx == nil {
+		t.Fatalf("%s: Expected list index to be ready", instanceType)
+	}
+	if names := listNames(index, "", 10); !reflect.DeepEqual(names, expected) {
+		t.Errorf("%s: Expected %v after retry, but found %v", instanceType, expected, names)
+	}
+
+	// Deleting the bucket drops its index.
+	for _, object := range expected {
+		if err = index.DeleteObject(bucket, object); err != nil {
+			t.Fatalf("%s: <ERROR> %s", instanceType, err)
+		}
+	}
+	if err = index.DeleteBucket(bucket); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	for _, disk := range disks {
+		if _, sErr := disk.StatFile(minioMetaBucket, journal); sErr != errFileNotFound {
+			t.Errorf("%s: Expected index to be removed, but found %v", instanceType, sErr)
+		}
+	}
+	if err = index.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if names := listNames(index, "", 10); len(names) != 0 {
+		t.Errorf("%s: Expected empty index of a new bucket, but found %v", instanceType, names)
+	}
+}
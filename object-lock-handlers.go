@@ -0,0 +1,373 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	mux "github.com/gorilla/mux"
+)
+
+// PutBucketObjectLockConfigHandler - PUT Bucket object lock configuration.
+// ----------
+// Places an object lock configuration on a bucket, the configuration
+// is applied as default retention to every new object in the bucket.
+// Object lock can only be configured on buckets which were created
+// with object lock enabled.
+func (api objectAPIHandlers) PutBucketObjectLockConfigHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// Verify if bucket exists.
+	if _, err := api.ObjectAPI.GetBucketInfo(bucket); err != nil {
+		errorIf(err, "Unable to fetch bucket info.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+
+	// Object lock can only be configured on buckets created with object lock.
+	if _, err := readBucketObjectLockConfig(bucket); err != nil {
+		if _, ok := err.(BucketObjectLockConfigNotFound); ok {
+			writeErrorResponse(w, r, ErrInvalidBucketState, r.URL.Path)
+			return
+		}
+		errorIf(err, "Unable to read object lock configuration.")
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+
+	// Read object lock configuration up to maxObjectLockConfigSize.
+	configBytes, err := ioutil.ReadAll(io.LimitReader(r.Body, maxObjectLockConfigSize))
+	if err != nil {
+		errorIf(err, "Unable to read object lock configuration.")
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+
+	config, err := parseObjectLockConfig(configBytes)
+	if err != nil {
+		errorIf(err, "Unable to parse object lock configuration.")
+		writeErrorResponse(w, r, ErrMalformedXML, r.URL.Path)
+		return
+	}
+
+	// Save object lock configuration.
+	if err = writeBucketObjectLockConfig(bucket, config); err != nil {
+		errorIf(err, "Unable to write object lock configuration.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+	writeSuccessResponse(w, nil)
+}
+
+// GetBucketObjectLockConfigHandler - GET Bucket object lock configuration.
+// ----------
+// Returns the object lock configuration of a bucket.
+func (api objectAPIHandlers) GetBucketObjectLockConfigHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// Verify if bucket exists.
+	if _, err := api.ObjectAPI.GetBucketInfo(bucket); err != nil {
+		errorIf(err, "Unable to fetch bucket info.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+
+	config, err := readBucketObjectLockConfig(bucket)
+	if err != nil {
+		errorIf(err, "Unable to read object lock configuration.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+
+	encodedSuccessResponse := encodeResponse(config)
+	// Write headers.
+	setCommonHeaders(w)
+	// Write success response.
+	writeSuccessResponse(w, encodedSuccessResponse)
+}
+
+// checkBucketObjectLock - verifies the bucket exists and was created
+// with object lock enabled.
+func (api objectAPIHandlers) checkBucketObjectLock(bucket string) APIErrorCode {
+	if _, err := api.ObjectAPI.GetBucketInfo(bucket); err != nil {
+		errorIf(err, "Unable to fetch bucket info.")
+		return toAPIErrorCode(err)
+	}
+	if _, err := readBucketObjectLockConfig(bucket); err != nil {
+		if _, ok := err.(BucketObjectLockConfigNotFound); !ok {
+			errorIf(err, "Unable to read object lock configuration.")
+		}
+		return toAPIErrorCode(err)
+	}
+	return ErrNone
+}
+
+// PutObjectLegalHoldHandler - PUT Object legal hold.
+// ----------
+// Places or removes a legal hold on an object, objects under legal
+// hold cannot be overwritten or deleted until the hold is removed.
+func (api objectAPIHandlers) PutObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	if s3Error := api.checkBucketObjectLock(bucket); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
+	legalHold := objectLegalHold{}
+	if err := xml.NewDecoder(io.LimitReader(r.Body, maxObjectLockConfigSize)).Decode(&legalHold); err != nil {
+		errorIf(err, "Unable to parse legal hold.")
+		writeErrorResponse(w, r, ErrMalformedXML, r.URL.Path)
+		return
+	}
+	if legalHold.Status != legalHoldOn && legalHold.Status != legalHoldOff {
+		writeErrorResponse(w, r, ErrInvalidLegalHoldStatus, r.URL.Path)
+		return
+	}
+
+	lockMeta := map[string]string{objectLockLegalHoldKey: legalHold.Status}
+	if err := api.ObjectAPI.SetObjectLock(bucket, object, lockMeta); err != nil {
+		errorIf(err, "Unable to set legal hold.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+	writeSuccessResponse(w, nil)
+}
+
+// GetObjectLegalHoldHandler - GET Object legal hold.
+// ----------
+// Returns the legal hold status of an object.
+func (api objectAPIHandlers) GetObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	if s3Error := api.checkBucketObjectLock(bucket); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
+	objInfo, err := api.ObjectAPI.GetObjectInfo(bucket, object)
+	if err != nil {
+		errorIf(err, "Unable to fetch object info.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+	legalHold := objectLegalHold{Status: objInfo.UserDefined[objectLockLegalHoldKey]}
+	if legalHold.Status == "" {
+		legalHold.Status = legalHoldOff
+	}
+
+	encodedSuccessResponse := encodeResponse(legalHold)
+	// Write headers.
+	setCommonHeaders(w)
+	// Write success response.
+	writeSuccessResponse(w, encodedSuccessResponse)
+}
+
+// PutObjectRetentionHandler - PUT Object retention.
+// ----------
+// Sets the retention of an object. Retention of an object can be set
+// once its previous retention expired, unexpired retention can only
+// be extended unless the owner bypasses governance retention.
+func (api objectAPIHandlers) PutObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	if s3Error := api.checkBucketObjectLock(bucket); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
+	retention := objectRetention{}
+	if err := xml.NewDecoder(io.LimitReader(r.Body, maxObjectLockConfigSize)).Decode(&retention); err != nil {
+		errorIf(err, "Unable to parse retention.")
+		writeErrorResponse(w, r, ErrMalformedXML, r.URL.Path)
+		return
+	}
+	if !isValidObjectLockMode(retention.Mode) {
+		writeErrorResponse(w, r, ErrInvalidObjectLockMode, r.URL.Path)
+		return
+	}
+	retainUntilDate, err := time.Parse(time.RFC3339, retention.RetainUntilDate)
+	if err != nil || !retainUntilDate.After(time.Now().UTC()) {
+		writeErrorResponse(w, r, ErrInvalidRetainUntilDate, r.URL.Path)
+		return
+	}
+
+	lockMeta := map[string]string{
+		objectLockModeKey:        retention.Mode,
+		objectLockRetainUntilKey: retainUntilDate.UTC().Format(time.RFC3339),
+	}
+	if isBypassGovernanceRequest(r) {
+		lockMeta[objectLockBypassGovernanceKey] = "true"
+	}
+	if err = api.ObjectAPI.SetObjectLock(bucket, object, lockMeta); err != nil {
+		errorIf(err, "Unable to set retention.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+	writeSuccessResponse(w, nil)
+}
+
+// GetObjectRetentionHandler - GET Object retention.
+// ----------
+// Returns the retention of an object.
+func (api objectAPIHandlers) GetObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	if s3Error := api.checkBucketObjectLock(bucket); s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
+	objInfo, err := api.ObjectAPI.GetObjectInfo(bucket, object)
+	if err != nil {
+		errorIf(err, "Unable to fetch object info.")
+		writeErrorResponse(w, r, toAPIErrorCode(err), r.URL.Path)
+		return
+	}
+	retention := objectRetention{
+		Mode:            objInfo.UserDefined[objectLockModeKey],
+		RetainUntilDate: objInfo.UserDefined[objectLockRetainUntilKey],
+	}
+	if retention.Mode == "" || retention.RetainUntilDate == "" {
+		writeErrorResponse(w, r, ErrNoSuchObjectLockConfiguration, r.URL.Path)
+		return
+	}
+
+	encodedSuccessResponse := encodeResponse(retention)
+	// Write headers.
+	setCommonHeaders(w)
+	// Write success response.
+	writeSuccessResponse(w, encodedSuccessResponse)
+}
+
+// isBypassGovernanceRequest - returns true if the request asks to bypass
+// governance retention, only signed requests of the owner can bypass it.
+// Called once the request is authenticated.
+func isBypassGovernanceRequest(r *http.Request) bool {
+	switch getRequestAuthType(r) {
+	case authTypePresigned, authTypeSigned:
+		return strings.EqualFold(r.Header.Get(objectLockBypassGovernanceHeader), "true")
+	}
+	return false
+}
+
+// removeGovernanceRetention - removes the governance retention of an
+// object which the owner deletes bypassing governance retention. Objects
+// under compliance retention or legal hold are left as is and fail to
+// be deleted.
+func (api objectAPIHandlers) removeGovernanceRetention(bucket, object string) error {
+	objInfo, err := api.ObjectAPI.GetObjectInfo(bucket, object)
+	if err != nil {
+		// Missing objects are reported by the delete.
+		return nil
+	}
+	if objInfo.UserDefined[objectLockModeKey] != objectLockGovernance || objInfo.UserDefined[objectLockLegalHoldKey] == legalHoldOn {
+		return nil
+	}
+	return api.ObjectAPI.SetObjectLock(bucket, object, map[string]string{
+		objectLockModeKey:             "",
+		objectLockRetainUntilKey:      "",
+		objectLockBypassGovernanceKey: "true",
+	})
+}
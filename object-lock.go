@@ -0,0 +1,372 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// Object lock metadata keys, saved along with the object metadata.
+	objectLockModeKey        = "x-amz-object-lock-mode"
+	objectLockRetainUntilKey = "x-amz-object-lock-retain-until-date"
+	objectLockLegalHoldKey   = "x-amz-object-lock-legal-hold"
+
+	// Header which enables object lock while creating a bucket.
+	bucketObjectLockEnabledHeader = "X-Amz-Bucket-Object-Lock-Enabled"
+
+	// Header with which the owner bypasses governance retention.
+	objectLockBypassGovernanceHeader = "X-Amz-Bypass-Governance-Retention"
+
+	// Internal key of object lock metadata updates which allows
+	// governance retention to be shortened or removed, never saved.
+	objectLockBypassGovernanceKey = "x-minio-internal-bypass-governance-retention"
+
+	// Object lock configuration file name, saved in bucket config path.
+	bucketObjectLockConfigFile = "object-lock.xml"
+
+	// Maximum size of an object lock configuration document.
+	maxObjectLockConfigSize = 20 * 1024
+)
+
+// Supported object lock retention modes.
+const (
+	objectLockGovernance = "GOVERNANCE"
+	objectLockCompliance = "COMPLIANCE"
+)
+
+// Supported legal hold status.
+const (
+	legalHoldOn  = "ON"
+	legalHoldOff = "OFF"
+)
+
+// objectLockEnabled - value of ObjectLockEnabled for a bucket with object lock.
+const objectLockEnabled = "Enabled"
+
+// objectLockRetention - default retention applied to new objects.
+type objectLockRetention struct {
+	Mode  string `xml:"Mode"`
+	Days  int    `xml:"Days,omitempty"`
+	Years int    `xml:"Years,omitempty"`
+}
+
+// objectLockRule - object lock rule.
+type objectLockRule struct {
+	DefaultRetention objectLockRetention `xml:"DefaultRetention"`
+}
+
+// objectLegalHold - legal hold of an object.
+type objectLegalHold struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LegalHold" json:"-"`
+	Status  string   `xml:"Status"`
+}
+
+// objectRetention - retention of an object.
+type objectRetention struct {
+	XMLName         xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ Retention" json:"-"`
+	Mode            string   `xml:"Mode"`
+	RetainUntilDate string   `xml:"RetainUntilDate"`
+}
+
+// objectLockConfiguration - bucket object lock configuration.
+type objectLockConfiguration struct {
+	XMLName           xml.Name        `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ObjectLockConfiguration" json:"-"`
+	ObjectLockEnabled string          `xml:"ObjectLockEnabled"`
+	Rule              *objectLockRule `xml:"Rule,omitempty"`
+}
+
+// Validates object lock configuration.
+func (config objectLockConfiguration) Validate() error {
+	if config.ObjectLockEnabled != objectLockEnabled {
+		return errors.New("ObjectLockEnabled must be set to 'Enabled'")
+	}
+	if config.Rule == nil {
+		return nil
+	}
+	retention := config.Rule.DefaultRetention
+	if !isValidObjectLockMode(retention.Mode) {
+		return errors.New("Unknown object lock mode '" + retention.Mode + "'")
+	}
+	if retention.Days < 0 || retention.Years < 0 {
+		return errors.New("Default retention period must be a positive integer value")
+	}
+	// Exactly one of Days or Years needs to be specified.
+	if (retention.Days == 0) == (retention.Years == 0) {
+		return errors.New("Default retention requires either Days or Years")
+	}
+	return nil
+}
+
+// retainUntil - returns the retain until date of default retention
+// for an object created at input time.
+func (config objectLockConfiguration) retainUntil(now time.Time) (time.Time, bool) {
+	if config.Rule == nil {
+		return time.Time{}, false
+	}
+	retention := config.Rule.DefaultRetention
+	return now.AddDate(retention.Years, 0, retention.Days), true
+}
+
+// parseObjectLockConfig - parses and validates object lock configuration.
+func parseObjectLockConfig(configBytes []byte) (config objectLockConfiguration, err error) {
+	if err = xml.Unmarshal(configBytes, &config); err != nil {
+		return objectLockConfiguration{}, err
+	}
+	if err = config.Validate(); err != nil {
+		return objectLockConfiguration{}, err
+	}
+	return config, nil
+}
+
+// isValidObjectLockMode - verifies if the retention mode is supported.
+func isValidObjectLockMode(mode string) bool {
+	return mode == objectLockGovernance || mode == objectLockCompliance
+}
+
+// isObjectLocked - returns true if the object metadata indicates the
+// object is under legal hold or under an unexpired retention period.
+func isObjectLocked(metadata map[string]string) bool {
+	if metadata[objectLockLegalHoldKey] == legalHoldOn {
+		return true
+	}
+	retainUntil, ok := metadata[objectLockRetainUntilKey]
+	if !ok || retainUntil == "" {
+		return false
+	}
+	retainUntilDate, err := time.Parse(time.RFC3339, retainUntil)
+	if err != nil {
+		// Treat malformed retention as locked, never lose protected data.
+		return true
+	}
+	return time.Now().UTC().Before(retainUntilDate)
+}
+
+// updateObjectLockMetadata - returns the object metadata with its object
+// lock metadata replaced by lockMeta, keys with empty values are removed.
+// Legal hold can always be changed, unexpired retention can only be
+// extended and its mode kept or raised to compliance, false is returned
+// otherwise. Governance retention can be changed freely when lockMeta
+// bypasses it.
+func updateObjectLockMetadata(metadata, lockMeta map[string]string) (map[string]string, bool) {
+	newMeta := make(map[string]string, len(metadata)+len(lockMeta))
+	for key, value := range metadata {
+		newMeta[key] = value
+	}
+	bypassGovernance := false
+	for key, value := range lockMeta {
+		switch {
+		case key == objectLockBypassGovernanceKey:
+			bypassGovernance = value == "true"
+		case value == "":
+			delete(newMeta, key)
+		default:
+			newMeta[key] = value
+		}
+	}
+	retainUntil, ok := lockMeta[objectLockRetainUntilKey]
+	if !ok {
+		return newMeta, true
+	}
+	currentRetainUntil := metadata[objectLockRetainUntilKey]
+	if currentRetainUntil == "" {
+		return newMeta, true
+	}
+	currentRetainUntilDate, err := time.Parse(time.RFC3339, currentRetainUntil)
+	if err != nil {
+		// Treat malformed retention as locked, never lose protected data.
+		return nil, false
+	}
+	if !time.Now().UTC().Before(currentRetainUntilDate) {
+		// Retention expired, a new retention can be set.
+		return newMeta, true
+	}
+	currentMode := metadata[objectLockModeKey]
+	if currentMode == objectLockGovernance && bypassGovernance {
+		return newMeta, true
+	}
+	retainUntilDate, err := time.Parse(time.RFC3339, retainUntil)
+	if err != nil || retainUntilDate.Before(currentRetainUntilDate) {
+		return nil, false
+	}
+	mode := lockMeta[objectLockModeKey]
+	return newMeta, mode == currentMode || mode == objectLockCompliance
+}
+
+// getObjectLockMetadata - returns only the object lock related metadata.
+func getObjectLockMetadata(metadata map[string]string) map[string]string {
+	lockMeta := make(map[string]string)
+	for _, key := range []string{objectLockModeKey, objectLockRetainUntilKey, objectLockLegalHoldKey} {
+		if value, ok := metadata[key]; ok {
+			lockMeta[key] = value
+		}
+	}
+	return lockMeta
+}
+
+// parseObjectLockHeaders - validates object lock headers of an incoming
+// request and returns the object lock metadata to be saved along with
+// the object. Default retention of the bucket is applied by the object
+// layer if the request doesn't specify its own retention.
+func parseObjectLockHeaders(bucket string, header http.Header) (map[string]string, APIErrorCode) {
+	mode := header.Get(objectLockModeKey)
+	retainUntil := header.Get(objectLockRetainUntilKey)
+	legalHold := header.Get(objectLockLegalHoldKey)
+
+	if _, err := readBucketObjectLockConfig(bucket); err != nil {
+		if _, ok := err.(BucketObjectLockConfigNotFound); !ok {
+			errorIf(err, "Unable to read object lock configuration.")
+			return nil, ErrInternalError
+		}
+		// Object lock headers are not allowed on buckets without object lock.
+		if mode != "" || retainUntil != "" || legalHold != "" {
+			return nil, ErrObjectLockHeadersMissing
+		}
+		return nil, ErrNone
+	}
+
+	metadata := make(map[string]string)
+	now := time.Now().UTC()
+	switch {
+	case mode != "" && retainUntil != "":
+		if !isValidObjectLockMode(mode) {
+			return nil, ErrInvalidObjectLockMode
+		}
+		retainUntilDate, err := time.Parse(time.RFC3339, retainUntil)
+		if err != nil || !retainUntilDate.After(now) {
+			return nil, ErrInvalidRetainUntilDate
+		}
+		metadata[objectLockModeKey] = mode
+		metadata[objectLockRetainUntilKey] = retainUntilDate.UTC().Format(time.RFC3339)
+	case mode != "" || retainUntil != "":
+		// Both mode and retain until date need to be specified together.
+		return nil, ErrObjectLockHeadersMissing
+	}
+
+	if legalHold != "" {
+		if legalHold != legalHoldOn && legalHold != legalHoldOff {
+			return nil, ErrInvalidLegalHoldStatus
+		}
+		metadata[objectLockLegalHoldKey] = legalHold
+	}
+	return metadata, ErrNone
+}
+
+// applyDefaultRetention - sets the default retention of the bucket in
+// the metadata of a new object, unless the object has its own retention.
+// Metadata is updated in place, a new map is allocated if it is nil.
+func applyDefaultRetention(bucket string, metadata map[string]string) (map[string]string, error) {
+	if metadata[objectLockModeKey] != "" {
+		return metadata, nil
+	}
+	config, err := readBucketObjectLockConfig(bucket)
+	if err != nil {
+		if _, ok := err.(BucketObjectLockConfigNotFound); ok {
+			return metadata, nil
+		}
+		return nil, err
+	}
+	retainUntilDate, ok := config.retainUntil(time.Now().UTC())
+	if !ok {
+		return metadata, nil
+	}
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadata[objectLockModeKey] = config.Rule.DefaultRetention.Mode
+	metadata[objectLockRetainUntilKey] = retainUntilDate.Format(time.RFC3339)
+	return metadata, nil
+}
+
+// readBucketObjectLockConfig - read bucket object lock configuration.
+func readBucketObjectLockConfig(bucket string) (objectLockConfiguration, error) {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return objectLockConfiguration{}, BucketNameInvalid{Bucket: bucket}
+	}
+
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return objectLockConfiguration{}, err
+	}
+
+	// Get object lock config file.
+	objectLockFile := filepath.Join(bucketConfigPath, bucketObjectLockConfigFile)
+	configBytes, err := ioutil.ReadFile(objectLockFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return objectLockConfiguration{}, BucketObjectLockConfigNotFound{Bucket: bucket}
+		}
+		return objectLockConfiguration{}, err
+	}
+	return parseObjectLockConfig(configBytes)
+}
+
+// writeBucketObjectLockConfig - save bucket object lock configuration.
+func writeBucketObjectLockConfig(bucket string, config objectLockConfiguration) error {
+	// Verify if bucket path legal
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+
+	configBytes, err := xml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	// Create bucket config path.
+	if err = createBucketConfigPath(bucket); err != nil {
+		return err
+	}
+
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return err
+	}
+
+	// Write object lock config.
+	objectLockFile := filepath.Join(bucketConfigPath, bucketObjectLockConfigFile)
+	return ioutil.WriteFile(objectLockFile, configBytes, 0600)
+}
+
+// removeBucketObjectLockConfig - remove bucket object lock configuration.
+func removeBucketObjectLockConfig(bucket string) error {
+	// Verify bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+
+	bucketConfigPath, err := getBucketConfigPath(bucket)
+	if err != nil {
+		return err
+	}
+
+	objectLockFile := filepath.Join(bucketConfigPath, bucketObjectLockConfigFile)
+	if err = os.Remove(objectLockFile); err != nil {
+		if os.IsNotExist(err) {
+			return BucketObjectLockConfigNotFound{Bucket: bucket}
+		}
+		return err
+	}
+	return nil
+}
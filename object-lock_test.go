@@ -0,0 +1,472 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Tests validate object lock state computed from object metadata.
+func TestIsObjectLocked(t *testing.T) {
+	future := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+
+	testCases := []struct {
+		metadata map[string]string
+		locked   bool
+	}{
+		// Test case - 1.
+		// No object lock metadata.
+		{map[string]string{"content-type": "application/octet-stream"}, false},
+		// Test case - 2.
+		// Unexpired retention.
+		{map[string]string{objectLockModeKey: objectLockCompliance, objectLockRetainUntilKey: future}, true},
+		// Test case - 3.
+		// Expired retention.
+		{map[string]string{objectLockModeKey: objectLockGovernance, objectLockRetainUntilKey: past}, false},
+		// Test case - 4.
+		// Legal hold on an object with expired retention.
+		{map[string]string{objectLockRetainUntilKey: past, objectLockLegalHoldKey: legalHoldOn}, true},
+		// Test case - 5.
+		// Legal hold turned off.
+		{map[string]string{objectLockLegalHoldKey: legalHoldOff}, false},
+		// Test case - 6.
+		// Malformed retain until date is treated as locked.
+		{map[string]string{objectLockRetainUntilKey: "tomorrow"}, true},
+	}
+	for i, testCase := range testCases {
+		if locked := isObjectLocked(testCase.metadata); locked != testCase.locked {
+			t.Errorf("Test %d: Expected locked to be '%v', but found '%v'", i+1, testCase.locked, locked)
+		}
+	}
+}
+
+// Tests validate parsing of object lock configuration.
+func TestParseObjectLockConfig(t *testing.T) {
+	testCases := []struct {
+		config     string
+		shouldPass bool
+	}{
+		// Test case - 1.
+		// Object lock enabled without default retention.
+		{`<ObjectLockConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><ObjectLockEnabled>Enabled</ObjectLockEnabled></ObjectLockConfiguration>`, true},
+		// Test case - 2.
+		// Default retention in days.
+		{`<ObjectLockConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>COMPLIANCE</Mode><Days>30</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, true},
+		// Test case - 3.
+		// Default retention in years.
+		{`<ObjectLockConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>COMPLIANCE</Mode><Years>1</Years></DefaultRetention></Rule></ObjectLockConfiguration>`, true},
+		// Test case - 4.
+		// Both days and years set.
+		{`<ObjectLockConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>COMPLIANCE</Mode><Days>1</Days><Years>1</Years></DefaultRetention></Rule></ObjectLockConfiguration>`, false},
+		// Test case - 5.
+		// Unknown mode.
+		{`<ObjectLockConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>FOREVER</Mode><Days>1</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, false},
+		// Test case - 6.
+		// Object lock not enabled.
+		{`<ObjectLockConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><ObjectLockEnabled>Disabled</ObjectLockEnabled></ObjectLockConfiguration>`, false},
+		// Test case - 7.
+		// Malformed XML.
+		{`<ObjectLockConfiguration>`, false},
+		// Test case - 8.
+		// Governance mode default retention.
+		{`<ObjectLockConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><ObjectLockEnabled>Enabled</ObjectLockEnabled><Rule><DefaultRetention><Mode>GOVERNANCE</Mode><Days>30</Days></DefaultRetention></Rule></ObjectLockConfiguration>`, true},
+	}
+	for i, testCase := range testCases {
+		_, err := parseObjectLockConfig([]byte(testCase.config))
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: Expected to pass, but failed with: <ERROR> %s", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: Expected to fail, but passed instead", i+1)
+		}
+	}
+}
+
+// Tests validate changes of object lock metadata.
+func TestUpdateObjectLockMetadata(t *testing.T) {
+	now := time.Now().UTC()
+	hour := now.Add(time.Hour).Format(time.RFC3339)
+	day := now.Add(24 * time.Hour).Format(time.RFC3339)
+	past := now.Add(-time.Hour).Format(time.RFC3339)
+	retention := func(date string) map[string]string {
+		return map[string]string{objectLockModeKey: objectLockCompliance, objectLockRetainUntilKey: date}
+	}
+	governance := func(date string) map[string]string {
+		return map[string]string{objectLockModeKey: objectLockGovernance, objectLockRetainUntilKey: date}
+	}
+	bypass := func(lockMeta map[string]string) map[string]string {
+		lockMeta[objectLockBypassGovernanceKey] = "true"
+		return lockMeta
+	}
+	removal := func() map[string]string {
+		return map[string]string{objectLockModeKey: "", objectLockRetainUntilKey: ""}
+	}
+
+	testCases := []struct {
+		metadata   map[string]string
+		lockMeta   map[string]string
+		shouldPass bool
+	}{
+		// Test case - 1.
+		// Retention of an object without retention.
+		{map[string]string{}, retention(hour), true},
+		// Test case - 2.
+		// Unexpired retention extended.
+		{retention(hour), retention(day), true},
+		// Test case - 3.
+		// Unexpired retention shortened.
+		{retention(day), retention(hour), false},
+		// Test case - 4.
+		// Expired retention replaced.
+		{retention(past), retention(hour), true},
+		// Test case - 5.
+		// Legal hold removed from an object under retention.
+		{retention(hour), map[string]string{objectLockLegalHoldKey: legalHoldOff}, true},
+		// Test case - 6.
+		// Malformed retain until date is not replaced.
+		{retention("tomorrow"), retention(day), false},
+		// Test case - 7.
+		// Unexpired governance retention shortened.
+		{governance(day), governance(hour), false},
+		// Test case - 8.
+		// Unexpired governance retention shortened bypassing governance.
+		{governance(day), bypass(governance(hour)), true},
+		// Test case - 9.
+		// Unexpired governance retention removed bypassing governance.
+		{governance(day), bypass(removal()), true},
+		// Test case - 10.
+		// Unexpired governance retention removed.
+		{governance(day), removal(), false},
+		// Test case - 11.
+		// Unexpired governance retention raised to compliance.
+		{governance(hour), retention(day), true},
+		// Test case - 12.
+		// Unexpired compliance retention lowered to governance.
+		{retention(hour), governance(day), false},
+		// Test case - 13.
+		// Unexpired compliance retention removed bypassing governance.
+		{retention(day), bypass(removal()), false},
+	}
+	for i, testCase := range testCases {
+		metadata, ok := updateObjectLockMetadata(testCase.metadata, testCase.lockMeta)
+		if ok != testCase.shouldPass {
+			t.Errorf("Test %d: Expected update to be allowed '%v', but found '%v'", i+1, testCase.shouldPass, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		for key, value := range testCase.lockMeta {
+			if key == objectLockBypassGovernanceKey {
+				value = ""
+			}
+			if found, ok := metadata[key]; found != value || (ok && value == "") {
+				t.Errorf("Test %d: Expected '%s' to be '%s', but found '%s'", i+1, key, value, found)
+			}
+		}
+	}
+}
+
+// Wrapper for calling object lock tests for both XL multiple disks and single node setup.
+func TestObjectLock(t *testing.T) {
+	ExecObjectLayerTest(t, testObjectLock)
+}
+
+// Tests validate locked objects cannot be overwritten or deleted.
+func testObjectLock(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := getRandomBucketName()
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	data := []byte("hello, world")
+	lockedMeta := map[string]string{
+		objectLockModeKey:        objectLockCompliance,
+		objectLockRetainUntilKey: time.Now().UTC().Add(time.Hour).Format(time.RFC3339),
+	}
+	heldMeta := map[string]string{objectLockLegalHoldKey: legalHoldOn}
+	for _, object := range []string{"locked", "held", "dir/unlocked"} {
+		var metadata map[string]string
+		switch object {
+		case "locked":
+			metadata = lockedMeta
+		case "held":
+			metadata = heldMeta
+		}
+		if _, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), metadata); err != nil {
+			t.Fatalf("%s: <ERROR> %s", instanceType, err)
+		}
+	}
+
+	// Object lock metadata is returned with object info.
+	objInfo, err := obj.GetObjectInfo(bucket, "locked")
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if objInfo.UserDefined[objectLockModeKey] != objectLockCompliance {
+		t.Errorf("%s: Expected object lock mode '%s', but found '%s'", instanceType, objectLockCompliance, objInfo.UserDefined[objectLockModeKey])
+	}
+
+	testCases := []struct {
+		object string
+		locked bool
+	}{
+		// Test case - 1.
+		// Object under retention.
+		{"locked", true},
+		// Test case - 2.
+		// Object under legal hold.
+		{"held", true},
+		// Test case - 3.
+		// Object without object lock.
+		{"dir/unlocked", false},
+	}
+	for i, testCase := range testCases {
+		_, err = obj.PutObject(bucket, testCase.object, int64(len(data)), bytes.NewReader(data), nil)
+		if _, ok := err.(ObjectLocked); ok != testCase.locked {
+			t.Errorf("Test %d: %s: Expected overwrite locked to be '%v', but found error '%v'", i+1, instanceType, testCase.locked, err)
+		}
+		err = obj.DeleteObject(bucket, testCase.object)
+		if _, ok := err.(ObjectLocked); ok != testCase.locked {
+			t.Errorf("Test %d: %s: Expected delete locked to be '%v', but found error '%v'", i+1, instanceType, testCase.locked, err)
+		}
+		if !testCase.locked && err != nil {
+			t.Errorf("Test %d: %s: Expected to delete object, but failed with: <ERROR> %s", i+1, instanceType, err)
+		}
+	}
+
+	// Retention can only be extended.
+	shortMeta := map[string]string{
+		objectLockModeKey:        objectLockCompliance,
+		objectLockRetainUntilKey: time.Now().UTC().Add(time.Minute).Format(time.RFC3339),
+	}
+	if err = obj.SetObjectLock(bucket, "locked", shortMeta); err == nil {
+		t.Errorf("%s: Expected shortening retention to fail", instanceType)
+	} else if _, ok := err.(ObjectLocked); !ok {
+		t.Errorf("%s: Expected ObjectLocked, but found error '%v'", instanceType, err)
+	}
+	longMeta := map[string]string{
+		objectLockModeKey:        objectLockCompliance,
+		objectLockRetainUntilKey: time.Now().UTC().Add(2 * time.Hour).Format(time.RFC3339),
+	}
+	if err = obj.SetObjectLock(bucket, "locked", longMeta); err != nil {
+		t.Errorf("%s: Expected to extend retention, but failed with: <ERROR> %s", instanceType, err)
+	}
+	objInfo, err = obj.GetObjectInfo(bucket, "locked")
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if objInfo.UserDefined[objectLockRetainUntilKey] != longMeta[objectLockRetainUntilKey] {
+		t.Errorf("%s: Expected retain until date '%s', but found '%s'", instanceType, longMeta[objectLockRetainUntilKey], objInfo.UserDefined[objectLockRetainUntilKey])
+	}
+	if objInfo.Size != int64(len(data)) {
+		t.Errorf("%s: Expected size %d to be kept, but found %d", instanceType, len(data), objInfo.Size)
+	}
+
+	// Object can be deleted once its legal hold is removed.
+	if err = obj.SetObjectLock(bucket, "held", map[string]string{objectLockLegalHoldKey: legalHoldOff}); err != nil {
+		t.Fatalf("%s: Unable to remove legal hold: <ERROR> %s", instanceType, err)
+	}
+	if err = obj.DeleteObject(bucket, "held"); err != nil {
+		t.Errorf("%s: Expected to delete object, but failed with: <ERROR> %s", instanceType, err)
+	}
+}
+
+// Tests validate objects whose metadata cannot be read are treated
+// as locked.
+func TestObjectLockMetadataError(t *testing.T) {
+	obj, fsDir, err := getSingleNodeObjectLayer()
+	if err != nil {
+		t.Fatalf("Unable to initialize FS backend: <ERROR> %s", err)
+	}
+	defer removeAll(fsDir)
+
+	bucket := getRandomBucketName()
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	data := []byte("hello, world")
+	heldMeta := map[string]string{objectLockLegalHoldKey: legalHoldOn}
+	if _, err = obj.PutObject(bucket, "held", int64(len(data)), bytes.NewReader(data), heldMeta); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	fsMetaPath := filepath.Join(fsDir, minioMetaBucket, bucketMetaPrefix, bucket, "held", fsMetaJSONFile)
+	if err = ioutil.WriteFile(fsMetaPath, []byte("{"), 0644); err != nil {
+		t.Fatalf("Unable to corrupt metadata: <ERROR> %s", err)
+	}
+	if err = obj.DeleteObject(bucket, "held"); err == nil {
+		t.Error("Expected delete of object with unreadable metadata to fail")
+	}
+}
+
+// Wrapper for calling default retention tests for both XL multiple disks and single node setup.
+func TestObjectLockDefaultRetention(t *testing.T) {
+	ExecObjectLayerTest(t, testObjectLockDefaultRetention)
+}
+
+// Tests validate default retention of the bucket is applied to objects
+// written without their own retention.
+func testObjectLockDefaultRetention(obj ObjectLayer, instanceType string, t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("%s: Unable to initialize config: <ERROR> %s", instanceType, err)
+	}
+	defer removeAll(rootPath)
+
+	bucket := getRandomBucketName()
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	config := objectLockConfiguration{
+		ObjectLockEnabled: objectLockEnabled,
+		Rule:              &objectLockRule{DefaultRetention: objectLockRetention{Mode: objectLockCompliance, Days: 1}},
+	}
+	if err = writeBucketObjectLockConfig(bucket, config); err != nil {
+		t.Fatalf("%s: Unable to write object lock configuration: <ERROR> %s", instanceType, err)
+	}
+
+	data := []byte("hello, world")
+	if _, err = obj.PutObject(bucket, "put", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if _, err = obj.CopyObject(bucket, "put", bucket, "copy", nil); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	uploadID, err := obj.NewMultipartUpload(bucket, "multipart", nil)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	partMD5, err := obj.PutObjectPart(bucket, "multipart", uploadID, 1, int64(len(data)), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if _, err = obj.CompleteMultipartUpload(bucket, "multipart", uploadID, []completePart{{PartNumber: 1, ETag: partMD5}}); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	retainUntil := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	ownMeta := map[string]string{objectLockModeKey: objectLockCompliance, objectLockRetainUntilKey: retainUntil}
+	if _, err = obj.PutObject(bucket, "own", int64(len(data)), bytes.NewReader(data), ownMeta); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+
+	for i, object := range []string{"put", "copy", "multipart", "own"} {
+		objInfo, err := obj.GetObjectInfo(bucket, object)
+		if err != nil {
+			t.Fatalf("Test %d: %s: <ERROR> %s", i+1, instanceType, err)
+		}
+		if objInfo.UserDefined[objectLockModeKey] != objectLockCompliance {
+			t.Errorf("Test %d: %s: Expected object lock mode '%s', but found '%s'", i+1, instanceType, objectLockCompliance, objInfo.UserDefined[objectLockModeKey])
+		}
+		retainUntilDate, err := time.Parse(time.RFC3339, objInfo.UserDefined[objectLockRetainUntilKey])
+		if err != nil {
+			t.Errorf("Test %d: %s: Expected a retain until date, but found '%s'", i+1, instanceType, objInfo.UserDefined[objectLockRetainUntilKey])
+			continue
+		}
+		if object == "own" {
+			if objInfo.UserDefined[objectLockRetainUntilKey] != retainUntil {
+				t.Errorf("Test %d: %s: Expected own retention '%s' to be kept, but found '%s'", i+1, instanceType, retainUntil, objInfo.UserDefined[objectLockRetainUntilKey])
+			}
+		} else if retainUntilDate.Before(time.Now().UTC().Add(23 * time.Hour)) {
+			t.Errorf("Test %d: %s: Expected default retention of a day, but found '%s'", i+1, instanceType, retainUntilDate)
+		}
+	}
+}
+
+// Wrapper for calling governance bypass tests for both XL multiple disks and single node setup.
+func TestObjectLockBypassGovernance(t *testing.T) {
+	ExecObjectLayerTest(t, testObjectLockBypassGovernance)
+}
+
+// Tests validate the owner can delete objects under governance retention
+// only by bypassing it, compliance retention and legal hold are kept.
+func testObjectLockBypassGovernance(obj ObjectLayer, instanceType string, t *testing.T) {
+	credentials, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("%s: Unable to initialize config: <ERROR> %s", instanceType, err)
+	}
+	defer removeAll(rootPath)
+
+	bucket := getRandomBucketName()
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	retainUntil := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	objects := map[string]map[string]string{
+		"governance": {objectLockModeKey: objectLockGovernance, objectLockRetainUntilKey: retainUntil},
+		"compliance": {objectLockModeKey: objectLockCompliance, objectLockRetainUntilKey: retainUntil},
+		"held":       {objectLockModeKey: objectLockGovernance, objectLockRetainUntilKey: retainUntil, objectLockLegalHoldKey: legalHoldOn},
+	}
+	data := []byte("hello, world")
+	for object, metadata := range objects {
+		if _, err = obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), metadata); err != nil {
+			t.Fatalf("%s: <ERROR> %s", instanceType, err)
+		}
+	}
+
+	apiRouter := initTestAPIEndPoints(obj, []string{"DeleteObject"})
+	testCases := []struct {
+		object             string
+		bypassGovernance   bool
+		expectedRespStatus int
+	}{
+		// Test case - 1.
+		// Governance retention is not bypassed.
+		{"governance", false, http.StatusForbidden},
+		// Test case - 2.
+		// Compliance retention can't be bypassed.
+		{"compliance", true, http.StatusForbidden},
+		// Test case - 3.
+		// Legal hold can't be bypassed.
+		{"held", true, http.StatusForbidden},
+		// Test case - 4.
+		// Governance retention is bypassed.
+		{"governance", true, http.StatusNoContent},
+	}
+	for i, testCase := range testCases {
+		rec := httptest.NewRecorder()
+		req, err := newTestRequest("DELETE", "/"+bucket+"/"+testCase.object, 0, bytes.NewReader([]byte{}), credentials.AccessKeyID, credentials.SecretAccessKey)
+		if err != nil {
+			t.Fatalf("Test %d: %s: Failed to create HTTP request: <ERROR> %s", i+1, instanceType, err)
+		}
+		if testCase.bypassGovernance {
+			req.Header.Set(objectLockBypassGovernanceHeader, "true")
+		}
+		apiRouter.ServeHTTP(rec, req)
+		if rec.Code != testCase.expectedRespStatus {
+			t.Errorf("Test %d: %s: Expected the response status to be `%d`, but instead found `%d`", i+1, instanceType, testCase.expectedRespStatus, rec.Code)
+		}
+		_, err = obj.GetObjectInfo(bucket, testCase.object)
+		if deleted := err != nil; deleted != (testCase.expectedRespStatus == http.StatusNoContent) {
+			t.Errorf("Test %d: %s: Expected object deleted to be '%v', but found '%v'", i+1, instanceType, !deleted, deleted)
+		}
+	}
+
+	// Retention of an object under legal hold is kept.
+	objInfo, err := obj.GetObjectInfo(bucket, "held")
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if objInfo.UserDefined[objectLockRetainUntilKey] != retainUntil {
+		t.Errorf("%s: Expected retain until date '%s', but found '%s'", instanceType, retainUntil, objInfo.UserDefined[objectLockRetainUntilKey])
+	}
+}
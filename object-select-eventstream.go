@@ -0,0 +1,126 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"hash/crc32"
+	"io"
+	"net/http"
+)
+
+// Type of string header values of event stream messages.
+const eventStreamStringHeader = 7
+
+// selectStats - bytes scanned, processed and returned by a select,
+// sent as Progress and Stats events.
+type selectStats struct {
+	XMLName        xml.Name
+	BytesScanned   int64
+	BytesProcessed int64
+	BytesReturned  int64
+}
+
+// selectEventWriter - writes select results as event stream messages.
+//
+// A message is a prelude of the total message length, the headers
+// length and a CRC32 of both, followed by headers, the payload and a
+// CRC32 of the whole message. Integers are big endian.
+type selectEventWriter struct {
+	writer io.Writer
+}
+
+// writeMessage - writes a message and flushes it to the client.
+func (e *selectEventWriter) writeMessage(headers [][2]string, payload []byte) error {
+	var headerBuf bytes.Buffer
+	for _, header := range headers {
+		headerBuf.WriteByte(byte(len(header[0])))
+		headerBuf.WriteString(header[0])
+		headerBuf.WriteByte(eventStreamStringHeader)
+		binary.Write(&headerBuf, binary.BigEndian, uint16(len(header[1])))
+		headerBuf.WriteString(header[1])
+	}
+
+	var message bytes.Buffer
+	binary.Write(&message, binary.BigEndian, uint32(12+headerBuf.Len()+len(payload)+4))
+	binary.Write(&message, binary.BigEndian, uint32(headerBuf.Len()))
+	binary.Write(&message, binary.BigEndian, crc32.ChecksumIEEE(message.Bytes()))
+	message.Write(headerBuf.Bytes())
+	message.Write(payload)
+	binary.Write(&message, binary.BigEndian, crc32.ChecksumIEEE(message.Bytes()))
+
+	if _, err := e.writer.Write(message.Bytes()); err != nil {
+		return err
+	}
+	if flusher, ok := e.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// writeRecords - writes a Records event.
+func (e *selectEventWriter) writeRecords(payload []byte) error {
+	return e.writeMessage([][2]string{
+		{":event-type", "Records"},
+		{":content-type", "application/octet-stream"},
+		{":message-type", "event"},
+	}, payload)
+}
+
+// writeStatsEvent - writes a Stats or Progress event.
+func (e *selectEventWriter) writeStatsEvent(eventType string, stats selectStats) error {
+	stats.XMLName = xml.Name{Local: eventType}
+	payload, err := xml.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return e.writeMessage([][2]string{
+		{":event-type", eventType},
+		{":content-type", "text/xml"},
+		{":message-type", "event"},
+	}, payload)
+}
+
+// writeProgress - writes a Progress event.
+func (e *selectEventWriter) writeProgress(stats selectStats) error {
+	return e.writeStatsEvent("Progress", stats)
+}
+
+// writeStats - writes a Stats event, sent once before End.
+func (e *selectEventWriter) writeStats(stats selectStats) error {
+	return e.writeStatsEvent("Stats", stats)
+}
+
+// writeEnd - writes the End event, the last event of a successful
+// select.
+func (e *selectEventWriter) writeEnd() error {
+	return e.writeMessage([][2]string{
+		{":event-type", "End"},
+		{":message-type", "event"},
+	}, nil)
+}
+
+// writeError - writes an error message, which ends the select.
+func (e *selectEventWriter) writeError(code, message string) error {
+	return e.writeMessage([][2]string{
+		{":error-code", code},
+		{":error-message", message},
+		{":message-type", "error"},
+	}, nil)
+}
@@ -0,0 +1,92 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	mux "github.com/gorilla/mux"
+)
+
+// SelectObjectContentHandler - POST Object?select&select-type=2
+// ----------
+// Filters the contents of a CSV or JSON object with an SQL expression,
+// matched records are streamed back as event stream messages while
+// the object is read.
+func (api objectAPIHandlers) SelectObjectContentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bucket := vars["bucket"]
+	object := vars["object"]
+
+	switch getRequestAuthType(r) {
+	default:
+		// For all unknown auth types return error.
+		writeErrorResponse(w, r, ErrAccessDenied, r.URL.Path)
+		return
+	case authTypeAnonymous:
+		// Selecting object content requires permission to read it.
+		if s3Error := enforceBucketPolicy("s3:GetObject", bucket, r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	case authTypePresigned, authTypeSigned:
+		if s3Error := isReqAuthenticated(r); s3Error != ErrNone {
+			writeErrorResponse(w, r, s3Error, r.URL.Path)
+			return
+		}
+	}
+
+	// Read select request up to maxSelectRequestSize.
+	requestBytes, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSelectRequestSize))
+	if err != nil {
+		errorIf(err, "Unable to read select request.")
+		writeErrorResponse(w, r, ErrInternalError, r.URL.Path)
+		return
+	}
+	req, s3Error := parseSelectRequest(requestBytes)
+	if s3Error != ErrNone {
+		writeErrorResponse(w, r, s3Error, r.URL.Path)
+		return
+	}
+
+	objInfo, err := api.ObjectAPI.GetObjectInfo(bucket, object)
+	if err != nil {
+		errorIf(err, "Unable to fetch object info.")
+		apiErr := toAPIErrorCode(err)
+		if apiErr == ErrNoSuchKey {
+			apiErr = errAllowableObjectNotFound(bucket, r)
+		}
+		writeErrorResponse(w, r, apiErr, r.URL.Path)
+		return
+	}
+
+	// Errors after this point are sent as error events.
+	setCommonHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	events := &selectEventWriter{writer: w}
+	if err = req.execute(api.ObjectAPI, bucket, object, objInfo.Size, events); err != nil {
+		code, message := "InternalError", getAPIError(ErrInternalError).Description
+		if sErr, ok := err.(selectError); ok {
+			code, message = sErr.code, sErr.message
+		} else {
+			errorIf(err, "Unable to select object content.")
+		}
+		events.writeError(code, message)
+	}
+}
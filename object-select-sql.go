@@ -0,0 +1,1270 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SQL values are nil for NULL and missing fields, bool, int64,
+// float64, string, or *jsonObject and []interface{} for nested JSON.
+
+// selectRecord - a record of the queried object.
+type selectRecord interface {
+	// Returns the value at path, exact is false for unquoted path
+	// components which match case insensitively.
+	get(path []string, exact []bool) interface{}
+	// Returns the names and values of all fields in order.
+	fields() (names []string, values []interface{})
+}
+
+// sqlToken kinds.
+const (
+	sqlTokenEOF = iota
+	sqlTokenIdent
+	sqlTokenQuotedIdent
+	sqlTokenString
+	sqlTokenNumber
+	sqlTokenOperator
+)
+
+// sqlToken - a lexical token of a query.
+type sqlToken struct {
+	kind  int
+	value string
+}
+
+// sqlLex - splits a query into tokens.
+func sqlLex(query string) (tokens []sqlToken, err error) {
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(query) && (query[j] == '_' || query[j] >= 'a' && query[j] <= 'z' ||
+				query[j] >= 'A' && query[j] <= 'Z' || query[j] >= '0' && query[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, sqlToken{sqlTokenIdent, query[i:j]})
+			i = j
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			j := i
+			for j < len(query) && (query[j] >= '0' && query[j] <= '9' || query[j] == '.') {
+				j++
+			}
+			if j < len(query) && (query[j] == 'e' || query[j] == 'E') {
+				j++
+				if j < len(query) && (query[j] == '+' || query[j] == '-') {
+					j++
+				}
+				for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+					j++
+				}
+			}
+			tokens = append(tokens, sqlToken{sqlTokenNumber, query[i:j]})
+			i = j
+		case c == '\'' || c == '"':
+			// Quotes are escaped by doubling them.
+			var value []byte
+			j := i + 1
+			for ; j < len(query); j++ {
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						value = append(value, c)
+						j++
+						continue
+					}
+					break
+				}
+				value = append(value, query[j])
+			}
+			if j == len(query) {
+				return nil, fmt.Errorf("Unterminated quote at %d", i)
+			}
+			kind := sqlTokenString
+			if c == '"' {
+				kind = sqlTokenQuotedIdent
+			}
+			tokens = append(tokens, sqlToken{kind, string(value)})
+			i = j + 1
+		default:
+			op := string(c)
+			if i+1 < len(query) {
+				switch query[i : i+2] {
+				case "<=", ">=", "<>", "!=", "||":
+					op = query[i : i+2]
+				}
+			}
+			if !strings.Contains("=<>!|+-*/%(),.[];", op[:1]) || op == "!" || op == "|" {
+				return nil, fmt.Errorf("Unexpected character %q at %d", c, i)
+			}
+			tokens = append(tokens, sqlToken{sqlTokenOperator, op})
+			i += len(op)
+		}
+	}
+	return append(tokens, sqlToken{kind: sqlTokenEOF}), nil
+}
+
+// selectColumn - a projected column of a query.
+type selectColumn struct {
+	name string
+	expr sqlExpr
+}
+
+// selectQuery - a parsed query.
+type selectQuery struct {
+	// Projected columns, nil for all columns.
+	columns []selectColumn
+	// Filter of records, nil for all records.
+	where sqlExpr
+	// Maximum number of matched records, negative for no limit.
+	limit int64
+	// Aggregates of the projection, the query returns a single
+	// record of aggregates if any.
+	aggregates []*sqlAggregate
+}
+
+// sqlParser - recursive descent parser of the supported SQL subset,
+// SELECT * | expr [[AS] name], ... FROM S3Object[[*]] [[AS] alias]
+// [WHERE expr] [LIMIT number].
+type sqlParser struct {
+	tokens []sqlToken
+	pos    int
+	alias  string
+
+	// Aggregates of the parsed query.
+	aggregates []*sqlAggregate
+	// Aggregates are allowed in the projection only.
+	allowAggregates bool
+	// Parsing the argument of an aggregate.
+	inAggregate bool
+	// Columns referenced outside of aggregates.
+	columnRefs int
+}
+
+// parseSelectQuery - parses a query.
+func parseSelectQuery(query string) (*selectQuery, error) {
+	tokens, err := sqlLex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{tokens: tokens, alias: "s3object"}
+	return p.parseQuery()
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sqlParser) next() sqlToken {
+	token := p.tokens[p.pos]
+	if token.kind != sqlTokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+// isKeyword - returns true if the next token is one of keywords.
+func (p *sqlParser) isKeyword(keywords ...string) bool {
+	token := p.peek()
+	if token.kind != sqlTokenIdent {
+		return false
+	}
+	for _, keyword := range keywords {
+		if strings.EqualFold(token.value, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptKeyword - consumes the next token if it is keyword.
+func (p *sqlParser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// isOperator - returns true if the next token is one of operators.
+func (p *sqlParser) isOperator(operators ...string) bool {
+	token := p.peek()
+	if token.kind != sqlTokenOperator {
+		return false
+	}
+	for _, operator := range operators {
+		if token.value == operator {
+			return true
+		}
+	}
+	return false
+}
+
+// expect - consumes the next token which must be the operator.
+func (p *sqlParser) expect(operator string) error {
+	if !p.isOperator(operator) {
+		return p.unexpected()
+	}
+	p.pos++
+	return nil
+}
+
+// unexpected - returns an error for the next token.
+func (p *sqlParser) unexpected() error {
+	token := p.peek()
+	if token.kind == sqlTokenEOF {
+		return fmt.Errorf("Unexpected end of query")
+	}
+	return fmt.Errorf("Unexpected token %q", token.value)
+}
+
+// Reserved words which end an expression or cannot be identifiers.
+var sqlReservedWords = []string{
+	"SELECT", "FROM", "WHERE", "LIMIT", "AS", "AND", "OR", "NOT", "LIKE",
+	"IS", "NULL", "BETWEEN", "IN", "TRUE", "FALSE", "CAST", "ESCAPE",
+}
+
+func (p *sqlParser) parseQuery() (*selectQuery, error) {
+	query := &selectQuery{limit: -1}
+	if !p.acceptKeyword("SELECT") {
+		return nil, p.unexpected()
+	}
+
+	// Columns are parsed after the alias is known.
+	start := p.pos
+	for depth := 0; !(depth == 0 && p.isKeyword("FROM")); {
+		switch {
+		case p.peek().kind == sqlTokenEOF:
+			return nil, p.unexpected()
+		case p.isOperator("("):
+			depth++
+		case p.isOperator(")"):
+			depth--
+		}
+		p.pos++
+	}
+	p.pos++
+	if !p.acceptKeyword("S3Object") {
+		return nil, p.unexpected()
+	}
+	if p.isOperator("[") {
+		p.pos++
+		if err := p.expect("*"); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	}
+	p.acceptKeyword("AS")
+	if token := p.peek(); token.kind == sqlTokenIdent && !p.isKeyword(sqlReservedWords...) || token.kind == sqlTokenQuotedIdent {
+		p.alias = strings.ToLower(token.value)
+		p.pos++
+	}
+	if p.acceptKeyword("WHERE") {
+		where, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		query.where = where
+	}
+	if p.acceptKeyword("LIMIT") {
+		token := p.next()
+		limit, err := strconv.ParseInt(token.value, 10, 64)
+		if token.kind != sqlTokenNumber || err != nil || limit < 0 {
+			return nil, fmt.Errorf("Invalid limit %q", token.value)
+		}
+		query.limit = limit
+	}
+	p.acceptOperator(";")
+	if p.peek().kind != sqlTokenEOF {
+		return nil, p.unexpected()
+	}
+	end := p.pos
+
+	p.pos = start
+	columns, err := p.parseColumns()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isKeyword("FROM") {
+		return nil, p.unexpected()
+	}
+	p.pos = end
+	query.columns = columns
+	query.aggregates = p.aggregates
+	return query, nil
+}
+
+// acceptOperator - consumes the next token if it is operator.
+func (p *sqlParser) acceptOperator(operator string) bool {
+	if p.isOperator(operator) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseColumns - parses the projection, nil for all columns.
+func (p *sqlParser) parseColumns() ([]selectColumn, error) {
+	if p.acceptOperator("*") {
+		return nil, nil
+	}
+	// alias.*
+	if token := p.peek(); (token.kind == sqlTokenIdent || token.kind == sqlTokenQuotedIdent) &&
+		strings.ToLower(token.value) == p.alias && p.pos+2 < len(p.tokens) &&
+		p.tokens[p.pos+1].value == "." && p.tokens[p.pos+2].value == "*" {
+		p.pos += 3
+		return nil, nil
+	}
+
+	var columns []selectColumn
+	aggregated := 0
+	p.allowAggregates = true
+	defer func() { p.allowAggregates = false }()
+	for {
+		aggregates := len(p.aggregates)
+		p.columnRefs = 0
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if len(p.aggregates) > aggregates {
+			aggregated++
+			if p.columnRefs > 0 {
+				return nil, fmt.Errorf("Columns must be aggregated")
+			}
+		}
+		column := selectColumn{name: fmt.Sprintf("_%d", len(columns)+1), expr: expr}
+		if ref, ok := expr.(*sqlColumn); ok {
+			column.name = ref.path[len(ref.path)-1]
+		}
+		if p.acceptKeyword("AS") || p.peek().kind == sqlTokenQuotedIdent ||
+			p.peek().kind == sqlTokenIdent && !p.isKeyword(sqlReservedWords...) {
+			token := p.peek()
+			if token.kind != sqlTokenIdent && token.kind != sqlTokenQuotedIdent {
+				return nil, p.unexpected()
+			}
+			p.pos++
+			column.name = token.value
+		}
+		columns = append(columns, column)
+		if !p.acceptOperator(",") {
+			break
+		}
+	}
+	if aggregated > 0 && aggregated != len(columns) {
+		return nil, fmt.Errorf("Columns must be aggregated")
+	}
+	return columns, nil
+}
+
+func (p *sqlParser) parseExpr() (sqlExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlBinary{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseAnd() (sqlExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlBinary{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseNot() (sqlExpr, error) {
+	if p.acceptKeyword("NOT") {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlUnary{op: "NOT", expr: expr}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *sqlParser) parseComparison() (sqlExpr, error) {
+	left, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOperator("=", "!=", "<>", "<", "<=", ">", ">=") {
+		op := p.next().value
+		right, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlBinary{op: op, left: left, right: right}, nil
+	}
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		if !p.acceptKeyword("NULL") && !p.acceptKeyword("MISSING") {
+			return nil, p.unexpected()
+		}
+		return &sqlIsNull{expr: left, not: not}, nil
+	}
+	not := p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("LIKE"):
+		pattern, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		like := &sqlLike{expr: left, pattern: pattern, not: not}
+		if p.acceptKeyword("ESCAPE") {
+			if like.escape, err = p.parseConcat(); err != nil {
+				return nil, err
+			}
+		}
+		return like, nil
+	case p.acceptKeyword("BETWEEN"):
+		low, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword("AND") {
+			return nil, p.unexpected()
+		}
+		high, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlBetween{expr: left, low: low, high: high, not: not}, nil
+	case p.acceptKeyword("IN"):
+		list, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlIn{expr: left, list: list, not: not}, nil
+	}
+	if not {
+		return nil, p.unexpected()
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseConcat() (sqlExpr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("||") {
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlBinary{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseAdditive() (sqlExpr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("+", "-") {
+		op := p.next().value
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlBinary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseMultiplicative() (sqlExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("*", "/", "%") {
+		op := p.next().value
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlBinary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseUnary() (sqlExpr, error) {
+	if p.isOperator("-", "+") {
+		op := p.next().value
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlUnary{op: op, expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parseArgs - parses a parenthesized list of expressions.
+func (p *sqlParser) parseArgs() (args []sqlExpr, err error) {
+	if err = p.expect("("); err != nil {
+		return nil, err
+	}
+	if p.acceptOperator(")") {
+		return nil, nil
+	}
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.acceptOperator(")") {
+			return args, nil
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *sqlParser) parsePrimary() (sqlExpr, error) {
+	token := p.peek()
+	switch token.kind {
+	case sqlTokenNumber:
+		p.pos++
+		if i, err := strconv.ParseInt(token.value, 10, 64); err == nil {
+			return &sqlLiteral{i}, nil
+		}
+		f, err := strconv.ParseFloat(token.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid number %q", token.value)
+		}
+		return &sqlLiteral{f}, nil
+	case sqlTokenString:
+		p.pos++
+		return &sqlLiteral{token.value}, nil
+	case sqlTokenOperator:
+		if !p.acceptOperator("(") {
+			return nil, p.unexpected()
+		}
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case sqlTokenQuotedIdent:
+		return p.parseColumn()
+	case sqlTokenIdent:
+		switch {
+		case p.acceptKeyword("NULL"), p.acceptKeyword("MISSING"):
+			return &sqlLiteral{nil}, nil
+		case p.acceptKeyword("TRUE"):
+			return &sqlLiteral{true}, nil
+		case p.acceptKeyword("FALSE"):
+			return &sqlLiteral{false}, nil
+		case p.acceptKeyword("CAST"):
+			return p.parseCast()
+		case p.isKeyword(sqlReservedWords...):
+			return nil, p.unexpected()
+		case p.tokens[p.pos+1].value == "(" && p.tokens[p.pos+1].kind == sqlTokenOperator:
+			return p.parseFunction()
+		}
+		return p.parseColumn()
+	}
+	return nil, p.unexpected()
+}
+
+func (p *sqlParser) parseColumn() (sqlExpr, error) {
+	column := &sqlColumn{}
+	for {
+		token := p.peek()
+		if token.kind != sqlTokenIdent && token.kind != sqlTokenQuotedIdent {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		column.path = append(column.path, token.value)
+		column.exact = append(column.exact, token.kind == sqlTokenQuotedIdent)
+		if !p.acceptOperator(".") {
+			break
+		}
+	}
+	// Strip the alias of the queried object.
+	if len(column.path) > 1 && (strings.ToLower(column.path[0]) == p.alias || strings.EqualFold(column.path[0], "S3Object")) {
+		column.path, column.exact = column.path[1:], column.exact[1:]
+	}
+	if !p.inAggregate {
+		p.columnRefs++
+	}
+	return column, nil
+}
+
+// Types of CAST.
+var sqlCastTypes = map[string]string{
+	"INT":     "INT",
+	"INTEGER": "INT",
+	"FLOAT":   "FLOAT",
+	"DECIMAL": "FLOAT",
+	"NUMERIC": "FLOAT",
+	"STRING":  "STRING",
+	"VARCHAR": "STRING",
+	"BOOL":    "BOOL",
+	"BOOLEAN": "BOOL",
+}
+
+func (p *sqlParser) parseCast() (sqlExpr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.acceptKeyword("AS") {
+		return nil, p.unexpected()
+	}
+	token := p.next()
+	typ, ok := sqlCastTypes[strings.ToUpper(token.value)]
+	if token.kind != sqlTokenIdent || !ok {
+		return nil, fmt.Errorf("Unsupported type %q", token.value)
+	}
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+	return &sqlCast{expr: expr, typ: typ}, nil
+}
+
+// Number of arguments of scalar functions.
+var sqlFunctions = map[string]int{
+	"LOWER":            1,
+	"UPPER":            1,
+	"TRIM":             1,
+	"CHAR_LENGTH":      1,
+	"CHARACTER_LENGTH": 1,
+	"COALESCE":         -1,
+}
+
+func (p *sqlParser) parseFunction() (sqlExpr, error) {
+	name := strings.ToUpper(p.next().value)
+	switch name {
+	case "COUNT", "SUM", "AVG", "MIN", "MAX":
+		if !p.allowAggregates || p.inAggregate {
+			return nil, fmt.Errorf("Aggregate %s is not allowed here", name)
+		}
+		aggregate := &sqlAggregate{name: name}
+		if name == "COUNT" && p.tokens[p.pos+1].value == "*" && p.tokens[p.pos+1].kind == sqlTokenOperator {
+			p.pos += 2
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+		} else {
+			p.inAggregate = true
+			args, err := p.parseArgs()
+			p.inAggregate = false
+			if err != nil {
+				return nil, err
+			}
+			if len(args) != 1 {
+				return nil, fmt.Errorf("%s takes one argument", name)
+			}
+			aggregate.arg = args[0]
+		}
+		p.aggregates = append(p.aggregates, aggregate)
+		return aggregate, nil
+	}
+	nargs, ok := sqlFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported function %s", name)
+	}
+	args, err := p.parseArgs()
+	if err != nil {
+		return nil, err
+	}
+	if nargs >= 0 && len(args) != nargs || len(args) == 0 {
+		return nil, fmt.Errorf("Invalid number of arguments to %s", name)
+	}
+	return &sqlFunction{name: name, args: args}, nil
+}
+
+// sqlExpr - an expression of a query.
+type sqlExpr interface {
+	eval(record selectRecord) (interface{}, error)
+}
+
+// sqlLiteral - a constant.
+type sqlLiteral struct {
+	value interface{}
+}
+
+func (e *sqlLiteral) eval(record selectRecord) (interface{}, error) {
+	return e.value, nil
+}
+
+// sqlColumn - a field of the record.
+type sqlColumn struct {
+	path  []string
+	exact []bool
+}
+
+func (e *sqlColumn) eval(record selectRecord) (interface{}, error) {
+	return record.get(e.path, e.exact), nil
+}
+
+// sqlUnary - NOT, unary minus and plus.
+type sqlUnary struct {
+	op   string
+	expr sqlExpr
+}
+
+func (e *sqlUnary) eval(record selectRecord) (interface{}, error) {
+	value, err := e.expr.eval(record)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	if e.op == "NOT" {
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("Expected a boolean, found %v", value)
+		}
+		return !b, nil
+	}
+	number, err := toSQLNumber(value)
+	if err != nil || e.op == "+" {
+		return number, err
+	}
+	if i, ok := number.(int64); ok {
+		return -i, nil
+	}
+	return -number.(float64), nil
+}
+
+// sqlBinary - logical, comparison, arithmetic and concatenation
+// operators.
+type sqlBinary struct {
+	op          string
+	left, right sqlExpr
+}
+
+func (e *sqlBinary) eval(record selectRecord) (interface{}, error) {
+	left, err := e.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	// Logical operators short circuit.
+	if e.op == "AND" || e.op == "OR" {
+		l, err := toSQLBool(left)
+		if err != nil {
+			return nil, err
+		}
+		if l != nil && *l == (e.op == "OR") {
+			return *l, nil
+		}
+		right, err := e.right.eval(record)
+		if err != nil {
+			return nil, err
+		}
+		r, err := toSQLBool(right)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil && *r == (e.op == "OR") {
+			return *r, nil
+		}
+		if l == nil || r == nil {
+			return nil, nil
+		}
+		return *r, nil
+	}
+
+	right, err := e.right.eval(record)
+	if err != nil || left == nil || right == nil {
+		return nil, err
+	}
+	switch e.op {
+	case "||":
+		return formatSQLValue(left) + formatSQLValue(right), nil
+	case "+", "-", "*", "/", "%":
+		return sqlArithmetic(e.op, left, right)
+	}
+	cmp, ok := compareSQLValues(left, right)
+	if !ok {
+		return nil, nil
+	}
+	switch e.op {
+	case "=":
+		return cmp == 0, nil
+	case "!=", "<>":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+// sqlIsNull - IS [NOT] NULL.
+type sqlIsNull struct {
+	expr sqlExpr
+	not  bool
+}
+
+func (e *sqlIsNull) eval(record selectRecord) (interface{}, error) {
+	value, err := e.expr.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	return (value == nil) != e.not, nil
+}
+
+// sqlLike - [NOT] LIKE with '%' matching any characters and '_'
+// matching a single character.
+type sqlLike struct {
+	expr, pattern, escape sqlExpr
+	not                   bool
+
+	// Compiled pattern, cached while the pattern is the same.
+	lastPattern string
+	regexp      *regexp.Regexp
+}
+
+func (e *sqlLike) eval(record selectRecord) (interface{}, error) {
+	value, err := e.expr.eval(record)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	patternValue, err := e.pattern.eval(record)
+	if err != nil || patternValue == nil {
+		return nil, err
+	}
+	escape := ""
+	if e.escape != nil {
+		escapeValue, eErr := e.escape.eval(record)
+		if eErr != nil {
+			return nil, eErr
+		}
+		if escape = formatSQLValue(escapeValue); utf8.RuneCountInString(escape) != 1 {
+			return nil, fmt.Errorf("Escape must be a single character")
+		}
+	}
+	pattern := formatSQLValue(patternValue)
+	if e.regexp == nil || pattern+"\x00"+escape != e.lastPattern {
+		var expr bytes.Buffer
+		expr.WriteString("(?s)^")
+		escaped := false
+		for _, c := range pattern {
+			switch {
+			case escaped:
+				expr.WriteString(regexp.QuoteMeta(string(c)))
+				escaped = false
+			case escape != "" && string(c) == escape:
+				escaped = true
+			case c == '%':
+				expr.WriteString(".*")
+			case c == '_':
+				expr.WriteString(".")
+			default:
+				expr.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		}
+		expr.WriteString("$")
+		if e.regexp, err = regexp.Compile(expr.String()); err != nil {
+			return nil, err
+		}
+		e.lastPattern = pattern + "\x00" + escape
+	}
+	return e.regexp.MatchString(formatSQLValue(value)) != e.not, nil
+}
+
+// sqlBetween - [NOT] BETWEEN low AND high.
+type sqlBetween struct {
+	expr, low, high sqlExpr
+	not             bool
+}
+
+func (e *sqlBetween) eval(record selectRecord) (interface{}, error) {
+	var values [3]interface{}
+	for i, expr := range []sqlExpr{e.expr, e.low, e.high} {
+		value, err := expr.eval(record)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	low, ok := compareSQLValues(values[0], values[1])
+	if !ok {
+		return nil, nil
+	}
+	high, ok := compareSQLValues(values[0], values[2])
+	if !ok {
+		return nil, nil
+	}
+	return (low >= 0 && high <= 0) != e.not, nil
+}
+
+// sqlIn - [NOT] IN (list).
+type sqlIn struct {
+	expr sqlExpr
+	list []sqlExpr
+	not  bool
+}
+
+func (e *sqlIn) eval(record selectRecord) (interface{}, error) {
+	value, err := e.expr.eval(record)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	for _, expr := range e.list {
+		item, err := expr.eval(record)
+		if err != nil {
+			return nil, err
+		}
+		if cmp, ok := compareSQLValues(value, item); ok && cmp == 0 {
+			return !e.not, nil
+		}
+	}
+	return e.not, nil
+}
+
+// sqlCast - CAST(expr AS type).
+type sqlCast struct {
+	expr sqlExpr
+	typ  string
+}
+
+func (e *sqlCast) eval(record selectRecord) (interface{}, error) {
+	value, err := e.expr.eval(record)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	switch e.typ {
+	case "INT":
+		number, err := toSQLNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := number.(float64); ok {
+			return int64(f), nil
+		}
+		return number, nil
+	case "FLOAT":
+		number, err := toSQLNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := number.(int64); ok {
+			return float64(i), nil
+		}
+		return number, nil
+	case "BOOL":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("Unable to cast %v to BOOL", value)
+	}
+	return formatSQLValue(value), nil
+}
+
+// sqlFunction - a scalar function.
+type sqlFunction struct {
+	name string
+	args []sqlExpr
+}
+
+func (e *sqlFunction) eval(record selectRecord) (interface{}, error) {
+	var values []interface{}
+	for _, arg := range e.args {
+		value, err := arg.eval(record)
+		if err != nil {
+			return nil, err
+		}
+		if e.name == "COALESCE" && value != nil {
+			return value, nil
+		}
+		values = append(values, value)
+	}
+	if e.name == "COALESCE" || values[0] == nil {
+		return nil, nil
+	}
+	s := formatSQLValue(values[0])
+	switch e.name {
+	case "LOWER":
+		return strings.ToLower(s), nil
+	case "UPPER":
+		return strings.ToUpper(s), nil
+	case "TRIM":
+		return strings.TrimSpace(s), nil
+	}
+	return int64(utf8.RuneCountInString(s)), nil
+}
+
+// sqlAggregate - an aggregate function, accumulated over all matched
+// records.
+type sqlAggregate struct {
+	name string
+	// Argument, nil for COUNT(*).
+	arg sqlExpr
+
+	count int64
+	sum   interface{}
+	value interface{}
+}
+
+// accumulate - adds a matched record to the aggregate.
+func (e *sqlAggregate) accumulate(record selectRecord) error {
+	if e.arg == nil {
+		e.count++
+		return nil
+	}
+	value, err := e.arg.eval(record)
+	if err != nil || value == nil {
+		return err
+	}
+	e.count++
+	switch e.name {
+	case "SUM", "AVG":
+		number, err := toSQLNumber(value)
+		if err != nil {
+			return err
+		}
+		if e.sum == nil {
+			e.sum = number
+			return nil
+		}
+		e.sum, err = sqlArithmetic("+", e.sum, number)
+		return err
+	case "MIN", "MAX":
+		if number, err := toSQLNumber(value); err == nil {
+			value = number
+		}
+		if e.value == nil {
+			e.value = value
+			return nil
+		}
+		cmp, ok := compareSQLValues(value, e.value)
+		if !ok {
+			return fmt.Errorf("Unable to compare %v and %v", value, e.value)
+		}
+		if cmp < 0 && e.name == "MIN" || cmp > 0 && e.name == "MAX" {
+			e.value = value
+		}
+	}
+	return nil
+}
+
+func (e *sqlAggregate) eval(record selectRecord) (interface{}, error) {
+	switch e.name {
+	case "COUNT":
+		return e.count, nil
+	case "SUM":
+		return e.sum, nil
+	case "AVG":
+		if e.count == 0 {
+			return nil, nil
+		}
+		return toSQLFloat(e.sum) / float64(e.count), nil
+	}
+	return e.value, nil
+}
+
+// toSQLBool - converts a value to a nullable boolean.
+func toSQLBool(value interface{}) (*bool, error) {
+	if value == nil {
+		return nil, nil
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("Expected a boolean, found %v", value)
+	}
+	return &b, nil
+}
+
+// toSQLNumber - converts a value to int64 or float64, strings are
+// parsed since CSV fields are always strings.
+func toSQLNumber(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int64, float64:
+		return v, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("Unable to convert %v to a number", value)
+}
+
+// sqlArithmetic - applies an arithmetic operator, integers are
+// promoted to floats if either operand is a float.
+func sqlArithmetic(op string, left, right interface{}) (interface{}, error) {
+	l, err := toSQLNumber(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := toSQLNumber(right)
+	if err != nil {
+		return nil, err
+	}
+	li, lInt := l.(int64)
+	ri, rInt := r.(int64)
+	if lInt && rInt {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		}
+		if ri == 0 {
+			return nil, fmt.Errorf("Division by zero")
+		}
+		if op == "/" {
+			return li / ri, nil
+		}
+		return li % ri, nil
+	}
+	lf, rf := toSQLFloat(l), toSQLFloat(r)
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	}
+	if rf == 0 {
+		return nil, fmt.Errorf("Division by zero")
+	}
+	if op == "/" {
+		return lf / rf, nil
+	}
+	return math.Mod(lf, rf), nil
+}
+
+// toSQLFloat - converts an int64 or float64 to float64.
+func toSQLFloat(number interface{}) float64 {
+	if i, ok := number.(int64); ok {
+		return float64(i)
+	}
+	return number.(float64)
+}
+
+// compareSQLValues - compares two values, strings compared to numbers
+// are parsed as numbers. Returns false if the values are not
+// comparable.
+func compareSQLValues(left, right interface{}) (int, bool) {
+	_, lString := left.(string)
+	_, rString := right.(string)
+	if lString && rString {
+		return strings.Compare(left.(string), right.(string)), true
+	}
+	if lBool, ok := left.(bool); ok {
+		rBool, ok := right.(bool)
+		if !ok {
+			return 0, false
+		}
+		if lBool == rBool {
+			return 0, true
+		}
+		if rBool {
+			return -1, true
+		}
+		return 1, true
+	}
+	l, err := toSQLNumber(left)
+	if err != nil {
+		return 0, false
+	}
+	r, err := toSQLNumber(right)
+	if err != nil {
+		return 0, false
+	}
+	li, lInt := l.(int64)
+	ri, rInt := r.(int64)
+	if lInt && rInt {
+		switch {
+		case li < ri:
+			return -1, true
+		case li > ri:
+			return 1, true
+		}
+		return 0, true
+	}
+	lf, rf := toSQLFloat(l), toSQLFloat(r)
+	switch {
+	case lf < rf:
+		return -1, true
+	case lf > rf:
+		return 1, true
+	}
+	return 0, true
+}
+
+// formatSQLValue - formats a value as a string.
+func formatSQLValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	buf, _ := marshalJSONValue(value)
+	return string(buf)
+}
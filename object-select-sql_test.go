@@ -0,0 +1,175 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Tests validate parsing of supported and unsupported queries.
+func TestParseSelectQuery(t *testing.T) {
+	testCases := []struct {
+		query      string
+		shouldPass bool
+	}{
+		// Test case - 1.
+		// All columns.
+		{"SELECT * FROM S3Object", true},
+		// Test case - 2.
+		// All columns of an alias, with a path.
+		{"select s.* from s3object[*] as s;", true},
+		// Test case - 3.
+		// Expressions, aliases and a limit.
+		{"SELECT s.a + 1 AS b, UPPER(s.\"Name\") n FROM S3Object s WHERE s.a BETWEEN 1 AND 5 OR s.b IN ('x', 'y') LIMIT 10", true},
+		// Test case - 4.
+		// Aggregates.
+		{"SELECT COUNT(*), AVG(CAST(a AS FLOAT)) / 2 FROM S3Object WHERE a IS NOT NULL", true},
+		// Test case - 5.
+		// Other tables are not supported.
+		{"SELECT * FROM table", false},
+		// Test case - 6.
+		// Columns mixed with aggregates.
+		{"SELECT a, COUNT(*) FROM S3Object", false},
+		// Test case - 7.
+		// Aggregates in a filter.
+		{"SELECT a FROM S3Object WHERE COUNT(*) > 1", false},
+		// Test case - 8.
+		// Nested aggregates.
+		{"SELECT SUM(COUNT(*)) FROM S3Object", false},
+		// Test case - 9.
+		// Unknown function.
+		{"SELECT NOW() FROM S3Object", false},
+		// Test case - 10.
+		// Invalid limit.
+		{"SELECT * FROM S3Object LIMIT -1", false},
+		// Test case - 11.
+		// Unterminated string.
+		{"SELECT * FROM S3Object WHERE a = 'x", false},
+		// Test case - 12.
+		// Trailing tokens.
+		{"SELECT * FROM S3Object s t", false},
+		// Test case - 13.
+		// Missing projection.
+		{"SELECT FROM S3Object", false},
+	}
+	for i, testCase := range testCases {
+		_, err := parseSelectQuery(testCase.query)
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: Expected to pass, but failed with: <ERROR> %s", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: Expected to fail, but passed", i+1)
+		}
+	}
+}
+
+// Tests validate evaluation of expressions over a record.
+func TestSelectQueryEval(t *testing.T) {
+	decoder := json.NewDecoder(strings.NewReader(`{"name": "Alice", "age": 31, "score": 7.5, "tags": ["a", "b"], "address": {"city": "Paris"}, "none": null}`))
+	decoder.UseNumber()
+	record, err := readJSONValue(decoder)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+
+	testCases := []struct {
+		expr  string
+		value string
+	}{
+		// Test case - 1.
+		// Case insensitive unquoted names.
+		{"s.NAME", "Alice"},
+		// Test case - 2.
+		// Case sensitive quoted names.
+		{"s.\"NAME\"", "null"},
+		// Test case - 3.
+		// Nested fields.
+		{"s.address.city", "Paris"},
+		// Test case - 4.
+		// Integer arithmetic.
+		{"s.age * 2 - 1", "61"},
+		// Test case - 5.
+		// Float arithmetic.
+		{"s.age / 2.0 + s.score", "23"},
+		// Test case - 6.
+		// Integer division.
+		{"s.age / 2", "15"},
+		// Test case - 7.
+		// Comparison of strings with numbers.
+		{"'31' = s.age", "true"},
+		// Test case - 8.
+		// Comparison with NULL is NULL.
+		{"s.none = 1", "null"},
+		// Test case - 9.
+		// NULL in logical operators.
+		{"s.none = 1 OR s.age > 30", "true"},
+		// Test case - 10.
+		// NULL in logical operators.
+		{"s.none = 1 AND s.age > 30", "null"},
+		// Test case - 11.
+		// Missing fields are NULL.
+		{"s.missing IS NULL AND s.none IS NULL", "true"},
+		// Test case - 12.
+		// LIKE with both wildcards.
+		{"s.name LIKE 'A_i%'", "true"},
+		// Test case - 13.
+		// LIKE with an escaped wildcard.
+		{"'50%' LIKE '50!%' ESCAPE '!' AND NOT '500' LIKE '50!%' ESCAPE '!'", "true"},
+		// Test case - 14.
+		// BETWEEN and IN.
+		{"s.age BETWEEN 30 AND 40 AND s.name NOT IN ('Bob', 'Carol')", "true"},
+		// Test case - 15.
+		// Scalar functions.
+		{"LOWER(s.name) || CHAR_LENGTH(s.address.city) || COALESCE(s.none, 'x')", "alice5x"},
+		// Test case - 16.
+		// Casts.
+		{"CAST('42' AS INT) + CAST(CAST(1.9 AS INT) AS STRING)", "43"},
+		// Test case - 17.
+		// Nested values.
+		{"s.tags", "[\"a\",\"b\"]"},
+	}
+	for i, testCase := range testCases {
+		query, err := parseSelectQuery("SELECT " + testCase.expr + " FROM S3Object s")
+		if err != nil {
+			t.Fatalf("Test %d: Unexpected error: <ERROR> %s", i+1, err)
+		}
+		value, err := query.columns[0].expr.eval(record.(*jsonObject))
+		if err != nil {
+			t.Fatalf("Test %d: Unexpected error: <ERROR> %s", i+1, err)
+		}
+		formatted := formatSQLValue(value)
+		if value == nil {
+			formatted = "null"
+		}
+		if formatted != testCase.value {
+			t.Errorf("Test %d: Expected %s, but found %s", i+1, testCase.value, formatted)
+		}
+	}
+
+	// Evaluation errors.
+	for i, expr := range []string{"s.name + 1", "s.age / 0", "NOT s.name", "CAST(s.name AS BOOL)"} {
+		query, err := parseSelectQuery("SELECT " + expr + " FROM S3Object s")
+		if err != nil {
+			t.Fatalf("Test %d: Unexpected error: <ERROR> %s", i+1, err)
+		}
+		if _, err = query.columns[0].expr.eval(record.(*jsonObject)); err == nil {
+			t.Errorf("Test %d: Expected %s to fail", i+1, expr)
+		}
+	}
+}
@@ -0,0 +1,692 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// Maximum size of a select request.
+	maxSelectRequestSize = 256 * 1024
+
+	// Records are sent in events of about this size.
+	selectRecordsEventSize = 128 * 1024
+
+	// Progress is sent every time this many bytes are processed.
+	selectProgressInterval = 4 * 1024 * 1024
+)
+
+// CSVInput - CSV format of the queried object.
+type CSVInput struct {
+	FileHeaderInfo             string
+	Comments                   string
+	QuoteEscapeCharacter       string
+	RecordDelimiter            string
+	FieldDelimiter             string
+	QuoteCharacter             string
+	AllowQuotedRecordDelimiter bool
+}
+
+// JSONInput - JSON format of the queried object.
+type JSONInput struct {
+	Type string
+}
+
+// CSVOutput - CSV format of returned records.
+type CSVOutput struct {
+	QuoteFields          string
+	QuoteEscapeCharacter string
+	RecordDelimiter      string
+	FieldDelimiter       string
+	QuoteCharacter       string
+}
+
+// JSONOutput - JSON format of returned records.
+type JSONOutput struct {
+	RecordDelimiter string
+}
+
+// SelectObjectContentRequest - select object content request.
+type SelectObjectContentRequest struct {
+	XMLName         xml.Name `xml:"SelectObjectContentRequest" json:"-"`
+	Expression      string
+	ExpressionType  string
+	RequestProgress struct {
+		Enabled bool
+	}
+	InputSerialization struct {
+		CompressionType string
+		CSV             *CSVInput
+		JSON            *JSONInput
+	}
+	OutputSerialization struct {
+		CSV  *CSVOutput
+		JSON *JSONOutput
+	}
+}
+
+// selectRequest - a validated select request.
+type selectRequest struct {
+	query    *selectQuery
+	progress bool
+	gzip     bool
+
+	// Input format, one of csvInput or jsonInput is set.
+	csvInput  *CSVInput
+	jsonInput *JSONInput
+
+	// Output format, one of csvOutput or jsonOutput is set.
+	csvOutput  *CSVOutput
+	jsonOutput *JSONOutput
+}
+
+// isSingleRune - returns true if s is a single character.
+func isSingleRune(s string) bool {
+	return utf8.RuneCountInString(s) == 1
+}
+
+// parseSelectRequest - parses and validates a select request, default
+// values of the request are filled in.
+func parseSelectRequest(body []byte) (*selectRequest, APIErrorCode) {
+	var request SelectObjectContentRequest
+	if err := xml.Unmarshal(body, &request); err != nil {
+		return nil, ErrMalformedXML
+	}
+	if request.ExpressionType != "SQL" {
+		return nil, ErrInvalidExpressionType
+	}
+	req := &selectRequest{progress: request.RequestProgress.Enabled}
+
+	input := request.InputSerialization
+	switch strings.ToUpper(input.CompressionType) {
+	case "", "NONE":
+	case "GZIP":
+		req.gzip = true
+	default:
+		return nil, ErrInvalidCompressionFormat
+	}
+	if (input.CSV == nil) == (input.JSON == nil) {
+		return nil, ErrInvalidDataSource
+	}
+	if csvInput := input.CSV; csvInput != nil {
+		switch strings.ToUpper(csvInput.FileHeaderInfo) {
+		case "", "NONE", "IGNORE", "USE":
+			csvInput.FileHeaderInfo = strings.ToUpper(csvInput.FileHeaderInfo)
+		default:
+			return nil, ErrInvalidFileHeaderInfo
+		}
+		if csvInput.FieldDelimiter == "" {
+			csvInput.FieldDelimiter = ","
+		}
+		if !isSingleRune(csvInput.FieldDelimiter) ||
+			csvInput.Comments != "" && !isSingleRune(csvInput.Comments) {
+			return nil, ErrInvalidRequestParameter
+		}
+		// Only the standard quote and record delimiters are supported.
+		switch {
+		case csvInput.QuoteCharacter != "" && csvInput.QuoteCharacter != "\"",
+			csvInput.QuoteEscapeCharacter != "" && csvInput.QuoteEscapeCharacter != "\"",
+			csvInput.RecordDelimiter != "" && csvInput.RecordDelimiter != "\n" && csvInput.RecordDelimiter != "\r\n":
+			return nil, ErrInvalidRequestParameter
+		}
+		req.csvInput = csvInput
+	}
+	if jsonInput := input.JSON; jsonInput != nil {
+		switch strings.ToUpper(jsonInput.Type) {
+		case "", "DOCUMENT", "LINES":
+		default:
+			return nil, ErrInvalidJSONType
+		}
+		req.jsonInput = jsonInput
+	}
+
+	output := request.OutputSerialization
+	if (output.CSV == nil) == (output.JSON == nil) {
+		return nil, ErrInvalidRequestParameter
+	}
+	if csvOutput := output.CSV; csvOutput != nil {
+		switch strings.ToUpper(csvOutput.QuoteFields) {
+		case "", "ASNEEDED", "ALWAYS":
+			csvOutput.QuoteFields = strings.ToUpper(csvOutput.QuoteFields)
+		default:
+			return nil, ErrInvalidQuoteFields
+		}
+		if csvOutput.FieldDelimiter == "" {
+			csvOutput.FieldDelimiter = ","
+		}
+		if csvOutput.RecordDelimiter == "" {
+			csvOutput.RecordDelimiter = "\n"
+		}
+		if csvOutput.QuoteCharacter == "" {
+			csvOutput.QuoteCharacter = "\""
+		}
+		if csvOutput.QuoteEscapeCharacter == "" {
+			csvOutput.QuoteEscapeCharacter = csvOutput.QuoteCharacter
+		}
+		if !isSingleRune(csvOutput.QuoteCharacter) || !isSingleRune(csvOutput.QuoteEscapeCharacter) {
+			return nil, ErrInvalidRequestParameter
+		}
+		req.csvOutput = csvOutput
+	}
+	if jsonOutput := output.JSON; jsonOutput != nil {
+		if jsonOutput.RecordDelimiter == "" {
+			jsonOutput.RecordDelimiter = "\n"
+		}
+		req.jsonOutput = jsonOutput
+	}
+
+	query, err := parseSelectQuery(request.Expression)
+	if err != nil {
+		errorIf(err, "Unable to parse select expression.")
+		return nil, ErrUnsupportedSyntax
+	}
+	req.query = query
+	return req, ErrNone
+}
+
+// selectError - an error of a running select, sent as an error event.
+type selectError struct {
+	code    string
+	message string
+}
+
+func (e selectError) Error() string {
+	return e.message
+}
+
+// csvRecord - a record of a CSV object.
+type csvRecord struct {
+	// Column names of the header, nil if the object has no header.
+	header []string
+	values []string
+}
+
+// get - returns a field by header name, or by position as _1, _2 ...
+func (r csvRecord) get(path []string, exact []bool) interface{} {
+	if len(path) != 1 {
+		return nil
+	}
+	name := path[0]
+	for i, column := range r.header {
+		if column == name && i < len(r.values) {
+			return r.values[i]
+		}
+	}
+	if !exact[0] {
+		for i, column := range r.header {
+			if strings.EqualFold(column, name) && i < len(r.values) {
+				return r.values[i]
+			}
+		}
+	}
+	if strings.HasPrefix(name, "_") {
+		if i, err := strconv.Atoi(name[1:]); err == nil && i >= 1 && i <= len(r.values) {
+			return r.values[i-1]
+		}
+	}
+	return nil
+}
+
+func (r csvRecord) fields() (names []string, values []interface{}) {
+	for i, value := range r.values {
+		name := fmt.Sprintf("_%d", i+1)
+		if i < len(r.header) {
+			name = r.header[i]
+		}
+		names = append(names, name)
+		values = append(values, value)
+	}
+	return names, values
+}
+
+// jsonObject - a JSON object which keeps the order of its keys.
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// get - returns a nested value, unquoted keys match case insensitively.
+func (o *jsonObject) get(path []string, exact []bool) interface{} {
+	var value interface{} = o
+	for i, key := range path {
+		object, ok := value.(*jsonObject)
+		if !ok {
+			return nil
+		}
+		var found bool
+		if value, found = object.values[key]; found || exact[i] {
+			continue
+		}
+		for _, k := range object.keys {
+			if strings.EqualFold(k, key) {
+				value, found = object.values[k], true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	return value
+}
+
+func (o *jsonObject) fields() (names []string, values []interface{}) {
+	for _, key := range o.keys {
+		names = append(names, key)
+		values = append(values, o.values[key])
+	}
+	return names, values
+}
+
+// readJSONValue - reads the next JSON value, objects are read as
+// *jsonObject and numbers as int64 or float64.
+func readJSONValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	value, err := readJSONToken(decoder, token)
+	if err == io.EOF {
+		// Value is incomplete.
+		err = io.ErrUnexpectedEOF
+	}
+	return value, err
+}
+
+// readJSONToken - reads the rest of a value starting with token.
+func readJSONToken(decoder *json.Decoder, token json.Token) (value interface{}, err error) {
+	switch v := token.(type) {
+	case json.Delim:
+		if v == '[' {
+			array := []interface{}{}
+			for decoder.More() {
+				value, err := readJSONValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			}
+			_, err = decoder.Token()
+			return array, err
+		}
+		object := &jsonObject{values: make(map[string]interface{})}
+		for decoder.More() {
+			token, err = decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := token.(string)
+			value, err := readJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := object.values[key]; !ok {
+				object.keys = append(object.keys, key)
+			}
+			object.values[key] = value
+		}
+		_, err = decoder.Token()
+		return object, err
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	}
+	return token, nil
+}
+
+// marshalJSONValue - encodes a value read by readJSONValue or
+// computed by a query.
+func marshalJSONValue(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := writeJSONValue(&buf, value)
+	return buf.Bytes(), err
+}
+
+func writeJSONValue(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case *jsonObject:
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONValue(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeJSONValue(buf, v.values[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONValue(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case float64:
+		// Floats are written as computed, not in exponent form.
+		buf.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+		return nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	buf.Write(encoded)
+	return nil
+}
+
+// selectReader - reads records of the queried object.
+type selectReader interface {
+	// Returns the next record, io.EOF after the last record.
+	read() (selectRecord, error)
+}
+
+// csvSelectReader - reads records of a CSV object.
+type csvSelectReader struct {
+	reader     *csv.Reader
+	headerInfo string
+	header     []string
+	started    bool
+}
+
+func newCSVSelectReader(r io.Reader, input *CSVInput) *csvSelectReader {
+	reader := csv.NewReader(r)
+	reader.Comma, _ = utf8.DecodeRuneInString(input.FieldDelimiter)
+	if input.Comments != "" {
+		reader.Comment, _ = utf8.DecodeRuneInString(input.Comments)
+	}
+	reader.FieldsPerRecord = -1
+	return &csvSelectReader{reader: reader, headerInfo: input.FileHeaderInfo}
+}
+
+func (r *csvSelectReader) read() (selectRecord, error) {
+	values, err := r.reader.Read()
+	if err == nil && !r.started {
+		r.started = true
+		switch r.headerInfo {
+		case "USE":
+			r.header = values
+			values, err = r.reader.Read()
+		case "IGNORE":
+			values, err = r.reader.Read()
+		}
+	}
+	if err != nil {
+		if _, ok := err.(*csv.ParseError); ok {
+			return nil, selectError{"CSVParsingError", err.Error()}
+		}
+		return nil, err
+	}
+	return csvRecord{header: r.header, values: values}, nil
+}
+
+// jsonSelectReader - reads records of a JSON object, documents and
+// lines are both read as a stream of objects.
+type jsonSelectReader struct {
+	decoder *json.Decoder
+}
+
+func newJSONSelectReader(r io.Reader) *jsonSelectReader {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return &jsonSelectReader{decoder: decoder}
+}
+
+func (r *jsonSelectReader) read() (selectRecord, error) {
+	value, err := readJSONValue(r.decoder)
+	switch err.(type) {
+	case nil:
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return nil, selectError{"JSONParsingError", err.Error()}
+	default:
+		if err == io.ErrUnexpectedEOF {
+			return nil, selectError{"JSONParsingError", "Unexpected end of JSON input"}
+		}
+		return nil, err
+	}
+	object, ok := value.(*jsonObject)
+	if !ok {
+		return nil, selectError{"JSONParsingError", "JSON record is not an object"}
+	}
+	return object, nil
+}
+
+// selectWriter - formats returned records.
+type selectWriter interface {
+	write(buf *bytes.Buffer, names []string, values []interface{}) error
+}
+
+// csvSelectWriter - formats records as CSV.
+type csvSelectWriter struct {
+	output *CSVOutput
+}
+
+func (w csvSelectWriter) write(buf *bytes.Buffer, names []string, values []interface{}) error {
+	for i, value := range values {
+		if i > 0 {
+			buf.WriteString(w.output.FieldDelimiter)
+		}
+		field := formatSQLValue(value)
+		if w.output.QuoteFields != "ALWAYS" && !strings.Contains(field, w.output.FieldDelimiter) &&
+			!strings.Contains(field, w.output.QuoteCharacter) && !strings.Contains(field, w.output.RecordDelimiter) &&
+			!strings.ContainsAny(field, "\r\n") {
+			buf.WriteString(field)
+			continue
+		}
+		buf.WriteString(w.output.QuoteCharacter)
+		buf.WriteString(strings.Replace(field, w.output.QuoteCharacter, w.output.QuoteEscapeCharacter+w.output.QuoteCharacter, -1))
+		buf.WriteString(w.output.QuoteCharacter)
+	}
+	buf.WriteString(w.output.RecordDelimiter)
+	return nil
+}
+
+// jsonSelectWriter - formats records as JSON objects.
+type jsonSelectWriter struct {
+	output *JSONOutput
+}
+
+func (w jsonSelectWriter) write(buf *bytes.Buffer, names []string, values []interface{}) error {
+	object := &jsonObject{keys: names, values: make(map[string]interface{})}
+	for i, name := range names {
+		object.values[name] = values[i]
+	}
+	if err := writeJSONValue(buf, object); err != nil {
+		return err
+	}
+	buf.WriteString(w.output.RecordDelimiter)
+	return nil
+}
+
+// countingReader - counts bytes read.
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// gzipSelectReader - reports errors of compressed data as select
+// errors, errors of the underlying reader are returned as is.
+type gzipSelectReader struct {
+	reader *gzip.Reader
+}
+
+func (r gzipSelectReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	switch err.(type) {
+	case flate.CorruptInputError:
+		return n, selectError{"InvalidCompressionFormat", err.Error()}
+	}
+	if err == gzip.ErrChecksum || err == gzip.ErrHeader || err == io.ErrUnexpectedEOF {
+		return n, selectError{"InvalidCompressionFormat", err.Error()}
+	}
+	return n, err
+}
+
+// errSelectDone - stops reading the object once the limit is reached.
+var errSelectDone = errors.New("select is done")
+
+// execute - runs the query over an object and writes the results as
+// events. Records are read as the object is streamed from objAPI.
+func (req *selectRequest) execute(objAPI ObjectLayer, bucket, object string, size int64, events *selectEventWriter) error {
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.CloseWithError(objAPI.GetObject(bucket, object, 0, size, pipeWriter))
+	}()
+	defer pipeReader.CloseWithError(errSelectDone)
+
+	scanned := &countingReader{reader: pipeReader}
+	var data io.Reader = scanned
+	if req.gzip {
+		gzipReader, err := gzip.NewReader(scanned)
+		if err != nil {
+			if err == gzip.ErrHeader || err == io.EOF || err == io.ErrUnexpectedEOF {
+				return selectError{"InvalidCompressionFormat", "The file is not in a supported compression format."}
+			}
+			return err
+		}
+		data = gzipSelectReader{reader: gzipReader}
+	}
+	processed := &countingReader{reader: data}
+	var reader selectReader
+	if req.csvInput != nil {
+		reader = newCSVSelectReader(processed, req.csvInput)
+	} else {
+		reader = newJSONSelectReader(processed)
+	}
+	var writer selectWriter = jsonSelectWriter{req.jsonOutput}
+	if req.csvOutput != nil {
+		writer = csvSelectWriter{req.csvOutput}
+	}
+
+	query := req.query
+	var buf bytes.Buffer
+	var returned, matched, lastProgress int64
+	stats := func() selectStats {
+		return selectStats{BytesScanned: scanned.n, BytesProcessed: processed.n, BytesReturned: returned}
+	}
+	// Sends buffered records.
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		returned += int64(buf.Len())
+		err := events.writeRecords(buf.Bytes())
+		buf.Reset()
+		return err
+	}
+	// Projects and buffers a record.
+	project := func(record selectRecord) error {
+		if query.columns == nil {
+			names, values := record.fields()
+			return writer.write(&buf, names, values)
+		}
+		names := make([]string, len(query.columns))
+		values := make([]interface{}, len(query.columns))
+		for i, column := range query.columns {
+			value, err := column.expr.eval(record)
+			if err != nil {
+				return selectError{"InvalidArgument", err.Error()}
+			}
+			names[i], values[i] = column.name, value
+		}
+		return writer.write(&buf, names, values)
+	}
+
+	for query.limit < 0 || matched < query.limit {
+		record, err := reader.read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if req.progress && processed.n-lastProgress >= selectProgressInterval {
+			lastProgress = processed.n
+			if err = events.writeProgress(stats()); err != nil {
+				return err
+			}
+		}
+		if query.where != nil {
+			value, err := query.where.eval(record)
+			if err != nil {
+				return selectError{"InvalidArgument", err.Error()}
+			}
+			if b, ok := value.(bool); !ok || !b {
+				continue
+			}
+		}
+		matched++
+		if len(query.aggregates) > 0 {
+			for _, aggregate := range query.aggregates {
+				if err = aggregate.accumulate(record); err != nil {
+					return selectError{"InvalidArgument", err.Error()}
+				}
+			}
+			continue
+		}
+		if err = project(record); err != nil {
+			return err
+		}
+		if buf.Len() >= selectRecordsEventSize {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(query.aggregates) > 0 {
+		if err := project(nil); err != nil {
+			return err
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if err := events.writeStats(stats()); err != nil {
+		return err
+	}
+	return events.writeEnd()
+}
@@ -0,0 +1,260 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+)
+
+// selectTestEvent - a decoded event stream message.
+type selectTestEvent struct {
+	headers map[string]string
+	payload []byte
+}
+
+// decodeSelectEvents - decodes event stream messages, verifying their
+// lengths and checksums.
+func decodeSelectEvents(r io.Reader) (events []selectTestEvent, err error) {
+	for {
+		prelude := make([]byte, 12)
+		if _, err = io.ReadFull(r, prelude); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		totalLength := binary.BigEndian.Uint32(prelude[0:4])
+		headersLength := binary.BigEndian.Uint32(prelude[4:8])
+		if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+			return nil, errors.New("prelude checksum mismatch")
+		}
+		message := make([]byte, totalLength)
+		copy(message, prelude)
+		if _, err = io.ReadFull(r, message[12:]); err != nil {
+			return nil, err
+		}
+		if crc32.ChecksumIEEE(message[:totalLength-4]) != binary.BigEndian.Uint32(message[totalLength-4:]) {
+			return nil, errors.New("message checksum mismatch")
+		}
+		event := selectTestEvent{headers: make(map[string]string)}
+		headers := message[12 : 12+headersLength]
+		for len(headers) > 0 {
+			nameLength := int(headers[0])
+			name := string(headers[1 : 1+nameLength])
+			if headers[1+nameLength] != eventStreamStringHeader {
+				return nil, fmt.Errorf("unexpected header type %d", headers[1+nameLength])
+			}
+			valueLength := int(binary.BigEndian.Uint16(headers[2+nameLength:]))
+			event.headers[name] = string(headers[4+nameLength : 4+nameLength+valueLength])
+			headers = headers[4+nameLength+valueLength:]
+		}
+		event.payload = message[12+headersLength : totalLength-4]
+		events = append(events, event)
+	}
+}
+
+// selectTestRequest - returns a select request body.
+func selectTestRequest(expression, input, output string) string {
+	return "<SelectObjectContentRequest><Expression>" + expression + "</Expression><ExpressionType>SQL</ExpressionType>" +
+		"<InputSerialization>" + input + "</InputSerialization><OutputSerialization>" + output + "</OutputSerialization>" +
+		"</SelectObjectContentRequest>"
+}
+
+// Tests validate select requests are rejected with the right errors.
+func TestParseSelectRequest(t *testing.T) {
+	csvInput := "<CSV><FileHeaderInfo>USE</FileHeaderInfo></CSV>"
+	csvOutput := "<CSV/>"
+	testCases := []struct {
+		request string
+		s3Error APIErrorCode
+	}{
+		// Test case - 1.
+		// Valid request.
+		{selectTestRequest("SELECT * FROM S3Object", csvInput, csvOutput), ErrNone},
+		// Test case - 2.
+		// Malformed XML.
+		{"<SelectObjectContentRequest>", ErrMalformedXML},
+		// Test case - 3.
+		// Unsupported expression type.
+		{strings.Replace(selectTestRequest("SELECT * FROM S3Object", csvInput, csvOutput), ">SQL<", ">XPath<", 1), ErrInvalidExpressionType},
+		// Test case - 4.
+		// Unsupported compression.
+		{selectTestRequest("SELECT * FROM S3Object", "<CompressionType>BZIP2</CompressionType>"+csvInput, csvOutput), ErrInvalidCompressionFormat},
+		// Test case - 5.
+		// Missing input format.
+		{selectTestRequest("SELECT * FROM S3Object", "", csvOutput), ErrInvalidDataSource},
+		// Test case - 6.
+		// Invalid file header info.
+		{selectTestRequest("SELECT * FROM S3Object", "<CSV><FileHeaderInfo>FIRST</FileHeaderInfo></CSV>", csvOutput), ErrInvalidFileHeaderInfo},
+		// Test case - 7.
+		// Invalid JSON type.
+		{selectTestRequest("SELECT * FROM S3Object", "<JSON><Type>ARRAY</Type></JSON>", csvOutput), ErrInvalidJSONType},
+		// Test case - 8.
+		// Invalid quote fields.
+		{selectTestRequest("SELECT * FROM S3Object", csvInput, "<CSV><QuoteFields>NEVER</QuoteFields></CSV>"), ErrInvalidQuoteFields},
+		// Test case - 9.
+		// Unsupported quote character.
+		{selectTestRequest("SELECT * FROM S3Object", "<CSV><QuoteCharacter>'</QuoteCharacter></CSV>", csvOutput), ErrInvalidRequestParameter},
+		// Test case - 10.
+		// Invalid expression.
+		{selectTestRequest("SELECT * FROM table", csvInput, csvOutput), ErrUnsupportedSyntax},
+	}
+	for i, testCase := range testCases {
+		if _, s3Error := parseSelectRequest([]byte(testCase.request)); s3Error != testCase.s3Error {
+			t.Errorf("Test %d: Expected error %v, but found %v", i+1, testCase.s3Error, s3Error)
+		}
+	}
+}
+
+// Wrapper for calling SelectObjectContent tests for both XL multiple disks and single node setup.
+func TestSelectObjectContent(t *testing.T) {
+	ExecObjectLayerTest(t, testSelectObjectContent)
+}
+
+// Tests validate queries over CSV and JSON objects, compressed or not.
+func testSelectObjectContent(obj ObjectLayer, instanceType string, t *testing.T) {
+	bucket := getRandomBucketName()
+	if err := obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	csvData := "name,city,age\nalice,paris,31\nbob,\"new york, ny\",25\ncarol,berlin,42\n"
+	jsonData := "{\"name\":\"alice\",\"age\":31,\"address\":{\"city\":\"paris\"}}\n" +
+		"{\"name\":\"bob\",\"age\":25,\"address\":{\"city\":\"new york\"}}\n" +
+		"{\"name\":\"carol\",\"age\":42.5}\n"
+	var gzipData bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipData)
+	gzipWriter.Write([]byte(csvData))
+	gzipWriter.Close()
+	objects := map[string][]byte{
+		"people.csv":    []byte(csvData),
+		"people.csv.gz": gzipData.Bytes(),
+		"people.json":   []byte(jsonData),
+		"broken.json":   []byte("{\"name\":\"alice\"}\n{\"name\":"),
+	}
+	for object, data := range objects {
+		if _, err := obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), nil); err != nil {
+			t.Fatalf("%s: <ERROR> %s", instanceType, err)
+		}
+	}
+
+	csvInput := "<CSV><FileHeaderInfo>USE</FileHeaderInfo></CSV>"
+	jsonInput := "<JSON><Type>LINES</Type></JSON>"
+	csvOutput := "<CSV/>"
+	jsonOutput := "<JSON/>"
+	testCases := []struct {
+		object     string
+		expression string
+		input      string
+		output     string
+		records    string
+		// Error code of an error event, empty if the select succeeds.
+		errorCode string
+	}{
+		// Test case - 1.
+		// All records of a CSV object.
+		{"people.csv", "SELECT * FROM S3Object", csvInput, csvOutput, "alice,paris,31\nbob,\"new york, ny\",25\ncarol,berlin,42\n", ""},
+		// Test case - 2.
+		// Filter and projection by header names.
+		{"people.csv", "SELECT s.name, s.age FROM S3Object s WHERE CAST(s.age AS INT) > 30", csvInput, csvOutput, "alice,31\ncarol,42\n", ""},
+		// Test case - 3.
+		// Positional columns without a header.
+		{"people.csv", "SELECT _1 FROM S3Object WHERE _2 LIKE 'new%'", "<CSV/>", csvOutput, "bob\n", ""},
+		// Test case - 4.
+		// Header ignored, limit.
+		{"people.csv", "SELECT s._1 FROM S3Object s LIMIT 2", "<CSV><FileHeaderInfo>IGNORE</FileHeaderInfo></CSV>", csvOutput, "alice\nbob\n", ""},
+		// Test case - 5.
+		// Compressed object.
+		{"people.csv.gz", "SELECT name FROM S3Object WHERE city = 'berlin'", "<CompressionType>GZIP</CompressionType>" + csvInput, csvOutput, "carol\n", ""},
+		// Test case - 6.
+		// CSV records as JSON.
+		{"people.csv", "SELECT name, age FROM S3Object WHERE name = 'bob'", csvInput, jsonOutput, "{\"name\":\"bob\",\"age\":\"25\"}\n", ""},
+		// Test case - 7.
+		// Nested JSON fields.
+		{"people.json", "SELECT s.name, s.address.city AS city FROM S3Object s WHERE s.address.city IS NOT NULL", jsonInput, jsonOutput,
+			"{\"name\":\"alice\",\"city\":\"paris\"}\n{\"name\":\"bob\",\"city\":\"new york\"}\n", ""},
+		// Test case - 8.
+		// All fields of JSON records in order.
+		{"people.json", "SELECT * FROM S3Object s WHERE s.age > 40", jsonInput, jsonOutput, "{\"name\":\"carol\",\"age\":42.5}\n", ""},
+		// Test case - 9.
+		// Aggregates.
+		{"people.json", "SELECT COUNT(*), SUM(s.age), MAX(s.name) FROM S3Object s", jsonInput, csvOutput, "3,98.5,carol\n", ""},
+		// Test case - 10.
+		// Aggregates over a filter without matches.
+		{"people.csv", "SELECT COUNT(*) FROM S3Object WHERE city = 'rome'", csvInput, csvOutput, "0\n", ""},
+		// Test case - 11.
+		// Invalid JSON.
+		{"broken.json", "SELECT * FROM S3Object", jsonInput, jsonOutput, "", "JSONParsingError"},
+		// Test case - 12.
+		// Uncompressed object read as compressed.
+		{"people.csv", "SELECT * FROM S3Object", "<CompressionType>GZIP</CompressionType>" + csvInput, csvOutput, "", "InvalidCompressionFormat"},
+		// Test case - 13.
+		// Evaluation error.
+		{"people.csv", "SELECT SUM(name) FROM S3Object", csvInput, csvOutput, "", "InvalidArgument"},
+	}
+	for i, testCase := range testCases {
+		req, s3Error := parseSelectRequest([]byte(selectTestRequest(testCase.expression, testCase.input, testCase.output)))
+		if s3Error != ErrNone {
+			t.Fatalf("%s: Test %d: Unexpected error %v", instanceType, i+1, s3Error)
+		}
+		var buf bytes.Buffer
+		size := int64(len(objects[testCase.object]))
+		err := req.execute(obj, bucket, testCase.object, size, &selectEventWriter{writer: &buf})
+		if testCase.errorCode != "" {
+			sErr, ok := err.(selectError)
+			if !ok || sErr.code != testCase.errorCode {
+				t.Errorf("%s: Test %d: Expected error %s, but found %v", instanceType, i+1, testCase.errorCode, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Test %d: <ERROR> %s", instanceType, i+1, err)
+		}
+		events, err := decodeSelectEvents(&buf)
+		if err != nil {
+			t.Fatalf("%s: Test %d: <ERROR> %s", instanceType, i+1, err)
+		}
+		var records string
+		for _, event := range events[:len(events)-2] {
+			if event.headers[":event-type"] != "Records" {
+				t.Errorf("%s: Test %d: Expected Records event, but found %v", instanceType, i+1, event.headers)
+			}
+			records += string(event.payload)
+		}
+		if records != testCase.records {
+			t.Errorf("%s: Test %d: Expected records %q, but found %q", instanceType, i+1, testCase.records, records)
+		}
+		// Reading stops early at the limit.
+		var stats selectStats
+		if err = xml.Unmarshal(events[len(events)-2].payload, &stats); err != nil {
+			t.Fatalf("%s: Test %d: <ERROR> %s", instanceType, i+1, err)
+		}
+		if stats.BytesScanned == 0 || stats.BytesScanned > size || stats.BytesReturned != int64(len(records)) {
+			t.Errorf("%s: Test %d: Expected %d bytes returned of at most %d scanned, but found %+v", instanceType, i+1, len(records), size, stats)
+		}
+		if end := events[len(events)-1]; end.headers[":event-type"] != "End" {
+			t.Errorf("%s: Test %d: Expected End event, but found %v", instanceType, i+1, end.headers)
+		}
+	}
+}
@@ -35,6 +35,8 @@ const (
 	mpartMetaPrefix = "multipart"
 	// Tmp meta prefix.
 	tmpMetaPrefix = "tmp"
+	// Bucket meta prefix, used to save object metadata for fs.
+	bucketMetaPrefix = "buckets"
 )
 
 // validBucket regexp.
@@ -151,6 +153,12 @@ func completeMultipartMD5(parts ...completePart) (string, error) {
 	return s3MD5, nil
 }
 
+// isMultipartMD5 - returns true if md5sum is an s3 compatible md5sum
+// of a complete multipart transaction.
+func isMultipartMD5(md5Sum string) bool {
+	return strings.Contains(md5Sum, "-")
+}
+
 // byBucketName is a collection satisfying sort.Interface.
 type byBucketName []BucketInfo
 
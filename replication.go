@@ -0,0 +1,464 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Replication status metadata key, also the response header.
+	replicationStatusKey = "x-amz-replication-status"
+
+	// Replication queue directory, under the config path.
+	replicationQueueDir = "replication"
+
+	// Number of replication workers.
+	replicationWorkers = 4
+
+	// Size of each replication worker queue.
+	replicationQueueSize = 10000
+
+	// Maximum number of attempts before replication is marked failed.
+	replicationMaxAttempts = 10
+
+	// Maximum interval between two replication attempts.
+	replicationMaxRetryInterval = 5 * time.Minute
+
+	// Interval of handing queued objects, which didn't fit in their
+	// worker queue, to the workers again.
+	replicationRescanInterval = 1 * time.Minute
+)
+
+// Replication status of an object.
+const (
+	replicationPending   = "PENDING"
+	replicationCompleted = "COMPLETED"
+	replicationFailed    = "FAILED"
+	replicationReplica   = "REPLICA"
+)
+
+// replicationOp - an object waiting to be replicated. Replication
+// always syncs the current state of the object to the destination,
+// the object is copied if it exists and deleted otherwise.
+type replicationOp struct {
+	ID       string `json:"id"`
+	Bucket   string `json:"bucket"`
+	Object   string `json:"object"`
+	Attempts int    `json:"attempts"`
+}
+
+// replicationQueue - persistent queue of objects waiting to be
+// replicated. Every queued object is saved in the queue directory
+// until it is replicated, objects which failed all attempts are
+// moved to the failed directory.
+type replicationQueue struct {
+	objAPI    ObjectLayer
+	queuePath string
+
+	mutex *sync.Mutex
+	// Number of queued operations per object.
+	pending map[string]int
+	// Failed operation per object.
+	failed map[string]string
+	// Queued operations not handed to a worker yet, per operation id.
+	waiting map[string]replicationOp
+
+	// Each object is always handled by the same worker, which
+	// keeps replication of an object in order.
+	opChs []chan replicationOp
+}
+
+// Global replication queue, initialized by the server.
+var globalReplication *replicationQueue
+
+// initReplication - initialize global replication queue under config path.
+func initReplication(objAPI ObjectLayer) error {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return err
+	}
+	globalReplication, err = newReplicationQueue(objAPI, filepath.Join(configPath, replicationQueueDir))
+	return err
+}
+
+// newReplicationQueue - initialize a new replication queue, loads
+// all the previously queued objects and starts replication workers.
+func newReplicationQueue(objAPI ObjectLayer, queuePath string) (*replicationQueue, error) {
+	q := &replicationQueue{
+		objAPI:    objAPI,
+		queuePath: queuePath,
+		mutex:     &sync.Mutex{},
+		pending:   make(map[string]int),
+		failed:    make(map[string]string),
+		waiting:   make(map[string]replicationOp),
+	}
+	for _, dir := range []string{q.queueDir(), q.failedDir()} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	// Load failed operations.
+	failedOps, err := q.loadOps(q.failedDir())
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range failedOps {
+		q.failed[pathJoin(op.Bucket, op.Object)] = op.ID
+	}
+
+	// Load queued operations.
+	queuedOps, err := q.loadOps(q.queueDir())
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range queuedOps {
+		q.pending[pathJoin(op.Bucket, op.Object)]++
+	}
+
+	for i := 0; i < replicationWorkers; i++ {
+		opCh := make(chan replicationOp, replicationQueueSize)
+		q.opChs = append(q.opChs, opCh)
+		go q.worker(opCh)
+	}
+	q.mutex.Lock()
+	for _, op := range queuedOps {
+		q.dispatch(op)
+	}
+	q.mutex.Unlock()
+	go q.rescan()
+	return q, nil
+}
+
+// queueDir - directory of queued operations.
+func (q *replicationQueue) queueDir() string {
+	return filepath.Join(q.queuePath, "queue")
+}
+
+// failedDir - directory of failed operations.
+func (q *replicationQueue) failedDir() string {
+	return filepath.Join(q.queuePath, "failed")
+}
+
+// loadOps - loads all operations saved in a directory.
+func (q *replicationQueue) loadOps(dir string) ([]replicationOp, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ops []replicationOp
+	for _, entry := range entries {
+		buf, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var op replicationOp
+		if err = json.Unmarshal(buf, &op); err != nil {
+			// Skip and remove corrupted entries.
+			errorIf(err, "Unable to parse replication entry %s.", entry.Name())
+			os.Remove(filepath.Join(dir, entry.Name()))
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// saveOp - saves operation in a directory.
+func (q *replicationQueue) saveOp(dir string, op replicationOp) error {
+	buf, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, op.ID+".json"), buf, 0600)
+}
+
+// opCh - returns the worker channel of an object.
+func (q *replicationQueue) opCh(op replicationOp) chan replicationOp {
+	h := fnv.New32a()
+	h.Write([]byte(pathJoin(op.Bucket, op.Object)))
+	return q.opChs[h.Sum32()%uint32(len(q.opChs))]
+}
+
+// dispatch - hands the operation to its worker without blocking, if
+// the worker queue is full the operation waits for the next rescan.
+// Should be called with mutex held.
+func (q *replicationQueue) dispatch(op replicationOp) {
+	select {
+	case q.opCh(op) <- op:
+		delete(q.waiting, op.ID)
+	default:
+		q.waiting[op.ID] = op
+	}
+}
+
+// rescan - periodically hands waiting operations to their workers.
+func (q *replicationQueue) rescan() {
+	for range time.Tick(replicationRescanInterval) {
+		q.mutex.Lock()
+		for _, op := range q.waiting {
+			q.dispatch(op)
+		}
+		q.mutex.Unlock()
+	}
+}
+
+// enqueue - queues an object for replication, the operation is saved
+// before it is handed to a worker so that it survives a restart.
+func (q *replicationQueue) enqueue(bucket, object string) {
+	op := replicationOp{
+		ID:     getUUID(),
+		Bucket: bucket,
+		Object: object,
+	}
+	key := pathJoin(bucket, object)
+
+	if err := q.saveOp(q.queueDir(), op); err != nil {
+		errorIf(err, "Unable to queue object for replication.")
+		return
+	}
+
+	q.mutex.Lock()
+	q.pending[key]++
+	// New operation supersedes any previously failed operation.
+	failedID, hasFailed := q.failed[key]
+	delete(q.failed, key)
+	q.dispatch(op)
+	q.mutex.Unlock()
+
+	if hasFailed {
+		os.Remove(filepath.Join(q.failedDir(), failedID+".json"))
+	}
+}
+
+// status - returns replication status of an object from the queue,
+// returns empty string if the object is not in the queue.
+func (q *replicationQueue) status(bucket, object string) string {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	key := pathJoin(bucket, object)
+	if q.pending[key] > 0 {
+		return replicationPending
+	}
+	if _, ok := q.failed[key]; ok {
+		return replicationFailed
+	}
+	return ""
+}
+
+// worker - replicates queued objects.
+func (q *replicationQueue) worker(opCh chan replicationOp) {
+	for op := range opCh {
+		err := q.replicate(op)
+		if err == nil {
+			q.done(op, false)
+			continue
+		}
+		op.Attempts++
+		errorIf(err, "Unable to replicate %s.", pathJoin(op.Bucket, op.Object))
+		if op.Attempts >= replicationMaxAttempts {
+			q.done(op, true)
+			continue
+		}
+		// Save attempts and retry later with backoff.
+		errorIf(q.saveOp(q.queueDir(), op), "Unable to save replication entry.")
+		retryOp := op
+		time.AfterFunc(replicationRetryInterval(op.Attempts), func() {
+			q.mutex.Lock()
+			q.dispatch(retryOp)
+			q.mutex.Unlock()
+		})
+	}
+}
+
+// replicationRetryInterval - exponential backoff between attempts.
+func replicationRetryInterval(attempts int) time.Duration {
+	interval := time.Second << uint(attempts)
+	if interval <= 0 || interval > replicationMaxRetryInterval {
+		interval = replicationMaxRetryInterval
+	}
+	return interval
+}
+
+// done - removes the operation from queue, failed operations are
+// moved to the failed directory.
+func (q *replicationQueue) done(op replicationOp, failed bool) {
+	if failed {
+		errorIf(q.saveOp(q.failedDir(), op), "Unable to save failed replication entry.")
+	}
+	os.Remove(filepath.Join(q.queueDir(), op.ID+".json"))
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	key := pathJoin(op.Bucket, op.Object)
+	if q.pending[key]--; q.pending[key] <= 0 {
+		delete(q.pending, key)
+	}
+	if failed {
+		q.failed[key] = op.ID
+	}
+}
+
+// replicate - syncs current state of the object to the destination.
+func (q *replicationQueue) replicate(op replicationOp) error {
+	config, err := readBucketReplicationConfig(op.Bucket)
+	if err != nil {
+		if _, ok := err.(BucketReplicationConfigNotFound); ok {
+			// Replication was removed, nothing to do.
+			return nil
+		}
+		return err
+	}
+	rule := config.matchRule(op.Object)
+	if rule == nil {
+		// Object is not replicated anymore.
+		return nil
+	}
+	dest := rule.Destination
+	client, err := newS3Client(dest.Endpoint, dest.AccessKey, dest.SecretKey, dest.Region)
+	if err != nil {
+		return err
+	}
+	destBucket := dest.BucketName()
+
+	// Replicated requests are marked as replica, destination never
+	// replicates them again.
+	header := make(http.Header)
+	header.Set(replicationStatusKey, replicationReplica)
+
+	objInfo, err := q.objAPI.GetObjectInfo(op.Bucket, op.Object)
+	if err != nil {
+		if _, ok := err.(ObjectNotFound); ok {
+			// Object was deleted, propagate the delete.
+			return client.deleteObject(destBucket, op.Object, header)
+		}
+		return err
+	}
+	if objInfo.UserDefined[replicationStatusKey] == replicationReplica {
+		// Replicas are never replicated again.
+		return nil
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		gErr := q.objAPI.GetObject(op.Bucket, op.Object, 0, objInfo.Size, pipeWriter)
+		pipeWriter.CloseWithError(gErr)
+	}()
+	defer pipeReader.Close()
+
+	if objInfo.ContentType != "" {
+		header.Set("Content-Type", objInfo.ContentType)
+	}
+	if objInfo.ContentEncoding != "" {
+		header.Set("Content-Encoding", objInfo.ContentEncoding)
+	}
+	for key, value := range objInfo.UserDefined {
+		if strings.HasPrefix(strings.ToLower(key), "x-amz-meta-") {
+			header.Set(key, value)
+		}
+	}
+	_, err = client.putObject(destBucket, op.Object, objInfo.Size, pipeReader, header)
+	return err
+}
+
+// isReplicaRequest - verifies if the request is made by replication
+// of another server. Replication signs the replica status header with
+// the credentials of this server, the header is ignored on presigned
+// requests and when it is not covered by the signature.
+func isReplicaRequest(r *http.Request) bool {
+	if r == nil || r.Header.Get(replicationStatusKey) != replicationReplica {
+		return false
+	}
+	if getRequestAuthType(r) != authTypeSigned {
+		return false
+	}
+	signV4Values, s3Error := parseSignV4(r.Header.Get("Authorization"))
+	if s3Error != ErrNone {
+		return false
+	}
+	if signV4Values.Credential.accessKey != serverConfig.GetCredential().AccessKeyID {
+		return false
+	}
+	for _, header := range signV4Values.SignedHeaders {
+		if header == replicationStatusKey {
+			return true
+		}
+	}
+	return false
+}
+
+// getReplicationMetadata - returns replication status to be saved
+// along with a new object. Objects written by replication are marked
+// as replica and are never replicated again.
+func getReplicationMetadata(bucket, object string, r *http.Request) map[string]string {
+	metadata := make(map[string]string)
+	if isReplicaRequest(r) {
+		metadata[replicationStatusKey] = replicationReplica
+		return metadata
+	}
+	config, err := readBucketReplicationConfig(bucket)
+	if err != nil {
+		return metadata
+	}
+	if config.matchRule(object) != nil {
+		metadata[replicationStatusKey] = replicationPending
+	}
+	return metadata
+}
+
+// queueReplication - queues an object for replication if the bucket
+// has a replication rule matching the object.
+func queueReplication(bucket, object string, r *http.Request) {
+	if globalReplication == nil || isReplicaRequest(r) {
+		return
+	}
+	config, err := readBucketReplicationConfig(bucket)
+	if err != nil {
+		if _, ok := err.(BucketReplicationConfigNotFound); !ok {
+			errorIf(err, "Unable to read replication configuration.")
+		}
+		return
+	}
+	if config.matchRule(object) == nil {
+		return
+	}
+	globalReplication.enqueue(bucket, object)
+}
+
+// getReplicationStatus - returns replication status of an object.
+func getReplicationStatus(objInfo ObjectInfo) string {
+	status := objInfo.UserDefined[replicationStatusKey]
+	if status != replicationPending || globalReplication == nil {
+		return status
+	}
+	if queueStatus := globalReplication.status(objInfo.Bucket, objInfo.Name); queueStatus != "" {
+		return queueStatus
+	}
+	return replicationCompleted
+}
@@ -0,0 +1,435 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tests validate parsing of replication configuration.
+func TestParseReplicationConfig(t *testing.T) {
+	testCases := []struct {
+		config     string
+		shouldPass bool
+	}{
+		// Test case - 1.
+		// Valid rule with destination bucket ARN.
+		{`<ReplicationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Rule><Status>Enabled</Status><Prefix>photos/</Prefix><Destination><Bucket>arn:aws:s3:::backup</Bucket><Endpoint>http://localhost:9001</Endpoint><AccessKey>access</AccessKey><SecretKey>secret</SecretKey></Destination></Rule></ReplicationConfiguration>`, true},
+		// Test case - 2.
+		// Valid disabled rule with empty prefix and plain bucket name.
+		{`<ReplicationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Rule><Status>Disabled</Status><Prefix></Prefix><Destination><Bucket>backup</Bucket><Endpoint>https://dr.example.com</Endpoint><AccessKey>access</AccessKey><SecretKey>secret</SecretKey></Destination></Rule></ReplicationConfiguration>`, true},
+		// Test case - 3.
+		// No rules.
+		{`<ReplicationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></ReplicationConfiguration>`, false},
+		// Test case - 4.
+		// Invalid rule status.
+		{`<ReplicationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Rule><Status>On</Status><Destination><Bucket>backup</Bucket><Endpoint>http://localhost:9001</Endpoint><AccessKey>access</AccessKey><SecretKey>secret</SecretKey></Destination></Rule></ReplicationConfiguration>`, false},
+		// Test case - 5.
+		// Invalid destination bucket.
+		{`<ReplicationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Rule><Status>Enabled</Status><Destination><Bucket>arn:aws:s3:::b</Bucket><Endpoint>http://localhost:9001</Endpoint><AccessKey>access</AccessKey><SecretKey>secret</SecretKey></Destination></Rule></ReplicationConfiguration>`, false},
+		// Test case - 6.
+		// Invalid destination endpoint.
+		{`<ReplicationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Rule><Status>Enabled</Status><Destination><Bucket>backup</Bucket><Endpoint>localhost:9001</Endpoint><AccessKey>access</AccessKey><SecretKey>secret</SecretKey></Destination></Rule></ReplicationConfiguration>`, false},
+		// Test case - 7.
+		// Missing destination credentials.
+		{`<ReplicationConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Rule><Status>Enabled</Status><Destination><Bucket>backup</Bucket><Endpoint>http://localhost:9001</Endpoint><AccessKey>access</AccessKey></Destination></Rule></ReplicationConfiguration>`, false},
+		// Test case - 8.
+		// Malformed XML.
+		{`<ReplicationConfiguration>`, false},
+	}
+	for i, testCase := range testCases {
+		_, err := parseReplicationConfig([]byte(testCase.config))
+		if err != nil && testCase.shouldPass {
+			t.Errorf("Test %d: Expected to pass, but failed with: <ERROR> %s", i+1, err)
+		}
+		if err == nil && !testCase.shouldPass {
+			t.Errorf("Test %d: Expected to fail, but passed instead", i+1)
+		}
+	}
+}
+
+// Tests validate matching of objects against replication rules.
+func TestReplicationMatchRule(t *testing.T) {
+	config := replicationConfiguration{
+		Rules: []replicationRule{
+			{ID: "disabled", Status: replicationRuleDisabled, Prefix: "logs/"},
+			{ID: "photos", Status: replicationRuleEnabled, Prefix: "photos/"},
+			{ID: "all", Status: replicationRuleEnabled, Prefix: ""},
+		},
+	}
+	testCases := []struct {
+		object string
+		ruleID string
+	}{
+		// Test case - 1.
+		// Object matching a prefix rule.
+		{"photos/2016/a.jpg", "photos"},
+		// Test case - 2.
+		// Disabled rules are skipped.
+		{"logs/access.log", "all"},
+		// Test case - 3.
+		// Object only matching the empty prefix.
+		{"a.txt", "all"},
+	}
+	for i, testCase := range testCases {
+		rule := config.matchRule(testCase.object)
+		if rule == nil {
+			t.Errorf("Test %d: Expected rule '%s' to match, but found none", i+1, testCase.ruleID)
+			continue
+		}
+		if rule.ID != testCase.ruleID {
+			t.Errorf("Test %d: Expected rule '%s', but found '%s'", i+1, testCase.ruleID, rule.ID)
+		}
+	}
+
+	config.Rules = config.Rules[:2]
+	if rule := config.matchRule("a.txt"); rule != nil {
+		t.Errorf("Expected no rule to match, but found '%s'", rule.ID)
+	}
+}
+
+// Tests validate backoff between replication attempts.
+func TestReplicationRetryInterval(t *testing.T) {
+	testCases := []struct {
+		attempts int
+		interval time.Duration
+	}{
+		{1, 2 * time.Second},
+		{4, 16 * time.Second},
+		{10, replicationMaxRetryInterval},
+		{100, replicationMaxRetryInterval},
+	}
+	for i, testCase := range testCases {
+		if interval := replicationRetryInterval(testCase.attempts); interval != testCase.interval {
+			t.Errorf("Test %d: Expected interval '%s', but found '%s'", i+1, testCase.interval, interval)
+		}
+	}
+}
+
+// Wrapper for calling replication tests for both XL multiple disks and single node setup.
+func TestReplication(t *testing.T) {
+	ExecObjectLayerTest(t, testReplication)
+}
+
+// Tests validate objects are replicated to a second server, and
+// deletes are propagated.
+func testReplication(obj ObjectLayer, instanceType string, t *testing.T) {
+	// Destination server.
+	destServer := StartTestServer(t, "FS")
+	defer destServer.Stop()
+
+	destBucket := getRandomBucketName()
+	req, err := newTestRequest("PUT", getMakeBucketURL(destServer.Server.URL, destBucket), 0, nil, destServer.AccessKey, destServer.SecretKey)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s: Expected to create destination bucket, but failed with %s", instanceType, resp.Status)
+	}
+
+	bucket := getRandomBucketName()
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	config := replicationConfiguration{
+		Rules: []replicationRule{{
+			Status: replicationRuleEnabled,
+			Prefix: "photos/",
+			Destination: replicationDestination{
+				Bucket:    replicationARNPrefix + destBucket,
+				Endpoint:  destServer.Server.URL,
+				AccessKey: destServer.AccessKey,
+				SecretKey: destServer.SecretKey,
+			},
+		}},
+	}
+	if err = writeBucketReplicationConfig(bucket, config); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+
+	queuePath, err := ioutil.TempDir("", "minio-replication-")
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	defer removeAll(queuePath)
+	queue, err := newReplicationQueue(obj, queuePath)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+
+	object := "photos/a.jpg"
+	data := []byte("hello, world")
+	metadata := getReplicationMetadata(bucket, object, nil)
+	if metadata[replicationStatusKey] != replicationPending {
+		t.Fatalf("%s: Expected replication status '%s', but found '%s'", instanceType, replicationPending, metadata[replicationStatusKey])
+	}
+	if _, err = obj.PutObject(bucket, object, int64(len(data)), bytes.NewReader(data), metadata); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	queue.enqueue(bucket, object)
+
+	// Object is replicated along with replica status.
+	destObjectURL := getGetObjectURL(destServer.Server.URL, destBucket, object)
+	resp = waitForReplication(t, destObjectURL, destServer, http.StatusOK)
+	replicated, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if !bytes.Equal(replicated, data) {
+		t.Errorf("%s: Expected replicated data '%s', but found '%s'", instanceType, data, replicated)
+	}
+	if status := resp.Header.Get(replicationStatusKey); status != replicationReplica {
+		t.Errorf("%s: Expected destination replication status '%s', but found '%s'", instanceType, replicationReplica, status)
+	}
+	waitForReplicationStatus(t, queue, bucket, object, "")
+
+	// Delete is propagated.
+	if err = obj.DeleteObject(bucket, object); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	queue.enqueue(bucket, object)
+	resp = waitForReplication(t, destObjectURL, destServer, http.StatusNotFound)
+	resp.Body.Close()
+
+	// Queue entries are removed once replicated.
+	waitForReplicationStatus(t, queue, bucket, object, "")
+	entries, err := ioutil.ReadDir(queue.queueDir())
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("%s: Expected empty replication queue, but found %d entries", instanceType, len(entries))
+	}
+
+	// Replicas are not replicated again.
+	replica := "photos/replica.jpg"
+	if _, err = obj.PutObject(bucket, replica, int64(len(data)), bytes.NewReader(data), map[string]string{replicationStatusKey: replicationReplica}); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	if err = queue.replicate(replicationOp{Bucket: bucket, Object: replica}); err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	req, err = newTestRequest("GET", getGetObjectURL(destServer.Server.URL, destBucket, replica), 0, nil, destServer.AccessKey, destServer.SecretKey)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s: <ERROR> %s", instanceType, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("%s: Expected replica not to be replicated, but found %s", instanceType, resp.Status)
+	}
+}
+
+// Tests validate the replica status is only honored on requests
+// signing it with the server credentials.
+func TestIsReplicaRequest(t *testing.T) {
+	cred, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to initialize config: <ERROR> %s", err)
+	}
+	defer removeAll(rootPath)
+
+	newReplicaRequest := func(accessKey string, signed bool) *http.Request {
+		client, cErr := newS3Client("http://localhost:9000", accessKey, cred.SecretAccessKey, "")
+		if cErr != nil {
+			t.Fatalf("Unexpected error: <ERROR> %s", cErr)
+		}
+		header := make(http.Header)
+		if signed {
+			header.Set(replicationStatusKey, replicationReplica)
+		}
+		req, cErr := client.newRequest("PUT", "bucket", "object", nil, nil, 0, header)
+		if cErr != nil {
+			t.Fatalf("Unexpected error: <ERROR> %s", cErr)
+		}
+		req.Header.Set(replicationStatusKey, replicationReplica)
+		return req
+	}
+	presignedReq, err := http.NewRequest("PUT", "http://localhost:9000/bucket/object?X-Amz-Credential="+cred.AccessKeyID, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	presignedReq.Header.Set(replicationStatusKey, replicationReplica)
+
+	testCases := []struct {
+		req     *http.Request
+		replica bool
+	}{
+		// Test case - 1.
+		// Replica status signed with server credentials.
+		{newReplicaRequest(cred.AccessKeyID, true), true},
+		// Test case - 2.
+		// Replica status not covered by the signature.
+		{newReplicaRequest(cred.AccessKeyID, false), false},
+		// Test case - 3.
+		// Replica status signed with other credentials.
+		{newReplicaRequest("other-access-key", true), false},
+		// Test case - 4.
+		// Replica status on a presigned request.
+		{presignedReq, false},
+	}
+	for i, testCase := range testCases {
+		if replica := isReplicaRequest(testCase.req); replica != testCase.replica {
+			t.Errorf("Test %d: Expected replica to be '%v', but found '%v'", i+1, testCase.replica, replica)
+		}
+	}
+}
+
+// Tests validate destination secret keys are saved encrypted.
+func TestReplicationConfigSecretKey(t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to initialize config: <ERROR> %s", err)
+	}
+	defer removeAll(rootPath)
+
+	bucket := "bucket"
+	secretKey := "destination-secret-key"
+	config := replicationConfiguration{
+		Rules: []replicationRule{{
+			Status: replicationRuleEnabled,
+			Destination: replicationDestination{
+				Bucket:    replicationARNPrefix + "backup",
+				Endpoint:  "http://localhost:9001",
+				AccessKey: "access",
+				SecretKey: secretKey,
+			},
+		}},
+	}
+	if err = writeBucketReplicationConfig(bucket, config); err != nil {
+		t.Fatalf("Unable to write replication configuration: <ERROR> %s", err)
+	}
+	if config.Rules[0].Destination.SecretKey != secretKey {
+		t.Errorf("Expected configuration of the caller to be unchanged")
+	}
+	configBytes, err := readBucketConfigFile(bucket, bucketReplicationConfigFile)
+	if err != nil {
+		t.Fatalf("Unable to read replication configuration: <ERROR> %s", err)
+	}
+	if bytes.Contains(configBytes, []byte(secretKey)) {
+		t.Errorf("Expected secret key to be encrypted, found '%s'", configBytes)
+	}
+	config, err = readBucketReplicationConfig(bucket)
+	if err != nil {
+		t.Fatalf("Unable to read replication configuration: <ERROR> %s", err)
+	}
+	if dest := config.Rules[0].Destination; dest.SecretKey != secretKey || dest.EncryptedSecretKey != "" {
+		t.Errorf("Expected decrypted secret key '%s', found '%s'", secretKey, dest.SecretKey)
+	}
+}
+
+// Tests validate queued objects are persisted and reloaded.
+func TestReplicationQueuePersistence(t *testing.T) {
+	queuePath, err := ioutil.TempDir("", "minio-replication-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer removeAll(queuePath)
+
+	ops := []replicationOp{
+		{ID: getUUID(), Bucket: "bucket", Object: "queued"},
+		{ID: getUUID(), Bucket: "bucket", Object: "failed", Attempts: replicationMaxAttempts},
+	}
+	q := &replicationQueue{queuePath: queuePath}
+	for _, dir := range []string{q.queueDir(), q.failedDir()} {
+		if err = os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = q.saveOp(q.queueDir(), ops[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err = q.saveOp(q.failedDir(), ops[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Queued operations are loaded back.
+	loaded, err := q.loadOps(q.queueDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0] != ops[0] {
+		t.Fatalf("Expected queued operations '%v', but found '%v'", ops[:1], loaded)
+	}
+
+	// Failed operations are reported as failed.
+	failedOps, err := q.loadOps(q.failedDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.mutex = &sync.Mutex{}
+	q.pending = make(map[string]int)
+	q.failed = make(map[string]string)
+	for _, op := range failedOps {
+		q.failed[pathJoin(op.Bucket, op.Object)] = op.ID
+	}
+	if status := q.status("bucket", "failed"); status != replicationFailed {
+		t.Errorf("Expected replication status '%s', but found '%s'", replicationFailed, status)
+	}
+	if status := q.status("bucket", "unknown"); status != "" {
+		t.Errorf("Expected empty replication status, but found '%s'", status)
+	}
+}
+
+// waitForReplication - polls the destination until the object
+// returns the expected status code.
+func waitForReplication(t *testing.T, objectURL string, destServer TestServer, statusCode int) *http.Response {
+	for i := 0; i < 100; i++ {
+		req, err := newTestRequest("GET", objectURL, 0, nil, destServer.AccessKey, destServer.SecretKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode == statusCode {
+			return resp
+		}
+		resp.Body.Close()
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s to return %d", objectURL, statusCode)
+	return nil
+}
+
+// waitForReplicationStatus - polls the queue until the object has the
+// expected queue status.
+func waitForReplicationStatus(t *testing.T, q *replicationQueue, bucket, object, status string) {
+	for i := 0; i < 100; i++ {
+		if q.status(bucket, object) == status {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for replication status '%s' of %s", status, pathJoin(bucket, object))
+}
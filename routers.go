@@ -24,8 +24,13 @@ import (
 )
 
 // newObjectLayer - initialize any object layer depending on the
-// number of export paths.
-func newObjectLayer(exportPaths []string) (ObjectLayer, error) {
+// number of export paths, gateway mode forwards to a remote endpoint.
+func newObjectLayer(srvCmdConfig serverCmdConfig) (ObjectLayer, error) {
+	if gw := srvCmdConfig.gateway; gw != nil {
+		// Initialize S3 gateway object layer.
+		return newS3Objects(gw.endpoint, gw.accessKey, gw.secretKey, gw.region)
+	}
+	exportPaths := srvCmdConfig.exportPaths
 	if len(exportPaths) == 1 {
 		exportPath := exportPaths[0]
 		// Initialize FS object layer.
@@ -39,20 +44,68 @@ func newObjectLayer(exportPaths []string) (ObjectLayer, error) {
 	return objAPI, err
 }
 
+// getObjectLayerDisks - returns the disks of an FS or XL object
+// layer, nil for other object layers.
+func getObjectLayerDisks(objAPI ObjectLayer) []StorageAPI {
+	switch objAPI := objAPI.(type) {
+	case fsObjects:
+		return []StorageAPI{objAPI.storage}
+	case xlObjects:
+		return objAPI.storageDisks
+	}
+	return nil
+}
+
 // configureServer handler returns final handler for the http server.
 func configureServerHandler(srvCmdConfig serverCmdConfig) http.Handler {
-	objAPI, err := newObjectLayer(srvCmdConfig.exportPaths)
+	objAPI, err := newObjectLayer(srvCmdConfig)
 	fatalIf(err, "Unable to intialize object layer.")
 
-	// Initialize storage rpc server.
-	storageRPC, err := newRPCServer(srvCmdConfig.exportPaths[0]) // FIXME: should only have one path.
-	fatalIf(err, "Unable to initialize storage RPC server.")
+	// Features keeping state on the local node are disabled when disks
+	// are spread over several nodes.
+	globalIsDistXL = srvCmdConfig.gateway == nil && isDistributedSetup(srvCmdConfig.exportPaths)
+
+	// Disks of the backend, saved before the object layer is wrapped.
+	backendDisks := getObjectLayerDisks(objAPI)
+
+	// Initialize transparent compression, remote objects of the
+	// gateway are not compressed.
+	if srvCmdConfig.gateway == nil {
+		objAPI = newCompressObjects(objAPI, globalCompressConfig)
+	}
+
+	// Initialize list indexes of configured buckets, remote buckets
+	// of the gateway are not indexed. Indexes are not shared between
+	// nodes, objects written through other nodes would be missing.
+	if srvCmdConfig.gateway == nil && !globalIsDistXL && len(globalListIndexBuckets) > 0 {
+		objAPI = newIndexObjects(objAPI, backendDisks, globalListIndexBuckets)
+	}
+
+	// Initialize replication queue.
+	err = initReplication(objAPI)
+	fatalIf(err, "Unable to initialize replication queue.")
+
+	// Initialize web token signing key and revocation list, saved on
+	// the disks to be shared by all the nodes.
+	err = initWebTokens(backendDisks)
+	fatalIf(err, "Unable to initialize web tokens.")
+
+	// Initialize cache drives in front of the object layer, if any.
+	if len(globalCacheConfig.drives) > 0 {
+		objAPI, err = newCacheObjects(objAPI, globalCacheConfig)
+		fatalIf(err, "Unable to initialize cache drives.")
+	}
 
 	// Initialize API.
 	apiHandlers := objectAPIHandlers{
 		ObjectAPI: objAPI,
 	}
 
+	// Initialize Admin API.
+	adminHandlers := adminAPIHandlers{
+		ObjectAPI: objAPI,
+	}
+
 	// Initialize Web.
 	webHandlers := &webAPIHandlers{
 		ObjectAPI: objAPI,
@@ -61,8 +114,16 @@ func configureServerHandler(srvCmdConfig serverCmdConfig) http.Handler {
 	// Initialize router.
 	mux := router.NewRouter()
 
+	// Register storage rpc router, not available in gateway mode.
+	if srvCmdConfig.gateway == nil {
+		// Initialize storage rpc server.
+		storageRPC, err := newRPCServer(srvCmdConfig.exportPaths[0]) // FIXME: should only have one path.
+		fatalIf(err, "Unable to initialize storage RPC server.")
+		registerStorageRPCRouter(mux, storageRPC)
+	}
+
 	// Register all routers.
-	registerStorageRPCRouter(mux, storageRPC)
+	registerAdminRouter(mux, adminHandlers)
 	registerWebRouter(mux, webHandlers)
 	registerAPIRouter(mux, apiHandlers)
 	// Add new routers here.
@@ -17,22 +17,67 @@
 package main
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
 	"net/http"
-	"net/rpc"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 type networkStorage struct {
-	netScheme  string
-	netAddr    string
-	netPath    string
-	rpcClient  *rpc.Client
-	httpClient *http.Client
+	netScheme string
+	netAddr   string
+	netPath   string
+
+	// Clients share a transport, which pools connections to the
+	// node. Metadata calls use a shorter timeout than calls
+	// transferring file contents.
+	httpClient     *http.Client
+	httpDataClient *http.Client
+
+	// Token sent with every call, renewed by login before it expires.
+	tokenMu     sync.Mutex
+	token       string
+	tokenExpiry time.Time
 }
 
 const (
 	storageRPCPath = reservedBucket + "/storage"
+
+	// Files are streamed in chunks of this size.
+	storageRPCChunkSize = 1 * 1024 * 1024
+
+	// Trailer carrying errors found after a read started streaming.
+	storageErrorTrailer = "X-Minio-Storage-Error"
+)
+
+const (
+	// Tokens are renewed this long before they expire, to allow for
+	// clock skew between the nodes.
+	rpcTokenRenewBefore = time.Minute
+
+	// Timeouts of metadata calls and of calls transferring file
+	// contents, including reading the response.
+	storageRPCTimeout     = 1 * time.Minute
+	storageRPCDataTimeout = 6 * time.Minute
+
+	// Calls are retried when the node can't be reached, with
+	// exponential backoff starting at storageRPCRetryBackoff, after
+	// which the disk is reported as not found.
+	storageRPCMaxRetries   = 4
+	storageRPCRetryBackoff = 100 * time.Millisecond
+
+	// Idle connections kept open to each node.
+	storageRPCMaxIdleConns = 16
 )
 
 // splits network path into its components Address and Path.
@@ -43,19 +88,27 @@ func splitNetPath(networkPath string) (netAddr, netPath string) {
 	return netAddr, netPath
 }
 
-// Converts rpc.ServerError to underlying error. This function is
-// written so that the storageAPI errors are consistent across network
-// disks as well.
+// Converts errors sent by the storage rpc server to underlying
+// error. This function is written so that the storageAPI errors are
+// consistent across network disks as well.
 func toStorageErr(err error) error {
 	switch err.Error() {
 	case errDiskFull.Error():
 		return errDiskFull
+	case errDiskNotFound.Error():
+		return errDiskNotFound
+	case errDiskAccessDenied.Error():
+		return errDiskAccessDenied
+	case errFaultyDisk.Error():
+		return errFaultyDisk
 	case errVolumeNotFound.Error():
 		return errVolumeNotFound
 	case errVolumeExists.Error():
 		return errVolumeExists
 	case errFileNotFound.Error():
 		return errFileNotFound
+	case errFileNameTooLong.Error():
+		return errFileNameTooLong
 	case errIsNotRegular.Error():
 		return errIsNotRegular
 	case errVolumeNotEmpty.Error():
@@ -64,11 +117,32 @@ func toStorageErr(err error) error {
 		return errFileAccessDenied
 	case errVolumeAccessDenied.Error():
 		return errVolumeAccessDenied
+	case errInvalidArgument.Error():
+		return errInvalidArgument
+	case errInvalidToken.Error():
+		return errInvalidToken
+	case errAuthentication.Error():
+		return errAuthentication
+	case io.EOF.Error():
+		return io.EOF
+	case io.ErrUnexpectedEOF.Error():
+		return io.ErrUnexpectedEOF
 	}
 	return err
 }
 
-// Initialize new rpc client.
+// isNetworkDialErr - returns true if err happened while connecting,
+// before anything was sent to the node.
+func isNetworkDialErr(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "dial"
+}
+
+// Initialize new rpc client, the node is connected to lazily so that
+// nodes may be started in any order.
 func newRPCClient(networkPath string) (StorageAPI, error) {
 	// Input validation.
 	if networkPath == "" || strings.LastIndex(networkPath, ":") == -1 {
@@ -78,92 +152,211 @@ func newRPCClient(networkPath string) (StorageAPI, error) {
 	// TODO validate netAddr and netPath.
 	netAddr, netPath := splitNetPath(networkPath)
 
-	// Dial minio rpc storage http path.
-	rpcClient, err := rpc.DialHTTPPath("tcp", netAddr, storageRPCPath)
-	if err != nil {
-		return nil, err
+	// Nodes talk over TLS when certificates are configured.
+	netScheme := "http"
+	var tlsConfig *tls.Config
+	if isSSL() {
+		var err error
+		if tlsConfig, err = getClientTLSConfig(netAddr); err != nil {
+			return nil, err
+		}
+		netScheme = "https"
 	}
 
-	// Initialize http client.
-	httpClient := &http.Client{
-		// Setting a sensible time out of 6minutes to wait for
-		// response headers. Request is pro-actively cancelled
-		// after 6minutes if no response was received from server.
-		Timeout:   6 * time.Minute,
-		Transport: http.DefaultTransport,
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).Dial,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: storageRPCMaxIdleConns,
 	}
 
 	// Initialize network storage.
 	ndisk := &networkStorage{
-		netScheme:  "http", // TODO: fix for ssl rpc support.
-		netAddr:    netAddr,
-		netPath:    netPath,
-		rpcClient:  rpcClient,
-		httpClient: httpClient,
+		netScheme: netScheme,
+		netAddr:   netAddr,
+		netPath:   netPath,
+		httpClient: &http.Client{
+			Timeout:   storageRPCTimeout,
+			Transport: transport,
+		},
+		httpDataClient: &http.Client{
+			Timeout:   storageRPCDataTimeout,
+			Transport: transport,
+		},
 	}
 
 	// Returns successfully here.
 	return ndisk, nil
 }
 
-// MakeVol - make a volume.
-func (n networkStorage) MakeVol(volume string) error {
-	reply := GenericReply{}
-	if err := n.rpcClient.Call("Storage.MakeVolHandler", volume, &reply); err != nil {
-		return toStorageErr(err)
+// getToken - returns the token for calls, logs in again if forced or
+// if the current token is about to expire.
+func (n *networkStorage) getToken(force bool) (string, error) {
+	n.tokenMu.Lock()
+	defer n.tokenMu.Unlock()
+	if !force && n.token != "" && time.Now().UTC().Before(n.tokenExpiry) {
+		return n.token, nil
+	}
+
+	jwt := initJWT()
+	loginToken, err := jwt.GenerateRPCToken(rpcLoginAudience, rpcLoginTokenExpires)
+	if err != nil {
+		return "", err
+	}
+	resp, err := n.do(n.httpClient, "POST", "/login", nil, nil, loginToken, true)
+	if err != nil {
+		if err == errInvalidToken {
+			err = errAuthentication
+		}
+		return "", err
+	}
+	reply := RPCLoginReply{}
+	if err = decodeStorageResponse(resp, &reply); err != nil {
+		return "", err
+	}
+	n.token = reply.Token
+	n.tokenExpiry = time.Now().UTC().Add(rpcTokenExpires - rpcTokenRenewBefore)
+	return n.token, nil
+}
+
+// do - sends a single storage rpc request, retrying with backoff
+// while the node can't be reached. Requests which may have reached
+// the node are retried only if idempotent. Responses with an error
+// status are closed and converted to the error sent by the server.
+func (n *networkStorage) do(client *http.Client, method, op string, query url.Values, body []byte, token string, idempotent bool) (*http.Response, error) {
+	reqURL := &url.URL{
+		Scheme:   n.netScheme,
+		Host:     n.netAddr,
+		Path:     storageRPCPath + op,
+		RawQuery: query.Encode(),
+	}
+	backoff := storageRPCRetryBackoff
+	for i := 0; ; i++ {
+		req, err := http.NewRequest(method, reqURL.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", jwtAlgorithm+" "+token)
+		req.Header.Set("User-Agent", "Minio-Storage")
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		resp, err := client.Do(req)
+		if err == nil {
+			if resp.StatusCode == http.StatusOK {
+				return resp, nil
+			}
+			return nil, readStorageErrorResponse(resp)
+		}
+		if i == storageRPCMaxRetries || (!idempotent && !isNetworkDialErr(err)) {
+			errorIf(err, "Unable to reach storage node %s.", n.netAddr)
+			return nil, errDiskNotFound
+		}
+		// Back off with jitter, so that nodes don't reconnect to
+		// a restarted node all at once.
+		time.Sleep(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2))))
+		backoff *= 2
+	}
+}
+
+// call - sends an authenticated storage rpc request, logging in again
+// and retrying once if the token was rejected.
+func (n *networkStorage) call(client *http.Client, method, op string, query url.Values, body []byte, idempotent bool) (*http.Response, error) {
+	token, err := n.getToken(false)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := n.do(client, method, op, query, body, token, idempotent)
+	if err != errInvalidToken {
+		return resp, err
+	}
+	if token, err = n.getToken(true); err != nil {
+		return nil, err
+	}
+	return n.do(client, method, op, query, body, token, idempotent)
+}
+
+// callJSON - sends an authenticated metadata call and decodes its
+// JSON reply.
+func (n *networkStorage) callJSON(method, op string, query url.Values, idempotent bool, reply interface{}) error {
+	resp, err := n.call(n.httpClient, method, op, query, nil, idempotent)
+	if err != nil {
+		return err
+	}
+	return decodeStorageResponse(resp, reply)
+}
+
+// decodeStorageResponse - decodes and closes a JSON reply.
+func decodeStorageResponse(resp *http.Response, reply interface{}) error {
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(reply); err != nil {
+		return errDiskNotFound
 	}
 	return nil
 }
 
+// readStorageErrorResponse - reads and closes an error response.
+func readStorageErrorResponse(resp *http.Response) error {
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Tokens may be rejected before reaching the storage
+		// handlers, without a message.
+		return errInvalidToken
+	}
+	message, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil || len(message) == 0 {
+		return errDiskNotFound
+	}
+	return toStorageErr(errors.New(string(message)))
+}
+
+// MakeVol - make a volume.
+func (n *networkStorage) MakeVol(volume string) error {
+	return n.callJSON("POST", "/makevol", url.Values{"volume": {volume}}, false, &GenericReply{})
+}
+
 // ListVols - List all volumes.
-func (n networkStorage) ListVols() (vols []VolInfo, err error) {
+func (n *networkStorage) ListVols() (vols []VolInfo, err error) {
 	ListVols := ListVolsReply{}
-	err = n.rpcClient.Call("Storage.ListVolsHandler", "", &ListVols)
-	if err != nil {
+	if err = n.callJSON("GET", "/listvols", nil, true, &ListVols); err != nil {
 		return nil, err
 	}
 	return ListVols.Vols, nil
 }
 
 // StatVol - get current Stat volume info.
-func (n networkStorage) StatVol(volume string) (volInfo VolInfo, err error) {
-	if err = n.rpcClient.Call("Storage.StatVolHandler", volume, &volInfo); err != nil {
-		return VolInfo{}, toStorageErr(err)
+func (n *networkStorage) StatVol(volume string) (volInfo VolInfo, err error) {
+	if err = n.callJSON("GET", "/statvol", url.Values{"volume": {volume}}, true, &volInfo); err != nil {
+		return VolInfo{}, err
 	}
 	return volInfo, nil
 }
 
 // DeleteVol - Delete a volume.
-func (n networkStorage) DeleteVol(volume string) error {
-	reply := GenericReply{}
-	if err := n.rpcClient.Call("Storage.DeleteVolHandler", volume, &reply); err != nil {
-		return toStorageErr(err)
-	}
-	return nil
+func (n *networkStorage) DeleteVol(volume string) error {
+	return n.callJSON("POST", "/deletevol", url.Values{"volume": {volume}}, false, &GenericReply{})
 }
 
 // File operations.
 
-// CreateFile - create file.
-func (n networkStorage) AppendFile(volume, path string, buffer []byte) (err error) {
-	reply := GenericReply{}
-	if err = n.rpcClient.Call("Storage.AppendFileHandler", AppendFileArgs{
-		Vol:    volume,
-		Path:   path,
-		Buffer: buffer,
-	}, &reply); err != nil {
-		return toStorageErr(err)
+// AppendFile - append buffer to the file at path, streamed as the
+// request body.
+func (n *networkStorage) AppendFile(volume, path string, buffer []byte) (err error) {
+	query := url.Values{"volume": {volume}, "path": {path}}
+	resp, err := n.call(n.httpDataClient, "POST", "/appendfile", query, buffer, false)
+	if err != nil {
+		return err
 	}
-	return nil
+	return decodeStorageResponse(resp, &GenericReply{})
 }
 
 // StatFile - get latest Stat information for a file at path.
-func (n networkStorage) StatFile(volume, path string) (fileInfo FileInfo, err error) {
-	if err = n.rpcClient.Call("Storage.StatFileHandler", StatFileArgs{
-		Vol:  volume,
-		Path: path,
-	}, &fileInfo); err != nil {
-		return FileInfo{}, toStorageErr(err)
+func (n *networkStorage) StatFile(volume, path string) (fileInfo FileInfo, err error) {
+	query := url.Values{"volume": {volume}, "path": {path}}
+	if err = n.callJSON("GET", "/statfile", query, true, &fileInfo); err != nil {
+		return FileInfo{}, err
 	}
 	return fileInfo, nil
 }
@@ -172,63 +365,72 @@ func (n networkStorage) StatFile(volume, path string) (fileInfo FileInfo, err er
 // contents in a byte slice. Returns buf == nil if err != nil.
 // This API is meant to be used on files which have small memory footprint, do
 // not use this on large files as it would cause server to crash.
-func (n networkStorage) ReadAll(volume, path string) (buf []byte, err error) {
-	if err = n.rpcClient.Call("Storage.ReadAllHandler", ReadAllArgs{
-		Vol:  volume,
-		Path: path,
-	}, &buf); err != nil {
-		return nil, toStorageErr(err)
+func (n *networkStorage) ReadAll(volume, path string) (buf []byte, err error) {
+	query := url.Values{"volume": {volume}, "path": {path}}
+	resp, err := n.call(n.httpDataClient, "GET", "/readall", query, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if buf, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, errDiskNotFound
 	}
 	return buf, nil
 }
 
-// ReadFile - reads a file.
-func (n networkStorage) ReadFile(volume string, path string, offset int64, buffer []byte) (m int64, err error) {
-	if err = n.rpcClient.Call("Storage.ReadFileHandler", ReadFileArgs{
-		Vol:    volume,
-		Path:   path,
-		Offset: offset,
-		Buffer: buffer,
-	}, &m); err != nil {
-		return 0, toStorageErr(err)
+// ReadFile - reads a file at offset into buffer, streamed as the
+// response body. Like a local disk, returns io.EOF if nothing was
+// read and io.ErrUnexpectedEOF if the file ended before the buffer
+// was filled.
+func (n *networkStorage) ReadFile(volume string, path string, offset int64, buffer []byte) (m int64, err error) {
+	query := url.Values{
+		"volume": {volume},
+		"path":   {path},
+		"offset": {strconv.FormatInt(offset, 10)},
+		"length": {strconv.Itoa(len(buffer))},
+	}
+	resp, err := n.call(n.httpDataClient, "GET", "/readfile", query, nil, true)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	read, err := io.ReadFull(resp.Body, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return 0, errDiskNotFound
+	}
+	// Read till the end of body, for the trailer to be available.
+	if _, cErr := io.Copy(ioutil.Discard, resp.Body); cErr != nil {
+		return 0, errDiskNotFound
+	}
+	if message := resp.Trailer.Get(storageErrorTrailer); message != "" {
+		return 0, toStorageErr(errors.New(message))
 	}
-	return m, nil
+	return int64(read), err
 }
 
 // ListDir - list all entries at prefix.
-func (n networkStorage) ListDir(volume, path string) (entries []string, err error) {
-	if err = n.rpcClient.Call("Storage.ListDirHandler", ListDirArgs{
-		Vol:  volume,
-		Path: path,
-	}, &entries); err != nil {
-		return nil, toStorageErr(err)
+func (n *networkStorage) ListDir(volume, path string) (entries []string, err error) {
+	query := url.Values{"volume": {volume}, "path": {path}}
+	if err = n.callJSON("GET", "/listdir", query, true, &entries); err != nil {
+		return nil, err
 	}
 	// Return successfully unmarshalled results.
 	return entries, nil
 }
 
 // DeleteFile - Delete a file at path.
-func (n networkStorage) DeleteFile(volume, path string) (err error) {
-	reply := GenericReply{}
-	if err = n.rpcClient.Call("Storage.DeleteFileHandler", DeleteFileArgs{
-		Vol:  volume,
-		Path: path,
-	}, &reply); err != nil {
-		return toStorageErr(err)
-	}
-	return nil
+func (n *networkStorage) DeleteFile(volume, path string) (err error) {
+	query := url.Values{"volume": {volume}, "path": {path}}
+	return n.callJSON("POST", "/deletefile", query, false, &GenericReply{})
 }
 
 // RenameFile - Rename file.
-func (n networkStorage) RenameFile(srcVolume, srcPath, dstVolume, dstPath string) (err error) {
-	reply := GenericReply{}
-	if err = n.rpcClient.Call("Storage.RenameFileHandler", RenameFileArgs{
-		SrcVol:  srcVolume,
-		SrcPath: srcPath,
-		DstVol:  dstVolume,
-		DstPath: dstPath,
-	}, &reply); err != nil {
-		return toStorageErr(err)
+func (n *networkStorage) RenameFile(srcVolume, srcPath, dstVolume, dstPath string) (err error) {
+	query := url.Values{
+		"src-volume": {srcVolume},
+		"src-path":   {srcPath},
+		"dst-volume": {dstVolume},
+		"dst-path":   {dstPath},
 	}
-	return nil
+	return n.callJSON("POST", "/renamefile", query, false, &GenericReply{})
 }
@@ -22,86 +22,15 @@ type GenericReply struct{}
 // GenericArgs represents any generic RPC arguments.
 type GenericArgs struct{}
 
+// RPCLoginReply represents login RPC reply.
+type RPCLoginReply struct {
+	// Token to be sent with every call, expires after
+	// rpcTokenExpires.
+	Token string
+}
+
 // ListVolsReply represents list of vols RPC reply.
 type ListVolsReply struct {
 	// List of volumes stat information.
 	Vols []VolInfo
 }
-
-// ReadAllArgs represents read all RPC arguments.
-type ReadAllArgs struct {
-	// Name of the volume.
-	Vol string
-
-	// Name of the path.
-	Path string
-}
-
-// ReadFileArgs represents read file RPC arguments.
-type ReadFileArgs struct {
-	// Name of the volume.
-	Vol string
-
-	// Name of the path.
-	Path string
-
-	// Starting offset to start reading into Buffer.
-	Offset int64
-
-	// Data buffer read from the path at offset.
-	Buffer []byte
-}
-
-// AppendFileArgs represents append file RPC arguments.
-type AppendFileArgs struct {
-	// Name of the volume.
-	Vol string
-
-	// Name of the path.
-	Path string
-
-	// Data buffer to be saved at path.
-	Buffer []byte
-}
-
-// StatFileArgs represents stat file RPC arguments.
-type StatFileArgs struct {
-	// Name of the volume.
-	Vol string
-
-	// Name of the path.
-	Path string
-}
-
-// DeleteFileArgs represents delete file RPC arguments.
-type DeleteFileArgs struct {
-	// Name of the volume.
-	Vol string
-
-	// Name of the path.
-	Path string
-}
-
-// ListDirArgs represents list contents RPC arguments.
-type ListDirArgs struct {
-	// Name of the volume.
-	Vol string
-
-	// Name of the path.
-	Path string
-}
-
-// RenameFileArgs represents rename file RPC arguments.
-type RenameFileArgs struct {
-	// Name of source volume.
-	SrcVol string
-
-	// Source path to be renamed.
-	SrcPath string
-
-	// Name of destination volume.
-	DstVol string
-
-	// Destination path of renamed file.
-	DstPath string
-}
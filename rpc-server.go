@@ -1,113 +1,268 @@
 package main
 
 import (
-	"net/rpc"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
 
 	router "github.com/gorilla/mux"
 )
 
-// Storage server implements rpc primitives to facilitate exporting a
-// disk over a network.
+// Storage server implements the storage rpc, which exports a disk
+// over http. Files are read and appended by streaming request and
+// response bodies, other calls reply in JSON.
 type storageServer struct {
 	storage StorageAPI
 }
 
+/// Auth operations
+
+// validateToken - validates the token sent with a storage rpc call.
+func (s *storageServer) validateToken(r *http.Request) error {
+	jwt := initJWT()
+	if !jwt.ValidateRPCToken(getRPCToken(r), rpcTokenAudience) {
+		return errInvalidToken
+	}
+	return nil
+}
+
+// authenticate - wraps handler, rejects calls without a valid token.
+func (s *storageServer) authenticate(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.validateToken(r); err != nil {
+			writeStorageErrorResponse(w, err)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// LoginHandler - login handler authenticates a node by a login token
+// signed with the server credential, and replies with a token valid
+// for rpcTokenExpires to be sent with every other call.
+func (s *storageServer) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	jwt := initJWT()
+	if !jwt.ValidateRPCToken(getRPCToken(r), rpcLoginAudience) {
+		writeStorageErrorResponse(w, errAuthentication)
+		return
+	}
+	token, err := jwt.GenerateRPCToken(rpcTokenAudience, rpcTokenExpires)
+	if err != nil {
+		writeStorageErrorResponse(w, err)
+		return
+	}
+	writeStorageResponse(w, RPCLoginReply{Token: token})
+}
+
 /// Volume operations handlers
 
 // MakeVolHandler - make vol handler is rpc wrapper for MakeVol operation.
-func (s *storageServer) MakeVolHandler(arg *string, reply *GenericReply) error {
-	err := s.storage.MakeVol(*arg)
-	if err != nil {
-		return err
+func (s *storageServer) MakeVolHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.storage.MakeVol(r.URL.Query().Get("volume")); err != nil {
+		writeStorageErrorResponse(w, err)
+		return
 	}
-	return nil
+	writeStorageResponse(w, GenericReply{})
 }
 
 // ListVolsHandler - list vols handler is rpc wrapper for ListVols operation.
-func (s *storageServer) ListVolsHandler(arg *string, reply *ListVolsReply) error {
+func (s *storageServer) ListVolsHandler(w http.ResponseWriter, r *http.Request) {
 	vols, err := s.storage.ListVols()
 	if err != nil {
-		return err
+		writeStorageErrorResponse(w, err)
+		return
 	}
-	reply.Vols = vols
-	return nil
+	writeStorageResponse(w, ListVolsReply{Vols: vols})
 }
 
 // StatVolHandler - stat vol handler is a rpc wrapper for StatVol operation.
-func (s *storageServer) StatVolHandler(arg *string, reply *VolInfo) error {
-	volInfo, err := s.storage.StatVol(*arg)
+func (s *storageServer) StatVolHandler(w http.ResponseWriter, r *http.Request) {
+	volInfo, err := s.storage.StatVol(r.URL.Query().Get("volume"))
 	if err != nil {
-		return err
+		writeStorageErrorResponse(w, err)
+		return
 	}
-	*reply = volInfo
-	return nil
+	writeStorageResponse(w, volInfo)
 }
 
 // DeleteVolHandler - delete vol handler is a rpc wrapper for
 // DeleteVol operation.
-func (s *storageServer) DeleteVolHandler(arg *string, reply *GenericReply) error {
-	err := s.storage.DeleteVol(*arg)
-	if err != nil {
-		return err
+func (s *storageServer) DeleteVolHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.storage.DeleteVol(r.URL.Query().Get("volume")); err != nil {
+		writeStorageErrorResponse(w, err)
+		return
 	}
-	return nil
+	writeStorageResponse(w, GenericReply{})
 }
 
 /// File operations
 
 // StatFileHandler - stat file handler is rpc wrapper to stat file.
-func (s *storageServer) StatFileHandler(arg *StatFileArgs, reply *FileInfo) error {
-	fileInfo, err := s.storage.StatFile(arg.Vol, arg.Path)
+func (s *storageServer) StatFileHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fileInfo, err := s.storage.StatFile(query.Get("volume"), query.Get("path"))
 	if err != nil {
-		return err
+		writeStorageErrorResponse(w, err)
+		return
 	}
-	*reply = fileInfo
-	return nil
+	writeStorageResponse(w, fileInfo)
 }
 
 // ListDirHandler - list directory handler is rpc wrapper to list dir.
-func (s *storageServer) ListDirHandler(arg *ListDirArgs, reply *[]string) error {
-	entries, err := s.storage.ListDir(arg.Vol, arg.Path)
+func (s *storageServer) ListDirHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	entries, err := s.storage.ListDir(query.Get("volume"), query.Get("path"))
 	if err != nil {
-		return err
+		writeStorageErrorResponse(w, err)
+		return
 	}
-	*reply = entries
-	return nil
+	writeStorageResponse(w, entries)
 }
 
-// ReadAllHandler - read all handler is rpc wrapper to read all storage API.
-func (s *storageServer) ReadAllHandler(arg *ReadFileArgs, reply *[]byte) error {
-	buf, err := s.storage.ReadAll(arg.Vol, arg.Path)
+// ReadAllHandler - read all handler is rpc wrapper to read all storage
+// API, the contents are sent as the response body.
+func (s *storageServer) ReadAllHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	buf, err := s.storage.ReadAll(query.Get("volume"), query.Get("path"))
 	if err != nil {
-		return err
+		writeStorageErrorResponse(w, err)
+		return
 	}
-	reply = &buf
-	return nil
+	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf)
 }
 
-// ReadFileHandler - read file handler is rpc wrapper to read file.
-func (s *storageServer) ReadFileHandler(arg *ReadFileArgs, reply *int64) error {
-	n, err := s.storage.ReadFile(arg.Vol, arg.Path, arg.Offset, arg.Buffer)
-	if err != nil {
-		return err
+// ReadFileHandler - read file handler is rpc wrapper to read file,
+// streams up to length bytes at offset as the response body in
+// chunks of storageRPCChunkSize. Fewer bytes are sent if the file
+// ends before, errors after the first chunk are sent in the
+// storageErrorTrailer trailer.
+func (s *storageServer) ReadFileHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	volume, path := query.Get("volume"), query.Get("path")
+	offset, err := strconv.ParseInt(query.Get("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeStorageErrorResponse(w, errInvalidArgument)
+		return
+	}
+	length, err := strconv.ParseInt(query.Get("length"), 10, 64)
+	if err != nil || length < 0 {
+		writeStorageErrorResponse(w, errInvalidArgument)
+		return
+	}
+
+	w.Header().Set("Trailer", storageErrorTrailer)
+	buf := make([]byte, minInt64(length, storageRPCChunkSize))
+	wroteHeader := false
+	for {
+		chunk := buf[:minInt64(length, int64(len(buf)))]
+		n, err := s.storage.ReadFile(volume, path, offset, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			if !wroteHeader {
+				writeStorageErrorResponse(w, err)
+				return
+			}
+			w.Header().Set(storageErrorTrailer, err.Error())
+			return
+		}
+		if !wroteHeader {
+			w.WriteHeader(http.StatusOK)
+			wroteHeader = true
+		}
+		if _, wErr := w.Write(chunk[:n]); wErr != nil {
+			return
+		}
+		offset += n
+		length -= n
+		// Stop at the end of file or once length is sent.
+		if err != nil || length == 0 {
+			return
+		}
 	}
-	reply = &n
-	return nil
 }
 
-// AppendFileHandler - append file handler is rpc wrapper to append file.
-func (s *storageServer) AppendFileHandler(arg *AppendFileArgs, reply *GenericReply) error {
-	return s.storage.AppendFile(arg.Vol, arg.Path, arg.Buffer)
+// AppendFileHandler - append file handler is rpc wrapper to append
+// file, the request body is appended in chunks of
+// storageRPCChunkSize as it is received.
+func (s *storageServer) AppendFileHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	volume, path := query.Get("volume"), query.Get("path")
+	if r.ContentLength < 0 {
+		writeStorageErrorResponse(w, errInvalidArgument)
+		return
+	}
+	remaining := r.ContentLength
+	buf := make([]byte, minInt64(remaining, storageRPCChunkSize))
+	for {
+		chunk := buf[:minInt64(remaining, int64(len(buf)))]
+		if _, err := io.ReadFull(r.Body, chunk); err != nil {
+			writeStorageErrorResponse(w, err)
+			return
+		}
+		if err := s.storage.AppendFile(volume, path, chunk); err != nil {
+			writeStorageErrorResponse(w, err)
+			return
+		}
+		remaining -= int64(len(chunk))
+		if remaining == 0 {
+			break
+		}
+	}
+	writeStorageResponse(w, GenericReply{})
 }
 
 // DeleteFileHandler - delete file handler is rpc wrapper to delete file.
-func (s *storageServer) DeleteFileHandler(arg *DeleteFileArgs, reply *GenericReply) error {
-	return s.storage.DeleteFile(arg.Vol, arg.Path)
+func (s *storageServer) DeleteFileHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if err := s.storage.DeleteFile(query.Get("volume"), query.Get("path")); err != nil {
+		writeStorageErrorResponse(w, err)
+		return
+	}
+	writeStorageResponse(w, GenericReply{})
 }
 
 // RenameFileHandler - rename file handler is rpc wrapper to rename file.
-func (s *storageServer) RenameFileHandler(arg *RenameFileArgs, reply *GenericReply) error {
-	return s.storage.RenameFile(arg.SrcVol, arg.SrcPath, arg.DstVol, arg.DstPath)
+func (s *storageServer) RenameFileHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	err := s.storage.RenameFile(query.Get("src-volume"), query.Get("src-path"), query.Get("dst-volume"), query.Get("dst-path"))
+	if err != nil {
+		writeStorageErrorResponse(w, err)
+		return
+	}
+	writeStorageResponse(w, GenericReply{})
+}
+
+// getRPCToken - returns the bearer token of a storage rpc request.
+func getRPCToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), jwtAlgorithm+" ")
+}
+
+// writeStorageResponse - writes a JSON encoded reply.
+func writeStorageResponse(w http.ResponseWriter, reply interface{}) {
+	replyBytes, err := json.Marshal(reply)
+	if err != nil {
+		writeStorageErrorResponse(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(replyBytes)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(replyBytes)
+}
+
+// writeStorageErrorResponse - writes the error message, converted
+// back to the same error by toStorageErr on the client.
+func writeStorageErrorResponse(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if err == errInvalidToken || err == errAuthentication {
+		status = http.StatusUnauthorized
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(status)
+	io.WriteString(w, err.Error())
 }
 
 // Initialize new storage rpc.
@@ -124,9 +279,19 @@ func newRPCServer(exportPath string) (*storageServer, error) {
 
 // registerStorageRPCRouter - register storage rpc router.
 func registerStorageRPCRouter(mux *router.Router, stServer *storageServer) {
-	storageRPCServer := rpc.NewServer()
-	storageRPCServer.RegisterName("Storage", stServer)
-	storageRouter := mux.NewRoute().PathPrefix(reservedBucket).Subrouter()
+	storageRouter := mux.NewRoute().PathPrefix(storageRPCPath).Subrouter()
+
 	// Add minio storage routes.
-	storageRouter.Path("/storage").Handler(storageRPCServer)
+	storageRouter.Methods("POST").Path("/login").HandlerFunc(stServer.LoginHandler)
+	storageRouter.Methods("POST").Path("/makevol").HandlerFunc(stServer.authenticate(stServer.MakeVolHandler))
+	storageRouter.Methods("GET").Path("/listvols").HandlerFunc(stServer.authenticate(stServer.ListVolsHandler))
+	storageRouter.Methods("GET").Path("/statvol").HandlerFunc(stServer.authenticate(stServer.StatVolHandler))
+	storageRouter.Methods("POST").Path("/deletevol").HandlerFunc(stServer.authenticate(stServer.DeleteVolHandler))
+	storageRouter.Methods("GET").Path("/statfile").HandlerFunc(stServer.authenticate(stServer.StatFileHandler))
+	storageRouter.Methods("GET").Path("/listdir").HandlerFunc(stServer.authenticate(stServer.ListDirHandler))
+	storageRouter.Methods("GET").Path("/readall").HandlerFunc(stServer.authenticate(stServer.ReadAllHandler))
+	storageRouter.Methods("GET").Path("/readfile").HandlerFunc(stServer.authenticate(stServer.ReadFileHandler))
+	storageRouter.Methods("POST").Path("/appendfile").HandlerFunc(stServer.authenticate(stServer.AppendFileHandler))
+	storageRouter.Methods("POST").Path("/deletefile").HandlerFunc(stServer.authenticate(stServer.DeleteFileHandler))
+	storageRouter.Methods("POST").Path("/renamefile").HandlerFunc(stServer.authenticate(stServer.RenameFileHandler))
 }
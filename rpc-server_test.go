@@ -0,0 +1,301 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	router "github.com/gorilla/mux"
+)
+
+// Tests validate remote disks behave like local disks.
+func TestStorageRPC(t *testing.T) {
+	testServer := StartTestServer(t, "FS")
+	defer testServer.Stop()
+
+	u, err := url.Parse(testServer.Server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	disk, err := newRPCClient(u.Host + ":" + testServer.Disks[0])
+	if err != nil {
+		t.Fatalf("Unable to initialize rpc client: <ERROR> %s", err)
+	}
+
+	if err = disk.MakeVol("rpc-volume"); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if err = disk.MakeVol("rpc-volume"); err != errVolumeExists {
+		t.Errorf("Expected %s, but found %v", errVolumeExists, err)
+	}
+
+	// Contents larger than a chunk are streamed in several chunks.
+	data := bytes.Repeat([]byte("abcdefghijklmnopqrstuvwxyz"), storageRPCChunkSize/10)
+	if err = disk.AppendFile("rpc-volume", "dir/object", data[:len(data)/2]); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if err = disk.AppendFile("rpc-volume", "dir/object", data[len(data)/2:]); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	fileInfo, err := disk.StatFile("rpc-volume", "dir/object")
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if fileInfo.Size != int64(len(data)) {
+		t.Errorf("Expected size %d, but found %d", len(data), fileInfo.Size)
+	}
+
+	buf, err := disk.ReadAll("rpc-volume", "dir/object")
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Errorf("Expected ReadAll to return the file contents")
+	}
+
+	testCases := []struct {
+		offset  int64
+		size    int
+		read    int64
+		readErr error
+	}{
+		// Test case - 1.
+		// Whole file.
+		{0, len(data), int64(len(data)), nil},
+		// Test case - 2.
+		// Across chunks at an offset.
+		{100, 2 * storageRPCChunkSize, 2 * storageRPCChunkSize, nil},
+		// Test case - 3.
+		// Past the end of file.
+		{int64(len(data)) - 10, 20, 10, io.ErrUnexpectedEOF},
+		// Test case - 4.
+		// At the end of file.
+		{int64(len(data)), 20, 0, io.EOF},
+	}
+	for i, testCase := range testCases {
+		buf = make([]byte, testCase.size)
+		read, err := disk.ReadFile("rpc-volume", "dir/object", testCase.offset, buf)
+		if err != testCase.readErr {
+			t.Errorf("Test %d: Expected error %v, but found %v", i+1, testCase.readErr, err)
+		}
+		if read != testCase.read {
+			t.Fatalf("Test %d: Expected to read %d bytes, but read %d", i+1, testCase.read, read)
+		}
+		if !bytes.Equal(buf[:read], data[testCase.offset:testCase.offset+read]) {
+			t.Errorf("Test %d: Read contents do not match", i+1)
+		}
+	}
+	if _, err = disk.ReadFile("rpc-volume", "missing", 0, make([]byte, 10)); err != errFileNotFound {
+		t.Errorf("Expected %s, but found %v", errFileNotFound, err)
+	}
+
+	entries, err := disk.ListDir("rpc-volume", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if len(entries) != 1 || entries[0] != "dir/" {
+		t.Errorf("Expected [dir/], but found %v", entries)
+	}
+	if err = disk.RenameFile("rpc-volume", "dir/object", "rpc-volume", "dir/renamed"); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if err = disk.DeleteFile("rpc-volume", "dir/renamed"); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if _, err = disk.StatFile("rpc-volume", "dir/renamed"); err != errFileNotFound {
+		t.Errorf("Expected %s, but found %v", errFileNotFound, err)
+	}
+	if err = disk.DeleteVol("rpc-volume"); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if _, err = disk.StatVol("rpc-volume"); err != errVolumeNotFound {
+		t.Errorf("Expected %s, but found %v", errVolumeNotFound, err)
+	}
+}
+
+// Tests validate that storage rpc calls are authenticated.
+func TestStorageRPCAuthentication(t *testing.T) {
+	testServer := StartTestServer(t, "FS")
+	defer testServer.Stop()
+
+	u, err := url.Parse(testServer.Server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	disk, err := newRPCClient(u.Host + ":" + testServer.Disks[0])
+	if err != nil {
+		t.Fatalf("Unable to initialize rpc client: <ERROR> %s", err)
+	}
+	if err = disk.AppendFile(minioMetaBucket, "object", []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+
+	// Expired tokens are renewed transparently.
+	ndisk := disk.(*networkStorage)
+	jwt := initJWT()
+	ndisk.token, err = jwt.GenerateRPCToken(rpcTokenAudience, -time.Minute)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if _, err = disk.StatFile(minioMetaBucket, "object"); err != nil {
+		t.Fatalf("Expected expired token to be renewed, but failed with: <ERROR> %s", err)
+	}
+
+	loginToken, err := jwt.GenerateRPCToken(rpcLoginAudience, rpcLoginTokenExpires)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	forgedJWT := &JWT{credential{AccessKeyID: jwt.AccessKeyID, SecretAccessKey: "forged-secret-key"}}
+	forgedToken, err := forgedJWT.GenerateRPCToken(rpcTokenAudience, rpcTokenExpires)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	forgedLoginToken, err := forgedJWT.GenerateRPCToken(rpcLoginAudience, rpcLoginTokenExpires)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+
+	testCases := []struct {
+		op    string
+		token string
+	}{
+		// Test case - 1.
+		// Calls without a token.
+		{"/deletefile", ""},
+		// Test case - 2.
+		// Calls with an invalid token.
+		{"/deletefile", "invalid"},
+		// Test case - 3.
+		// Calls with a login token.
+		{"/deletefile", loginToken},
+		// Test case - 4.
+		// Calls with a token signed with other credentials.
+		{"/deletefile", forgedToken},
+		// Test case - 5.
+		// Login signed with other credentials.
+		{"/login", forgedLoginToken},
+		// Test case - 6.
+		// Login with a call token.
+		{"/login", ndisk.token},
+	}
+	for i, testCase := range testCases {
+		reqURL := testServer.Server.URL + storageRPCPath + testCase.op + "?volume=" + minioMetaBucket + "&path=object"
+		req, err := http.NewRequest("POST", reqURL, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Unexpected error: <ERROR> %s", i+1, err)
+		}
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		if testCase.token != "" {
+			req.Header.Set("Authorization", jwtAlgorithm+" "+testCase.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: Unexpected error: <ERROR> %s", i+1, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Test %d: Expected status %d, but found %d", i+1, http.StatusUnauthorized, resp.StatusCode)
+		}
+	}
+	if _, err = disk.StatFile(minioMetaBucket, "object"); err != nil {
+		t.Fatalf("Expected object to be intact, but failed with: <ERROR> %s", err)
+	}
+}
+
+// Tests validate unreachable nodes are reported as disks not found.
+func TestStorageRPCUnreachable(t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	defer removeAll(rootPath)
+
+	// Address of a closed listener, nothing is listening on it.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	netAddr := listener.Addr().String()
+	listener.Close()
+
+	disk, err := newRPCClient(netAddr + ":/export")
+	if err != nil {
+		t.Fatalf("Unable to initialize rpc client: <ERROR> %s", err)
+	}
+	if _, err = disk.StatVol("volume"); err != errDiskNotFound {
+		t.Errorf("Expected %s, but found %v", errDiskNotFound, err)
+	}
+	if err = disk.AppendFile("volume", "object", []byte("hello")); err != errDiskNotFound {
+		t.Errorf("Expected %s, but found %v", errDiskNotFound, err)
+	}
+}
+
+// Tests validate XL over remote disks, one disk per node.
+func TestStorageRPCXL(t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	defer removeAll(rootPath)
+
+	var disks []string
+	for i := 0; i < 8; i++ {
+		diskPath, err := ioutil.TempDir(os.TempDir(), "minio-")
+		if err != nil {
+			t.Fatalf("Unexpected error: <ERROR> %s", err)
+		}
+		defer removeAll(diskPath)
+		stServer, err := newRPCServer(diskPath)
+		if err != nil {
+			t.Fatalf("Unexpected error: <ERROR> %s", err)
+		}
+		mux := router.NewRouter()
+		registerStorageRPCRouter(mux, stServer)
+		server := httptest.NewServer(mux)
+		defer server.Close()
+		disks = append(disks, server.Listener.Addr().String()+":"+diskPath)
+	}
+
+	initNSLock()
+	objLayer, err := newXLObjects(disks)
+	if err != nil {
+		t.Fatalf("Unable to initialize XL over remote disks: <ERROR> %s", err)
+	}
+	if err = objLayer.MakeBucket("bucket"); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	data := bytes.Repeat([]byte("a"), 3*storageRPCChunkSize)
+	if _, err = objLayer.PutObject("bucket", "object", int64(len(data)), bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	var buffer bytes.Buffer
+	if err = objLayer.GetObject("bucket", "object", 0, int64(len(data)), &buffer); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	if !bytes.Equal(buffer.Bytes(), data) {
+		t.Errorf("Expected object contents to match")
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Default region used for signing requests to remote endpoints.
+const s3DefaultRegion = "us-east-1"
+
+const (
+	// Timeouts of connecting to the remote endpoint and of waiting for
+	// its response headers, response bodies are streamed and only
+	// bounded by the caller.
+	s3ClientDialTimeout           = 30 * time.Second
+	s3ClientTLSHandshakeTimeout   = 10 * time.Second
+	s3ClientResponseHeaderTimeout = 1 * time.Minute
+)
+
+// s3ResponseError - error response returned by the remote endpoint.
+type s3ResponseError struct {
+	StatusCode int
+	APIErrorResponse
+}
+
+func (e s3ResponseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Remote endpoint failed with %d %s", e.StatusCode, e.Code)
+	}
+	return fmt.Sprintf("Remote endpoint failed with %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// s3Client - minimal S3 client used to talk to a remote S3 compatible
+// endpoint, requests are signed with signature v4.
+type s3Client struct {
+	endpoint   *url.URL
+	accessKey  string
+	secretKey  string
+	region     string
+	httpClient *http.Client
+}
+
+// newS3Client - initialize a new S3 client for a remote endpoint.
+func newS3Client(endpoint, accessKey, secretKey, region string) (*s3Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Invalid endpoint '%s'", endpoint)
+	}
+	if region == "" {
+		region = s3DefaultRegion
+	}
+	return &s3Client{
+		endpoint:  u,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		region:    region,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				Dial: (&net.Dialer{
+					Timeout:   s3ClientDialTimeout,
+					KeepAlive: 30 * time.Second,
+				}).Dial,
+				TLSHandshakeTimeout:   s3ClientTLSHandshakeTimeout,
+				ResponseHeaderTimeout: s3ClientResponseHeaderTimeout,
+				ExpectContinueTimeout: 1 * time.Second,
+			},
+		},
+	}, nil
+}
+
+// newRequest - creates a new signed request for bucket and object,
+// payload is only signed for requests without body.
+func (c *s3Client) newRequest(method, bucket, object string, query url.Values, body io.Reader, size int64, header http.Header) (*http.Request, error) {
+	u := *c.endpoint
+	u.Path = "/"
+	if bucket != "" {
+		u.Path += bucket
+		if object != "" {
+			u.Path += "/" + object
+		}
+	}
+	// Use the same encoding as signature calculation.
+	u.RawPath = strings.Replace(getURLEncodedName(u.Path), "+", "%20", -1)
+	queryStr := strings.Replace(query.Encode(), "+", "%20", -1)
+	u.RawQuery = queryStr
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+
+	payload := unsignedPayload
+	if body == nil {
+		payload = hex.EncodeToString(sum256(nil))
+	}
+
+	t := time.Now().UTC()
+	signedHeaders := make(http.Header)
+	for key, values := range header {
+		signedHeaders[http.CanonicalHeaderKey(key)] = values
+	}
+	signedHeaders.Set("X-Amz-Date", t.Format(iso8601Format))
+	signedHeaders.Set("X-Amz-Content-Sha256", payload)
+	for key, values := range signedHeaders {
+		req.Header[key] = values
+	}
+
+	canonicalRequest := getCanonicalRequest(signedHeaders, payload, queryStr, u.Path, method, req.URL.Host)
+	stringToSign := getStringToSign(canonicalRequest, t, c.region)
+	signature := getSignature(getSigningKey(c.secretKey, t, c.region), stringToSign)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signV4Algorithm, c.accessKey, getScope(t, c.region), getSignedHeaders(signedHeaders), signature))
+	return req, nil
+}
+
+// execute - executes the request, responses other than 2xx are
+// returned as s3ResponseError. Caller must close the response body.
+func (c *s3Client) execute(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	errResp := s3ResponseError{StatusCode: resp.StatusCode}
+	errBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(errBody, &errResp.APIErrorResponse) != nil || errResp.Code == "" {
+		// HEAD responses and some proxies don't carry an error body.
+		errResp.Code = strings.Replace(http.StatusText(resp.StatusCode), " ", "", -1)
+	}
+	return nil, errResp
+}
+
+// executeXML - executes the request and decodes the XML response into v.
+func (c *s3Client) executeXML(req *http.Request, v interface{}) error {
+	resp, err := c.execute(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+// executeNoBody - executes the request and discards the response.
+func (c *s3Client) executeNoBody(req *http.Request) (http.Header, error) {
+	resp, err := c.execute(req)
+	if err != nil {
+		return nil, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.Header, nil
+}
+
+// putObject - uploads an object to the remote bucket, returns the
+// ETag of the uploaded object.
+func (c *s3Client) putObject(bucket, object string, size int64, data io.Reader, header http.Header) (string, error) {
+	// Zero sized objects are sent without body, to avoid chunked encoding.
+	if size == 0 {
+		data = nil
+	}
+	req, err := c.newRequest("PUT", bucket, object, nil, data, size, header)
+	if err != nil {
+		return "", err
+	}
+	respHeader, err := c.executeNoBody(req)
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(respHeader.Get("ETag"), "\""), nil
+}
+
+// deleteObject - removes an object from the remote bucket, missing
+// objects are not treated as errors.
+func (c *s3Client) deleteObject(bucket, object string, header http.Header) error {
+	req, err := c.newRequest("DELETE", bucket, object, nil, nil, 0, header)
+	if err != nil {
+		return err
+	}
+	if _, err = c.executeNoBody(req); err != nil {
+		if errResp, ok := err.(s3ResponseError); ok && errResp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
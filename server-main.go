@@ -54,6 +54,13 @@ OPTIONS:
 ENVIRONMENT VARIABLES:
   MINIO_ACCESS_KEY: Access key string of 5 to 20 characters in length.
   MINIO_SECRET_KEY: Secret key string of 8 to 40 characters in length.
+  MINIO_CACHE_DRIVES: Comma separated list of drives to cache recently read objects.
+  MINIO_CACHE_HIGH_WATERMARK: Cache drive usage in percent above which eviction starts, defaults to 90.
+  MINIO_CACHE_LOW_WATERMARK: Cache drive usage in percent at which eviction stops, defaults to 70.
+  MINIO_COMPRESS: Set to "on" to compress new objects.
+  MINIO_COMPRESS_EXTENSIONS: Comma separated list of object extensions to compress, defaults to ".txt,.log,.csv,.json,.xml".
+  MINIO_COMPRESS_MIMETYPES: Comma separated list of content types to compress, defaults to "text/*,application/json,application/xml".
+  MINIO_LIST_INDEX: Comma separated list of buckets to list from a persistent index.
 
 EXAMPLES:
   1. Start minio server.
@@ -75,6 +82,8 @@ EXAMPLES:
 type serverCmdConfig struct {
 	serverAddr  string
 	exportPaths []string
+	// Remote S3 endpoint, only set in gateway mode.
+	gateway *gatewayConfig
 }
 
 // configureServer configure a new server instance
@@ -141,6 +150,29 @@ func initServerConfig(c *cli.Context) {
 		fatalIf(err, "Unable to convert MINIO_MAXCONN=%s environment variable into its integer value.", maxConnStr)
 	}
 
+	// Fetch deadline of disk calls from environment variable.
+	if timeoutStr := os.Getenv("MINIO_DISK_CALL_TIMEOUT"); timeoutStr != "" {
+		var err error
+		globalDiskCallTimeout, err = time.ParseDuration(timeoutStr)
+		fatalIf(err, "Unable to convert MINIO_DISK_CALL_TIMEOUT=%s environment variable into a duration.", timeoutStr)
+	}
+
+	// Fetch cache drives and watermarks from environment variables if any.
+	if cacheDrives := os.Getenv("MINIO_CACHE_DRIVES"); cacheDrives != "" {
+		globalCacheConfig, err = parseCacheConfig(cacheDrives, os.Getenv("MINIO_CACHE_HIGH_WATERMARK"), os.Getenv("MINIO_CACHE_LOW_WATERMARK"))
+		fatalIf(err, "Unable to parse MINIO_CACHE_DRIVES=%s environment variable.", cacheDrives)
+	}
+
+	// Fetch compression extensions and content types from environment variables if enabled.
+	if compress := os.Getenv("MINIO_COMPRESS"); strings.EqualFold(compress, "on") {
+		globalCompressConfig, err = parseCompressConfig(os.Getenv("MINIO_COMPRESS_EXTENSIONS"), os.Getenv("MINIO_COMPRESS_MIMETYPES"))
+		fatalIf(err, "Unable to parse MINIO_COMPRESS_EXTENSIONS or MINIO_COMPRESS_MIMETYPES environment variables.")
+	}
+
+	// Fetch buckets to list from an index if any.
+	globalListIndexBuckets, err = parseListIndexBuckets(os.Getenv("MINIO_LIST_INDEX"))
+	fatalIf(err, "Unable to parse MINIO_LIST_INDEX environment variable.")
+
 	// Fetch access keys from environment variables if any and update the config.
 	accessKey := os.Getenv("MINIO_ACCESS_KEY")
 	secretKey := os.Getenv("MINIO_SECRET_KEY")
@@ -257,11 +289,21 @@ func serverMain(c *cli.Context) {
 	// Initialize server config.
 	initServerConfig(c)
 
+	// Save all command line args as export paths.
+	startServer(serverCmdConfig{
+		serverAddr:  c.String("address"),
+		exportPaths: c.Args(),
+	})
+}
+
+// startServer - starts the server configured by server or gateway
+// command, prints listen addresses and credentials.
+func startServer(srvCmdConfig serverCmdConfig) {
 	// If https.
 	tls := isSSL()
 
 	// Server address.
-	serverAddress := c.String("address")
+	serverAddress := srvCmdConfig.serverAddr
 
 	host, port, _ := net.SplitHostPort(serverAddress)
 	// If port empty, default to port '80'
@@ -276,14 +318,8 @@ func serverMain(c *cli.Context) {
 	// Check if requested port is available.
 	checkPortAvailability(getPort(net.JoinHostPort(host, port)))
 
-	// Save all command line args as export paths.
-	exportPaths := c.Args()
-
 	// Configure server.
-	apiServer := configureServer(serverCmdConfig{
-		serverAddr:  serverAddress,
-		exportPaths: exportPaths,
-	})
+	apiServer := configureServer(srvCmdConfig)
 
 	// Credential.
 	cred := serverConfig.GetCredential()
@@ -19,6 +19,7 @@ package main
 import (
 	"bytes"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/xml"
@@ -26,7 +27,11 @@ import (
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -705,6 +710,196 @@ func (s *TestSuiteFS) TestCopyObject(c *C) {
 	object, err := ioutil.ReadAll(response.Body)
 	c.Assert(err, IsNil)
 	c.Assert(string(object), Equals, "hello world")
+
+	// copying an object onto itself is only allowed when replacing its metadata.
+	for _, directive := range []string{"", "REPLACE"} {
+		request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, objectName2),
+			0, nil, s.accessKey, s.secretKey)
+		c.Assert(err, IsNil)
+		request.Header.Set("X-Amz-Copy-Source", "/"+bucketName+"/"+objectName2)
+		if directive != "" {
+			request.Header.Set("X-Amz-Metadata-Directive", directive)
+		}
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		if directive == "" {
+			c.Assert(response.StatusCode, Equals, http.StatusBadRequest)
+		} else {
+			c.Assert(response.StatusCode, Equals, http.StatusOK)
+		}
+	}
+}
+
+// TestCopyObjectAnonymous - Validates anonymous copy requests need to
+// be allowed to read the source object.
+func (s *TestSuiteFS) TestCopyObjectAnonymous(c *C) {
+	// Anonymous clients can only write objects.
+	bucketPolicyBuf := `{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Action": [
+                "s3:PutObject"
+            ],
+            "Effect": "Allow",
+            "Principal": {
+                "AWS": [
+                    "*"
+                ]
+            },
+            "Resource": [
+                "arn:aws:s3:::%s/*"
+            ]
+        }
+    ]
+}`
+	// generate a random bucket name.
+	bucketName := getRandomBucketName()
+	request, err := newTestRequest("PUT", getMakeBucketURL(s.endPoint, bucketName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	bucketPolicyStr := fmt.Sprintf(bucketPolicyBuf, bucketName)
+	request, err = newTestRequest("PUT", getPutPolicyURL(s.endPoint, bucketName),
+		int64(len(bucketPolicyStr)), bytes.NewReader([]byte(bucketPolicyStr)), s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNoContent)
+
+	// private object to be copied.
+	buffer := bytes.NewReader([]byte("hello world"))
+	objectName := "private"
+	request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, objectName),
+		int64(buffer.Len()), buffer, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// anonymous copy of the private object is denied.
+	request, err = http.NewRequest("PUT", getPutObjectURL(s.endPoint, bucketName, "copy"), nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Copy-Source", "/"+bucketName+"/"+objectName)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+
+	// anonymous copy of the private object as a part is denied.
+	request, err = http.NewRequest("PUT", getPartUploadURL(s.endPoint, bucketName, "copy", "upload-id", "1"), nil)
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Copy-Source", "/"+bucketName+"/"+objectName)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "AccessDenied", "Access Denied.", http.StatusForbidden)
+}
+
+// TestCopyObjectPart - Validates upload part copy.
+// The following is the test flow.
+// 1. Create bucket.
+// 2. Insert Object.
+// 3. Initiate a multipart upload and copy a range of the previously inserted object as a part.
+// 4. Complete the multipart upload and validate the content of the object.
+func (s *TestSuiteFS) TestCopyObjectPart(c *C) {
+	// generate a random bucket name.
+	bucketName := getRandomBucketName()
+	// HTTP request to create the bucket.
+	request, err := newTestRequest("PUT", getMakeBucketURL(s.endPoint, bucketName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	// execute the HTTP request to create bucket.
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// content for the object to be inserted.
+	buffer1 := bytes.NewReader([]byte("hello world"))
+	objectName := "testObject"
+	// create HTTP request for object upload.
+	request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, objectName),
+		int64(buffer1.Len()), buffer1, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	// execute the HTTP request for object upload.
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	objectName2 := "testObject2"
+	// construct HTTP request to initiate a NewMultipart upload.
+	request, err = newTestRequest("POST", getNewMultipartURL(s.endPoint, bucketName, objectName2),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	// parse the response body and obtain the new upload ID.
+	newResponse := &InitiateMultipartUploadResponse{}
+	err = xml.NewDecoder(response.Body).Decode(newResponse)
+	c.Assert(err, IsNil)
+	uploadID := newResponse.UploadID
+
+	// copy source range beyond the source object is rejected.
+	request, err = newTestRequest("PUT", getPartUploadURL(s.endPoint, bucketName, objectName2, uploadID, "1"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Copy-Source", "/"+bucketName+"/"+objectName)
+	request.Header.Set("X-Amz-Copy-Source-Range", "bytes=6-11")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusRequestedRangeNotSatisfiable)
+
+	// copy source conditions are verified.
+	request, err = newTestRequest("PUT", getPartUploadURL(s.endPoint, bucketName, objectName2, uploadID, "1"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Copy-Source", "/"+bucketName+"/"+objectName)
+	request.Header.Set("X-Amz-Copy-Source-If-Unmodified-Since", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusPreconditionFailed)
+
+	// copy "world" from the previously uploaded object as the only part.
+	request, err = newTestRequest("PUT", getPartUploadURL(s.endPoint, bucketName, objectName2, uploadID, "1"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("X-Amz-Copy-Source", "/"+bucketName+"/"+objectName)
+	request.Header.Set("X-Amz-Copy-Source-Range", "bytes=6-10")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	copyResponse := &CopyObjectPartResponse{}
+	err = xml.NewDecoder(response.Body).Decode(copyResponse)
+	c.Assert(err, IsNil)
+
+	// Complete multipart upload
+	completeBytes, err := xml.Marshal(&completeMultipartUpload{
+		Parts: []completePart{{PartNumber: 1, ETag: copyResponse.ETag}},
+	})
+	c.Assert(err, IsNil)
+	request, err = newTestRequest("POST", getCompleteMultipartUploadURL(s.endPoint, bucketName, objectName2, uploadID),
+		int64(len(completeBytes)), bytes.NewReader(completeBytes), s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// creating HTTP request to fetch the assembled object.
+	request, err = newTestRequest("GET", getGetObjectURL(s.endPoint, bucketName, objectName2),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	object, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(object), Equals, "world")
 }
 
 // TestPutObject -  Tests successful put object request.
@@ -1031,6 +1226,55 @@ func (s *TestSuiteFS) TestContentTypePersists(c *C) {
 	c.Assert(response.Header.Get("Content-Type"), Equals, "application/json")
 }
 
+// TestObjectMetadataPersists - Validates the ETag, content headers and
+// user metadata saved with an object are returned by HEAD and GET.
+func (s *TestSuiteFS) TestObjectMetadataPersists(c *C) {
+	// generate a random bucket name.
+	bucketName := getRandomBucketName()
+	// HTTP request to create the bucket.
+	request, err := newTestRequest("PUT", getMakeBucketURL(s.endPoint, bucketName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// Upload an object with content headers and user metadata.
+	buffer := bytes.NewReader([]byte("hello world"))
+	objectName := "test-object"
+	request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, objectName),
+		int64(buffer.Len()), buffer, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("Content-Type", "text/plain")
+	request.Header.Set("Content-Encoding", "identity")
+	request.Header.Set("Content-Disposition", "attachment; filename=hello.txt")
+	request.Header.Set("Cache-Control", "no-cache")
+	request.Header.Set("X-Amz-Meta-Color", "blue")
+
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	etag := response.Header.Get("ETag")
+	c.Assert(etag, Equals, "\"5eb63bbbe01eeed093cb22bb8f5acdc3\"")
+
+	for _, method := range []string{"HEAD", "GET"} {
+		request, err = newTestRequest(method, getGetObjectURL(s.endPoint, bucketName, objectName),
+			0, nil, s.accessKey, s.secretKey)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		c.Assert(response.Header.Get("ETag"), Equals, etag)
+		c.Assert(response.Header.Get("Content-Type"), Equals, "text/plain")
+		c.Assert(response.Header.Get("Content-Encoding"), Equals, "identity")
+		c.Assert(response.Header.Get("Content-Disposition"), Equals, "attachment; filename=hello.txt")
+		c.Assert(response.Header.Get("Cache-Control"), Equals, "no-cache")
+		c.Assert(response.Header.Get("X-Amz-Meta-Color"), Equals, "blue")
+	}
+}
+
 // TestPartialContent - Validating for GetObject with partial content request.
 // By setting the Range header, A request to send specific bytes range of data from an
 // already uploaded object can be done.
@@ -1099,6 +1343,109 @@ func (s *TestSuiteFS) TestListObjectsHandlerErrors(c *C) {
 	verifyError(c, response, "InvalidArgument", "Argument maxKeys must be an integer between 0 and 2147483647.", http.StatusBadRequest)
 }
 
+// TestListObjectsV2 - Tests paging through objects with ListObjects V2
+// continuation tokens.
+func (s *TestSuiteFS) TestListObjectsV2(c *C) {
+	// generate a random bucket name.
+	bucketName := getRandomBucketName()
+	// HTTP request to create the bucket.
+	request, err := newTestRequest("PUT", getMakeBucketURL(s.endPoint, bucketName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	// execute the HTTP request to create bucket.
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	objectNames := []string{"a/1.txt", "a/2.txt", "a/3.txt", "b/1.txt"}
+	for _, objectName := range objectNames {
+		buffer := bytes.NewReader([]byte("hello world"))
+		request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, objectName),
+			int64(buffer.Len()), buffer, s.accessKey, s.secretKey)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	// page through all the objects two at a time.
+	var listedNames []string
+	token := ""
+	for {
+		request, err = newTestRequest("GET", getListObjectsV2URL(s.endPoint, bucketName, "", token, "", "2", ""),
+			0, nil, s.accessKey, s.secretKey)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		listResponse := ListObjectsV2Response{}
+		err = xml.NewDecoder(response.Body).Decode(&listResponse)
+		c.Assert(err, IsNil)
+		c.Assert(listResponse.ContinuationToken, Equals, token)
+		c.Assert(listResponse.KeyCount, Equals, len(listResponse.Contents))
+		for _, object := range listResponse.Contents {
+			// owner is not sent unless requested.
+			c.Assert(object.Owner, IsNil)
+			listedNames = append(listedNames, object.Key)
+		}
+		if !listResponse.IsTruncated {
+			break
+		}
+		c.Assert(listResponse.NextContinuationToken, Not(Equals), "")
+		token = listResponse.NextContinuationToken
+	}
+	c.Assert(listedNames, DeepEquals, objectNames)
+
+	// start-after with owner.
+	request, err = newTestRequest("GET", getListObjectsV2URL(s.endPoint, bucketName, "", "", "a/2.txt", "", "true"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	listResponse := ListObjectsV2Response{}
+	err = xml.NewDecoder(response.Body).Decode(&listResponse)
+	c.Assert(err, IsNil)
+	c.Assert(listResponse.StartAfter, Equals, "a/2.txt")
+	c.Assert(listResponse.KeyCount, Equals, 2)
+	c.Assert(listResponse.Contents[0].Key, Equals, "a/3.txt")
+	c.Assert(listResponse.Contents[0].Owner, NotNil)
+
+	// start-after sorting before the prefix lists all objects at prefix.
+	request, err = newTestRequest("GET", getListObjectsV2URL(s.endPoint, bucketName, "b/", "", "a/2.txt", "", ""),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	listResponse = ListObjectsV2Response{}
+	err = xml.NewDecoder(response.Body).Decode(&listResponse)
+	c.Assert(err, IsNil)
+	c.Assert(listResponse.KeyCount, Equals, 1)
+
+	// start-after sorting after the prefix lists nothing.
+	request, err = newTestRequest("GET", getListObjectsV2URL(s.endPoint, bucketName, "a/", "", "b/", "", ""),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	listResponse = ListObjectsV2Response{}
+	err = xml.NewDecoder(response.Body).Decode(&listResponse)
+	c.Assert(err, IsNil)
+	c.Assert(listResponse.KeyCount, Equals, 0)
+
+	// invalid continuation token.
+	request, err = newTestRequest("GET", getListObjectsV2URL(s.endPoint, bucketName, "", "invalid-token", "", "", ""),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "The continuation token provided is incorrect.", http.StatusBadRequest)
+}
+
 // TestPutBucketErrors - request for non valid bucket operation
 // and validate it with expected error result.
 func (s *TestSuiteFS) TestPutBucketErrors(c *C) {
@@ -1367,6 +1714,93 @@ func (s *TestSuiteFS) TestGetPartialObjectMisAligned(c *C) {
 	}
 }
 
+// TestGetObjectMultipleRanges - Tests multiple ranges of an object are
+// sent as a multipart/byteranges response.
+func (s *TestSuiteFS) TestGetObjectMultipleRanges(c *C) {
+	// generate a random bucket name.
+	bucketName := getRandomBucketName()
+	// HTTP request to create the bucket.
+	request, err := newTestRequest("PUT", getMakeBucketURL(s.endPoint, bucketName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	// execute the HTTP request to create bucket.
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	putContent := "hello world, this is a multi-range test object"
+	buffer := bytes.NewReader([]byte(putContent))
+	objectName := "testObject.txt"
+	// HTTP request to upload the object.
+	request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, objectName),
+		int64(buffer.Len()), buffer, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	// overlapping ranges 0-2 and 1-4 are collapsed into 0-4.
+	request, err = newTestRequest("GET", getGetObjectURL(s.endPoint, bucketName, objectName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("Range", "bytes=6-10, 0-2,1-4,-6")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+	mediaType, params, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	c.Assert(err, IsNil)
+	c.Assert(mediaType, Equals, "multipart/byteranges")
+	body, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(response.ContentLength, Equals, int64(len(body)))
+
+	size := len(putContent)
+	expectedParts := []struct {
+		contentRange string
+		data         string
+	}{
+		{fmt.Sprintf("bytes 0-4/%d", size), putContent[0:5]},
+		{fmt.Sprintf("bytes 6-10/%d", size), putContent[6:11]},
+		{fmt.Sprintf("bytes %d-%d/%d", size-6, size-1, size), putContent[size-6:]},
+	}
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for _, expectedPart := range expectedParts {
+		part, err := mr.NextPart()
+		c.Assert(err, IsNil)
+		c.Assert(part.Header.Get("Content-Range"), Equals, expectedPart.contentRange)
+		c.Assert(part.Header.Get("Content-Type"), Equals, "text/plain")
+		data, err := ioutil.ReadAll(part)
+		c.Assert(err, IsNil)
+		c.Assert(string(data), Equals, expectedPart.data)
+	}
+	_, err = mr.NextPart()
+	c.Assert(err, Equals, io.EOF)
+
+	// ranges collapsing into one are sent as a single range.
+	request, err = newTestRequest("GET", getGetObjectURL(s.endPoint, bucketName, objectName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("Range", "bytes=0-4,5-10")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+	c.Assert(response.Header.Get("Content-Range"), Equals, fmt.Sprintf("bytes 0-10/%d", size))
+	body, err = ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, putContent[0:11])
+
+	// too many ranges are rejected.
+	request, err = newTestRequest("GET", getGetObjectURL(s.endPoint, bucketName, objectName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("Range", "bytes="+strings.Repeat("0-0,", maxHTTPRanges)+"0-0")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidRange", "The requested range cannot be satisfied.", http.StatusRequestedRangeNotSatisfiable)
+}
+
 // TestGetPartialObjectLarge11MiB - Test validates partial content request for a 11MiB object.
 func (s *TestSuiteFS) TestGetPartialObjectLarge11MiB(c *C) {
 	// generate a random bucket name.
@@ -1929,6 +2363,73 @@ func (s *TestSuiteFS) TestObjectValidMD5(c *C) {
 	verifyError(c, response, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided. Check your key and signing method.", http.StatusForbidden)
 }
 
+// TestObjectChecksum - uploads objects with an additional checksum and
+// validates it is verified and returned.
+func (s *TestSuiteFS) TestObjectChecksum(c *C) {
+	// generate a random bucket name.
+	bucketName := getRandomBucketName()
+	// HTTP request to create the bucket.
+	request, err := newTestRequest("PUT", getMakeBucketURL(s.endPoint, bucketName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	// execute the HTTP request to create bucket.
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	data := []byte("hello world")
+	sum := sha256.Sum256(data)
+	checksum := base64.StdEncoding.EncodeToString(sum[:])
+	objectName := "test-object"
+	// HTTP request for the object to be uploaded with its checksum.
+	request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, objectName),
+		int64(len(data)), bytes.NewReader(data), s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("x-amz-checksum-sha256", checksum)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("x-amz-checksum-sha256"), Equals, checksum)
+
+	// Checksum is returned only if requested.
+	request, err = newTestRequest("HEAD", getHeadObjectURL(s.endPoint, bucketName, objectName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("x-amz-checksum-sha256"), Equals, "")
+	request, err = newTestRequest("HEAD", getHeadObjectURL(s.endPoint, bucketName, objectName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("x-amz-checksum-mode", "ENABLED")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	c.Assert(response.Header.Get("x-amz-checksum-sha256"), Equals, checksum)
+
+	// Upload with a wrong checksum fails.
+	request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, "test-object-bad"),
+		int64(len(data)), bytes.NewReader(data), s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("x-amz-checksum-crc32", "AAAAAA==")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "BadDigest", "The checksum you specified did not match the calculated checksum.", http.StatusBadRequest)
+
+	// Upload with more than one checksum fails.
+	request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, "test-object-bad"),
+		int64(len(data)), bytes.NewReader(data), s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("x-amz-checksum-crc32", "AAAAAA==")
+	request.Header.Set("x-amz-checksum-sha256", checksum)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusBadRequest)
+}
+
 // TestObjectMultipart - Initiates a NewMultipart upload, uploads 2 parts,
 // completes the multipart upload and validates the status of the operation.
 func (s *TestSuiteFS) TestObjectMultipart(c *C) {
@@ -2038,3 +2539,203 @@ func (s *TestSuiteFS) TestObjectMultipart(c *C) {
 	// verify whether complete multipart was successfull.
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 }
+
+// TestGetObjectPartNumber - Tests fetching parts of a multipart object
+// with the partNumber query parameter.
+func (s *TestSuiteFS) TestGetObjectPartNumber(c *C) {
+	// generate a random bucket name.
+	bucketName := getRandomBucketName()
+	// HTTP request to create the bucket.
+	request, err := newTestRequest("PUT", getMakeBucketURL(s.endPoint, bucketName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	// execute the HTTP request to create bucket.
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	objectName := "testObject"
+	// construct HTTP request to initiate a NewMultipart upload.
+	request, err = newTestRequest("POST", getNewMultipartURL(s.endPoint, bucketName, objectName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	// parse the response body and obtain the new upload ID.
+	newResponse := &InitiateMultipartUploadResponse{}
+	err = xml.NewDecoder(response.Body).Decode(newResponse)
+	c.Assert(err, IsNil)
+	uploadID := newResponse.UploadID
+
+	// upload a 5MB part followed by a small last part.
+	parts := [][]byte{
+		bytes.Repeat([]byte("0123456789abcdef"), 5*1024*1024/16),
+		[]byte("hello world"),
+	}
+	var completeParts []completePart
+	for i, data := range parts {
+		partNumber := strconv.Itoa(i + 1)
+		request, err = newTestRequest("PUT", getPartUploadURL(s.endPoint, bucketName, objectName, uploadID, partNumber),
+			int64(len(data)), bytes.NewReader(data), s.accessKey, s.secretKey)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		completeParts = append(completeParts, completePart{PartNumber: i + 1, ETag: response.Header.Get("ETag")})
+	}
+	completeBytes, err := xml.Marshal(&completeMultipartUpload{Parts: completeParts})
+	c.Assert(err, IsNil)
+	request, err = newTestRequest("POST", getCompleteMultipartUploadURL(s.endPoint, bucketName, objectName, uploadID),
+		int64(len(completeBytes)), bytes.NewReader(completeBytes), s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	objectSize := len(parts[0]) + len(parts[1])
+	// fetch the second part and verify its range.
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, objectName, "2"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+	c.Assert(response.Header.Get("Content-Range"), Equals, fmt.Sprintf("bytes %d-%d/%d", len(parts[0]), objectSize-1, objectSize))
+	c.Assert(response.Header.Get("x-amz-mp-parts-count"), Equals, "2")
+	object, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(object), Equals, "hello world")
+
+	// HEAD of the first part.
+	request, err = newTestRequest("HEAD", getGetObjectPartURL(s.endPoint, bucketName, objectName, "1"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+	c.Assert(response.ContentLength, Equals, int64(len(parts[0])))
+	c.Assert(response.Header.Get("x-amz-mp-parts-count"), Equals, "2")
+
+	// preconditions are verified before replying with partial content.
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, objectName, "2"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("If-Match", "\"mismatch\"")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "PreconditionFailed", "At least one of the preconditions you specified did not hold.", http.StatusPreconditionFailed)
+
+	request, err = newTestRequest("HEAD", getGetObjectPartURL(s.endPoint, bucketName, objectName, "1"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusNotModified)
+
+	// part numbers beyond the object are not satisfiable.
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, objectName, "3"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidPartNumber", "The requested partnumber is not satisfiable.", http.StatusRequestedRangeNotSatisfiable)
+
+	// invalid part number.
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, objectName, "0"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "Part number must be an integer between 1 and 10000, inclusive.", http.StatusBadRequest)
+
+	// part number cannot be combined with a range.
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, objectName, "1"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("Range", "bytes=0-10")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusBadRequest)
+
+	// objects uploaded with a single PUT only have part 1.
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, "singleObject"),
+		int64(buffer.Len()), buffer, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, "singleObject", "1"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+	c.Assert(response.Header.Get("x-amz-mp-parts-count"), Equals, "")
+	object, err = ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(object), Equals, "hello world")
+
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, "singleObject", "2"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidPartNumber", "The requested partnumber is not satisfiable.", http.StatusRequestedRangeNotSatisfiable)
+}
+
+// TestSelectObjectContent - Tests filtering an object with an SQL
+// expression, records are returned as event stream messages.
+func (s *TestSuiteFS) TestSelectObjectContent(c *C) {
+	// generate a random bucket name.
+	bucketName := getRandomBucketName()
+	// HTTP request to create the bucket.
+	request, err := newTestRequest("PUT", getMakeBucketURL(s.endPoint, bucketName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	// execute the HTTP request to create bucket.
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	objectName := "people.csv"
+	data := []byte("name,age\nalice,31\nbob,25\n")
+	request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, objectName),
+		int64(len(data)), bytes.NewReader(data), s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	selectRequest := []byte(selectTestRequest("SELECT s.name FROM S3Object s WHERE s.age &lt; 30",
+		"<CSV><FileHeaderInfo>USE</FileHeaderInfo></CSV>", "<CSV/>"))
+	request, err = newTestRequest("POST", getSelectObjectContentURL(s.endPoint, bucketName, objectName),
+		int64(len(selectRequest)), bytes.NewReader(selectRequest), s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	events, err := decodeSelectEvents(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(len(events), Equals, 3)
+	c.Assert(events[0].headers[":event-type"], Equals, "Records")
+	c.Assert(string(events[0].payload), Equals, "bob\n")
+	c.Assert(events[1].headers[":event-type"], Equals, "Stats")
+	c.Assert(events[2].headers[":event-type"], Equals, "End")
+
+	// invalid expressions are rejected before the object is read.
+	selectRequest = []byte(selectTestRequest("SELECT name FROM people", "<CSV/>", "<CSV/>"))
+	request, err = newTestRequest("POST", getSelectObjectContentURL(s.endPoint, bucketName, objectName),
+		int64(len(selectRequest)), bytes.NewReader(selectRequest), s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "UnsupportedSyntax", "The SQL expression is invalid or uses unsupported syntax.", http.StatusBadRequest)
+}
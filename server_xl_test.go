@@ -27,6 +27,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -1207,6 +1208,109 @@ func (s *TestSuiteXL) TestListObjectsHandlerErrors(c *C) {
 	verifyError(c, response, "InvalidArgument", "Argument maxKeys must be an integer between 0 and 2147483647.", http.StatusBadRequest)
 }
 
+// TestListObjectsV2 - Tests paging through objects with ListObjects V2
+// continuation tokens.
+func (s *TestSuiteXL) TestListObjectsV2(c *C) {
+	// generate a random bucket name.
+	bucketName := getRandomBucketName()
+	// HTTP request to create the bucket.
+	request, err := newTestRequest("PUT", getMakeBucketURL(s.endPoint, bucketName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	// execute the HTTP request to create bucket.
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	objectNames := []string{"a/1.txt", "a/2.txt", "a/3.txt", "b/1.txt"}
+	for _, objectName := range objectNames {
+		buffer := bytes.NewReader([]byte("hello world"))
+		request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, objectName),
+			int64(buffer.Len()), buffer, s.accessKey, s.secretKey)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+	}
+
+	// page through all the objects two at a time.
+	var listedNames []string
+	token := ""
+	for {
+		request, err = newTestRequest("GET", getListObjectsV2URL(s.endPoint, bucketName, "", token, "", "2", ""),
+			0, nil, s.accessKey, s.secretKey)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		listResponse := ListObjectsV2Response{}
+		err = xml.NewDecoder(response.Body).Decode(&listResponse)
+		c.Assert(err, IsNil)
+		c.Assert(listResponse.ContinuationToken, Equals, token)
+		c.Assert(listResponse.KeyCount, Equals, len(listResponse.Contents))
+		for _, object := range listResponse.Contents {
+			// owner is not sent unless requested.
+			c.Assert(object.Owner, IsNil)
+			listedNames = append(listedNames, object.Key)
+		}
+		if !listResponse.IsTruncated {
+			break
+		}
+		c.Assert(listResponse.NextContinuationToken, Not(Equals), "")
+		token = listResponse.NextContinuationToken
+	}
+	c.Assert(listedNames, DeepEquals, objectNames)
+
+	// start-after with owner.
+	request, err = newTestRequest("GET", getListObjectsV2URL(s.endPoint, bucketName, "", "", "a/2.txt", "", "true"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	listResponse := ListObjectsV2Response{}
+	err = xml.NewDecoder(response.Body).Decode(&listResponse)
+	c.Assert(err, IsNil)
+	c.Assert(listResponse.StartAfter, Equals, "a/2.txt")
+	c.Assert(listResponse.KeyCount, Equals, 2)
+	c.Assert(listResponse.Contents[0].Key, Equals, "a/3.txt")
+	c.Assert(listResponse.Contents[0].Owner, NotNil)
+
+	// start-after sorting before the prefix lists all objects at prefix.
+	request, err = newTestRequest("GET", getListObjectsV2URL(s.endPoint, bucketName, "b/", "", "a/2.txt", "", ""),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	listResponse = ListObjectsV2Response{}
+	err = xml.NewDecoder(response.Body).Decode(&listResponse)
+	c.Assert(err, IsNil)
+	c.Assert(listResponse.KeyCount, Equals, 1)
+
+	// start-after sorting after the prefix lists nothing.
+	request, err = newTestRequest("GET", getListObjectsV2URL(s.endPoint, bucketName, "a/", "", "b/", "", ""),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	listResponse = ListObjectsV2Response{}
+	err = xml.NewDecoder(response.Body).Decode(&listResponse)
+	c.Assert(err, IsNil)
+	c.Assert(listResponse.KeyCount, Equals, 0)
+
+	// invalid continuation token.
+	request, err = newTestRequest("GET", getListObjectsV2URL(s.endPoint, bucketName, "", "invalid-token", "", "", ""),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "The continuation token provided is incorrect.", http.StatusBadRequest)
+}
+
 // TestPutBucketErrors - request for non valid bucket operation
 // and validate it with expected error result.
 func (s *TestSuiteXL) TestPutBucketErrors(c *C) {
@@ -2222,6 +2326,138 @@ func (s *TestSuiteXL) TestObjectMultipart(c *C) {
 	c.Assert(response.StatusCode, Equals, http.StatusOK)
 }
 
+// TestGetObjectPartNumber - Tests fetching parts of a multipart object
+// with the partNumber query parameter.
+func (s *TestSuiteXL) TestGetObjectPartNumber(c *C) {
+	// generate a random bucket name.
+	bucketName := getRandomBucketName()
+	// HTTP request to create the bucket.
+	request, err := newTestRequest("PUT", getMakeBucketURL(s.endPoint, bucketName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+
+	client := http.Client{}
+	// execute the HTTP request to create bucket.
+	response, err := client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	objectName := "testObject"
+	// construct HTTP request to initiate a NewMultipart upload.
+	request, err = newTestRequest("POST", getNewMultipartURL(s.endPoint, bucketName, objectName),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+	// parse the response body and obtain the new upload ID.
+	newResponse := &InitiateMultipartUploadResponse{}
+	err = xml.NewDecoder(response.Body).Decode(newResponse)
+	c.Assert(err, IsNil)
+	uploadID := newResponse.UploadID
+
+	// upload a 5MB part followed by a small last part.
+	parts := [][]byte{
+		bytes.Repeat([]byte("0123456789abcdef"), 5*1024*1024/16),
+		[]byte("hello world"),
+	}
+	var completeParts []completePart
+	for i, data := range parts {
+		partNumber := strconv.Itoa(i + 1)
+		request, err = newTestRequest("PUT", getPartUploadURL(s.endPoint, bucketName, objectName, uploadID, partNumber),
+			int64(len(data)), bytes.NewReader(data), s.accessKey, s.secretKey)
+		c.Assert(err, IsNil)
+		response, err = client.Do(request)
+		c.Assert(err, IsNil)
+		c.Assert(response.StatusCode, Equals, http.StatusOK)
+		completeParts = append(completeParts, completePart{PartNumber: i + 1, ETag: response.Header.Get("ETag")})
+	}
+	completeBytes, err := xml.Marshal(&completeMultipartUpload{Parts: completeParts})
+	c.Assert(err, IsNil)
+	request, err = newTestRequest("POST", getCompleteMultipartUploadURL(s.endPoint, bucketName, objectName, uploadID),
+		int64(len(completeBytes)), bytes.NewReader(completeBytes), s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	objectSize := len(parts[0]) + len(parts[1])
+	// fetch the second part and verify its range.
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, objectName, "2"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+	c.Assert(response.Header.Get("Content-Range"), Equals, fmt.Sprintf("bytes %d-%d/%d", len(parts[0]), objectSize-1, objectSize))
+	c.Assert(response.Header.Get("x-amz-mp-parts-count"), Equals, "2")
+	object, err := ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(object), Equals, "hello world")
+
+	// HEAD of the first part.
+	request, err = newTestRequest("HEAD", getGetObjectPartURL(s.endPoint, bucketName, objectName, "1"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+	c.Assert(response.ContentLength, Equals, int64(len(parts[0])))
+	c.Assert(response.Header.Get("x-amz-mp-parts-count"), Equals, "2")
+
+	// part numbers beyond the object are not satisfiable.
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, objectName, "3"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidPartNumber", "The requested partnumber is not satisfiable.", http.StatusRequestedRangeNotSatisfiable)
+
+	// invalid part number.
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, objectName, "0"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidArgument", "Part number must be an integer between 1 and 10000, inclusive.", http.StatusBadRequest)
+
+	// part number cannot be combined with a range.
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, objectName, "1"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	request.Header.Set("Range", "bytes=0-10")
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusBadRequest)
+
+	// objects uploaded with a single PUT only have part 1.
+	buffer := bytes.NewReader([]byte("hello world"))
+	request, err = newTestRequest("PUT", getPutObjectURL(s.endPoint, bucketName, "singleObject"),
+		int64(buffer.Len()), buffer, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusOK)
+
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, "singleObject", "1"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	c.Assert(response.StatusCode, Equals, http.StatusPartialContent)
+	c.Assert(response.Header.Get("x-amz-mp-parts-count"), Equals, "")
+	object, err = ioutil.ReadAll(response.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(object), Equals, "hello world")
+
+	request, err = newTestRequest("GET", getGetObjectPartURL(s.endPoint, bucketName, "singleObject", "2"),
+		0, nil, s.accessKey, s.secretKey)
+	c.Assert(err, IsNil)
+	response, err = client.Do(request)
+	c.Assert(err, IsNil)
+	verifyError(c, response, "InvalidPartNumber", "The requested partnumber is not satisfiable.", http.StatusRequestedRangeNotSatisfiable)
+}
+
 // TestObjectMultipartOverwriteSinglePut - Initiates a NewMultipart upload, uploads 2 parts,
 // completes the multipart upload and validates the status of the operation.
 // then, after PutObject with same object name on the bucket,
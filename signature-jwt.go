@@ -17,6 +17,7 @@
 package main
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -31,9 +32,26 @@ type JWT struct {
 	credential
 }
 
-// Default - each token expires in 10hrs.
+// Web browser tokens, short lived access tokens are sent with every
+// request and are renewed with a refresh token until the session
+// expires.
 const (
-	tokenExpires time.Duration = 10
+	webAccessTokenExpires  = 15 * time.Minute
+	webRefreshTokenExpires = 10 * time.Hour
+
+	webAccessAudience  = "minio-browser"
+	webRefreshAudience = "minio-browser-refresh"
+)
+
+// Storage rpc tokens, login tokens are signed by a node to prove it
+// knows the server credential and are exchanged for short lived
+// tokens sent with every storage rpc call.
+const (
+	rpcLoginTokenExpires = 5 * time.Minute
+	rpcTokenExpires      = 15 * time.Minute
+
+	rpcLoginAudience = "minio-storage-login"
+	rpcTokenAudience = "minio-storage"
 )
 
 // initJWT - initialize.
@@ -47,14 +65,73 @@ func initJWT() *JWT {
 	return jwt
 }
 
-// GenerateToken - generates a new Json Web Token based on the incoming user id.
+// GenerateToken - generates a new Json Web Token based on the incoming
+// user id, an access token for web requests.
 func (jwt *JWT) GenerateToken(userName string) (string, error) {
+	return jwt.generateWebToken(userName, webAccessAudience, webAccessTokenExpires)
+}
+
+// GenerateRefreshToken - generates a refresh token for the incoming user
+// id, exchanged for new access tokens until the session expires.
+func (jwt *JWT) GenerateRefreshToken(userName string) (string, error) {
+	return jwt.generateWebToken(userName, webRefreshAudience, webRefreshTokenExpires)
+}
+
+// generateWebToken - generates a web token for the given audience valid
+// for expiry, signed with the web token signing key. Each token has a
+// unique id to be revoked.
+func (jwt *JWT) generateWebToken(userName, audience string, expiry time.Duration) (string, error) {
+	if globalWebTokens == nil {
+		return "", errWebTokensNotInitialized
+	}
 	token := jwtgo.New(jwtgo.SigningMethodHS512)
-	// Token expires in 10hrs.
-	token.Claims["exp"] = time.Now().Add(time.Hour * tokenExpires).Unix()
-	token.Claims["iat"] = time.Now().Unix()
+	now := time.Now().UTC()
+	token.Claims["exp"] = now.Add(expiry).Unix()
+	token.Claims["iat"] = now.Unix()
 	token.Claims["sub"] = userName
-	return token.SignedString([]byte(jwt.SecretAccessKey))
+	token.Claims["aud"] = audience
+	token.Claims["jti"] = getUUID()
+	return token.SignedString(globalWebTokens.SigningKey())
+}
+
+// ParseWebToken - parses a web token, it is valid if it is signed with
+// the web token signing key, not expired nor revoked and was issued to
+// the server credential for the given audience.
+func (jwt *JWT) ParseWebToken(tokenStr, audience string) (*jwtgo.Token, error) {
+	if globalWebTokens == nil {
+		return nil, errWebTokensNotInitialized
+	}
+	token, e := jwtgo.Parse(tokenStr, func(token *jwtgo.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwtgo.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return globalWebTokens.SigningKey(), nil
+	})
+	if e != nil || !token.Valid {
+		return nil, errInvalidToken
+	}
+	subject, _ := token.Claims["sub"].(string)
+	tokenAudience, _ := token.Claims["aud"].(string)
+	tokenID, _ := token.Claims["jti"].(string)
+	if subject != jwt.AccessKeyID || tokenAudience != audience || tokenID == "" {
+		return nil, errInvalidToken
+	}
+	if globalWebTokens.IsRevoked(tokenID) {
+		return nil, errInvalidToken
+	}
+	return token, nil
+}
+
+// RevokeWebToken - revokes a valid web token of the given audience
+// until it expires.
+func (jwt *JWT) RevokeWebToken(tokenStr, audience string) error {
+	token, err := jwt.ParseWebToken(tokenStr, audience)
+	if err != nil {
+		return err
+	}
+	tokenID, _ := token.Claims["jti"].(string)
+	expiry, _ := token.Claims["exp"].(float64)
+	return globalWebTokens.Revoke(tokenID, time.Unix(int64(expiry), 0))
 }
 
 // Authenticate - authenticates incoming username and password.
@@ -67,3 +144,33 @@ func (jwt *JWT) Authenticate(userName, password string) bool {
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(jwt.SecretAccessKey), bcrypt.DefaultCost)
 	return bcrypt.CompareHashAndPassword(hashedPassword, []byte(password)) == nil
 }
+
+// GenerateRPCToken - generates a storage rpc token for the given
+// audience, valid for expiry.
+func (jwt *JWT) GenerateRPCToken(audience string, expiry time.Duration) (string, error) {
+	token := jwtgo.New(jwtgo.SigningMethodHS512)
+	now := time.Now().UTC()
+	token.Claims["exp"] = now.Add(expiry).Unix()
+	token.Claims["iat"] = now.Unix()
+	token.Claims["sub"] = jwt.AccessKeyID
+	token.Claims["aud"] = audience
+	return token.SignedString([]byte(jwt.SecretAccessKey))
+}
+
+// ValidateRPCToken - validates signature and expiry of a storage rpc
+// token, and that it was issued to the server credential for the
+// given audience.
+func (jwt *JWT) ValidateRPCToken(tokenStr, audience string) bool {
+	token, e := jwtgo.Parse(tokenStr, func(token *jwtgo.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwtgo.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(jwt.SecretAccessKey), nil
+	})
+	if e != nil || !token.Valid {
+		return false
+	}
+	subject, _ := token.Claims["sub"].(string)
+	tokenAudience, _ := token.Claims["aud"].(string)
+	return subject == jwt.AccessKeyID && tokenAudience == audience
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -39,6 +40,12 @@ func toInteger(val interface{}) int {
 	switch v := val.(type) {
 	case int:
 		return v
+	case float64:
+		// JSON numbers are decoded as float64.
+		return int(v)
+	case string:
+		i, _ := strconv.Atoi(v)
+		return i
 	}
 	return 0
 }
@@ -151,8 +158,9 @@ func parsePostPolicyFormV4(policy string) (PostPolicyForm, error) {
 	return parsedPolicy, nil
 }
 
-// checkPostPolicy - apply policy conditions and validate input values.
-func checkPostPolicy(formValues map[string]string) APIErrorCode {
+// checkPostPolicy - apply policy conditions and validate input values
+// and the size of the uploaded file.
+func checkPostPolicy(formValues map[string]string, size int64) APIErrorCode {
 	if formValues["X-Amz-Algorithm"] != signV4Algorithm {
 		return ErrSignatureVersionNotSupported
 	}
@@ -198,5 +206,13 @@ func checkPostPolicy(formValues map[string]string) APIErrorCode {
 			return ErrMissingFields
 		}
 	}
+	if lengthRange := postPolicyForm.Conditions.ContentLengthRange; lengthRange.Max > 0 {
+		if size < int64(lengthRange.Min) {
+			return ErrEntityTooSmall
+		}
+		if size > int64(lengthRange.Max) {
+			return ErrEntityTooLarge
+		}
+	}
 	return ErrNone
 }
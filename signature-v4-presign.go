@@ -0,0 +1,109 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// This file implements generation of AWS Signature Version '4'
+// presigned URLs and POST policies.
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum expiry of presigned URLs and POST policies.
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// errInvalidPresignExpiry - expiry is not between a second and 7 days.
+var errInvalidPresignExpiry = errors.New("Expiry should be between 1 second and 7 days")
+
+// isValidPresignExpiry - verifies expiry of presigned URLs and POST
+// policies, a multiple of a second up to 7 days.
+func isValidPresignExpiry(expiry time.Duration) bool {
+	return expiry >= time.Second && expiry <= maxPresignExpiry && expiry%time.Second == 0
+}
+
+// preSignV4 - returns the presigned path and query of a request of
+// method for urlPath, only the host header is signed. Presigned
+// requests are valid for expiry from t.
+//   - http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+func preSignV4(cred credential, region, method, host, urlPath string, t time.Time, expiry time.Duration) (string, error) {
+	if !isValidPresignExpiry(expiry) {
+		return "", errInvalidPresignExpiry
+	}
+	query := make(url.Values)
+	query.Set("X-Amz-Algorithm", signV4Algorithm)
+	query.Set("X-Amz-Credential", cred.AccessKeyID+"/"+getScope(t, region))
+	query.Set("X-Amz-Date", t.Format(iso8601Format))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expiry/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	encodedQuery := query.Encode()
+
+	// Sign the canonical request, presigned requests have no signed payload.
+	canonicalReq := getCanonicalRequest(make(http.Header), "UNSIGNED-PAYLOAD", encodedQuery, urlPath, method, host)
+	stringToSign := getStringToSign(canonicalReq, t, region)
+	signature := getSignature(getSigningKey(cred.SecretAccessKey, t, region), stringToSign)
+
+	encodedPath := strings.Replace(getURLEncodedName(urlPath), "+", "%20", -1)
+	return encodedPath + "?" + encodedQuery + "&X-Amz-Signature=" + signature, nil
+}
+
+// preSignPostPolicyV4 - returns the form fields of a signed POST policy
+// which allows uploads into bucket of objects named with keyPrefix, up
+// to maxSize bytes if maxSize is positive. The policy is valid for
+// expiry from t.
+//   - http://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-HTTPPOSTConstructPolicy.html
+func preSignPostPolicyV4(cred credential, region, bucket, keyPrefix string, maxSize int64, t time.Time, expiry time.Duration) (map[string]string, error) {
+	if !isValidPresignExpiry(expiry) {
+		return nil, errInvalidPresignExpiry
+	}
+	credential := cred.AccessKeyID + "/" + getScope(t, region)
+	date := t.Format(iso8601Format)
+	conditions := []interface{}{
+		[]string{"eq", "$bucket", bucket},
+		[]string{"starts-with", "$key", keyPrefix},
+		[]string{"eq", "$x-amz-algorithm", signV4Algorithm},
+		[]string{"eq", "$x-amz-credential", credential},
+		[]string{"eq", "$x-amz-date", date},
+	}
+	if maxSize > 0 {
+		conditions = append(conditions, []interface{}{"content-length-range", 0, maxSize})
+	}
+	policyBytes, err := json.Marshal(struct {
+		Expiration string        `json:"expiration"`
+		Conditions []interface{} `json:"conditions"`
+	}{
+		Expiration: t.Add(expiry).UTC().Format("2006-01-02T15:04:05.000Z"),
+		Conditions: conditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	policy := base64.StdEncoding.EncodeToString(policyBytes)
+	return map[string]string{
+		"key":              keyPrefix,
+		"policy":           policy,
+		"x-amz-algorithm":  signV4Algorithm,
+		"x-amz-credential": credential,
+		"x-amz-date":       date,
+		"x-amz-signature":  getSignature(getSigningKey(cred.SecretAccessKey, t, region), policy),
+	}, nil
+}
@@ -0,0 +1,144 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Tests validate presigned URLs are accepted by signature verification.
+func TestPreSignV4(t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to initialize config: <ERROR> %s", err)
+	}
+	defer removeAll(rootPath)
+	cred := serverConfig.GetCredential()
+	region := serverConfig.GetRegion()
+	now := time.Now().UTC()
+
+	testCases := []struct {
+		method      string
+		object      string
+		requestTime time.Time
+		expiry      time.Duration
+		expectedErr APIErrorCode
+	}{
+		// Test case - 1.
+		// Valid presigned GET.
+		{"GET", "object", now, time.Hour, ErrNone},
+		// Test case - 2.
+		// Valid presigned PUT of an object name to be encoded.
+		{"PUT", "dir/hello world+ü.txt", now, maxPresignExpiry, ErrNone},
+		// Test case - 3.
+		// Expired URL.
+		{"GET", "object", now.Add(-2 * time.Hour), time.Hour, ErrExpiredPresignRequest},
+	}
+	for i, testCase := range testCases {
+		presignedPath, pErr := preSignV4(cred, region, testCase.method, "localhost:9000", "/bucket/"+testCase.object, testCase.requestTime, testCase.expiry)
+		if pErr != nil {
+			t.Fatalf("Test %d: Unexpected error: <ERROR> %s", i+1, pErr)
+		}
+		req, rErr := http.NewRequest(testCase.method, "http://localhost:9000"+presignedPath, nil)
+		if rErr != nil {
+			t.Fatalf("Test %d: Unexpected error: <ERROR> %s", i+1, rErr)
+		}
+		if req.URL.Path != "/bucket/"+testCase.object {
+			t.Errorf("Test %d: Expected path /bucket/%s, found %s", i+1, testCase.object, req.URL.Path)
+		}
+		if s3Error := doesPresignedSignatureMatch("", req, true); s3Error != testCase.expectedErr {
+			t.Errorf("Test %d: Expected %v, found %v", i+1, testCase.expectedErr, s3Error)
+		}
+		// URL is valid only for its method.
+		req.Method = "DELETE"
+		if s3Error := doesPresignedSignatureMatch("", req, true); s3Error == ErrNone {
+			t.Errorf("Test %d: Expected signature of other method to fail", i+1)
+		}
+	}
+
+	// Expiry is limited to 7 days.
+	for _, expiry := range []time.Duration{0, -time.Second, maxPresignExpiry + time.Second, 1500 * time.Millisecond} {
+		if _, err = preSignV4(cred, region, "GET", "localhost:9000", "/bucket/object", now, expiry); err != errInvalidPresignExpiry {
+			t.Errorf("Expected %s for expiry %s, found %v", errInvalidPresignExpiry, expiry, err)
+		}
+	}
+}
+
+// Tests validate POST policies are accepted by policy verification.
+func TestPreSignPostPolicyV4(t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to initialize config: <ERROR> %s", err)
+	}
+	defer removeAll(rootPath)
+	cred := serverConfig.GetCredential()
+	region := serverConfig.GetRegion()
+
+	formData, err := preSignPostPolicyV4(cred, region, "bucket", "uploads/", 1024, time.Now().UTC(), time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+
+	testCases := []struct {
+		bucket      string
+		key         string
+		size        int64
+		expectedErr APIErrorCode
+	}{
+		// Test case - 1.
+		// Upload within the prefix and size.
+		{"bucket", "uploads/object", 1024, ErrNone},
+		// Test case - 2.
+		// Upload larger than the maximum size.
+		{"bucket", "uploads/object", 1025, ErrEntityTooLarge},
+		// Test case - 3.
+		// Upload outside of the prefix.
+		{"bucket", "object", 10, ErrMissingFields},
+		// Test case - 4.
+		// Upload into another bucket.
+		{"other-bucket", "uploads/object", 10, ErrMissingFields},
+	}
+	for i, testCase := range testCases {
+		formValues := make(map[string]string)
+		for key, value := range formData {
+			formValues[http.CanonicalHeaderKey(key)] = value
+		}
+		formValues["Bucket"] = testCase.bucket
+		formValues["Key"] = testCase.key
+		if s3Error := doesPolicySignatureMatch(formValues); s3Error != ErrNone {
+			t.Fatalf("Test %d: Expected policy signature to match, found %v", i+1, s3Error)
+		}
+		if s3Error := checkPostPolicy(formValues, testCase.size); s3Error != testCase.expectedErr {
+			t.Errorf("Test %d: Expected %v, found %v", i+1, testCase.expectedErr, s3Error)
+		}
+	}
+
+	// Expired policies are refused.
+	formData, err = preSignPostPolicyV4(cred, region, "bucket", "", 0, time.Now().UTC().Add(-2*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	formValues := map[string]string{"Bucket": "bucket", "Key": "object"}
+	for key, value := range formData {
+		formValues[http.CanonicalHeaderKey(key)] = value
+	}
+	if s3Error := checkPostPolicy(formValues, 10); s3Error != ErrPolicyAlreadyExpired {
+		t.Errorf("Expected %v, found %v", ErrPolicyAlreadyExpired, s3Error)
+	}
+}
@@ -0,0 +1,408 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Consecutive faults after which a disk is marked offline.
+	diskMaxFaults = 5
+
+	// Calls slower than this are counted as faults, a hung disk
+	// is as bad as a missing one.
+	diskSlowCall = 15 * time.Second
+
+	// Calls not done by this deadline fail with errFaultyDisk, they
+	// keep running in the background. Calls have no deadline if it
+	// is zero.
+	diskCallTimeout = 1 * time.Minute
+
+	// Interval at which offline disks are probed.
+	diskProbeInterval = 5 * time.Second
+
+	// Weight of the latest call in the moving average of latency.
+	diskLatencyWeight = 0.2
+)
+
+// healthStorage - wraps a StorageAPI, tracking its errors and
+// latency. After maxFaults consecutive faults, errDiskNotFound,
+// errFaultyDisk or slow calls, the disk is marked offline and calls
+// fail fast with errDiskNotFound instead of waiting on the disk. An
+// offline disk is probed every probeInterval in the background, and
+// brought back online once a probe succeeds.
+type healthStorage struct {
+	// Set by a probe for disks missing at startup, never changed
+	// while the disk is online.
+	disk StorageAPI
+	path string
+
+	// Connects a disk missing at startup, nil for other disks.
+	connect func() (StorageAPI, string, error)
+
+	// Thresholds, set from the constants above.
+	maxFaults     int
+	slowCall      time.Duration
+	callTimeout   time.Duration
+	probeInterval time.Duration
+
+	mutex        sync.Mutex
+	online       bool
+	faults       int
+	calls        int64
+	errors       int64
+	latency      time.Duration
+	lastErr      error
+	offlineSince time.Time
+}
+
+// newHealthStorage - wraps disk at path with health tracking.
+func newHealthStorage(path string, disk StorageAPI) *healthStorage {
+	return &healthStorage{
+		disk:          disk,
+		path:          path,
+		maxFaults:     diskMaxFaults,
+		slowCall:      diskSlowCall,
+		callTimeout:   globalDiskCallTimeout,
+		probeInterval: diskProbeInterval,
+		online:        true,
+	}
+}
+
+// newMissingHealthStorage - returns an offline disk for the slot of a
+// disk missing at startup, whose format has the disk uuid. The disks
+// at paths, which were all missing, are connected by probes until one
+// of them has the format of the slot, probes should be started by the
+// caller.
+func newMissingHealthStorage(paths []string, uuid string) *healthStorage {
+	h := newHealthStorage(strings.Join(paths, ","), nil)
+	h.online = false
+	h.lastErr = errDiskNotFound
+	h.offlineSince = time.Now().UTC()
+	h.connect = func() (StorageAPI, string, error) {
+		for _, path := range paths {
+			disk, err := newStorageAPI(path)
+			if err != nil {
+				continue
+			}
+			if format, err := loadFormat(disk); err == nil && format.XL.Disk == uuid {
+				return disk, path, nil
+			}
+		}
+		return nil, "", errDiskNotFound
+	}
+	return h
+}
+
+// withDeadline - runs fn and waits at most timeout for it to return,
+// returns errFaultyDisk if it does not. Results set by fn should not
+// be read if errFaultyDisk is returned, fn may still be running. fn
+// is called directly without a timeout.
+func withDeadline(timeout time.Duration, fn func() error) error {
+	if timeout <= 0 {
+		return fn()
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fn()
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-errCh:
+		return err
+	case <-timer.C:
+		return errFaultyDisk
+	}
+}
+
+// healthBufPool - buffers of calls with a deadline, a buffer is only
+// put back once the call using it returned in time.
+var healthBufPool sync.Pool
+
+// getHealthBuf - returns a buffer of size bytes from the pool.
+func getHealthBuf(size int) []byte {
+	if bufp, ok := healthBufPool.Get().(*[]byte); ok && cap(*bufp) >= size {
+		return (*bufp)[:size]
+	}
+	return make([]byte, size)
+}
+
+// putHealthBuf - puts back a buffer to the pool.
+func putHealthBuf(buf []byte) {
+	healthBufPool.Put(&buf)
+}
+
+// isDiskFault - returns true for errors caused by the disk itself,
+// as opposed to errors caused by the call.
+func isDiskFault(err error) bool {
+	return err == errDiskNotFound || err == errFaultyDisk
+}
+
+// begin - fails fast with errDiskNotFound if the disk is offline.
+func (h *healthStorage) begin() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if !h.online {
+		return errDiskNotFound
+	}
+	return nil
+}
+
+// call - runs fn on an online disk with a deadline and records its
+// outcome.
+func (h *healthStorage) call(fn func() error) error {
+	if err := h.begin(); err != nil {
+		return err
+	}
+	startTime := time.Now()
+	err := withDeadline(h.callTimeout, fn)
+	h.end(startTime, err)
+	return err
+}
+
+// end - records the outcome of a call started at startTime, marks
+// the disk offline once it reaches maxFaults consecutive faults.
+func (h *healthStorage) end(startTime time.Time, err error) {
+	latency := time.Since(startTime)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.calls++
+	if h.latency == 0 {
+		h.latency = latency
+	} else {
+		h.latency += time.Duration(diskLatencyWeight * float64(latency-h.latency))
+	}
+	if err != nil && isDiskFault(err) {
+		h.errors++
+		h.lastErr = err
+	} else if latency > h.slowCall {
+		err = fmt.Errorf("call took %s", latency)
+		h.lastErr = err
+	} else {
+		h.faults = 0
+		return
+	}
+
+	h.faults++
+	if h.online && h.faults >= h.maxFaults {
+		h.online = false
+		h.offlineSince = time.Now().UTC()
+		errorIf(err, "Disk %s is offline after %d consecutive faults.", h.path, h.faults)
+		go h.probe()
+	}
+}
+
+// probe - probes an offline disk until its meta volume is found in
+// time, then brings it back online. A disk which lost its meta volume
+// is not the disk which went offline.
+func (h *healthStorage) probe() {
+	for {
+		time.Sleep(h.probeInterval)
+		disk, path := h.disk, ""
+		startTime := time.Now()
+		err := withDeadline(h.callTimeout, func() (err error) {
+			if disk == nil {
+				if disk, path, err = h.connect(); err != nil {
+					return err
+				}
+			}
+			_, err = disk.StatVol(minioMetaBucket)
+			return err
+		})
+		if err != nil || time.Since(startTime) > h.slowCall {
+			continue
+		}
+
+		h.mutex.Lock()
+		h.disk = disk
+		if path != "" {
+			h.path = path
+		}
+		h.online = true
+		h.faults = 0
+		h.offlineSince = time.Time{}
+		h.mutex.Unlock()
+		log.Infof("Disk %s is back online.", h.path)
+		return
+	}
+}
+
+// healthInfo - returns the current health of the disk.
+func (h *healthStorage) healthInfo() DiskHealth {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	info := DiskHealth{
+		Path:         h.path,
+		Online:       h.online,
+		Calls:        h.calls,
+		Errors:       h.errors,
+		Latency:      h.latency,
+		OfflineSince: h.offlineSince,
+	}
+	if h.lastErr != nil {
+		info.LastError = h.lastErr.Error()
+	}
+	return info
+}
+
+/// Volume operations.
+
+// MakeVol - make a volume.
+func (h *healthStorage) MakeVol(volume string) error {
+	return h.call(func() error {
+		return h.disk.MakeVol(volume)
+	})
+}
+
+// ListVols - list all volumes.
+func (h *healthStorage) ListVols() ([]VolInfo, error) {
+	var vols []VolInfo
+	err := h.call(func() (err error) {
+		vols, err = h.disk.ListVols()
+		return err
+	})
+	if err == errFaultyDisk {
+		return nil, err
+	}
+	return vols, err
+}
+
+// StatVol - stat a volume.
+func (h *healthStorage) StatVol(volume string) (VolInfo, error) {
+	var vol VolInfo
+	err := h.call(func() (err error) {
+		vol, err = h.disk.StatVol(volume)
+		return err
+	})
+	if err == errFaultyDisk {
+		return VolInfo{}, err
+	}
+	return vol, err
+}
+
+// DeleteVol - delete a volume.
+func (h *healthStorage) DeleteVol(volume string) error {
+	return h.call(func() error {
+		return h.disk.DeleteVol(volume)
+	})
+}
+
+/// File operations.
+
+// ListDir - list entries at dirPath.
+func (h *healthStorage) ListDir(volume, dirPath string) ([]string, error) {
+	var entries []string
+	err := h.call(func() (err error) {
+		entries, err = h.disk.ListDir(volume, dirPath)
+		return err
+	})
+	if err == errFaultyDisk {
+		return nil, err
+	}
+	return entries, err
+}
+
+// ReadFile - read a file at offset into buf. With a deadline the file
+// is read into a pooled buffer, buf is not written after a call which
+// is late.
+func (h *healthStorage) ReadFile(volume string, path string, offset int64, buf []byte) (n int64, err error) {
+	if h.callTimeout <= 0 {
+		err = h.call(func() (err error) {
+			n, err = h.disk.ReadFile(volume, path, offset, buf)
+			return err
+		})
+		return n, err
+	}
+	readBuf := getHealthBuf(len(buf))
+	err = h.call(func() (err error) {
+		n, err = h.disk.ReadFile(volume, path, offset, readBuf)
+		return err
+	})
+	if err == errFaultyDisk {
+		// Late call may still write readBuf, it is not put back.
+		return 0, err
+	}
+	copy(buf, readBuf[:n])
+	putHealthBuf(readBuf)
+	return n, err
+}
+
+// AppendFile - append buf to a file. With a deadline a pooled copy of
+// buf is written, buf may be reused as soon as a call which is late
+// returns.
+func (h *healthStorage) AppendFile(volume string, path string, buf []byte) error {
+	if h.callTimeout <= 0 {
+		return h.call(func() error {
+			return h.disk.AppendFile(volume, path, buf)
+		})
+	}
+	data := getHealthBuf(len(buf))
+	copy(data, buf)
+	err := h.call(func() error {
+		return h.disk.AppendFile(volume, path, data)
+	})
+	if err != errFaultyDisk {
+		putHealthBuf(data)
+	}
+	return err
+}
+
+// RenameFile - rename a file.
+func (h *healthStorage) RenameFile(srcVolume, srcPath, dstVolume, dstPath string) error {
+	return h.call(func() error {
+		return h.disk.RenameFile(srcVolume, srcPath, dstVolume, dstPath)
+	})
+}
+
+// StatFile - stat a file.
+func (h *healthStorage) StatFile(volume string, path string) (FileInfo, error) {
+	var file FileInfo
+	err := h.call(func() (err error) {
+		file, err = h.disk.StatFile(volume, path)
+		return err
+	})
+	if err == errFaultyDisk {
+		return FileInfo{}, err
+	}
+	return file, err
+}
+
+// DeleteFile - delete a file.
+func (h *healthStorage) DeleteFile(volume string, path string) error {
+	return h.call(func() error {
+		return h.disk.DeleteFile(volume, path)
+	})
+}
+
+// ReadAll - read all contents of a file.
+func (h *healthStorage) ReadAll(volume string, path string) ([]byte, error) {
+	var buf []byte
+	err := h.call(func() (err error) {
+		buf, err = h.disk.ReadAll(volume, path)
+		return err
+	})
+	if err == errFaultyDisk {
+		return nil, err
+	}
+	return buf, err
+}
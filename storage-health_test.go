@@ -0,0 +1,358 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+// faultyStorage - StorageAPI whose StatVol fails with err, after
+// sleeping for delay.
+type faultyStorage struct {
+	StorageAPI
+	mutex sync.Mutex
+	calls int
+	err   error
+	delay time.Duration
+}
+
+func (f *faultyStorage) setErr(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.err = err
+}
+
+func (f *faultyStorage) StatVol(volume string) (VolInfo, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls++
+	time.Sleep(f.delay)
+	return VolInfo{Name: volume}, f.err
+}
+
+// Tests validate disks go offline after repeated faults and come back
+// online once probes succeed.
+func TestHealthStorage(t *testing.T) {
+	disk := &faultyStorage{}
+	hDisk := newHealthStorage("disk1", disk)
+	hDisk.maxFaults = 3
+	hDisk.probeInterval = 10 * time.Millisecond
+
+	// Errors of the call are not faults of the disk.
+	disk.setErr(errVolumeNotFound)
+	for i := 0; i < 5; i++ {
+		if _, err := hDisk.StatVol("volume"); err != errVolumeNotFound {
+			t.Fatalf("Expected %s, but found %v", errVolumeNotFound, err)
+		}
+	}
+	if info := hDisk.healthInfo(); !info.Online || info.Errors != 0 || info.Calls != 5 {
+		t.Fatalf("Expected disk online without errors after 5 calls, but found %#v", info)
+	}
+
+	// Consecutive faults below the threshold are tolerated.
+	disk.setErr(errFaultyDisk)
+	hDisk.StatVol("volume")
+	hDisk.StatVol("volume")
+	disk.setErr(nil)
+	hDisk.StatVol("volume")
+	disk.setErr(errDiskNotFound)
+	hDisk.StatVol("volume")
+	hDisk.StatVol("volume")
+	if info := hDisk.healthInfo(); !info.Online || info.Errors != 4 {
+		t.Fatalf("Expected disk online with 4 errors, but found %#v", info)
+	}
+
+	// Disk goes offline at the threshold, calls fail fast.
+	hDisk.StatVol("volume")
+	info := hDisk.healthInfo()
+	if info.Online || info.OfflineSince.IsZero() || info.LastError != errDiskNotFound.Error() {
+		t.Fatalf("Expected disk offline, but found %#v", info)
+	}
+	disk.mutex.Lock()
+	calls := disk.calls
+	disk.mutex.Unlock()
+	if _, err := hDisk.StatVol("volume"); err != errDiskNotFound {
+		t.Fatalf("Expected %s, but found %v", errDiskNotFound, err)
+	}
+	if info = hDisk.healthInfo(); info.Calls != 11 {
+		t.Errorf("Expected calls to offline disk not to be counted, but found %d calls", info.Calls)
+	}
+
+	// Disk comes back online once a probe succeeds.
+	disk.setErr(nil)
+	for i := 0; !hDisk.healthInfo().Online; i++ {
+		if i == 100 {
+			t.Fatalf("Expected disk back online")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	disk.mutex.Lock()
+	probes := disk.calls - calls
+	disk.mutex.Unlock()
+	if probes < 1 {
+		t.Errorf("Expected disk to be probed, but found %d probes", probes)
+	}
+	if _, err := hDisk.StatVol("volume"); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+
+	// Slow calls are faults.
+	hDisk.slowCall = time.Millisecond
+	disk.mutex.Lock()
+	disk.delay = 5 * time.Millisecond
+	disk.mutex.Unlock()
+	for i := 0; i < 3; i++ {
+		hDisk.StatVol("volume")
+	}
+	if info = hDisk.healthInfo(); info.Online || info.Latency < time.Millisecond {
+		t.Fatalf("Expected slow disk offline, but found %#v", info)
+	}
+}
+
+// Tests validate calls past the deadline are faults, and disks are
+// probed for their meta volume.
+func TestHealthStorageDeadline(t *testing.T) {
+	disk := &faultyStorage{}
+	hDisk := newHealthStorage("disk1", disk)
+	hDisk.maxFaults = 1
+	hDisk.callTimeout = 10 * time.Millisecond
+	hDisk.probeInterval = 10 * time.Millisecond
+
+	// Test case - 1.
+	// A hung call fails with errFaultyDisk at the deadline.
+	disk.mutex.Lock()
+	disk.delay = time.Second
+	disk.mutex.Unlock()
+	startTime := time.Now()
+	if _, err := hDisk.StatVol("volume"); err != errFaultyDisk {
+		t.Fatalf("Expected %s, but found %v", errFaultyDisk, err)
+	}
+	if elapsed := time.Since(startTime); elapsed > 500*time.Millisecond {
+		t.Fatalf("Expected call to return at the deadline, but took %s", elapsed)
+	}
+	if info := hDisk.healthInfo(); info.Online {
+		t.Fatalf("Expected hung disk offline, but found %#v", info)
+	}
+
+	// Test case - 2.
+	// A disk without meta volume is kept offline.
+	disk.mutex.Lock()
+	disk.delay = 0
+	disk.err = errVolumeNotFound
+	disk.mutex.Unlock()
+	time.Sleep(100 * time.Millisecond)
+	if info := hDisk.healthInfo(); info.Online {
+		t.Fatalf("Expected disk without meta volume offline, but found %#v", info)
+	}
+
+	// Test case - 3.
+	// Disk comes back online with its meta volume.
+	disk.setErr(nil)
+	for i := 0; !hDisk.healthInfo().Online; i++ {
+		if i == 100 {
+			t.Fatalf("Expected disk back online")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Tests validate disks missing at startup come online once a disk
+// with the format of their slot is back.
+func TestMissingHealthStorage(t *testing.T) {
+	_, disks, err := getXLObjectLayer()
+	if err != nil {
+		t.Fatalf("Unable to initialize XL: <ERROR> %s", err)
+	}
+	defer removeRoots(disks)
+
+	disk, err := newStorageAPI(disks[1])
+	if err != nil {
+		t.Fatalf("Unable to initialize disk: <ERROR> %s", err)
+	}
+	format, err := loadFormat(disk)
+	if err != nil {
+		t.Fatalf("Unable to load format: <ERROR> %s", err)
+	}
+
+	// Test case - 1.
+	// Disks with the format of another slot are not connected.
+	hDisk := newMissingHealthStorage([]string{disks[0], disks[2]}, format.XL.Disk)
+	hDisk.probeInterval = 10 * time.Millisecond
+	go hDisk.probe()
+	if _, err = hDisk.StatVol(minioMetaBucket); err != errDiskNotFound {
+		t.Fatalf("Expected %s, but found %v", errDiskNotFound, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if info := hDisk.healthInfo(); info.Online {
+		t.Fatalf("Expected missing disk offline, but found %#v", info)
+	}
+
+	// Test case - 2.
+	// Disk with the format of the slot is connected.
+	hDisk = newMissingHealthStorage([]string{disks[0], disks[1]}, format.XL.Disk)
+	hDisk.probeInterval = 10 * time.Millisecond
+	go hDisk.probe()
+	for i := 0; !hDisk.healthInfo().Online; i++ {
+		if i == 100 {
+			t.Fatalf("Expected missing disk online")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if info := hDisk.healthInfo(); info.Path != disks[1] {
+		t.Errorf("Expected disk %s, but found %s", disks[1], info.Path)
+	}
+	if _, err = hDisk.StatVol(minioMetaBucket); err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+}
+
+// Tests validate health of disks is reported in XL storage info.
+func TestXLStorageInfoDisks(t *testing.T) {
+	objLayer, disks, err := getXLObjectLayer()
+	if err != nil {
+		t.Fatalf("Unable to initialize XL: <ERROR> %s", err)
+	}
+	defer removeRoots(disks)
+
+	storageInfo := objLayer.StorageInfo()
+	if len(storageInfo.Disks) != len(disks) {
+		t.Fatalf("Expected health of %d disks, but found %d", len(disks), len(storageInfo.Disks))
+	}
+	for _, diskHealth := range storageInfo.Disks {
+		if !diskHealth.Online || diskHealth.Calls == 0 {
+			t.Errorf("Expected disk %s online and in use, but found %#v", diskHealth.Path, diskHealth)
+		}
+	}
+}
+
+// Tests validate reads and appends with and without a deadline.
+func TestHealthStorageReadAppend(t *testing.T) {
+	for i, callTimeout := range []time.Duration{0, time.Minute} {
+		disk, dir := newBenchmarkPosix(t)
+		hDisk := newHealthStorage(dir, disk)
+		hDisk.callTimeout = callTimeout
+
+		data := []byte("hello, world")
+		if err := hDisk.AppendFile("volume", "file", data); err != nil {
+			t.Fatalf("Test %d: <ERROR> %s", i+1, err)
+		}
+		// Changes of the appended buffer are not written.
+		data[0] = 'j'
+		if err := hDisk.AppendFile("volume", "file", data[:5]); err != nil {
+			t.Fatalf("Test %d: <ERROR> %s", i+1, err)
+		}
+		buf := make([]byte, 32)
+		n, err := hDisk.ReadFile("volume", "file", 7, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			t.Fatalf("Test %d: <ERROR> %s", i+1, err)
+		}
+		if string(buf[:n]) != "worldjello" {
+			t.Errorf("Test %d: Expected 'worldjello', but found '%s'", i+1, buf[:n])
+		}
+		removeAll(dir)
+	}
+}
+
+// newBenchmarkPosix - returns a posix disk in a temporary directory
+// with a volume named volume.
+func newBenchmarkPosix(tb testing.TB) (StorageAPI, string) {
+	dir, err := ioutil.TempDir("", "minio-health-")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	disk, err := newPosix(dir)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err = disk.MakeVol("volume"); err != nil {
+		tb.Fatal(err)
+	}
+	return disk, dir
+}
+
+// benchmarkReadFile - reads blocks of a file from disk.
+func benchmarkReadFile(b *testing.B, disk StorageAPI) {
+	block := make([]byte, readSizeV1)
+	if err := disk.AppendFile("volume", "file", block); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(block)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := disk.ReadFile("volume", "file", 0, block); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkAppendFile - appends blocks to a file on disk.
+func benchmarkAppendFile(b *testing.B, disk StorageAPI) {
+	block := make([]byte, readSizeV1)
+	b.SetBytes(int64(len(block)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := disk.AppendFile("volume", "file", block); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPosixReadFile(b *testing.B) {
+	disk, dir := newBenchmarkPosix(b)
+	defer removeAll(dir)
+	benchmarkReadFile(b, disk)
+}
+
+func BenchmarkHealthStorageReadFile(b *testing.B) {
+	disk, dir := newBenchmarkPosix(b)
+	defer removeAll(dir)
+	benchmarkReadFile(b, newHealthStorage(dir, disk))
+}
+
+func BenchmarkHealthStorageReadFileNoDeadline(b *testing.B) {
+	disk, dir := newBenchmarkPosix(b)
+	defer removeAll(dir)
+	hDisk := newHealthStorage(dir, disk)
+	hDisk.callTimeout = 0
+	benchmarkReadFile(b, hDisk)
+}
+
+func BenchmarkPosixAppendFile(b *testing.B) {
+	disk, dir := newBenchmarkPosix(b)
+	defer removeAll(dir)
+	benchmarkAppendFile(b, disk)
+}
+
+func BenchmarkHealthStorageAppendFile(b *testing.B) {
+	disk, dir := newBenchmarkPosix(b)
+	defer removeAll(dir)
+	benchmarkAppendFile(b, newHealthStorage(dir, disk))
+}
+
+func BenchmarkHealthStorageAppendFileNoDeadline(b *testing.B) {
+	disk, dir := newBenchmarkPosix(b)
+	defer removeAll(dir)
+	hDisk := newHealthStorage(dir, disk)
+	hDisk.callTimeout = 0
+	benchmarkAppendFile(b, hDisk)
+}
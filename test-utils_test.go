@@ -147,6 +147,10 @@ func initTestConfig(bucketLocation string) (credential, string, error) {
 	if err != nil {
 		return credential{}, "", err
 	}
+	// Initialize web tokens under the new config path.
+	if err = initWebTokens(nil); err != nil {
+		return credential{}, "", err
+	}
 	return credentials, rootPath, nil
 }
 
@@ -426,6 +430,21 @@ func getGetObjectURL(endPoint, bucketName, objectName string) string {
 	return makeTestTargetURL(endPoint, bucketName, objectName, url.Values{})
 }
 
+// return URL for fetching a part of the object from the bucket.
+func getGetObjectPartURL(endPoint, bucketName, objectName, partNumber string) string {
+	queryValue := url.Values{}
+	queryValue.Set("partNumber", partNumber)
+	return makeTestTargetURL(endPoint, bucketName, objectName, queryValue)
+}
+
+// return URL for selecting the content of an object.
+func getSelectObjectContentURL(endPoint, bucketName, objectName string) string {
+	queryValue := url.Values{}
+	queryValue.Set("select", "")
+	queryValue.Set("select-type", "2")
+	return makeTestTargetURL(endPoint, bucketName, objectName, queryValue)
+}
+
 // return URL for deleting the object from the bucket.
 func getDeleteObjectURL(endPoint, bucketName, objectName string) string {
 	return makeTestTargetURL(endPoint, bucketName, objectName, url.Values{})
@@ -485,6 +504,24 @@ func getListObjectsURL(endPoint, bucketName string, maxKeys string) string {
 	return makeTestTargetURL(endPoint, bucketName, "", queryValue)
 }
 
+// return URL for listing objects in the bucket with V2 API.
+func getListObjectsV2URL(endPoint, bucketName, prefix, token, startAfter, maxKeys, fetchOwner string) string {
+	queryValue := url.Values{}
+	queryValue.Set("list-type", "2")
+	for key, value := range map[string]string{
+		"prefix":             prefix,
+		"continuation-token": token,
+		"start-after":        startAfter,
+		"max-keys":           maxKeys,
+		"fetch-owner":        fetchOwner,
+	} {
+		if value != "" {
+			queryValue.Set(key, value)
+		}
+	}
+	return makeTestTargetURL(endPoint, bucketName, "", queryValue)
+}
+
 // return URL for a new multipart upload.
 func getNewMultipartURL(endPoint, bucketName, objectName string) string {
 	queryValue := url.Values{}
@@ -676,6 +713,10 @@ func initTestAPIEndPoints(objLayer ObjectLayer, apiFunctions []string) http.Hand
 		case "GetBucketPolicy":
 			bucket.Methods("GET").HandlerFunc(api.GetBucketPolicyHandler).Queries("policy", "")
 
+			// Register Delete bucket HTTP handler.
+		case "DeleteBucket":
+			bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketHandler)
+
 			// Register Post Bucket policy function.
 		case "PostBucketPolicy":
 			bucket.Methods("POST").HeadersRegexp("Content-Type", "multipart/form-data*").HandlerFunc(api.PostPolicyBucketHandler)
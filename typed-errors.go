@@ -27,5 +27,20 @@ var errInvalidArgument = errors.New("Invalid arguments specified")
 // errSignatureMismatch means signature did not match.
 var errSignatureMismatch = errors.New("Signature does not match")
 
+// errMalformedTrailer - aws-chunked body or its trailers are malformed.
+var errMalformedTrailer = errors.New("Malformed aws-chunked body or trailer")
+
+// errClientDisconnected - client closed the connection while a
+// response was written.
+var errClientDisconnected = errors.New("Client disconnected")
+
 // used when token used for authentication by the MinioBrowser has expired
 var errInvalidToken = errors.New("Invalid token")
+
+// errWebTokensNotInitialized - web tokens are issued before the web
+// token store is initialized.
+var errWebTokensNotInitialized = errors.New("Web token store not initialized")
+
+// errAuthentication - storage rpc login was rejected, credentials of
+// the nodes do not match.
+var errAuthentication = errors.New("Authentication failed, check your access credentials")
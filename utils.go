@@ -67,3 +67,11 @@ func contains(stringList []string, element string) bool {
 	}
 	return false
 }
+
+// minInt64 - returns the smaller of a and b.
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -17,16 +17,20 @@
 package main
 
 import (
+	"archive/zip"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
-	jwtgo "github.com/dgrijalva/jwt-go"
 	"github.com/dustin/go-humanize"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/rpc/v2/json2"
@@ -36,17 +40,24 @@ import (
 // isJWTReqAuthenticated validates if any incoming request to be a
 // valid JWT authenticated request.
 func isJWTReqAuthenticated(req *http.Request) bool {
-	jwt := initJWT()
-	token, e := jwtgo.ParseFromRequest(req, func(token *jwtgo.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwtgo.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(jwt.SecretAccessKey), nil
-	})
-	if e != nil {
-		return false
+	return isAuthTokenValid(getWebToken(req))
+}
+
+// isAuthTokenValid validates a JWT sent as a query parameter, by
+// requests which cannot set an authorization header.
+func isAuthTokenValid(tokenString string) bool {
+	_, err := initJWT().ParseWebToken(tokenString, webAccessAudience)
+	return err == nil
+}
+
+// getWebToken - returns the JWT of a request, sent as a bearer token
+// in the authorization header or as the access_token form value.
+func getWebToken(req *http.Request) string {
+	if ah := req.Header.Get("Authorization"); len(ah) > len(jwtAlgorithm) && strings.EqualFold(ah[:len(jwtAlgorithm)], jwtAlgorithm) {
+		return strings.TrimSpace(ah[len(jwtAlgorithm):])
 	}
-	return token.Valid
+	req.ParseMultipartForm(10e6)
+	return req.Form.Get("access_token")
 }
 
 // WebGenericArgs - empty struct for calls that don't accept arguments
@@ -238,6 +249,8 @@ func (web *webAPIHandlers) RemoveObject(r *http.Request, args *RemoveObjectArgs,
 	if err := web.ObjectAPI.DeleteObject(args.BucketName, args.ObjectName); err != nil {
 		return &json2.Error{Message: err.Error()}
 	}
+	// Queue the delete for replication.
+	queueReplication(args.BucketName, args.ObjectName, nil)
 	return nil
 }
 
@@ -249,8 +262,9 @@ type LoginArgs struct {
 
 // LoginRep - login reply.
 type LoginRep struct {
-	Token     string `json:"token"`
-	UIVersion string `json:"uiVersion"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	UIVersion    string `json:"uiVersion"`
 }
 
 // Login - user login handler.
@@ -261,13 +275,79 @@ func (web *webAPIHandlers) Login(r *http.Request, args *LoginArgs, reply *LoginR
 		if err != nil {
 			return &json2.Error{Message: err.Error()}
 		}
+		refreshToken, err := jwt.GenerateRefreshToken(args.Username)
+		if err != nil {
+			return &json2.Error{Message: err.Error()}
+		}
 		reply.Token = token
+		reply.RefreshToken = refreshToken
 		reply.UIVersion = miniobrowser.UIVersion
 		return nil
 	}
 	return &json2.Error{Message: "Invalid credentials"}
 }
 
+// RefreshTokenArgs - refresh token arguments.
+type RefreshTokenArgs struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RefreshTokenRep - refresh token reply.
+type RefreshTokenRep struct {
+	Token     string `json:"token"`
+	UIVersion string `json:"uiVersion"`
+}
+
+// RefreshToken - exchanges a refresh token for a new access token, the
+// access token of the request may have expired already.
+func (web *webAPIHandlers) RefreshToken(r *http.Request, args *RefreshTokenArgs, reply *RefreshTokenRep) error {
+	jwt := initJWT()
+	if _, err := jwt.ParseWebToken(args.RefreshToken, webRefreshAudience); err != nil {
+		return &json2.Error{Message: "Unauthorized request"}
+	}
+	token, err := jwt.GenerateToken(jwt.AccessKeyID)
+	if err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	reply.Token = token
+	reply.UIVersion = miniobrowser.UIVersion
+	return nil
+}
+
+// LogoutArgs - logout arguments.
+type LogoutArgs struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Logout - revokes the access token of the request and the refresh
+// token of the session, if any. Either is enough to logout, the access
+// token of the session may have expired already.
+func (web *webAPIHandlers) Logout(r *http.Request, args *LogoutArgs, reply *WebGenericRep) error {
+	jwt := initJWT()
+	validAccessToken := isJWTReqAuthenticated(r)
+	validRefreshToken := false
+	if args.RefreshToken != "" {
+		_, err := jwt.ParseWebToken(args.RefreshToken, webRefreshAudience)
+		validRefreshToken = err == nil
+	}
+	if !validAccessToken && !validRefreshToken {
+		return &json2.Error{Message: "Unauthorized request"}
+	}
+	if validAccessToken {
+		if err := jwt.RevokeWebToken(getWebToken(r), webAccessAudience); err != nil {
+			return &json2.Error{Message: err.Error()}
+		}
+	}
+	if validRefreshToken {
+		// Refresh tokens which became invalid meanwhile need no revocation.
+		if err := jwt.RevokeWebToken(args.RefreshToken, webRefreshAudience); err != nil && err != errInvalidToken {
+			return &json2.Error{Message: err.Error()}
+		}
+	}
+	reply.UIVersion = miniobrowser.UIVersion
+	return nil
+}
+
 // GenerateAuthReply - reply for GenerateAuth
 type GenerateAuthReply struct {
 	AccessKey string `json:"accessKey"`
@@ -294,8 +374,9 @@ type SetAuthArgs struct {
 
 // SetAuthReply - reply for SetAuth
 type SetAuthReply struct {
-	Token     string `json:"token"`
-	UIVersion string `json:"uiVersion"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	UIVersion    string `json:"uiVersion"`
 }
 
 // SetAuth - Set accessKey and secretKey credentials.
@@ -315,6 +396,11 @@ func (web *webAPIHandlers) SetAuth(r *http.Request, args *SetAuthArgs, reply *Se
 		return &json2.Error{Message: err.Error()}
 	}
 
+	// Tokens issued for the previous credentials are invalidated.
+	if err := globalWebTokens.RotateSigningKey(); err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+
 	jwt := initJWT()
 	if !jwt.Authenticate(args.AccessKey, args.SecretKey) {
 		return &json2.Error{Message: "Invalid credentials"}
@@ -323,7 +409,12 @@ func (web *webAPIHandlers) SetAuth(r *http.Request, args *SetAuthArgs, reply *Se
 	if err != nil {
 		return &json2.Error{Message: err.Error()}
 	}
+	refreshToken, err := jwt.GenerateRefreshToken(args.AccessKey)
+	if err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
 	reply.Token = token
+	reply.RefreshToken = refreshToken
 	reply.UIVersion = miniobrowser.UIVersion
 	return nil
 }
@@ -347,6 +438,265 @@ func (web *webAPIHandlers) GetAuth(r *http.Request, args *WebGenericArgs, reply
 	return nil
 }
 
+// PresignedGetArgs - presigned-get API args.
+type PresignedGetArgs struct {
+	// Host of the URL, host of the request if not set.
+	HostName   string `json:"host"`
+	BucketName string `json:"bucketName"`
+	ObjectName string `json:"objectName"`
+	// Expiry in seconds, 7 days if not set.
+	Expiry int64 `json:"expiry"`
+}
+
+// PresignedGetRep - presigned-get URL reply.
+type PresignedGetRep struct {
+	URL       string `json:"url"`
+	UIVersion string `json:"uiVersion"`
+}
+
+// PresignedGet - returns a presigned URL to download an object.
+func (web *webAPIHandlers) PresignedGet(r *http.Request, args *PresignedGetArgs, reply *PresignedGetRep) error {
+	if !isJWTReqAuthenticated(r) {
+		return &json2.Error{Message: "Unauthorized request"}
+	}
+	if _, err := web.ObjectAPI.GetObjectInfo(args.BucketName, args.ObjectName); err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	url, err := presignedURL(r, args.HostName, "GET", args.BucketName, args.ObjectName, args.Expiry)
+	if err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	reply.URL = url
+	reply.UIVersion = miniobrowser.UIVersion
+	return nil
+}
+
+// PresignedPutArgs - presigned-put API args.
+type PresignedPutArgs struct {
+	// Host of the URL, host of the request if not set.
+	HostName   string `json:"host"`
+	BucketName string `json:"bucketName"`
+	ObjectName string `json:"objectName"`
+	// Expiry in seconds, 7 days if not set.
+	Expiry int64 `json:"expiry"`
+}
+
+// PresignedPutRep - presigned-put URL reply.
+type PresignedPutRep struct {
+	URL       string `json:"url"`
+	UIVersion string `json:"uiVersion"`
+}
+
+// PresignedPut - returns a presigned URL to upload an object.
+func (web *webAPIHandlers) PresignedPut(r *http.Request, args *PresignedPutArgs, reply *PresignedPutRep) error {
+	if !isJWTReqAuthenticated(r) {
+		return &json2.Error{Message: "Unauthorized request"}
+	}
+	if _, err := web.ObjectAPI.GetBucketInfo(args.BucketName); err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	if !IsValidObjectName(args.ObjectName) {
+		return &json2.Error{Message: ObjectNameInvalid{Bucket: args.BucketName, Object: args.ObjectName}.Error()}
+	}
+	url, err := presignedURL(r, args.HostName, "PUT", args.BucketName, args.ObjectName, args.Expiry)
+	if err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	reply.URL = url
+	reply.UIVersion = miniobrowser.UIVersion
+	return nil
+}
+
+// PresignedPostPolicyArgs - presigned-post-policy API args.
+type PresignedPostPolicyArgs struct {
+	// Host of the URL, host of the request if not set.
+	HostName   string `json:"host"`
+	BucketName string `json:"bucketName"`
+	// Uploaded objects are named with this prefix.
+	Prefix string `json:"prefix"`
+	// Maximum size in bytes of uploaded objects, unlimited if not set.
+	MaxSize int64 `json:"maxSize"`
+	// Expiry in seconds, 7 days if not set.
+	Expiry int64 `json:"expiry"`
+}
+
+// PresignedPostPolicyRep - presigned-post-policy reply, the form data
+// to send with an upload to URL.
+type PresignedPostPolicyRep struct {
+	URL       string            `json:"url"`
+	FormData  map[string]string `json:"formData"`
+	UIVersion string            `json:"uiVersion"`
+}
+
+// PresignedPostPolicy - returns a POST policy to upload objects with a
+// prefix through an HTML form, without any other access.
+func (web *webAPIHandlers) PresignedPostPolicy(r *http.Request, args *PresignedPostPolicyArgs, reply *PresignedPostPolicyRep) error {
+	if !isJWTReqAuthenticated(r) {
+		return &json2.Error{Message: "Unauthorized request"}
+	}
+	if _, err := web.ObjectAPI.GetBucketInfo(args.BucketName); err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	if args.MaxSize < 0 {
+		return &json2.Error{Message: "Maximum size cannot be negative"}
+	}
+	formData, err := preSignPostPolicyV4(serverConfig.GetCredential(), serverConfig.GetRegion(), args.BucketName, args.Prefix, args.MaxSize, time.Now().UTC(), getPresignExpiry(args.Expiry))
+	if err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	reply.URL = getPresignURLPrefix(r, args.HostName) + "/" + args.BucketName
+	reply.FormData = formData
+	reply.UIVersion = miniobrowser.UIVersion
+	return nil
+}
+
+// getPresignExpiry - returns expiry of presigned URLs in seconds, 7
+// days if not set.
+func getPresignExpiry(expiry int64) time.Duration {
+	if expiry == 0 {
+		return maxPresignExpiry
+	}
+	return time.Duration(expiry) * time.Second
+}
+
+// getPresignURLPrefix - returns scheme and host of presigned URLs.
+func getPresignURLPrefix(r *http.Request, host string) string {
+	if host == "" {
+		host = r.Host
+	}
+	scheme := "http"
+	if isSSL() {
+		scheme = "https"
+	}
+	return scheme + "://" + host
+}
+
+// presignedURL - returns a URL for a request of method on an object,
+// presigned with the server credentials.
+func presignedURL(r *http.Request, host, method, bucket, object string, expiry int64) (string, error) {
+	if host == "" {
+		host = r.Host
+	}
+	presignedPath, err := preSignV4(serverConfig.GetCredential(), serverConfig.GetRegion(), method, host, "/"+bucket+"/"+object, time.Now().UTC(), getPresignExpiry(expiry))
+	if err != nil {
+		return "", err
+	}
+	return getPresignURLPrefix(r, host) + presignedPath, nil
+}
+
+// GetBucketPolicyArgs - get bucket policy args.
+type GetBucketPolicyArgs struct {
+	BucketName string `json:"bucketName"`
+	Prefix     string `json:"prefix"`
+}
+
+// GetBucketPolicyRep - get bucket policy reply.
+type GetBucketPolicyRep struct {
+	UIVersion string             `json:"uiVersion"`
+	Policy    bucketAccessPolicy `json:"policy"`
+}
+
+// GetBucketPolicy - get the access policy of a prefix of a bucket.
+func (web *webAPIHandlers) GetBucketPolicy(r *http.Request, args *GetBucketPolicyArgs, reply *GetBucketPolicyRep) error {
+	if !isJWTReqAuthenticated(r) {
+		return &json2.Error{Message: "Unauthorized request"}
+	}
+	if _, err := web.ObjectAPI.GetBucketInfo(args.BucketName); err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	policy, err := readBucketAccessPolicies(args.BucketName)
+	if err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	reply.UIVersion = miniobrowser.UIVersion
+	reply.Policy = accessPolicyNone
+	if access, ok := getBucketAccessPolicies(args.BucketName, policy)[args.Prefix]; ok {
+		reply.Policy = access
+	}
+	return nil
+}
+
+// ListAllBucketPoliciesArgs - list all bucket policies args.
+type ListAllBucketPoliciesArgs struct {
+	BucketName string `json:"bucketName"`
+}
+
+// BucketAccessPolicy - access policy of a prefix of a bucket.
+type BucketAccessPolicy struct {
+	Prefix string             `json:"prefix"`
+	Policy bucketAccessPolicy `json:"policy"`
+}
+
+// ListAllBucketPoliciesRep - list all bucket policies reply.
+type ListAllBucketPoliciesRep struct {
+	UIVersion string               `json:"uiVersion"`
+	Policies  []BucketAccessPolicy `json:"policies"`
+}
+
+// ListAllBucketPolicies - list the access policies of all prefixes of
+// a bucket.
+func (web *webAPIHandlers) ListAllBucketPolicies(r *http.Request, args *ListAllBucketPoliciesArgs, reply *ListAllBucketPoliciesRep) error {
+	if !isJWTReqAuthenticated(r) {
+		return &json2.Error{Message: "Unauthorized request"}
+	}
+	if _, err := web.ObjectAPI.GetBucketInfo(args.BucketName); err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	policy, err := readBucketAccessPolicies(args.BucketName)
+	if err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	reply.UIVersion = miniobrowser.UIVersion
+	policies := getBucketAccessPolicies(args.BucketName, policy)
+	var prefixes []string
+	for prefix := range policies {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		reply.Policies = append(reply.Policies, BucketAccessPolicy{
+			Prefix: prefix,
+			Policy: policies[prefix],
+		})
+	}
+	return nil
+}
+
+// SetBucketPolicyArgs - set bucket policy args.
+type SetBucketPolicyArgs struct {
+	BucketName string             `json:"bucketName"`
+	Prefix     string             `json:"prefix"`
+	Policy     bucketAccessPolicy `json:"policy"`
+}
+
+// SetBucketPolicy - set the access policy of a prefix of a bucket,
+// "none" removes it.
+func (web *webAPIHandlers) SetBucketPolicy(r *http.Request, args *SetBucketPolicyArgs, reply *WebGenericRep) error {
+	if !isJWTReqAuthenticated(r) {
+		return &json2.Error{Message: "Unauthorized request"}
+	}
+	if !isValidBucketAccessPolicy(args.Policy) {
+		return &json2.Error{Message: "Invalid policy " + string(args.Policy)}
+	}
+	if _, err := web.ObjectAPI.GetBucketInfo(args.BucketName); err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	// Policy is read, changed and written under lock, concurrent
+	// changes of other prefixes are kept.
+	lockBucketPolicy(args.BucketName)
+	defer unlockBucketPolicy(args.BucketName)
+	policy, err := readBucketAccessPolicies(args.BucketName)
+	if err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	policy = setBucketAccessPolicy(args.BucketName, policy, args.Prefix, args.Policy)
+	if err = writeBucketAccessPolicy(args.BucketName, policy); err != nil {
+		return &json2.Error{Message: err.Error()}
+	}
+	reply.UIVersion = miniobrowser.UIVersion
+	return nil
+}
+
 // Upload - file upload handler.
 func (web *webAPIHandlers) Upload(w http.ResponseWriter, r *http.Request) {
 	if !isJWTReqAuthenticated(r) {
@@ -356,9 +706,14 @@ func (web *webAPIHandlers) Upload(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	bucket := vars["bucket"]
 	object := vars["object"]
-	if _, err := web.ObjectAPI.PutObject(bucket, object, -1, r.Body, nil); err != nil {
+	// Mark the object for replication, if any.
+	metadata := getReplicationMetadata(bucket, object, nil)
+	if _, err := web.ObjectAPI.PutObject(bucket, object, -1, r.Body, metadata); err != nil {
 		writeWebErrorResponse(w, err)
+		return
 	}
+	// Queue the new object for replication.
+	queueReplication(bucket, object, nil)
 }
 
 // Download - file download handler.
@@ -368,14 +723,7 @@ func (web *webAPIHandlers) Download(w http.ResponseWriter, r *http.Request) {
 	object := vars["object"]
 	token := r.URL.Query().Get("token")
 
-	jwt := initJWT()
-	jwttoken, e := jwtgo.Parse(token, func(token *jwtgo.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwtgo.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(jwt.SecretAccessKey), nil
-	})
-	if e != nil || !jwttoken.Valid {
+	if !isAuthTokenValid(token) {
 		writeWebErrorResponse(w, errInvalidToken)
 		return
 	}
@@ -395,6 +743,152 @@ func (web *webAPIHandlers) Download(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// DownloadZipArgs - objects to download as a zip archive.
+type DownloadZipArgs struct {
+	BucketName string `json:"bucketName"`
+	// Current folder in the browser, objects are relative to it.
+	Prefix string `json:"prefix"`
+	// Objects and folders to download, folder names end with "/".
+	Objects []string `json:"objects"`
+}
+
+// Maximum size of the request body of zip downloads.
+const maxDownloadZipArgsSize = 1 * 1024 * 1024
+
+// DownloadZip - downloads objects and whole folders as a zip archive.
+// The archive is streamed while objects are read and stops as soon as
+// the client disconnects.
+func (web *webAPIHandlers) DownloadZip(w http.ResponseWriter, r *http.Request) {
+	if !isAuthTokenValid(r.URL.Query().Get("token")) {
+		writeWebErrorResponse(w, errInvalidToken)
+		return
+	}
+	var args DownloadZipArgs
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxDownloadZipArgsSize)).Decode(&args); err != nil || len(args.Objects) == 0 {
+		writeWebErrorResponse(w, errInvalidArgument)
+		return
+	}
+	if _, err := web.ObjectAPI.GetBucketInfo(args.BucketName); err != nil {
+		writeWebErrorResponse(w, err)
+		return
+	}
+	// Verify objects exist before the archive is started, errors
+	// cannot be sent once it is.
+	objInfos := make(map[string]ObjectInfo)
+	for _, object := range args.Objects {
+		if strings.HasSuffix(object, "/") {
+			continue
+		}
+		objInfo, err := web.ObjectAPI.GetObjectInfo(args.BucketName, args.Prefix+object)
+		if err != nil {
+			writeWebErrorResponse(w, err)
+			return
+		}
+		objInfos[object] = objInfo
+	}
+
+	// Name the archive after the current folder.
+	archiveName := path.Base(path.Join(args.BucketName, args.Prefix))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", archiveName))
+
+	// Writes fail once the client disconnects, reading of objects stops.
+	// The connection is aborted on failure, so that a truncated archive
+	// is not taken for a complete one.
+	var closed <-chan bool
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		closed = notifier.CloseNotify()
+	}
+	archive := zip.NewWriter(&cancelWriter{Writer: w, closed: closed})
+	zipObject := func(objInfo ObjectInfo) error {
+		header := &zip.FileHeader{
+			Name:   strings.TrimPrefix(objInfo.Name, args.Prefix),
+			Method: zip.Deflate,
+		}
+		header.SetModTime(objInfo.ModTime)
+		writer, err := archive.CreateHeader(header)
+		if err != nil || objInfo.Size == 0 {
+			return err
+		}
+		return web.ObjectAPI.GetObject(args.BucketName, objInfo.Name, 0, objInfo.Size, writer)
+	}
+	for _, object := range args.Objects {
+		objectName := args.Prefix + object
+		if objInfo, ok := objInfos[object]; ok {
+			if err := zipObject(objInfo); err != nil {
+				errorIf(err, "Unable to add %s to zip archive.", objectName)
+				abortConnection(w)
+				return
+			}
+			continue
+		}
+		// Add all objects of a folder.
+		marker := ""
+		for {
+			lo, err := web.ObjectAPI.ListObjects(args.BucketName, objectName, marker, "", 1000)
+			if err != nil {
+				errorIf(err, "Unable to list %s for zip archive.", objectName)
+				abortConnection(w)
+				return
+			}
+			for _, objInfo := range lo.Objects {
+				if err = zipObject(objInfo); err != nil {
+					errorIf(err, "Unable to add %s to zip archive.", objInfo.Name)
+					abortConnection(w)
+					return
+				}
+			}
+			if !lo.IsTruncated {
+				break
+			}
+			marker = lo.NextMarker
+		}
+	}
+	// Write the central directory, the archive is incomplete otherwise.
+	if err := archive.Close(); err != nil {
+		errorIf(err, "Unable to complete zip archive.")
+		abortConnection(w)
+	}
+}
+
+// cancelWriter - fails writes once the client closed the connection,
+// as notified on closed.
+type cancelWriter struct {
+	io.Writer
+	closed    <-chan bool
+	cancelled bool
+}
+
+func (c *cancelWriter) Write(p []byte) (int, error) {
+	if !c.cancelled {
+		select {
+		case <-c.closed:
+			c.cancelled = true
+		default:
+		}
+	}
+	if c.cancelled {
+		return 0, errClientDisconnected
+	}
+	return c.Writer.Write(p)
+}
+
+// abortConnection - closes the connection of a response which cannot
+// be completed, so that the client does not take a truncated response
+// for a complete one.
+func abortConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		errorIf(err, "Unable to abort connection.")
+		return
+	}
+	conn.Close()
+}
+
 // writeWebErrorResponse - set HTTP status code and write error description to the body.
 func writeWebErrorResponse(w http.ResponseWriter, err error) {
 	// Handle invalid token as a special case.
@@ -403,6 +897,12 @@ func writeWebErrorResponse(w http.ResponseWriter, err error) {
 		w.Write([]byte(err.Error()))
 		return
 	}
+	// Malformed requests.
+	if err == errInvalidArgument {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
 	// Convert error type to api error code.
 	var apiErrCode APIErrorCode
 	switch err.(type) {
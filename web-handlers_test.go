@@ -0,0 +1,209 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Wrapper for calling zip download tests for both XL multiple disks and single node setup.
+func TestWebDownloadZip(t *testing.T) {
+	ExecObjectLayerTest(t, testWebDownloadZip)
+}
+
+// Tests validate objects and folders are downloaded as a zip archive.
+func testWebDownloadZip(obj ObjectLayer, instanceType string, t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("%s: Unable to initialize config: <ERROR> %s", instanceType, err)
+	}
+	defer removeAll(rootPath)
+
+	bucket := "bucket"
+	if err = obj.MakeBucket(bucket); err != nil {
+		t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+	}
+	objects := map[string]string{
+		"photos/a.jpg":           "a",
+		"photos/2016/b.jpg":      "bb",
+		"photos/2016/empty.jpg":  "",
+		"photos/2016/june/c.jpg": "ccc",
+		"photos/other.jpg":       "other",
+		"videos/d.mp4":           "dddd",
+	}
+	for object, data := range objects {
+		if _, err = obj.PutObject(bucket, object, int64(len(data)), strings.NewReader(data), nil); err != nil {
+			t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+		}
+	}
+	token, err := initJWT().GenerateToken(serverConfig.GetCredential().AccessKeyID)
+	if err != nil {
+		t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+	}
+	rpcToken, err := initJWT().GenerateRPCToken(rpcTokenAudience, rpcTokenExpires)
+	if err != nil {
+		t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+	}
+	web := &webAPIHandlers{ObjectAPI: obj}
+
+	testCases := []struct {
+		token              string
+		body               string
+		expectedStatusCode int
+		expectedFiles      map[string]string
+	}{
+		// Test case - 1.
+		// Objects and folders relative to the current folder.
+		{token, `{"bucketName": "bucket", "prefix": "photos/", "objects": ["a.jpg", "2016/"]}`, http.StatusOK, map[string]string{
+			"a.jpg":           "a",
+			"2016/b.jpg":      "bb",
+			"2016/empty.jpg":  "",
+			"2016/june/c.jpg": "ccc",
+		}},
+		// Test case - 2.
+		// Folders of the bucket.
+		{token, `{"bucketName": "bucket", "objects": ["videos/"]}`, http.StatusOK, map[string]string{
+			"videos/d.mp4": "dddd",
+		}},
+		// Test case - 3.
+		// Invalid token.
+		{"invalid", `{"bucketName": "bucket", "objects": ["videos/"]}`, http.StatusForbidden, nil},
+		// Test case - 4.
+		// Missing object, no archive is sent.
+		{token, `{"bucketName": "bucket", "objects": ["missing"]}`, http.StatusNotFound, nil},
+		// Test case - 5.
+		// Missing bucket.
+		{token, `{"bucketName": "missing-bucket", "objects": ["a"]}`, http.StatusNotFound, nil},
+		// Test case - 6.
+		// Malformed request.
+		{token, `{"bucketName": "bucket", "objects": []}`, http.StatusBadRequest, nil},
+		// Test case - 7.
+		// Token of the storage RPC.
+		{rpcToken, `{"bucketName": "bucket", "objects": ["videos/"]}`, http.StatusForbidden, nil},
+	}
+	for i, testCase := range testCases {
+		req, rErr := http.NewRequest("POST", "/minio/zip?token="+testCase.token, strings.NewReader(testCase.body))
+		if rErr != nil {
+			t.Fatalf("%s: Test %d: Unexpected error: <ERROR> %s", instanceType, i+1, rErr)
+		}
+		rec := httptest.NewRecorder()
+		web.DownloadZip(rec, req)
+		if rec.Code != testCase.expectedStatusCode {
+			t.Fatalf("%s: Test %d: Expected status %d, found %d", instanceType, i+1, testCase.expectedStatusCode, rec.Code)
+		}
+		if testCase.expectedFiles == nil {
+			continue
+		}
+		archive, zErr := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		if zErr != nil {
+			t.Fatalf("%s: Test %d: Unable to read zip archive: <ERROR> %s", instanceType, i+1, zErr)
+		}
+		files := make(map[string]string)
+		for _, file := range archive.File {
+			reader, fErr := file.Open()
+			if fErr != nil {
+				t.Fatalf("%s: Test %d: Unexpected error: <ERROR> %s", instanceType, i+1, fErr)
+			}
+			data, fErr := ioutil.ReadAll(reader)
+			reader.Close()
+			if fErr != nil {
+				t.Fatalf("%s: Test %d: Unexpected error: <ERROR> %s", instanceType, i+1, fErr)
+			}
+			files[file.Name] = string(data)
+		}
+		if len(files) != len(testCase.expectedFiles) {
+			t.Errorf("%s: Test %d: Expected files %v, found %v", instanceType, i+1, testCase.expectedFiles, files)
+		}
+		for name, data := range testCase.expectedFiles {
+			if files[name] != data {
+				t.Errorf("%s: Test %d: Expected %s with %q, found %q", instanceType, i+1, name, data, files[name])
+			}
+		}
+	}
+
+	// Archive stops once the client disconnects.
+	req, err := http.NewRequest("POST", "/minio/zip?token="+token, strings.NewReader(`{"bucketName": "bucket", "objects": ["photos/"]}`))
+	if err != nil {
+		t.Fatalf("%s: Unexpected error: <ERROR> %s", instanceType, err)
+	}
+	rec := newDisconnectedRecorder()
+	web.DownloadZip(rec, req)
+	if !rec.hijacked {
+		t.Errorf("%s: Expected aborted connection after disconnect", instanceType)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("%s: Expected no archive after disconnect, found %d bytes", instanceType, rec.Body.Len())
+	}
+
+	// Connection is aborted when an object fails to be read, the
+	// archive is not completed.
+	server := httptest.NewServer(http.HandlerFunc((&webAPIHandlers{ObjectAPI: failingGetObjectLayer{obj}}).DownloadZip))
+	defer server.Close()
+	resp, err := http.Post(server.URL+"/minio/zip?token="+token, "application/json", strings.NewReader(`{"bucketName": "bucket", "objects": ["photos/"]}`))
+	if err == nil {
+		_, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Errorf("%s: Expected aborted connection on read failure, found status %d", instanceType, resp.StatusCode)
+	}
+}
+
+// disconnectedRecorder - response recorder of a client which closed
+// its connection, records whether the connection was hijacked.
+type disconnectedRecorder struct {
+	*httptest.ResponseRecorder
+	closed   chan bool
+	hijacked bool
+}
+
+func newDisconnectedRecorder() *disconnectedRecorder {
+	closed := make(chan bool, 1)
+	closed <- true
+	return &disconnectedRecorder{ResponseRecorder: httptest.NewRecorder(), closed: closed}
+}
+
+func (d *disconnectedRecorder) CloseNotify() <-chan bool {
+	return d.closed
+}
+
+func (d *disconnectedRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	d.hijacked = true
+	server, client := net.Pipe()
+	client.Close()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+// failingGetObjectLayer - object layer whose GetObject fails after
+// writing part of the object.
+type failingGetObjectLayer struct {
+	ObjectLayer
+}
+
+func (f failingGetObjectLayer) GetObject(bucket, object string, startOffset int64, length int64, writer io.Writer) error {
+	writer.Write([]byte("partial"))
+	return errFaultyDisk
+}
@@ -0,0 +1,290 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Web token directory, in minioMetaBucket.
+	webTokensDir = "jwt"
+
+	// Key signing web tokens.
+	webSigningKeyFile = "signing.key"
+
+	// Revoked web tokens, with their expiry.
+	webRevokedTokensFile = "revoked.json"
+
+	// Length of the signing key in bytes.
+	webSigningKeyLength = 64
+
+	// Interval after which the signing key and revoked tokens are
+	// reloaded, changes made by other nodes are seen by then.
+	webTokensReloadInterval = 5 * time.Second
+)
+
+// webTokenStore - signing key and revocation list of web browser
+// tokens. The signing key is generated once and is independent of the
+// server credentials, tokens are revoked until they expire. Both are
+// saved in minioMetaBucket of all the disks, so that all the nodes of a
+// distributed setup share them.
+type webTokenStore struct {
+	disks          []StorageAPI
+	reloadInterval time.Duration
+
+	mutex      *sync.Mutex
+	signingKey []byte
+	// Expiry of revoked tokens per token id.
+	revoked  map[string]int64
+	loadedAt time.Time
+}
+
+// Global web token store, initialized by the server.
+var globalWebTokens *webTokenStore
+
+// initWebTokens - initialize global web token store on disks. Tokens
+// of the gateway, which has no disks, are saved under config path.
+func initWebTokens(disks []StorageAPI) error {
+	if len(disks) == 0 {
+		configPath, err := getConfigPath()
+		if err != nil {
+			return err
+		}
+		disk, err := newPosix(configPath)
+		if err != nil {
+			return err
+		}
+		if err = disk.MakeVol(minioMetaBucket); err != nil && err != errVolumeExists {
+			return err
+		}
+		disks = []StorageAPI{disk}
+	}
+	var err error
+	globalWebTokens, err = newWebTokenStore(disks)
+	return err
+}
+
+// newWebTokenStore - loads the signing key and revoked tokens saved
+// on disks, a new signing key is generated if there is none.
+func newWebTokenStore(disks []StorageAPI) (*webTokenStore, error) {
+	s := &webTokenStore{
+		disks:          disks,
+		reloadInterval: webTokensReloadInterval,
+		mutex:          &sync.Mutex{},
+		revoked:        make(map[string]int64),
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		if err != errFileNotFound {
+			return nil, err
+		}
+		if err = s.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// load - reads the signing key and revoked tokens from disks, called
+// with the lock held.
+func (s *webTokenStore) load() error {
+	keyBytes, err := readWebTokenFile(s.disks, webSigningKeyFile)
+	if err != nil {
+		return err
+	}
+	signingKey, err := hex.DecodeString(strings.TrimSpace(string(keyBytes)))
+	if err != nil {
+		return err
+	}
+	revoked := make(map[string]int64)
+	revokedBytes, err := readWebTokenFile(s.disks, webRevokedTokensFile)
+	if err == nil {
+		err = json.Unmarshal(revokedBytes, &revoked)
+	}
+	if err != nil && err != errFileNotFound {
+		return err
+	}
+	s.signingKey = signingKey
+	s.revoked = revoked
+	s.loadedAt = time.Now()
+	return nil
+}
+
+// reload - reloads the signing key and revoked tokens once they are
+// older than the reload interval, called with the lock held. Loaded
+// ones are kept if disks cannot be read.
+func (s *webTokenStore) reload() {
+	if time.Since(s.loadedAt) < s.reloadInterval {
+		return
+	}
+	if err := s.load(); err != nil {
+		errorIf(err, "Unable to reload web tokens.")
+		s.loadedAt = time.Now()
+	}
+}
+
+// SigningKey - returns the key signing web tokens.
+func (s *webTokenStore) SigningKey() []byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reload()
+	return s.signingKey
+}
+
+// RotateSigningKey - replaces the signing key, all the tokens signed
+// with the previous key become invalid. The key is written with the
+// lock held, so that concurrent rotations save the key in use.
+func (s *webTokenStore) RotateSigningKey() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.rotate()
+}
+
+// rotate - generates and saves a new signing key, called with the
+// lock held.
+func (s *webTokenStore) rotate() error {
+	signingKey := make([]byte, webSigningKeyLength)
+	if _, err := rand.Read(signingKey); err != nil {
+		return err
+	}
+	if err := writeWebTokenFile(s.disks, webSigningKeyFile, []byte(hex.EncodeToString(signingKey))); err != nil {
+		return err
+	}
+	s.signingKey = signingKey
+	// Revoked tokens can no longer be verified.
+	s.revoked = make(map[string]int64)
+	s.loadedAt = time.Now()
+	return s.saveRevoked()
+}
+
+// Revoke - revokes the token id until expiry, expired tokens are
+// removed from the revocation list. Revoked tokens are reloaded
+// first, not to drop tokens revoked by other nodes.
+func (s *webTokenStore) Revoke(id string, expiry time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	now := time.Now().UTC().Unix()
+	for revokedID, revokedExpiry := range s.revoked {
+		if revokedExpiry < now {
+			delete(s.revoked, revokedID)
+		}
+	}
+	s.revoked[id] = expiry.Unix()
+	return s.saveRevoked()
+}
+
+// IsRevoked - returns true if the token id was revoked.
+func (s *webTokenStore) IsRevoked(id string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reload()
+	_, ok := s.revoked[id]
+	return ok
+}
+
+// saveRevoked - saves the revocation list, called with the lock held.
+func (s *webTokenStore) saveRevoked() error {
+	revokedBytes, err := json.Marshal(s.revoked)
+	if err != nil {
+		return err
+	}
+	return writeWebTokenFile(s.disks, webRevokedTokensFile, revokedBytes)
+}
+
+// readWebTokenFile - reads a file of the token store from all disks,
+// returns the content found on most disks. errFileNotFound is returned
+// if no disk has the file and at least one disk is sure of it.
+func readWebTokenFile(disks []StorageAPI, name string) ([]byte, error) {
+	var contents []string
+	counts := make(map[string]int)
+	var err error
+	notFound := false
+	for _, disk := range disks {
+		if disk == nil {
+			continue
+		}
+		data, rErr := disk.ReadAll(minioMetaBucket, path.Join(webTokensDir, name))
+		if rErr != nil {
+			if rErr == errFileNotFound {
+				notFound = true
+			} else {
+				err = rErr
+			}
+			continue
+		}
+		if counts[string(data)] == 0 {
+			contents = append(contents, string(data))
+		}
+		counts[string(data)]++
+	}
+	if len(contents) == 0 {
+		if notFound || err == nil {
+			return nil, errFileNotFound
+		}
+		return nil, err
+	}
+	content := contents[0]
+	for _, c := range contents[1:] {
+		if counts[c] > counts[content] {
+			content = c
+		}
+	}
+	return []byte(content), nil
+}
+
+// writeWebTokenFile - replaces the content of a file of the token
+// store on all disks, readers see either the previous or the new
+// content. Files are read from most disks, writes need to succeed on
+// a majority of the disks.
+func writeWebTokenFile(disks []StorageAPI, name string, data []byte) error {
+	tmpPath := path.Join(tmpMetaPrefix, getUUID())
+	var err error
+	written := 0
+	for _, disk := range disks {
+		if disk == nil {
+			continue
+		}
+		wErr := disk.AppendFile(minioMetaBucket, tmpPath, data)
+		if wErr == nil {
+			wErr = disk.RenameFile(minioMetaBucket, tmpPath, minioMetaBucket, path.Join(webTokensDir, name))
+		}
+		if wErr != nil {
+			disk.DeleteFile(minioMetaBucket, tmpPath)
+			err = wErr
+			continue
+		}
+		written++
+	}
+	if written <= len(disks)/2 {
+		if err == nil {
+			err = errDiskNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+/*
+ * Minio Cloud Storage, (C) 2016 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+)
+
+// Tests validate web tokens are verified with the web token signing key
+// and for their audience.
+func TestWebTokens(t *testing.T) {
+	_, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to initialize config: <ERROR> %s", err)
+	}
+	defer removeAll(rootPath)
+
+	jwt := initJWT()
+	accessToken, err := jwt.GenerateToken(jwt.AccessKeyID)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	refreshToken, err := jwt.GenerateRefreshToken(jwt.AccessKeyID)
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	otherUserToken, err := jwt.GenerateToken("other-user")
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+	// Token signed with the S3 secret key as before.
+	secretKeyToken := jwtgo.New(jwtgo.SigningMethodHS512)
+	secretKeyToken.Claims["sub"] = jwt.AccessKeyID
+	secretKeyToken.Claims["aud"] = webAccessAudience
+	secretKeyToken.Claims["jti"] = getUUID()
+	secretKeyTokenStr, err := secretKeyToken.SignedString([]byte(jwt.SecretAccessKey))
+	if err != nil {
+		t.Fatalf("Unexpected error: <ERROR> %s", err)
+	}
+
+	testCases := []struct {
+		token      string
+		audience   string
+		shouldPass bool
+	}{
+		// Test case - 1.
+		// Valid access token.
+		{accessToken, webAccessAudience, true},
+		// Test case - 2.
+		// Valid refresh token.
+		{refreshToken, webRefreshAudience, true},
+		// Test case - 3.
+		// Refresh token used as an access token.
+		{refreshToken, webAccessAudience, false},
+		// Test case - 4.
+		// Access token used as a refresh token.
+		{accessToken, webRefreshAudience, false},
+		// Test case - 5.
+		// Token of another user.
+		{otherUserToken, webAccessAudience, false},
+		// Test case - 6.
+		// Token signed with the S3 secret key.
+		{secretKeyTokenStr, webAccessAudience, false},
+		// Test case - 7.
+		// Malformed token.
+		{"invalid", webAccessAudience, false},
+	}
+	for i, testCase := range testCases {
+		if _, err = jwt.ParseWebToken(testCase.token, testCase.audience); (err == nil) != testCase.shouldPass {
+			t.Errorf("Test %d: Expected token to be valid %v, found error %v", i+1, testCase.shouldPass, err)
+		}
+	}
+
+	// Revoked tokens stay revoked after a restart.
+	if err = jwt.RevokeWebToken(accessToken, webAccessAudience); err != nil {
+		t.Fatalf("Unable to revoke token: <ERROR> %s", err)
+	}
+	if err = initWebTokens(nil); err != nil {
+		t.Fatalf("Unable to initialize web tokens: <ERROR> %s", err)
+	}
+	if _, err = jwt.ParseWebToken(accessToken, webAccessAudience); err != errInvalidToken {
+		t.Errorf("Expected %s for revoked token, found %v", errInvalidToken, err)
+	}
+	if _, err = jwt.ParseWebToken(refreshToken, webRefreshAudience); err != nil {
+		t.Errorf("Expected token to stay valid after a restart, found error %s", err)
+	}
+
+	// Tokens are invalid once the signing key is rotated.
+	if err = globalWebTokens.RotateSigningKey(); err != nil {
+		t.Fatalf("Unable to rotate signing key: <ERROR> %s", err)
+	}
+	if _, err = jwt.ParseWebToken(refreshToken, webRefreshAudience); err != errInvalidToken {
+		t.Errorf("Expected %s after key rotation, found %v", errInvalidToken, err)
+	}
+
+	// Key saved by concurrent rotations is the key in use.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			globalWebTokens.RotateSigningKey()
+		}()
+	}
+	wg.Wait()
+	signingKey := globalWebTokens.SigningKey()
+	if err = initWebTokens(nil); err != nil {
+		t.Fatalf("Unable to initialize web tokens: <ERROR> %s", err)
+	}
+	if !bytes.Equal(globalWebTokens.SigningKey(), signingKey) {
+		t.Errorf("Expected saved signing key to be the key in use")
+	}
+}
+
+// Tests validate nodes sharing the disks share the signing key and
+// revoked tokens.
+func TestWebTokensSharedStore(t *testing.T) {
+	var roots []string
+	defer func() {
+		removeRoots(roots)
+	}()
+	var disks []StorageAPI
+	for i := 0; i < 4; i++ {
+		root, err := ioutil.TempDir("", "minio-")
+		if err != nil {
+			t.Fatalf("Unable to create disk: <ERROR> %s", err)
+		}
+		roots = append(roots, root)
+		disk, err := newPosix(root)
+		if err != nil {
+			t.Fatalf("Unable to initialize disk: <ERROR> %s", err)
+		}
+		if err = disk.MakeVol(minioMetaBucket); err != nil {
+			t.Fatalf("Unable to create meta volume: <ERROR> %s", err)
+		}
+		disks = append(disks, disk)
+	}
+
+	// Test case - 1.
+	// Nodes started together use the key generated by the first one.
+	node1, err := newWebTokenStore(disks)
+	if err != nil {
+		t.Fatalf("Unable to initialize web tokens: <ERROR> %s", err)
+	}
+	node2, err := newWebTokenStore(disks)
+	if err != nil {
+		t.Fatalf("Unable to initialize web tokens: <ERROR> %s", err)
+	}
+	node2.reloadInterval = 0
+	if !bytes.Equal(node1.SigningKey(), node2.SigningKey()) {
+		t.Fatal("Test 1: Expected nodes to share the signing key")
+	}
+
+	// Test case - 2.
+	// Tokens revoked on a node are revoked on the others.
+	expiry := time.Now().UTC().Add(time.Hour)
+	if err = node1.Revoke("token-1", expiry); err != nil {
+		t.Fatalf("Unable to revoke token: <ERROR> %s", err)
+	}
+	if err = node2.Revoke("token-2", expiry); err != nil {
+		t.Fatalf("Unable to revoke token: <ERROR> %s", err)
+	}
+	if !node2.IsRevoked("token-1") {
+		t.Error("Test 2: Expected token revoked by another node to be revoked")
+	}
+	node1.reloadInterval = 0
+	if !node1.IsRevoked("token-1") || !node1.IsRevoked("token-2") {
+		t.Error("Test 2: Expected revocations of both nodes to be kept")
+	}
+
+	// Test case - 3.
+	// Key rotated on a node is used by the others.
+	if err = node1.RotateSigningKey(); err != nil {
+		t.Fatalf("Unable to rotate signing key: <ERROR> %s", err)
+	}
+	if !bytes.Equal(node1.SigningKey(), node2.SigningKey()) {
+		t.Error("Test 3: Expected rotated signing key to be shared")
+	}
+
+	// Test case - 4.
+	// Store is read while a minority of the disks is missing.
+	if err = removeAll(roots[0]); err != nil {
+		t.Fatalf("Unable to remove disk: <ERROR> %s", err)
+	}
+	node3, err := newWebTokenStore(disks)
+	if err != nil {
+		t.Fatalf("Unable to initialize web tokens: <ERROR> %s", err)
+	}
+	if !bytes.Equal(node1.SigningKey(), node3.SigningKey()) {
+		t.Error("Test 4: Expected signing key to be read from the other disks")
+	}
+}
+
+// Tests validate web sessions are refreshed and closed with the web RPC.
+func TestWebLogout(t *testing.T) {
+	cred, rootPath, err := initTestConfig("us-east-1")
+	if err != nil {
+		t.Fatalf("Unable to initialize config: <ERROR> %s", err)
+	}
+	defer removeAll(rootPath)
+
+	web := &webAPIHandlers{}
+	loginReply := &LoginRep{}
+	if err = web.Login(&http.Request{}, &LoginArgs{Username: cred.AccessKeyID, Password: cred.SecretAccessKey}, loginReply); err != nil {
+		t.Fatalf("Unable to login: <ERROR> %s", err)
+	}
+	newRequest := func(token string) *http.Request {
+		req, rErr := http.NewRequest("POST", "/minio/rpc", nil)
+		if rErr != nil {
+			t.Fatalf("Unexpected error: <ERROR> %s", rErr)
+		}
+		req.Header.Set("Authorization", jwtAlgorithm+" "+token)
+		return req
+	}
+	if !isJWTReqAuthenticated(newRequest(loginReply.Token)) {
+		t.Fatal("Expected access token of login to be valid")
+	}
+	if isJWTReqAuthenticated(newRequest(loginReply.RefreshToken)) {
+		t.Fatal("Expected refresh token to be refused for requests")
+	}
+
+	refreshReply := &RefreshTokenRep{}
+	if err = web.RefreshToken(&http.Request{}, &RefreshTokenArgs{RefreshToken: loginReply.RefreshToken}, refreshReply); err != nil {
+		t.Fatalf("Unable to refresh token: <ERROR> %s", err)
+	}
+	if !isJWTReqAuthenticated(newRequest(refreshReply.Token)) {
+		t.Fatal("Expected refreshed access token to be valid")
+	}
+	if err = web.RefreshToken(&http.Request{}, &RefreshTokenArgs{RefreshToken: loginReply.Token}, &RefreshTokenRep{}); err == nil {
+		t.Fatal("Expected access token to be refused for refresh")
+	}
+
+	if err = web.Logout(newRequest(refreshReply.Token), &LogoutArgs{RefreshToken: loginReply.RefreshToken}, &WebGenericRep{}); err != nil {
+		t.Fatalf("Unable to logout: <ERROR> %s", err)
+	}
+	if isJWTReqAuthenticated(newRequest(refreshReply.Token)) {
+		t.Error("Expected access token to be revoked after logout")
+	}
+	if err = web.RefreshToken(&http.Request{}, &RefreshTokenArgs{RefreshToken: loginReply.RefreshToken}, &RefreshTokenRep{}); err == nil {
+		t.Error("Expected refresh token to be revoked after logout")
+	}
+	// Access tokens which were not used to logout stay valid until they expire.
+	if !isJWTReqAuthenticated(newRequest(loginReply.Token)) {
+		t.Error("Expected access token of login to stay valid")
+	}
+
+	// Refresh token alone is enough to logout, once the access token expired.
+	loginReply = &LoginRep{}
+	if err = web.Login(&http.Request{}, &LoginArgs{Username: cred.AccessKeyID, Password: cred.SecretAccessKey}, loginReply); err != nil {
+		t.Fatalf("Unable to login: <ERROR> %s", err)
+	}
+	if err = web.Logout(newRequest("expired"), &LogoutArgs{RefreshToken: loginReply.RefreshToken}, &WebGenericRep{}); err != nil {
+		t.Fatalf("Unable to logout with a refresh token: <ERROR> %s", err)
+	}
+	if err = web.RefreshToken(&http.Request{}, &RefreshTokenArgs{RefreshToken: loginReply.RefreshToken}, &RefreshTokenRep{}); err == nil {
+		t.Error("Expected refresh token to be revoked after logout")
+	}
+	// Logout needs either a valid access token or a valid refresh token.
+	if err = web.Logout(newRequest("expired"), &LogoutArgs{RefreshToken: loginReply.RefreshToken}, &WebGenericRep{}); err == nil {
+		t.Error("Expected logout with revoked tokens to be refused")
+	}
+	if err = web.Logout(newRequest("expired"), &LogoutArgs{RefreshToken: loginReply.Token}, &WebGenericRep{}); err == nil {
+		t.Error("Expected logout with an access token as refresh token to be refused")
+	}
+}
@@ -74,3 +74,39 @@ func (xl xlObjects) isObject(bucket, prefix string) (ok bool) {
 	} // Exhausted all disks - return false.
 	return false
 }
+
+// checkObjectLock - returns ObjectLocked if an existing object is
+// protected by object lock retention or legal hold on any disk. Objects
+// are only considered unlocked if they are not found on read quorum of
+// the disks, any other error is returned.
+func (xl xlObjects) checkObjectLock(bucket, object string) error {
+	metaArr, errs := xl.readAllXLMetadata(bucket, object)
+	var found, notFound int
+	for index, err := range errs {
+		if err == nil {
+			if isObjectLocked(metaArr[index].Meta) {
+				return ObjectLocked{Bucket: bucket, Object: object}
+			}
+			found++
+			continue
+		}
+		// Objects with too long names cannot exist.
+		if err == errFileNotFound || err == errFileNameTooLong {
+			notFound++
+		}
+	}
+	if found == 0 && notFound < xl.readQuorum {
+		return toObjectErr(errXLReadQuorum, bucket, object)
+	}
+	return nil
+}
+
+// objectUsage - returns size and count of an existing object, used
+// for bucket quota accounting.
+func (xl xlObjects) objectUsage(bucket, object string) (size int64, objects int64) {
+	xlMeta, err := xl.readXLMetadata(bucket, object)
+	if err != nil {
+		return 0, 0
+	}
+	return getQuotaSize(xlMeta.Meta, xlMeta.Stat.Size), 1
+}
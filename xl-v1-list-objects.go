@@ -96,12 +96,14 @@ func (xl xlObjects) listObjects(bucket, prefix, marker, delimiter string, maxKey
 			ModTime: objInfo.ModTime,
 			Size:    objInfo.Size,
 			IsDir:   false,
+			// Metadata is needed by layers in front of XL.
+			UserDefined: objInfo.UserDefined,
 		})
 	}
 	return result, nil
 }
 
-// ListObjects - list all objects at prefix, delimited by '/'.
+// ListObjects - list all objects at prefix, grouped by delimiter.
 func (xl xlObjects) ListObjects(bucket, prefix, marker, delimiter string, maxKeys int) (ListObjectsInfo, error) {
 	// Verify if bucket is valid.
 	if !IsValidBucketName(bucket) {
@@ -114,12 +116,6 @@ func (xl xlObjects) ListObjects(bucket, prefix, marker, delimiter string, maxKey
 	if !IsValidObjectPrefix(prefix) {
 		return ListObjectsInfo{}, ObjectNameInvalid{Bucket: bucket, Object: prefix}
 	}
-	// Verify if delimiter is anything other than '/', which we do not support.
-	if delimiter != "" && delimiter != slashSeparator {
-		return ListObjectsInfo{}, UnsupportedDelimiter{
-			Delimiter: delimiter,
-		}
-	}
 	// Verify if marker has prefix.
 	if marker != "" {
 		if !strings.HasPrefix(marker, prefix) {
@@ -148,6 +144,11 @@ func (xl xlObjects) ListObjects(bucket, prefix, marker, delimiter string, maxKey
 		maxKeys = maxObjectList
 	}
 
+	// Delimiters other than '/' group the keys of a recursive listing.
+	if delimiter != "" && delimiter != slashSeparator {
+		return listObjectsDelimited(xl.ListObjects, bucket, prefix, marker, delimiter, maxKeys)
+	}
+
 	// Initiate a list operation, if successful filter and return quickly.
 	listObjInfo, err := xl.listObjects(bucket, prefix, marker, delimiter, maxKeys)
 	if err == nil {
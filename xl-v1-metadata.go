@@ -33,10 +33,11 @@ const (
 // objectPartInfo Info of each part kept in the multipart metadata
 // file after CompleteMultipartUpload() is called.
 type objectPartInfo struct {
-	Number int    `json:"number"`
-	Name   string `json:"name"`
-	ETag   string `json:"etag"`
-	Size   int64  `json:"size"`
+	Number   int    `json:"number"`
+	Name     string `json:"name"`
+	ETag     string `json:"etag"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum,omitempty"`
 }
 
 // byObjectPartNumber is a collection satisfying sort.Interface.
@@ -142,12 +143,13 @@ func (m xlMetaV1) ObjectPartIndex(partNumber int) (index int) {
 }
 
 // AddObjectPart - add a new object part in order.
-func (m *xlMetaV1) AddObjectPart(partNumber int, partName string, partETag string, partSize int64) {
+func (m *xlMetaV1) AddObjectPart(partNumber int, partName string, partETag string, partSize int64, partChecksum string) {
 	partInfo := objectPartInfo{
-		Number: partNumber,
-		Name:   partName,
-		ETag:   partETag,
-		Size:   partSize,
+		Number:   partNumber,
+		Name:     partName,
+		ETag:     partETag,
+		Size:     partSize,
+		Checksum: partChecksum,
 	}
 
 	// Update part info if it already exists.
@@ -43,7 +43,7 @@ func TestXLMetaV1(t *testing.T) {
 
 	// Add 5 parts.
 	for _, test := range testCases {
-		meta.AddObjectPart(test.partNum, test.partName, test.etag, test.size)
+		meta.AddObjectPart(test.partNum, test.partName, test.etag, test.size, "")
 	}
 
 	// Test for ObjectPartIndex()
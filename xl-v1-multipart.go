@@ -294,6 +294,11 @@ func (xl xlObjects) NewMultipartUpload(bucket, object string, meta map[string]st
 	if !IsValidObjectName(object) {
 		return "", ObjectNameInvalid{Bucket: bucket, Object: object}
 	}
+	// Uploaded object is retained as per the bucket default retention.
+	meta, err := applyDefaultRetention(bucket, meta)
+	if err != nil {
+		return "", err
+	}
 	// No metadata is set, allocate a new one.
 	if meta == nil {
 		meta = make(map[string]string)
@@ -334,6 +339,11 @@ func (xl xlObjects) putObjectPart(bucket string, object string, uploadID string,
 	// Pick one from the first valid metadata.
 	xlMeta := pickValidXLMeta(partsMetadata)
 
+	// Verify if the upload fits in bucket quota before writing the part.
+	if err = globalBucketQuotas.checkUploadPart(bucket, xlMeta.Parts, partID, size); err != nil {
+		return "", err
+	}
+
 	partSuffix := fmt.Sprintf("part.%d", partID)
 	tmpPartPath := path.Join(tmpMetaPrefix, uploadID, partSuffix)
 
@@ -347,8 +357,12 @@ func (xl xlObjects) putObjectPart(bucket string, object string, uploadID string,
 		data = io.LimitReader(data, size+1)
 	} // else we read till EOF.
 
-	// Construct a tee reader for md5sum.
-	teeReader := io.TeeReader(data, md5Writer)
+	// Initialize checksum of the part, if the upload has a checksum
+	// algorithm.
+	checksumHash := newChecksumHash(xlMeta.Meta)
+
+	// Construct a tee reader for md5sum and the checksum.
+	teeReader := io.TeeReader(data, newChecksumWriter(md5Writer, checksumHash))
 
 	// Collect all the previous erasure infos across the disk.
 	var eInfos []erasureInfo
@@ -395,7 +409,7 @@ func (xl xlObjects) putObjectPart(bucket string, object string, uploadID string,
 	xlMeta.Stat.Version = higherVersion
 
 	// Add the current part.
-	xlMeta.AddObjectPart(partID, partSuffix, newMD5Hex, size)
+	xlMeta.AddObjectPart(partID, partSuffix, newMD5Hex, size, encodeChecksum(checksumHash))
 
 	// Update `xl.json` content for each disks.
 	for index := range partsMetadata {
@@ -458,6 +472,7 @@ func (xl xlObjects) listObjectParts(bucket, object, uploadID string, partNumberM
 	result.Object = object
 	result.UploadID = uploadID
 	result.MaxParts = maxParts
+	result.ChecksumAlgorithm = xlMeta.Meta[checksumAlgorithmKey]
 
 	// For empty number of parts or maxParts as zero, return right here.
 	if len(xlMeta.Parts) == 0 || maxParts == 0 {
@@ -487,6 +502,7 @@ func (xl xlObjects) listObjectParts(bucket, object, uploadID string, partNumberM
 			ETag:         part.ETag,
 			LastModified: fi.ModTime,
 			Size:         part.Size,
+			Checksum:     part.Checksum,
 		})
 		count--
 		if count == 0 {
@@ -625,10 +641,11 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 
 		// Add incoming parts.
 		xlMeta.Parts[i] = objectPartInfo{
-			Number: part.PartNumber,
-			ETag:   part.ETag,
-			Size:   currentXLMeta.Parts[partIdx].Size,
-			Name:   fmt.Sprintf("part.%d", part.PartNumber),
+			Number:   part.PartNumber,
+			ETag:     part.ETag,
+			Size:     currentXLMeta.Parts[partIdx].Size,
+			Name:     fmt.Sprintf("part.%d", part.PartNumber),
+			Checksum: currentXLMeta.Parts[partIdx].Checksum,
 		}
 	}
 
@@ -641,6 +658,12 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 	xlMeta.Stat.Size = objectSize
 	xlMeta.Stat.ModTime = time.Now().UTC()
 
+	// Save the checksum of the object, if the upload has a checksum
+	// algorithm.
+	if err = completeMultipartChecksum(xlMeta.Meta, parts, xlMeta.Parts); err != nil {
+		return "", err
+	}
+
 	// Save successfully calculated md5sum.
 	xlMeta.Meta["md5Sum"] = s3MD5
 	uploadIDPath = path.Join(mpartMetaPrefix, bucket, object, uploadID)
@@ -666,11 +689,23 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 	nsMutex.Lock(bucket, object)
 	defer nsMutex.Unlock(bucket, object)
 
+	// Objects protected by object lock cannot be overwritten.
+	if err := xl.checkObjectLock(bucket, object); err != nil {
+		return "", err
+	}
+
+	// Verify and account for the new object in bucket quota.
+	oldSize, oldObjects := xl.objectUsage(bucket, object)
+	if err = globalBucketQuotas.reserve(bucket, objectSize-oldSize, 1-oldObjects); err != nil {
+		return "", err
+	}
+
 	// Rename if an object already exists to temporary location.
 	uniqueID := getUUID()
 	if xl.isObject(bucket, object) {
 		err = xl.renameObject(bucket, object, minioMetaBucket, path.Join(tmpMetaPrefix, uniqueID))
 		if err != nil {
+			globalBucketQuotas.update(bucket, oldSize-objectSize, oldObjects-1)
 			return "", toObjectErr(err, bucket, object)
 		}
 	}
@@ -690,6 +725,7 @@ func (xl xlObjects) CompleteMultipartUpload(bucket string, object string, upload
 
 	// Rename the multipart object to final location.
 	if err = xl.renameObject(minioMetaBucket, uploadIDPath, bucket, object); err != nil {
+		globalBucketQuotas.update(bucket, oldSize-objectSize, oldObjects-1)
 		return "", toObjectErr(err, bucket, object)
 	}
 
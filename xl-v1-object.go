@@ -161,6 +161,12 @@ func (xl xlObjects) getObjectInfo(bucket, object string) (objInfo ObjectInfo, er
 		MD5Sum:          xlMeta.Meta["md5Sum"],
 		ContentType:     xlMeta.Meta["content-type"],
 		ContentEncoding: xlMeta.Meta["content-encoding"],
+		UserDefined:     xlMeta.Meta,
+	}
+	// Objects created with a single PUT are saved as a single part
+	// as well, only multipart objects carry a multipart md5sum.
+	if isMultipartMD5(objInfo.MD5Sum) {
+		objInfo.Parts = xlMeta.Parts
 	}
 	return objInfo, nil
 }
@@ -296,6 +302,11 @@ func (xl xlObjects) PutObject(bucket string, object string, size int64, data io.
 			Object: object,
 		}
 	}
+	// New objects are retained as per the bucket default retention.
+	metadata, err := applyDefaultRetention(bucket, metadata)
+	if err != nil {
+		return "", err
+	}
 	// No metadata is set, allocate a new one.
 	if metadata == nil {
 		metadata = make(map[string]string)
@@ -303,6 +314,29 @@ func (xl xlObjects) PutObject(bucket string, object string, size int64, data io.
 	nsMutex.Lock(bucket, object)
 	defer nsMutex.Unlock(bucket, object)
 
+	// Objects protected by object lock cannot be overwritten.
+	if err := xl.checkObjectLock(bucket, object); err != nil {
+		return "", err
+	}
+
+	// Verify and account for the new object in bucket quota before
+	// writing it, size of chunked uploads is accounted once written.
+	oldSize, oldObjects := xl.objectUsage(bucket, object)
+	reservedSize := getQuotaSize(metadata, size)
+	if reservedSize < 0 {
+		reservedSize = 0
+	}
+	if err := globalBucketQuotas.reserve(bucket, reservedSize-oldSize, 1-oldObjects); err != nil {
+		return "", err
+	}
+	committed := false
+	defer func() {
+		// Release the reserved usage if the object was not saved.
+		if !committed {
+			globalBucketQuotas.update(bucket, oldSize-reservedSize, oldObjects-1)
+		}
+	}()
+
 	uniqueID := getUUID()
 	tempErasureObj := path.Join(tmpMetaPrefix, uniqueID, "part.1")
 	minioMetaTmpBucket := path.Join(minioMetaBucket, tmpMetaPrefix)
@@ -339,9 +373,12 @@ func (xl xlObjects) PutObject(bucket string, object string, size int64, data io.
 		data = io.LimitReader(data, size+1)
 	} // else we read till EOF.
 
+	// Initialize checksum of the object, if any.
+	checksumHash := newChecksumHash(metadata)
+
 	// Tee reader combines incoming data stream and md5, data read
-	// from input stream is written to md5.
-	teeReader := io.TeeReader(data, md5Writer)
+	// from input stream is written to md5 and the checksum.
+	teeReader := io.TeeReader(data, newChecksumWriter(md5Writer, checksumHash))
 
 	// Collect all the previous erasure infos across the disk.
 	var eInfos []erasureInfo
@@ -390,12 +427,27 @@ func (xl xlObjects) PutObject(bucket string, object string, size int64, data io.
 		}
 	}
 
+	// Save the checksum or verify it if it was set.
+	if err = updateChecksum(metadata, checksumHash); err != nil {
+		xl.deleteObject(minioMetaTmpBucket, tempObj)
+		return "", err
+	}
+
 	// Check if an object is present as one of the parent dir.
 	// -- FIXME. (needs a new kind of lock).
 	if xl.parentDirIsObject(bucket, path.Dir(object)) {
 		return "", toObjectErr(errFileAccessDenied, bucket, object)
 	}
 
+	// Account for the difference to the reserved size in bucket quota.
+	if quotaSize := getQuotaSize(metadata, size); quotaSize != reservedSize {
+		if err = globalBucketQuotas.reserve(bucket, quotaSize-reservedSize, 0); err != nil {
+			xl.deleteObject(minioMetaTmpBucket, tempObj)
+			return "", err
+		}
+		reservedSize = quotaSize
+	}
+
 	// Rename if an object already exists to temporary location.
 	newUniqueID := getUUID()
 	if xl.isObject(bucket, object) {
@@ -411,7 +463,7 @@ func (xl xlObjects) PutObject(bucket string, object string, size int64, data io.
 	xlMeta.Stat.ModTime = modTime
 	xlMeta.Stat.Version = higherVersion
 	// Add the final part.
-	xlMeta.AddObjectPart(1, "part.1", newMD5Hex, xlMeta.Stat.Size)
+	xlMeta.AddObjectPart(1, "part.1", newMD5Hex, xlMeta.Stat.Size, "")
 
 	// Update `xl.json` content on each disks.
 	for index := range partsMetadata {
@@ -429,6 +481,7 @@ func (xl xlObjects) PutObject(bucket string, object string, size int64, data io.
 	if err != nil {
 		return "", toObjectErr(err, bucket, object)
 	}
+	committed = true
 
 	// Delete the temporary object.
 	xl.deleteObject(minioMetaTmpBucket, newUniqueID)
@@ -437,6 +490,301 @@ func (xl xlObjects) PutObject(bucket string, object string, size int64, data io.
 	return newMD5Hex, nil
 }
 
+// CopyObject - copies an object by copying its erasure coded parts
+// disk to disk in parallel, data is neither decoded nor re-encoded.
+// Only `xl.json` is rewritten if source and destination are the same
+// object.
+func (xl xlObjects) CopyObject(srcBucket, srcObject, dstBucket, dstObject string, metadata map[string]string) (string, error) {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(srcBucket) {
+		return "", BucketNameInvalid{Bucket: srcBucket}
+	}
+	if !IsValidBucketName(dstBucket) {
+		return "", BucketNameInvalid{Bucket: dstBucket}
+	}
+	// Verify bucket exists.
+	if !xl.isBucketExist(dstBucket) {
+		return "", BucketNotFound{Bucket: dstBucket}
+	}
+	if !IsValidObjectName(srcObject) {
+		return "", ObjectNameInvalid{Bucket: srcBucket, Object: srcObject}
+	}
+	if !IsValidObjectName(dstObject) {
+		return "", ObjectNameInvalid{Bucket: dstBucket, Object: dstObject}
+	}
+	// New objects are retained as per the bucket default retention.
+	metadata, err := applyDefaultRetention(dstBucket, metadata)
+	if err != nil {
+		return "", err
+	}
+	// No metadata is set, allocate a new one.
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	// Guess content-type from the extension if possible.
+	if metadata["content-type"] == "" {
+		if objectExt := filepath.Ext(dstObject); objectExt != "" {
+			if content, ok := mimedb.DB[strings.ToLower(strings.TrimPrefix(objectExt, "."))]; ok {
+				metadata["content-type"] = content.ContentType
+			}
+		}
+	}
+
+	// Metadata only update.
+	if srcBucket == dstBucket && srcObject == dstObject {
+		return xl.updateObjectMetadata(dstBucket, dstObject, metadata)
+	}
+
+	tempObj := getUUID()
+	minioMetaTmpBucket := path.Join(minioMetaBucket, tmpMetaPrefix)
+
+	// Copy all the parts to the temporary location on each disk.
+	nsMutex.RLock(srcBucket, srcObject)
+	xlMeta, err := xl.copyObjectParts(srcBucket, srcObject, minioMetaTmpBucket, tempObj, metadata)
+	nsMutex.RUnlock(srcBucket, srcObject)
+	if err != nil {
+		return "", toObjectErr(err, srcBucket, srcObject)
+	}
+
+	nsMutex.Lock(dstBucket, dstObject)
+	defer nsMutex.Unlock(dstBucket, dstObject)
+
+	// Objects protected by object lock cannot be overwritten.
+	if err := xl.checkObjectLock(dstBucket, dstObject); err != nil {
+		xl.deleteObject(minioMetaTmpBucket, tempObj)
+		return "", err
+	}
+
+	// Check if an object is present as one of the parent dir.
+	if xl.parentDirIsObject(dstBucket, path.Dir(dstObject)) {
+		xl.deleteObject(minioMetaTmpBucket, tempObj)
+		return "", toObjectErr(errFileAccessDenied, dstBucket, dstObject)
+	}
+
+	// Verify and account for the new object in bucket quota.
+	size := getQuotaSize(xlMeta.Meta, xlMeta.Stat.Size)
+	oldSize, oldObjects := xl.objectUsage(dstBucket, dstObject)
+	if err = globalBucketQuotas.reserve(dstBucket, size-oldSize, 1-oldObjects); err != nil {
+		xl.deleteObject(minioMetaTmpBucket, tempObj)
+		return "", err
+	}
+
+	// Rename if an object already exists to temporary location.
+	newUniqueID := getUUID()
+	if xl.isObject(dstBucket, dstObject) {
+		err = xl.renameObject(dstBucket, dstObject, minioMetaTmpBucket, newUniqueID)
+		if err != nil {
+			globalBucketQuotas.update(dstBucket, oldSize-size, oldObjects-1)
+			xl.deleteObject(minioMetaTmpBucket, tempObj)
+			return "", toObjectErr(err, dstBucket, dstObject)
+		}
+	}
+
+	// Rename the successfully copied temporary object to final location.
+	err = xl.renameObject(minioMetaTmpBucket, tempObj, dstBucket, dstObject)
+	if err != nil {
+		globalBucketQuotas.update(dstBucket, oldSize-size, oldObjects-1)
+		return "", toObjectErr(err, dstBucket, dstObject)
+	}
+
+	// Delete the temporary object.
+	xl.deleteObject(minioMetaTmpBucket, newUniqueID)
+
+	// Data is unchanged, md5sum of the source object is retained.
+	return xlMeta.Meta["md5Sum"], nil
+}
+
+// copyObjectParts - copies all the parts of an object to dstPrefix on
+// each disk in parallel along with `xl.json` carrying the new metadata.
+func (xl xlObjects) copyObjectParts(srcBucket, srcObject, dstBucket, dstPrefix string, metadata map[string]string) (xlMetaV1, error) {
+	if !xl.isObject(srcBucket, srcObject) {
+		return xlMetaV1{}, errFileNotFound
+	}
+
+	// Read metadata associated with the object from all disks.
+	metaArr, errs := xl.readAllXLMetadata(srcBucket, srcObject)
+	// Do we have read quorum?
+	if !isQuorum(errs, xl.readQuorum) {
+		return xlMetaV1{}, errXLReadQuorum
+	}
+
+	// List all online disks.
+	onlineDisks, highestVersion, err := xl.listOnlineDisks(metaArr, errs)
+	if err != nil {
+		return xlMetaV1{}, err
+	}
+
+	// Pick latest valid metadata.
+	var xlMeta xlMetaV1
+	for _, meta := range metaArr {
+		if meta.IsValid() && meta.Stat.Version == highestVersion {
+			xlMeta = meta
+			break
+		}
+	}
+
+	// Increment version only if we have online disks less than configured storage disks.
+	if diskCount(onlineDisks) < len(xl.storageDisks) {
+		highestVersion++
+	}
+
+	// Data is unchanged, md5sum of the source object is retained.
+	metadata["md5Sum"] = xlMeta.Meta["md5Sum"]
+	modTime := time.Now().UTC()
+
+	var wg = &sync.WaitGroup{}
+	var cErrs = make([]error, len(xl.storageDisks))
+	for index, disk := range onlineDisks {
+		if disk == nil {
+			cErrs[index] = errDiskNotFound
+			continue
+		}
+		wg.Add(1)
+		go func(index int, disk StorageAPI) {
+			defer wg.Done()
+			// Each disk keeps its own erasure index and checksums.
+			partsMetadata := metaArr[index]
+			for _, part := range partsMetadata.Parts {
+				if err := copyFile(disk, srcBucket, path.Join(srcObject, part.Name), dstBucket, path.Join(dstPrefix, part.Name)); err != nil {
+					cErrs[index] = err
+					return
+				}
+			}
+			partsMetadata.Meta = metadata
+			partsMetadata.Stat.ModTime = modTime
+			partsMetadata.Stat.Version = highestVersion
+			cErrs[index] = writeXLMetadata(disk, dstBucket, dstPrefix, partsMetadata)
+		}(index, disk)
+	}
+
+	// Wait for all the copies to finish.
+	wg.Wait()
+
+	if !isQuorum(cErrs, xl.writeQuorum) {
+		xl.deleteObject(dstBucket, dstPrefix)
+		return xlMetaV1{}, errXLWriteQuorum
+	}
+
+	xlMeta.Meta = metadata
+	xlMeta.Stat.ModTime = modTime
+	return xlMeta, nil
+}
+
+// updateObjectMetadata - replaces the metadata of an object by
+// rewriting `xl.json` on each disk in parallel.
+func (xl xlObjects) updateObjectMetadata(bucket, object string, metadata map[string]string) (string, error) {
+	nsMutex.Lock(bucket, object)
+	defer nsMutex.Unlock(bucket, object)
+
+	if !xl.isObject(bucket, object) {
+		return "", toObjectErr(errFileNotFound, bucket, object)
+	}
+	// Objects protected by object lock cannot be overwritten.
+	if err := xl.checkObjectLock(bucket, object); err != nil {
+		return "", err
+	}
+	return xl.rewriteObjectMetadata(bucket, object, func(xlMeta xlMetaV1) (map[string]string, error) {
+		// Data is unchanged, md5sum of the object is retained.
+		metadata["md5Sum"] = xlMeta.Meta["md5Sum"]
+		return metadata, nil
+	})
+}
+
+// SetObjectLock - replaces the object lock metadata of an object,
+// retention of a locked object can only be extended.
+func (xl xlObjects) SetObjectLock(bucket, object string, lockMeta map[string]string) error {
+	// Verify if bucket is valid.
+	if !IsValidBucketName(bucket) {
+		return BucketNameInvalid{Bucket: bucket}
+	}
+	if !IsValidObjectName(object) {
+		return ObjectNameInvalid{Bucket: bucket, Object: object}
+	}
+	nsMutex.Lock(bucket, object)
+	defer nsMutex.Unlock(bucket, object)
+
+	if !xl.isObject(bucket, object) {
+		return toObjectErr(errFileNotFound, bucket, object)
+	}
+	_, err := xl.rewriteObjectMetadata(bucket, object, func(xlMeta xlMetaV1) (map[string]string, error) {
+		metadata, ok := updateObjectLockMetadata(xlMeta.Meta, lockMeta)
+		if !ok {
+			return nil, ObjectLocked{Bucket: bucket, Object: object}
+		}
+		return metadata, nil
+	})
+	return err
+}
+
+// rewriteObjectMetadata - replaces the metadata of an object with the
+// metadata returned by update for its latest metadata, `xl.json` is
+// rewritten on each disk in parallel. Called with the object locked.
+func (xl xlObjects) rewriteObjectMetadata(bucket, object string, update func(xlMeta xlMetaV1) (map[string]string, error)) (string, error) {
+	// Read metadata associated with the object from all disks.
+	metaArr, errs := xl.readAllXLMetadata(bucket, object)
+	// Do we have write quroum?.
+	if !isQuorum(errs, xl.writeQuorum) {
+		return "", toObjectErr(errXLWriteQuorum, bucket, object)
+	}
+
+	// List all online disks.
+	onlineDisks, highestVersion, err := xl.listOnlineDisks(metaArr, errs)
+	if err != nil {
+		return "", toObjectErr(err, bucket, object)
+	}
+
+	// Pick latest valid metadata.
+	var xlMeta xlMetaV1
+	for _, meta := range metaArr {
+		if meta.IsValid() && meta.Stat.Version == highestVersion {
+			xlMeta = meta
+			break
+		}
+	}
+
+	metadata, err := update(xlMeta)
+	if err != nil {
+		return "", err
+	}
+	modTime := time.Now().UTC()
+
+	// Write `xl.json` to the temporary location and rename it over
+	// the existing one.
+	tempObj := getUUID()
+	minioMetaTmpBucket := path.Join(minioMetaBucket, tmpMetaPrefix)
+	var wg = &sync.WaitGroup{}
+	var mErrs = make([]error, len(xl.storageDisks))
+	for index, disk := range onlineDisks {
+		if disk == nil {
+			mErrs[index] = errDiskNotFound
+			continue
+		}
+		wg.Add(1)
+		go func(index int, disk StorageAPI) {
+			defer wg.Done()
+			partsMetadata := metaArr[index]
+			partsMetadata.Meta = metadata
+			partsMetadata.Stat.ModTime = modTime
+			if err := writeXLMetadata(disk, minioMetaTmpBucket, tempObj, partsMetadata); err != nil {
+				mErrs[index] = err
+				return
+			}
+			mErrs[index] = disk.RenameFile(minioMetaTmpBucket, path.Join(tempObj, xlMetaJSONFile), bucket, path.Join(object, xlMetaJSONFile))
+		}(index, disk)
+	}
+
+	// Wait for all the routines.
+	wg.Wait()
+
+	// Delete the temporary object.
+	xl.deleteObject(minioMetaTmpBucket, tempObj)
+
+	if !isQuorum(mErrs, xl.writeQuorum) {
+		return "", toObjectErr(errXLWriteQuorum, bucket, object)
+	}
+	return metadata["md5Sum"], nil
+}
+
 // deleteObject - wrapper for delete object, deletes an object from
 // all the disks in parallel, including `xl.json` associated with the
 // object.
@@ -493,12 +841,23 @@ func (xl xlObjects) DeleteObject(bucket, object string) (err error) {
 		return ObjectNotFound{bucket, object}
 	} // else proceed to delete the object.
 
+	// Objects protected by object lock cannot be deleted.
+	if err := xl.checkObjectLock(bucket, object); err != nil {
+		return err
+	}
+
+	// Save object size for bucket quota accounting.
+	size, objects := xl.objectUsage(bucket, object)
+
 	// Delete the object on all disks.
 	err = xl.deleteObject(bucket, object)
 	if err != nil {
 		return toObjectErr(err, bucket, object)
 	}
 
+	// Release the deleted object from bucket quota.
+	globalBucketQuotas.update(bucket, -size, -objects)
+
 	// Success.
 	return nil
 }
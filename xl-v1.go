@@ -113,13 +113,17 @@ func newXLObjects(disks []string) (ObjectLayer, error) {
 	// Bootstrap disks.
 	storageDisks := make([]StorageAPI, len(disks))
 	for index, disk := range disks {
-		var err error
 		// Intentionally ignore disk not found errors. XL will
 		// manage such errors internally.
-		storageDisks[index], err = newStorageAPI(disk)
+		storage, err := newStorageAPI(disk)
 		if err != nil && err != errDiskNotFound {
 			return nil, err
 		}
+		if storage != nil {
+			// Track health of the disk, so that faulty disks are
+			// skipped until they recover.
+			storageDisks[index] = newHealthStorage(disk, storage)
+		}
 	}
 
 	// Attempt to load all `format.json`.
@@ -165,6 +169,10 @@ func newXLObjects(disks []string) (ObjectLayer, error) {
 		return nil, fmt.Errorf("Unable to recognize backend format, %s", err)
 	}
 
+	// Track disks missing at startup, so that they are used once
+	// they come back.
+	fillMissingDisks(disks, storageDisks, newPosixDisks)
+
 	// Calculate data and parity blocks.
 	dataBlocks, parityBlocks := len(newPosixDisks)/2, len(newPosixDisks)/2
 
@@ -186,6 +194,50 @@ func newXLObjects(disks []string) (ObjectLayer, error) {
 	return xl, nil
 }
 
+// fillMissingDisks - fills the slots of disks missing at startup in
+// the ordered disks with offline disks, which come online once one of
+// the missing disks is back with the format of the slot.
+func fillMissingDisks(disks []string, storageDisks, orderedDisks []StorageAPI) {
+	var jbod []string
+	for _, disk := range orderedDisks {
+		if disk == nil {
+			continue
+		}
+		format, err := loadFormat(disk)
+		if err != nil {
+			continue
+		}
+		jbod = format.XL.JBOD
+		break
+	}
+	if len(jbod) != len(orderedDisks) {
+		return
+	}
+
+	// Disks which are not in the ordered disks are missing.
+	var missingPaths []string
+	for index, storage := range storageDisks {
+		found := false
+		for _, disk := range orderedDisks {
+			if storage != nil && disk == storage {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missingPaths = append(missingPaths, disks[index])
+		}
+	}
+
+	for index, disk := range orderedDisks {
+		if disk == nil {
+			hDisk := newMissingHealthStorage(missingPaths, jbod[index])
+			go hDisk.probe()
+			orderedDisks[index] = hDisk
+		}
+	}
+}
+
 // byDiskTotal is a collection satisfying sort.Interface.
 type byDiskTotal []disk.Info
 
@@ -207,6 +259,17 @@ func (xl xlObjects) StorageInfo() StorageInfo {
 		disksInfo = append(disksInfo, info)
 	}
 
+	// Health of each disk.
+	var disksHealth []DiskHealth
+	for _, storageDisk := range xl.storageDisks {
+		if hDisk, ok := storageDisk.(*healthStorage); ok {
+			disksHealth = append(disksHealth, hDisk.healthInfo())
+		}
+	}
+	if len(disksInfo) == 0 {
+		return StorageInfo{Disks: disksHealth}
+	}
+
 	// Sort so that the first element is the smallest.
 	sort.Sort(byDiskTotal(disksInfo))
 
@@ -216,5 +279,6 @@ func (xl xlObjects) StorageInfo() StorageInfo {
 	return StorageInfo{
 		Total: disksInfo[0].Total * int64(len(xl.storageDisks)),
 		Free:  disksInfo[0].Free * int64(len(xl.storageDisks)),
+		Disks: disksHealth,
 	}
 }